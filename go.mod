@@ -2,4 +2,4 @@ module github.com/zishida/tamo
 
 go 1.21
 
-require github.com/google/uuid v1.6.0 // indirect
+require github.com/google/uuid v1.6.0
@@ -2,37 +2,95 @@ package cli
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/zishida/tamo/internal/config"
 	"github.com/zishida/tamo/internal/model"
 	"github.com/zishida/tamo/internal/storage"
 	"github.com/zishida/tamo/internal/utils"
+	"github.com/zishida/tamo/internal/version"
 )
 
 // Command represents a CLI command
 type Command struct {
 	Name        string
 	Description string
+	Category    string
+	Usage       string   // Usage lines and options, shown by 'help <command>'
+	Examples    []string // Example command lines, shown by 'help <command>'
 	Execute     func(args []string) error
 }
 
+// Command categories, in the order help lists them
+const (
+	categoryItems   = "Tasks and memos"
+	categoryTasks   = "Tasks"
+	categoryMemos   = "Memos"
+	categoryStore   = "Store"
+	categoryGeneral = "General"
+)
+
+var commandCategories = []string{categoryItems, categoryTasks, categoryMemos, categoryStore, categoryGeneral}
+
+// Exit codes
+const (
+	exitOK             = 0
+	exitError          = 1 // Any other error
+	exitNotInitialized = 2 // The data file doesn't exist
+	exitNotFound       = 3 // An ID or name matches no item, or an ID prefix matches several
+)
+
+// ExitError is an error that makes tamo exit with a specific status code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // CLI represents the command-line interface
 type CLI struct {
 	commands map[string]Command
+	dir      string
+	noBackup bool
+	quiet    bool
+	color    string
+
+	// configWarned is set once the problems of the configuration file have been reported
+	configWarned bool
 }
 
 // NewCLI creates a new CLI
 func NewCLI() *CLI {
 	cli := &CLI{
 		commands: make(map[string]Command),
+		dir:      os.Getenv("TAMO_DIR"),
+		noBackup: os.Getenv("TAMO_NO_BACKUP") == "1",
 	}
 
 	// Register commands
@@ -47,145 +105,912 @@ func (c *CLI) registerCommands() {
 	c.commands["init"] = Command{
 		Name:        "init",
 		Description: "Initialize tamo in the current directory",
-		Execute:     c.executeInit,
+		Category:    categoryStore,
+		Usage:       `tamo init`,
+		Examples: []string{
+			"tamo init",
+			"tamo --dir ~/notes init",
+		},
+		Execute: c.executeInit,
 	}
 
 	// Register help command
 	c.commands["help"] = Command{
 		Name:        "help",
 		Description: "Show help information",
-		Execute:     c.executeHelp,
+		Category:    categoryGeneral,
+		Usage:       `tamo help [<command>]`,
+		Examples: []string{
+			"tamo help",
+			"tamo help mv",
+		},
+		Execute: c.executeHelp,
 	}
 
 	// Register add commands
 	c.commands["add"] = Command{
 		Name:        "add",
 		Description: "Add a new task or memo",
-		Execute:     c.executeAdd,
+		Category:    categoryItems,
+		Usage: `tamo add task "<title>" [-d "<description>"] [-m <memo_id>,...] [--tag <tag>]... [--priority <priority>] [--top]
+              [--related <task_id>]... [--bidirectional] [--depends-on <task_id>]... [--auto-suffix] [--parent <task_id>]
+tamo add task -f <filepath> | --from-stdin [--h2-as-subtasks]
+tamo add task --interactive ["<title>"]
+tamo add tasks -f <filepath> | --from-stdin [--h1-as-prefix]
+tamo add memo [<title>] [-c "<content>" | --from-stdin | --editor] [--to-task|--task <task_id>]...
+
+Options of add task:
+  -d <description>    Task description
+  -m <memo_id>,...    Comma-separated list of memo IDs to reference
+  --tag <tag>         Tag for the task (can be repeated or comma-separated)
+  --priority <p>      Task priority
+  --like-last-tag <t> Copy tags, priority, and description from the latest task tagged <t>
+  --top               Put the task at the top of the list
+  --depends-on <id>   ID of a task this task depends on (can be repeated)
+  --parent <task_id>  Add the task as a subtask of the given task
+  -f <filepath>       Create the task from a Markdown file
+
+Options of add memo:
+  -c <content>        Memo content
+  --from-stdin        Read the content from stdin
+  --editor            Write the content in $EDITOR
+  --task <task_id>    Reference the memo from the task (can be repeated)`,
+		Examples: []string{
+			"tamo add task \"Write report\" --tag work",
+			"tamo add memo \"API notes\" -c \"Rate limit: 100/min\" --task 1a2b",
+		},
+		Execute: c.executeAdd,
 	}
 
 	// Register push command (alias for add task with order at end)
 	c.commands["push"] = Command{
 		Name:        "push",
 		Description: "Add a new task at the end of the list",
-		Execute:     c.executePush,
+		Category:    categoryTasks,
+		Usage:       `tamo push task "<title>" [flags of add task]`,
+		Examples: []string{
+			"tamo push task \"Deploy\"",
+		},
+		Execute: c.executePush,
 	}
 
 	// Register unshift command (alias for add task with order at beginning)
 	c.commands["unshift"] = Command{
 		Name:        "unshift",
 		Description: "Add a new task at the beginning of the list",
-		Execute:     c.executeUnshift,
+		Category:    categoryTasks,
+		Usage:       `tamo unshift task "<title>" [flags of add task]`,
+		Examples: []string{
+			"tamo unshift task \"Fix the build\"",
+		},
+		Execute: c.executeUnshift,
 	}
 
 	// Register list command
 	c.commands["list"] = Command{
 		Name:        "list",
 		Description: "List tasks and/or memos",
-		Execute:     c.executeList,
+		Category:    categoryItems,
+		Usage: `tamo list [tasks|memos|all] [--done|--undone] [--refs <memo_id>] [--timeline] [--limit <n>] [--check-refs]
+          [--show-gaps [--gap-threshold <n>]] [--orphans] [--no-hidden-count] [--show-tags]
+          [--pending-first] [--sort order|created|updated|title|usage] [--reverse] [--duplicate-titles]
+          [--inline-tag <tag>] [--show-full-id] [--stale <days>] [--hide-done-except-recent <duration>]
+tamo list --activity <days> [--activity-completed]
+
+Options:
+  --done, --undone    Show only completed or uncompleted tasks
+  --refs <memo_id>    Show only tasks referencing the memo
+  --timeline          Show tasks and memos mixed, most recently updated first
+  --limit <n>         Show at most n items
+  --show-tags         Show the tags of each task as badges
+  --pending-first     Show uncompleted tasks before completed ones
+  --sort <key>        Sort by order, created, updated, title, or usage (memos only)
+  --reverse           Show the items in reverse order, or by the --sort key descending
+  --stale <days>      Show only uncompleted tasks created at least this many days ago
+  --activity <days>   Show the number of tasks created each day as a sparkline`,
+		Examples: []string{
+			"tamo list --undone",
+			"tamo list memos --orphans",
+		},
+		Execute: c.executeList,
 	}
 
 	// Register show command
 	c.commands["show"] = Command{
 		Name:        "show",
 		Description: "Show details of a task or memo",
-		Execute:     c.executeShow,
+		Category:    categoryItems,
+		Usage: `tamo show <id> [--sort-memos created|title] [--render] [--stats] [--with-memos] [--no-hints]
+tamo show [--pick] [flags]
+
+Options:
+  --sort-memos <key>  Sort referenced memos by created or title
+  --render            Mark fenced code blocks with a vertical bar
+  --stats             Show the length and estimated reading time
+  --with-memos        Show the full content of the referenced memos
+  --no-hints          Don't mark memos updated after the task
+  --pick              Pick the task from a numbered list`,
+		Examples: []string{
+			"tamo show 1a2b",
+			"tamo show %1 --with-memos",
+		},
+		Execute: c.executeShow,
 	}
 
 	// Register remove command
 	c.commands["rm"] = Command{
 		Name:        "rm",
 		Description: "Remove a task or memo",
-		Execute:     c.executeRemove,
+		Category:    categoryItems,
+		Usage: `tamo rm <id>... [-f|--force] [--show-content] [--cascade]
+tamo rm [--pick] [-f|--force]
+
+Options:
+  -f, --force         Remove without confirmation, even if the items are referenced
+  --show-content      Show the whole content of memos to remove
+  --cascade           Also remove the subtasks of the tasks
+  --pick              Pick the task from a numbered list`,
+		Examples: []string{
+			"tamo rm 1a2b",
+			"tamo rm %3 --cascade -f",
+		},
+		Execute: c.executeRemove,
 	}
 
 	// Register edit command
 	c.commands["edit"] = Command{
 		Name:        "edit",
 		Description: "Edit a task or memo",
-		Execute:     c.executeEdit,
+		Category:    categoryItems,
+		Usage: `tamo edit <id>... [--editor]
+tamo edit <id>... [--title "<title>"] [--description "<description>"] [--content "<content>"]
+                  [--add-memo <memo_id>]... [--remove-memo <memo_id>]... [--done|--undone]
+                  [--add-depends-on <task_id>]... [--remove-depends-on <task_id>]... [--archive|--unarchive]
+tamo edit [--pick] [flags]
+
+Options:
+  --editor            Edit in $EDITOR (the default without other flags)
+  --title <title>     Set the title of the task or memo
+  -d, --description   Set the description of the task
+  -c, --content       Set the content of the memo
+  --add-memo <id>     Add a memo reference to the task (can be repeated)
+  --remove-memo <id>  Remove a memo reference from the task (can be repeated)
+  --done, --undone    Mark the task as done or not done
+  --archive           Archive the memo (--unarchive to undo)`,
+		Examples: []string{
+			"tamo edit 1a2b",
+			"tamo edit 1a2b --title \"New title\"",
+		},
+		Execute: c.executeEdit,
+	}
+
+	// Register attach command
+	c.commands["attach"] = Command{
+		Name:        "attach",
+		Description: "Add memo references to a task",
+		Category:    categoryTasks,
+		Usage: `tamo attach <task_id> <memo_id>... [--include-archived]
+
+Options:
+  --include-archived  Reference archived memos without asking`,
+		Examples: []string{
+			"tamo attach 1a2b 3c4d 5e6f",
+		},
+		Execute: c.executeAttach,
+	}
+
+	// Register detach command
+	c.commands["detach"] = Command{
+		Name:        "detach",
+		Description: "Remove memo references from a task",
+		Category:    categoryTasks,
+		Usage: `tamo detach <task_id> <memo_id>...
+tamo detach <task_id> --all
+
+Options:
+  --all               Remove all memo references`,
+		Examples: []string{
+			"tamo detach 1a2b 3c4d",
+		},
+		Execute: c.executeDetach,
+	}
+
+	// Register block command
+	c.commands["block"] = Command{
+		Name:        "block",
+		Description: "Record that a task is blocked by other tasks",
+		Category:    categoryTasks,
+		Usage: `tamo block <task_id> --by <other_task_id>...
+
+Blockers are the same as dependencies: block adds to the tasks the task
+depends on, like 'edit <task_id> --add-depends-on'.
+
+Options:
+  --by <task_id>      A task that blocks the task (can be repeated)`,
+		Examples: []string{
+			"tamo block 1a2b --by 3c4d",
+		},
+		Execute: c.executeBlock,
+	}
+
+	// Register unblock command
+	c.commands["unblock"] = Command{
+		Name:        "unblock",
+		Description: "Remove the tasks blocking a task",
+		Category:    categoryTasks,
+		Usage: `tamo unblock <task_id> --by <other_task_id>...
+tamo unblock <task_id> --all
+
+Options:
+  --by <task_id>      A blocking task to remove (can be repeated)
+  --all               Remove all blocking tasks`,
+		Examples: []string{
+			"tamo unblock 1a2b --all",
+		},
+		Execute: c.executeUnblock,
+	}
+
+	// Register append command
+	c.commands["append"] = Command{
+		Name:        "append",
+		Description: "Append text to a memo or task",
+		Category:    categoryItems,
+		Usage: `tamo append <id> "<text>" [--timestamp]
+tamo append <id> --from-stdin [--timestamp]
+
+Options:
+  --from-stdin        Read the text to append from stdin
+  --timestamp         Prefix the text with the current time`,
+		Examples: []string{
+			"tamo append 1a2b \"Asked the vendor\" --timestamp",
+		},
+		Execute: c.executeAppend,
 	}
 
 	// Register done command
 	c.commands["done"] = Command{
 		Name:        "done",
 		Description: "Mark a task as done",
-		Execute:     c.executeDone,
+		Category:    categoryTasks,
+		Usage: `tamo done <task_id>... [--strict]
+tamo done [--pick] [--strict]
+
+Options:
+  --strict            Refuse to mark tasks that depend on incomplete tasks as done
+  --pick              Pick the task from a numbered list`,
+		Examples: []string{
+			"tamo done 1a2b",
+			"tamo done %1 %2",
+		},
+		Execute: c.executeDone,
 	}
 
 	// Register undone command
 	c.commands["undone"] = Command{
 		Name:        "undone",
 		Description: "Mark a task as not done",
-		Execute:     c.executeUndone,
+		Category:    categoryTasks,
+		Usage:       `tamo undone <task_id>...`,
+		Examples: []string{
+			"tamo undone 1a2b",
+		},
+		Execute: c.executeUndone,
 	}
 
 	// Register move command
 	c.commands["mv"] = Command{
 		Name:        "mv",
 		Description: "Move a task to a specific order or relative to another task",
-		Execute:     c.executeMove,
+		Category:    categoryTasks,
+		Usage: `tamo mv <task_id> --order <order>
+tamo mv <task_id> before|after <other_task_id>
+tamo mv <task_id> top|bottom (or first|last)
+tamo mv <task_id> under <parent_task_id>|none
+
+The order may also be given without --order, as in 'tamo mv <task_id> <order>'.`,
+		Examples: []string{
+			"tamo mv 1a2b after 3c4d",
+			"tamo mv %5 top",
+			"tamo mv 1a2b under 3c4d",
+		},
+		Execute: c.executeMove,
 	}
 
 	// Register pop command
 	c.commands["pop"] = Command{
 		Name:        "pop",
 		Description: "Show, mark as done, or remove the last task",
-		Execute:     c.executePop,
+		Category:    categoryTasks,
+		Usage: `tamo pop task [--undone] [--done | --rm [-f]]
+
+Options:
+  --undone            Only consider undone tasks
+  --done              Mark the last task as done
+  --rm                Remove the last task
+  -f                  Remove without confirmation`,
+		Examples: []string{
+			"tamo pop task --rm",
+		},
+		Execute: c.executePop,
 	}
 
 	// Register shift command
 	c.commands["shift"] = Command{
 		Name:        "shift",
 		Description: "Show, mark as done, or remove the first task",
-		Execute:     c.executeShift,
+		Category:    categoryTasks,
+		Usage: `tamo shift task [--undone [--include-blocked]] [--done | --rm [-f]]
+
+Options:
+  --undone            Only consider undone tasks that are not blocked
+  --include-blocked   With --undone, also consider blocked tasks
+  --done              Mark the first task as done
+  --rm                Remove the first task
+  -f                  Remove without confirmation`,
+		Examples: []string{
+			"tamo shift task --undone --done",
+		},
+		Execute: c.executeShift,
 	}
 
 	// Register next command (alias for shift task)
 	c.commands["next"] = Command{
 		Name:        "next",
 		Description: "Show the first undone task",
-		Execute:     c.executeNext,
+		Category:    categoryTasks,
+		Usage: `tamo next [-n <n> | --count <n> | --compact | --done | --rm [-f]] [--include-blocked]
+
+Options:
+  -n <n>              Show the first n undone tasks as a compact list
+  --count <n>         Show the first n undone tasks with their descriptions
+  --compact           Print only the short ID and title
+  --done              Mark the next undone task as done
+  --rm                Remove the next undone task
+  --include-blocked   Don't skip tasks blocked by undone tasks`,
+		Examples: []string{
+			"tamo next",
+			"tamo next -n 5",
+		},
+		Execute: c.executeNext,
 	}
 
 	// Register flattask command
 	c.commands["flattask"] = Command{
 		Name:        "flattask",
 		Description: "Flatten a task by expanding all memo references",
-		Execute:     c.executeFlattask,
+		Category:    categoryTasks,
+		Usage: `tamo flattask <task_id>... [-o <file>]
+tamo flattask --all-undone [-o <file>]
+
+Options:
+  --all-undone        Flatten every undone task into one document
+  -o <file>           Write the document to the file instead of stdout`,
+		Examples: []string{
+			"tamo flattask 1a2b | pbcopy",
+		},
+		Execute: c.executeFlattask,
+	}
+
+	// Register reorder command
+	c.commands["reorder"] = Command{
+		Name:        "reorder",
+		Description: "Reassign task orders sorted by creation time, update time, or title",
+		Category:    categoryTasks,
+		Usage: `tamo reorder --by created|updated|title [--yes]
+
+Options:
+  --by <key>          Sort key: created, updated, or title
+  --yes               Reorder without confirmation`,
+		Examples: []string{
+			"tamo reorder --by created",
+		},
+		Execute: c.executeReorder,
+	}
+
+	// Register swap command
+	c.commands["swap"] = Command{
+		Name:        "swap",
+		Description: "Exchange the orders of two tasks",
+		Category:    categoryTasks,
+		Usage:       `tamo swap <task_id> <other_task_id>`,
+		Examples: []string{
+			"tamo swap %1 %2",
+		},
+		Execute: c.executeSwap,
+	}
+
+	// Register cp command
+	c.commands["cp"] = Command{
+		Name:        "cp",
+		Description: "Copy a task to the end of the list",
+		Category:    categoryTasks,
+		Usage: `tamo cp <task_id> ["<new title>"] [--deep]
+
+Options:
+  --deep              Also copy the referenced memos, and reference the copies`,
+		Examples: []string{
+			"tamo cp 1a2b \"Release v2\"",
+		},
+		Execute: c.executeCopy,
+	}
+
+	// Register renumber command
+	c.commands["renumber"] = Command{
+		Name:        "renumber",
+		Description: "Reassign task orders 1.0, 2.0, ... keeping the current order",
+		Category:    categoryTasks,
+		Usage:       `tamo renumber`,
+		Examples: []string{
+			"tamo renumber",
+		},
+		Execute: c.executeRenumber,
+	}
+
+	// Register archive command
+	c.commands["archive"] = Command{
+		Name:        "archive",
+		Description: "Move completed tasks out of the task list",
+		Category:    categoryTasks,
+		Usage: `tamo archive [--completed-before <date>] [-f|--force]
+
+Options:
+  --completed-before <date>  Archive only tasks completed before the date (YYYY-MM-DD)
+  -f, --force         Archive without confirmation`,
+		Examples: []string{
+			"tamo archive --completed-before 2024-01-01",
+		},
+		Execute: c.executeArchive,
+	}
+
+	// Register backup command
+	c.commands["backup"] = Command{
+		Name:        "backup",
+		Description: "List or restore backups of the data file",
+		Category:    categoryStore,
+		Usage: `tamo backup list
+tamo backup restore <timestamp>`,
+		Examples: []string{
+			"tamo backup list",
+		},
+		Execute: c.executeBackup,
+	}
+
+	// Register template command
+	c.commands["template"] = Command{
+		Name:        "template",
+		Description: "Save tasks as templates and create tasks from them",
+		Category:    categoryTasks,
+		Usage: `tamo template save <task_id> <name> [--own-memos] [-f|--force]
+tamo template list
+tamo template use <name> [--title "<title>"]
+tamo template rm <name>`,
+		Examples: []string{
+			"tamo template save 1a2b release",
+			"tamo template use release --title \"Release v3\"",
+		},
+		Execute: c.executeTemplate,
+	}
+
+	// Register trash command
+	c.commands["trash"] = Command{
+		Name:        "trash",
+		Description: "List or empty removed tasks and memos",
+		Category:    categoryStore,
+		Usage: `tamo trash list
+tamo trash empty [-f|--force]`,
+		Examples: []string{
+			"tamo trash list",
+		},
+		Execute: c.executeTrash,
+	}
+
+	// Register restore command
+	c.commands["restore"] = Command{
+		Name:        "restore",
+		Description: "Restore a removed task or memo from the trash",
+		Category:    categoryStore,
+		Usage:       `tamo restore <id>`,
+		Examples: []string{
+			"tamo restore 1a2b",
+		},
+		Execute: c.executeRestore,
+	}
+
+	// Register gc command
+	c.commands["gc"] = Command{
+		Name:        "gc",
+		Description: "Remove memos that no task references",
+		Category:    categoryMemos,
+		Usage: `tamo gc --memos [-f|--force]
+
+Options:
+  --memos             Remove memos that no task references
+  -f, --force         Remove without confirmation`,
+		Examples: []string{
+			"tamo gc --memos",
+		},
+		Execute: c.executeGC,
+	}
+
+	// Register export command
+	c.commands["export"] = Command{
+		Name:        "export",
+		Description: "Export tasks and memos as JSON or Markdown",
+		Category:    categoryStore,
+		Usage: `tamo export [--format json|markdown] [-o <file>] [--done|--undone] [--tasks|--memos]
+
+Options:
+  --format <format>   Output format: json (default) or markdown
+  -o <file>           Write to the file instead of stdout
+  --done, --undone    Export only completed or uncompleted tasks
+  --tasks, --memos    Export only tasks or memos`,
+		Examples: []string{
+			"tamo export --format markdown -o tasks.md",
+		},
+		Execute: c.executeExport,
+	}
+
+	// Register import command
+	c.commands["import"] = Command{
+		Name:        "import",
+		Description: "Merge tasks and memos from a data or export JSON file",
+		Category:    categoryStore,
+		Usage: `tamo import <file.json> [--overwrite] [--keep-order]
+
+Options:
+  --overwrite         Overwrite existing items when the imported item is newer
+  --keep-order        Keep the order values of imported tasks`,
+		Examples: []string{
+			"tamo import backup.json",
+		},
+		Execute: c.executeImport,
+	}
+
+	// Register search command
+	c.commands["search"] = Command{
+		Name:        "search",
+		Description: "Search tasks and memos for a keyword",
+		Category:    categoryItems,
+		Usage: `tamo search "<keyword>" [-i|--ignore-case] [--highlight]
+
+Options:
+  -i, --ignore-case   Ignore case when matching
+  --highlight         Highlight the matches`,
+		Examples: []string{
+			"tamo search -i timeout",
+		},
+		Execute: c.executeSearch,
+	}
+
+	// Register reindex command
+	c.commands["reindex"] = Command{
+		Name:        "reindex",
+		Description: "Rebuild the search index",
+		Category:    categoryStore,
+		Usage:       `tamo reindex`,
+		Examples: []string{
+			"tamo reindex",
+		},
+		Execute: c.executeReindex,
+	}
+
+	// Register config command
+	c.commands["config"] = Command{
+		Name:        "config",
+		Description: "Show or change settings",
+		Category:    categoryStore,
+		Usage: `tamo config [list]
+tamo config get <key>
+tamo config set <key> <value>
+tamo config unset <key>`,
+		Examples: []string{
+			"tamo config set list.pending_first true",
+			"tamo config set editor \"code --wait\"",
+		},
+		Execute: c.executeConfig,
+	}
+
+	// Register doctor command
+	c.commands["doctor"] = Command{
+		Name:        "doctor",
+		Description: "Check the data file for problems and repair them",
+		Category:    categoryStore,
+		Usage: `tamo doctor [--fix]
+
+Options:
+  --fix               Repair the problems that can be repaired safely, after writing a backup`,
+		Examples: []string{
+			"tamo doctor --fix",
+		},
+		Execute: c.executeDoctor,
+	}
+
+	// Register stats command
+	c.commands["stats"] = Command{
+		Name:        "stats",
+		Description: "Show a summary of tasks and memos",
+		Category:    categoryStore,
+		Usage: `tamo stats [--json]
+
+Options:
+  --json              Output statistics as JSON`,
+		Examples: []string{
+			"tamo stats",
+		},
+		Execute: c.executeStats,
+	}
+
+	// Register status command
+	c.commands["status"] = Command{
+		Name:        "status",
+		Description: "Show which data file is used and an overview of it",
+		Category:    categoryStore,
+		Usage: `tamo status [--json]
+
+Options:
+  --json              Output the status as JSON`,
+		Examples: []string{
+			"tamo status",
+		},
+		Execute: c.executeStatus,
+	}
+
+	// Register count command
+	c.commands["count"] = Command{
+		Name:        "count",
+		Description: "Print the number of tasks or memos",
+		Category:    categoryItems,
+		Usage: `tamo count [--done|--undone] [--tag <tag>] [--overdue]
+tamo count --memos
+
+Options:
+  --done, --undone    Count only completed or uncompleted tasks
+  --tag <tag>         Count only tasks with the tag
+  --memos             Count memos instead of tasks`,
+		Examples: []string{
+			"tamo count --undone",
+		},
+		Execute: c.executeCount,
+	}
+
+	// Register completion command
+	c.commands["completion"] = Command{
+		Name:        "completion",
+		Description: "Print a shell completion script (bash, zsh, or fish)",
+		Category:    categoryGeneral,
+		Usage:       `tamo completion bash|zsh|fish`,
+		Examples: []string{
+			"source <(tamo completion bash)",
+			"tamo completion fish | source",
+		},
+		Execute: c.executeCompletion,
+	}
+
+	// Register version command
+	c.commands["version"] = Command{
+		Name:        "version",
+		Description: "Show the version of tamo and of its data format",
+		Category:    categoryGeneral,
+		Usage: `tamo version
+tamo --version`,
+		Examples: []string{
+			"tamo version",
+		},
+		Execute: c.executeVersion,
 	}
 }
 
-// Execute executes the CLI with the given arguments
-func Execute() error {
-	cli := NewCLI()
+// Run executes the CLI with the given arguments, prints the error if any, and returns the exit code
+func Run(args []string) int {
+	err := NewCLI().run(args)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		// The usage requested with -h has been printed by the flag set
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return exitCode(err)
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var exitErr *ExitError
+	var notFound *notFoundError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.Is(err, storage.ErrNotInitialized):
+		return exitNotInitialized
+	case errors.As(err, &notFound), errors.Is(err, errAmbiguousID):
+		return exitNotFound
+	default:
+		return exitError
+	}
+}
+
+// unknownCommandError returns the error for a command name that is not registered,
+// suggesting the closest command name
+func (c *CLI) unknownCommandError(name string) error {
+	closest := ""
+	closestDistance := 0
+	for _, candidate := range c.sortedCommandNames() {
+		distance := utils.EditDistance(name, candidate)
+		if closest == "" || distance < closestDistance {
+			closest, closestDistance = candidate, distance
+		}
+	}
+	if closest != "" && closestDistance <= max(1, len(name)/3) {
+		return fmt.Errorf("unknown command '%s'. Did you mean '%s'?", name, closest)
+	}
+	return fmt.Errorf("unknown command '%s'. Run 'tamo help' for the list of commands", name)
+}
+
+// sortedCommandNames returns the names of the registered commands in alphabetical order
+func (c *CLI) sortedCommandNames() []string {
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// run parses the global options and executes the command named in args
+func (c *CLI) run(args []string) error {
+	// Parse global options given before the command name
+globalOptions:
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--dir":
+			if len(args) < 2 || args[1] == "" {
+				return fmt.Errorf("--dir requires a path")
+			}
+			c.dir = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--dir="):
+			c.dir = strings.TrimPrefix(args[0], "--dir=")
+			if c.dir == "" {
+				return fmt.Errorf("--dir requires a path")
+			}
+			args = args[1:]
+		case args[0] == "--no-backup":
+			c.noBackup = true
+			args = args[1:]
+		case args[0] == "--quiet":
+			c.quiet = true
+			args = args[1:]
+		case args[0] == "--color":
+			if len(args) < 2 {
+				return fmt.Errorf("--color requires a mode: always, never, or auto")
+			}
+			mode, err := parseColorMode(args[1])
+			if err != nil {
+				return err
+			}
+			c.color = mode
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--color="):
+			mode, err := parseColorMode(strings.TrimPrefix(args[0], "--color="))
+			if err != nil {
+				return err
+			}
+			c.color = mode
+			args = args[1:]
+		default:
+			break globalOptions
+		}
+	}
 
 	// If no arguments, show help
-	if len(os.Args) < 2 {
-		return cli.executeHelp([]string{})
+	if len(args) < 1 {
+		return c.executeHelp([]string{})
 	}
 
 	// Get command name
-	cmdName := os.Args[1]
+	cmdName := args[0]
+
+	// Show the version for --version, as most tools do
+	if cmdName == "--version" {
+		return c.executeVersion(args[1:])
+	}
+
+	// List completion candidates for the completion scripts. The command is not registered, to keep it out of help.
+	if cmdName == completeCommand {
+		return c.executeComplete(args[1:])
+	}
 
 	// Find command
-	cmd, ok := cli.commands[cmdName]
+	cmd, ok := c.commands[cmdName]
 	if !ok {
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmdName)
-		return cli.executeHelp([]string{})
+		return c.unknownCommandError(cmdName)
 	}
 
 	// Execute command
-	return cmd.Execute(os.Args[2:])
+	return cmd.Execute(args[1:])
+}
+
+// printf prints a message telling what a command did, unless --quiet is given.
+// Prompts, warnings, and other messages that are not the requested output go to stderr instead.
+func (c *CLI) printf(format string, a ...any) {
+	if !c.quiet {
+		fmt.Printf(format, a...)
+	}
+}
+
+// printAddedID prints the ID of an added item after the message, or only the ID if --quiet is given
+func (c *CLI) printAddedID(message, id string) {
+	if c.quiet {
+		fmt.Println(id)
+		return
+	}
+	fmt.Printf("%s: %s\n", message, id)
+}
+
+// loadConfig loads the configuration file in the data directory, warning once about unknown keys and invalid values
+func (c *CLI) loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(c.newStorage().DirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !c.configWarned {
+		for _, warning := range cfg.Warnings() {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		c.configWarned = true
+	}
+	return cfg, nil
+}
+
+// configValue returns a setting, or its default if the configuration file can't be read.
+// Commands whose main settings come from the file load it with loadConfig to report the error instead.
+func (c *CLI) configValue(name string) string {
+	cfg, err := c.loadConfig()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	return cfg.Get(name)
+}
+
+// removalNeedsConfirmation reports whether removing tasks and memos asks first, as set with rm.confirm
+func (c *CLI) removalNeedsConfirmation() bool {
+	return c.configValue("rm.confirm") == "true"
+}
+
+// dateLayouts are the time layouts of the date_format setting
+var dateLayouts = map[string]string{
+	"datetime": "2006-01-02 15:04:05",
+	"date":     "2006-01-02",
+	"rfc3339":  time.RFC3339,
+}
+
+// formatTime formats a time shown with the details of a task or memo, as set with date_format
+func (c *CLI) formatTime(t time.Time) string {
+	layout, ok := dateLayouts[c.configValue("date_format")]
+	if !ok {
+		layout = dateLayouts["datetime"]
+	}
+	return t.Format(layout)
+}
+
+// newStorage returns the storage for the data directory given with --dir or TAMO_DIR,
+// falling back to .tamo in the current directory
+func (c *CLI) newStorage() *storage.Storage {
+	s := storage.NewStorage()
+	if c.dir != "" {
+		s = storage.NewStorageWithPath(c.dir, filepath.Join(c.dir, storage.DefaultFileName))
+	}
+	s.NoBackup = c.noBackup
+	return s
 }
 
 // executeInit initializes tamo in the current directory
 func (c *CLI) executeInit(args []string) error {
 	// Parse flags
-	initCmd := flag.NewFlagSet("init", flag.ExitOnError)
+	initCmd := flag.NewFlagSet("init", flag.ContinueOnError)
 	initCmd.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: tamo init\n\n")
-		fmt.Fprintf(os.Stderr, "Initialize tamo in the current directory\n\n")
+		fmt.Fprintf(os.Stderr, "Initialize tamo in the current directory, or in the directory given with --dir or TAMO_DIR\n\n")
 		initCmd.PrintDefaults()
 	}
 
@@ -194,7 +1019,7 @@ func (c *CLI) executeInit(args []string) error {
 	}
 
 	// Create storage
-	s := storage.NewStorage()
+	s := c.newStorage()
 
 	// Check if already initialized
 	if s.Exists() {
@@ -206,17 +1031,54 @@ func (c *CLI) executeInit(args []string) error {
 	if err := s.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize tamo: %w", err)
 	}
+	if err := config.Create(s.DirPath); err != nil {
+		return fmt.Errorf("failed to initialize tamo: %w", err)
+	}
+
+	c.printf("tamo initialized successfully\n")
+	return nil
+}
+
+// executeVersion handles the 'version' command
+func (c *CLI) executeVersion(args []string) error {
+	versionCmd := flag.NewFlagSet("version", flag.ContinueOnError)
+	versionCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo version\n\n")
+		fmt.Fprintf(os.Stderr, "Show the version, commit, and build date of tamo, and the version of the data format it writes\n\n")
+	}
+	if err := versionCmd.Parse(args); err != nil {
+		return err
+	}
+	if versionCmd.NArg() > 0 {
+		versionCmd.Usage()
+		return fmt.Errorf("unexpected argument: %s", versionCmd.Arg(0))
+	}
 
-	fmt.Println("tamo initialized successfully")
+	info := version.Get()
+	fmt.Printf("tamo %s\n", info.Version)
+	fmt.Printf("%-12s %s\n", "Commit:", info.Commit)
+	fmt.Printf("%-12s %s\n", "Built:", info.Date)
+	fmt.Printf("%-12s %s %s/%s\n", "Go:", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("%-12s %d\n", "Data format:", model.CurrentVersion)
 	return nil
 }
 
 // executeHelp shows help information
 func (c *CLI) executeHelp(args []string) error {
+	if len(args) > 0 {
+		return c.printCommandHelp(args[0])
+	}
+
 	fmt.Println("tamo - Task and Memo Management CLI")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  tamo <command> [arguments]")
+	fmt.Println("  tamo [--dir <path>] [--no-backup] [--quiet] [--color=always|never|auto] <command> [arguments]")
+	fmt.Println()
+	fmt.Println("Global options:")
+	fmt.Println("  --dir <path>  Use <path> as the data directory instead of .tamo (also set with TAMO_DIR)")
+	fmt.Println("  --no-backup   Don't back up the data file before saving (also set with TAMO_NO_BACKUP=1)")
+	fmt.Println("  --quiet       Print only the IDs of added items and no other success messages")
+	fmt.Println("  --color=<m>   Color output always, never, or auto: on a terminal unless NO_COLOR is set (default auto)")
 	fmt.Println()
 	fmt.Println("Available commands:")
 
@@ -228,18 +1090,63 @@ func (c *CLI) executeHelp(args []string) error {
 		}
 	}
 
-	// Print commands
-	for _, cmd := range c.commands {
-		fmt.Printf("  %-*s  %s\n", maxLen, cmd.Name, cmd.Description)
+	// Print commands by category, sorted by name
+	names := c.sortedCommandNames()
+	for _, category := range commandCategories {
+		fmt.Println()
+		fmt.Printf("%s:\n", category)
+		for _, name := range names {
+			if cmd := c.commands[name]; cmd.Category == category {
+				fmt.Printf("  %-*s  %s\n", maxLen, cmd.Name, cmd.Description)
+			}
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Exit codes:")
+	fmt.Printf("  %d  Success\n", exitOK)
+	fmt.Printf("  %d  Error\n", exitError)
+	fmt.Printf("  %d  Not initialized: the data file doesn't exist\n", exitNotInitialized)
+	fmt.Printf("  %d  Not found: an ID or name matches nothing, or an ID prefix matches several items\n", exitNotFound)
+
+	fmt.Println()
+	fmt.Println("Run 'tamo help <command>' for the usage of a command.")
+	return nil
+}
+
+// printCommandHelp prints the description, usage, and examples of a command
+func (c *CLI) printCommandHelp(name string) error {
+	cmd, ok := c.commands[name]
+	if !ok {
+		return c.unknownCommandError(name)
 	}
 
+	fmt.Printf("tamo %s - %s\n", cmd.Name, cmd.Description)
+	if cmd.Usage != "" {
+		fmt.Println()
+		fmt.Println("Usage:")
+		for _, line := range strings.Split(cmd.Usage, "\n") {
+			if line == "" {
+				fmt.Println()
+				continue
+			}
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	if len(cmd.Examples) > 0 {
+		fmt.Println()
+		fmt.Println("Examples:")
+		for _, example := range cmd.Examples {
+			fmt.Printf("  %s\n", example)
+		}
+	}
 	return nil
 }
 
 // executeAdd handles the 'add' command for both tasks and memos
 func (c *CLI) executeAdd(args []string) error {
 	if len(args) == 0 {
-		return fmt.Errorf("missing subcommand: 'task' or 'memo'")
+		return fmt.Errorf("missing subcommand: 'task', 'tasks', or 'memo'")
 	}
 
 	subCmd := args[0]
@@ -248,6 +1155,8 @@ func (c *CLI) executeAdd(args []string) error {
 		return c.executeAddMemo(args[1:])
 	case "task":
 		return c.executeAddTask(args[1:], "add")
+	case "tasks":
+		return c.executeAddTasks(args[1:])
 	default:
 		return fmt.Errorf("unknown subcommand: %s", subCmd)
 	}
@@ -256,32 +1165,59 @@ func (c *CLI) executeAdd(args []string) error {
 // executeAddMemo handles the 'add memo' command
 func (c *CLI) executeAddMemo(args []string) error {
 	// Create flag set
-	memoCmd := flag.NewFlagSet("add memo", flag.ExitOnError)
+	memoCmd := flag.NewFlagSet("add memo", flag.ContinueOnError)
 
 	// Define flags
 	contentFlag := memoCmd.String("c", "", "Memo content")
 	fromStdinFlag := memoCmd.Bool("from-stdin", false, "Read content from stdin")
 	editorFlag := memoCmd.Bool("editor", false, "Open editor to input content")
+	var toTaskFlag stringListFlag
+	memoCmd.Var(&toTaskFlag, "to-task", "Add the memo to the memo references of this task (can be repeated)")
+	memoCmd.Var(&toTaskFlag, "task", "Shorthand for --to-task")
+	var attachFlag stringListFlag
+	memoCmd.Var(&attachFlag, "attach", "Record the `path` of a related file, stored as an absolute path (can be repeated)")
+	checkAttachmentsFlag := memoCmd.Bool("check-attachments", false, "Warn about --attach paths that don't exist")
 
 	// Set usage
 	memoCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo add memo [<title>] [-c \"<content>\" | --from-stdin | --editor]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: tamo add memo [<title>] [-c \"<content>\" | --from-stdin | --editor] [--to-task|--task <task_id>]...\n")
+		fmt.Fprintf(os.Stderr, "                     [--attach <path>]... [--check-attachments]\n\n")
 		fmt.Fprintf(os.Stderr, "Add a new memo\n\n")
 		memoCmd.PrintDefaults()
 	}
 
 	// Parse flags
-	if err := memoCmd.Parse(args); err != nil {
+	positional, err := parseFlagsAnywhere(memoCmd, args)
+	if err != nil {
 		return err
 	}
 
 	// Get title (optional)
 	var title *string
-	if memoCmd.NArg() > 0 {
-		t := memoCmd.Arg(0)
+	if len(positional) > 0 {
+		t := positional[0]
 		title = &t
 	}
 
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Resolve the tasks to link before creating anything
+	var linkedTasks []*model.Task
+	for _, taskID := range toTaskFlag {
+		task, err := resolveTask(store, taskID)
+		if err != nil {
+			return err
+		}
+		if !containsTask(linkedTasks, task) {
+			linkedTasks = append(linkedTasks, task)
+		}
+	}
+
 	// Get content based on flags
 	var content string
 
@@ -306,33 +1242,38 @@ func (c *CLI) executeAddMemo(args []string) error {
 		content = *contentFlag
 	} else if *fromStdinFlag {
 		// Read from stdin
-		scanner := bufio.NewScanner(os.Stdin)
-		var contentBuilder strings.Builder
-		for scanner.Scan() {
-			contentBuilder.WriteString(scanner.Text())
-			contentBuilder.WriteString("\n")
-		}
-		if err := scanner.Err(); err != nil {
+		text, err := readText(os.Stdin)
+		if err != nil {
 			return fmt.Errorf("error reading from stdin: %w", err)
 		}
-		content = contentBuilder.String()
+		content = text
 	} else if *editorFlag {
-		// TODO: Implement editor support
-		return fmt.Errorf("editor support not implemented yet")
+		// Open editor with an empty template
+		template := "# \n\n"
+		if title != nil {
+			template = fmt.Sprintf("# %s\n\n", *title)
+		}
+
+		editedContent, err := c.editInEditor("tamo-memo-*.md", template)
+		if err != nil {
+			return err
+		}
+
+		// Parse edited content
+		title, content = parseMemoEditorContent(editedContent)
+		if content == "" {
+			fmt.Println("Memo content is empty, memo not created")
+			return nil
+		}
 	} else {
 		// Default to simple input if no flag is specified
 		// For now, we'll just use a simple prompt
 		fmt.Println("Enter memo content (press Ctrl+D when finished):")
-		scanner := bufio.NewScanner(os.Stdin)
-		var contentBuilder strings.Builder
-		for scanner.Scan() {
-			contentBuilder.WriteString(scanner.Text())
-			contentBuilder.WriteString("\n")
-		}
-		if err := scanner.Err(); err != nil {
+		text, err := readText(os.Stdin)
+		if err != nil {
 			return fmt.Errorf("error reading content: %w", err)
 		}
-		content = contentBuilder.String()
+		content = text
 	}
 
 	// Generate UUID
@@ -341,70 +1282,201 @@ func (c *CLI) executeAddMemo(args []string) error {
 		return fmt.Errorf("failed to generate UUID: %w", err)
 	}
 
+	// Store attachments as absolute paths, so they can be found from any directory
+	attachments := make([]string, 0, len(attachFlag))
+	for _, path := range attachFlag {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve attachment path %s: %w", path, err)
+		}
+		attachments = append(attachments, absPath)
+	}
+
 	// Create new memo
 	memo := model.NewMemo(id, title, content)
+	if len(attachments) > 0 {
+		memo.Attachments = attachments
+	}
 
-	// Load store
-	s := storage.NewStorage()
-	store, err := s.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load data: %w", err)
+	// Warn about attachments that don't exist, but record them anyway
+	if *checkAttachmentsFlag {
+		for _, path := range memo.Attachments {
+			if _, err := os.Stat(path); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: attachment not found: %s\n", path)
+			}
+		}
 	}
 
 	// Add memo to store
 	store.AddMemo(memo)
 
+	// Link memo to tasks
+	for _, task := range linkedTasks {
+		if store.AddMemoRef(task, memo.ID) {
+			task.Touch()
+		}
+	}
+
 	// Save store
 	if err := s.Save(store); err != nil {
 		return fmt.Errorf("failed to save data: %w", err)
 	}
 
-	fmt.Printf("Memo added with ID: %s\n", id)
+	c.printAddedID("Memo added with ID", id)
+	if len(linkedTasks) > 0 {
+		c.printf("Linked to tasks:\n")
+		for _, task := range linkedTasks {
+			c.printf("  %s  %s\n", task.ID[:8], task.Title)
+		}
+	}
 	return nil
 }
 
 // executeAddTask handles the 'add task' command
 func (c *CLI) executeAddTask(args []string, mode string) error {
-	// Check for Markdown parsing options
-	if len(args) > 0 && (args[0] == "-f" || args[0] == "--from-stdin") {
-		return c.executeAddTaskFromMarkdown(args)
-	}
+	// Create flag set
+	taskCmd := flag.NewFlagSet(mode+" task", flag.ContinueOnError)
+
+	// Define flags
+	descriptionFlag := taskCmd.String("d", "", "Task description")
+	memoRefsFlag := taskCmd.String("m", "", "Comma-separated list of memo IDs")
+	includeArchivedFlag := taskCmd.Bool("include-archived", false, "Resolve -m to archived memos without asking")
+	fileFlag := taskCmd.String("f", "", "Create task from Markdown file")
+	fromStdinFlag := taskCmd.Bool("from-stdin", false, "Create task from Markdown input on stdin")
+	h2AsSubtasksFlag := taskCmd.Bool("h2-as-subtasks", false, "With -f or --from-stdin, create a subtask for each H2 section")
+	interactiveFlag := taskCmd.Bool("interactive", false, "Prompt for each field of the task")
+	var tagFlag stringListFlag
+	taskCmd.Var(&tagFlag, "tag", "Tag for the task (can be repeated or comma-separated)")
+	priorityFlag := taskCmd.String("priority", "", "Task priority")
+	likeLastTagFlag := taskCmd.String("like-last-tag", "", "Copy tags, priority, and description from the latest task with this tag")
+	topFlag := taskCmd.Bool("top", false, "Put the task at the top of the list")
+	var relatedFlag stringListFlag
+	taskCmd.Var(&relatedFlag, "related", "ID of a related task to mention in the description (can be repeated)")
+	bidirectionalFlag := taskCmd.Bool("bidirectional", false, "Also mention the new task in the descriptions of the --related tasks")
+	var dependsOnFlag stringListFlag
+	taskCmd.Var(&dependsOnFlag, "depends-on", "ID of a task this task depends on (can be repeated)")
+	autoSuffixFlag := taskCmd.Bool("auto-suffix", false, "Number the title when tasks with the same title exist")
+	parentFlag := taskCmd.String("parent", "", "ID of the parent task to add the task as a subtask of")
 
-	// Manual argument parsing
 	// Set usage
 	usage := func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo %s task \"<title>\" [-d \"<description>\"] [-m <memo_id>,...]\n", mode)
-		fmt.Fprintf(os.Stderr, "       tamo %s task -f <filepath> | --from-stdin\n\n", mode)
+		fmt.Fprintf(os.Stderr, "Usage: tamo %s task \"<title>\" [-d \"<description>\"] [-m <memo_id>,...] [--tag <tag>]... [--priority <priority>] [--top]\n", mode)
+		fmt.Fprintf(os.Stderr, "       %*s [--related <task_id>]... [--bidirectional] [--depends-on <task_id>]... [--auto-suffix] [--parent <task_id>]\n", len(mode)+len("tamo  task \"<title>\""), "")
+		fmt.Fprintf(os.Stderr, "       tamo %s task \"<title>\" --like-last-tag <tag> [flags to override]\n", mode)
+		fmt.Fprintf(os.Stderr, "       tamo %s task --interactive [\"<title>\"] [flags used as defaults]\n", mode)
+		fmt.Fprintf(os.Stderr, "       tamo %s task -f <filepath> | --from-stdin [--h2-as-subtasks]\n\n", mode)
 		fmt.Fprintf(os.Stderr, "Add a new task\n\n")
 		fmt.Fprintf(os.Stderr, "  -d <description>    Task description\n")
 		fmt.Fprintf(os.Stderr, "  -m <memo_id>,...    Comma-separated list of memo IDs\n")
+		fmt.Fprintf(os.Stderr, "  --include-archived  Resolve -m to archived memos without asking\n")
+		fmt.Fprintf(os.Stderr, "  --tag <tag>         Tag for the task (can be repeated or comma-separated)\n")
+		fmt.Fprintf(os.Stderr, "  --priority <p>      Task priority\n")
+		fmt.Fprintf(os.Stderr, "  --like-last-tag <t> Copy tags, priority, and description from the latest task tagged <t>\n")
+		fmt.Fprintf(os.Stderr, "  --top               Put the task at the top of the list (like unshift)\n")
+		fmt.Fprintf(os.Stderr, "  --related <task_id> Add \"Related: <id> <title>\" for the task to the description (can be repeated)\n")
+		fmt.Fprintf(os.Stderr, "  --bidirectional     Also add the new task to the descriptions of the --related tasks\n")
+		fmt.Fprintf(os.Stderr, "  --depends-on <id>   ID of a task this task depends on (can be repeated)\n")
+		fmt.Fprintf(os.Stderr, "  --auto-suffix       Append \" (N)\" to the title when tasks with the same title exist\n")
+		fmt.Fprintf(os.Stderr, "  --parent <task_id>  Add the task as a subtask of the given task\n")
 		fmt.Fprintf(os.Stderr, "  -f <filepath>       Create task from Markdown file\n")
 		fmt.Fprintf(os.Stderr, "  --from-stdin        Create task from Markdown input on stdin\n")
+		fmt.Fprintf(os.Stderr, "  --h2-as-subtasks    With -f or --from-stdin, create a subtask for each H2 section\n")
+		fmt.Fprintf(os.Stderr, "  --interactive       Prompt for each field of the task, using the other flags as defaults\n")
+		fmt.Fprintf(os.Stderr, "\nFlags may appear before or after the title. Use -- to pass a title starting with '-'.\n")
 	}
+	taskCmd.Usage = usage
 
-	// Check if we have at least a title
-	if len(args) < 1 {
-		usage()
-		return fmt.Errorf("missing task title")
+	// Parse flags
+	positional, err := parseFlagsAnywhere(taskCmd, args)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	// Get title
-	title := args[0]
+	// Check for Markdown parsing options
+	if *fileFlag != "" || *fromStdinFlag {
+		if *fileFlag != "" && *fromStdinFlag {
+			return fmt.Errorf("-f and --from-stdin flags cannot be used together")
+		}
+		if *topFlag {
+			return fmt.Errorf("--top cannot be used with -f or --from-stdin")
+		}
+		if len(relatedFlag) > 0 {
+			return fmt.Errorf("--related cannot be used with -f or --from-stdin")
+		}
+		if len(dependsOnFlag) > 0 {
+			return fmt.Errorf("--depends-on cannot be used with -f or --from-stdin")
+		}
+		if *autoSuffixFlag {
+			return fmt.Errorf("--auto-suffix cannot be used with -f or --from-stdin")
+		}
+		if *parentFlag != "" {
+			return fmt.Errorf("--parent cannot be used with -f or --from-stdin")
+		}
+		return c.executeAddTaskFromMarkdown(*fileFlag, *fromStdinFlag, *h2AsSubtasksFlag)
+	}
+	if *h2AsSubtasksFlag {
+		return fmt.Errorf("--h2-as-subtasks can only be used with -f or --from-stdin")
+	}
 
-	// Parse remaining arguments for flags
-	var description string
-	var memoRefsStr string
+	// Put the task at the top instead of the end
+	if *topFlag {
+		if mode == "push" {
+			return fmt.Errorf("--top cannot be used with push, which adds the task at the end")
+		}
+		mode = "unshift"
+	}
 
-	for i := 1; i < len(args); i++ {
-		if args[i] == "-d" && i+1 < len(args) {
-			description = args[i+1]
-			i++ // Skip the next argument
-		} else if args[i] == "-m" && i+1 < len(args) {
-			memoRefsStr = args[i+1]
-			i++ // Skip the next argument
+	// Check related task options
+	if *bidirectionalFlag && len(relatedFlag) == 0 {
+		return fmt.Errorf("--bidirectional requires --related")
+	}
+
+	// Check for interactive mode
+	if *interactiveFlag {
+		if len(relatedFlag) > 0 {
+			return fmt.Errorf("--related cannot be used with --interactive")
+		}
+		if len(dependsOnFlag) > 0 {
+			return fmt.Errorf("--depends-on cannot be used with --interactive")
+		}
+		if *autoSuffixFlag {
+			return fmt.Errorf("--auto-suffix cannot be used with --interactive")
+		}
+		if *parentFlag != "" {
+			return fmt.Errorf("--parent cannot be used with --interactive")
 		}
+		// The other flags give the defaults of the prompts
+		defaults := interactiveTaskDefaults{
+			description:     *descriptionFlag,
+			tags:            parseTags(tagFlag),
+			priority:        *priorityFlag,
+			likeLastTag:     *likeLastTagFlag,
+			includeArchived: *includeArchivedFlag,
+			isSet:           flagsSet(taskCmd),
+		}
+		for _, ref := range strings.Split(*memoRefsFlag, ",") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				defaults.memoRefs = append(defaults.memoRefs, ref)
+			}
+		}
+		return c.executeAddTaskInteractive(positional, mode, defaults)
+	}
+
+	// Check if we have exactly one title
+	if len(positional) < 1 {
+		usage()
+		return fmt.Errorf("missing task title")
+	}
+	if len(positional) > 1 {
+		usage()
+		return fmt.Errorf("unexpected arguments: %s (quote the title if it contains spaces)", strings.Join(positional[1:], " "))
 	}
 
+	// Get title
+	title := positional[0]
+	description := *descriptionFlag
+	memoRefsStr := *memoRefsFlag
+
 	// Parse memo refs
 	var memoRefs []string
 	if memoRefsStr != "" {
@@ -416,109 +1488,538 @@ func (c *CLI) executeAddTask(args []string, mode string) error {
 	}
 
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
 	// Convert partial memo IDs to full IDs
-	for i, refID := range memoRefs {
-		// Find the full memo ID if a partial ID is provided
-		if len(refID) < 36 {
-			found := false
-			for _, memo := range store.Memos {
-				if strings.HasPrefix(memo.ID, refID) {
-					memoRefs[i] = memo.ID
-					found = true
-					break
-				}
-			}
-			if !found {
-				return fmt.Errorf("memo with ID %s not found", refID)
-			}
+	memoRefs, err = resolveMemoRefs(store, memoRefs, *includeArchivedFlag)
+	if err != nil {
+		return err
+	}
+
+	// Find related tasks
+	var relatedTasks []*model.Task
+	for _, relatedID := range relatedFlag {
+		related, err := resolveTask(store, relatedID)
+		if err != nil {
+			return fmt.Errorf("invalid --related: %w", err)
+		}
+		if !containsTask(relatedTasks, related) {
+			relatedTasks = append(relatedTasks, related)
+		}
+	}
+
+	// Find the tasks the new task depends on
+	dependsOn, err := resolveDependencies(store, dependsOnFlag)
+	if err != nil {
+		return err
+	}
+
+	// Find the parent task
+	var parent *model.Task
+	if *parentFlag != "" {
+		parent, err = resolveTask(store, *parentFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --parent: %w", err)
 		}
 	}
 
+	tags := parseTags(tagFlag)
+	priority := *priorityFlag
+
+	// Copy attributes from the latest task with the given tag, unless overridden by flags
+	if *likeLastTagFlag != "" {
+		copyLikeLastTag(store, *likeLastTagFlag, flagsSet(taskCmd), &tags, &priority, &description)
+	}
+
+	// Number the title after the existing tasks with the same title
+	if *autoSuffixFlag {
+		title = suffixedTitle(store, title)
+	}
+
 	// Generate UUID
 	id, err := utils.GenerateUUID()
 	if err != nil {
 		return fmt.Errorf("failed to generate UUID: %w", err)
 	}
 
-	// Validate memo refs (for full IDs)
-	for _, memoID := range memoRefs {
-		if len(memoID) == 36 && store.FindMemoByID(memoID) == nil {
-			return fmt.Errorf("memo with ID %s not found", memoID)
-		}
+	// Mention related tasks at the end of the description
+	for _, related := range relatedTasks {
+		description = appendRelatedLine(description, related)
 	}
 
 	// Create new task
 	task := model.NewTask(id, title, description, memoRefs)
+	task.Tags = tags
+	task.Priority = priority
+	task.DependsOn = dependsOn
+	if parent != nil {
+		task.ParentID = parent.ID
+	}
 
 	// Set order based on mode
-	switch mode {
-	case "add", "push":
-		// Add to end (max order + 1.0)
-		task.Order = store.GetMaxTaskOrder() + 1.0
-	case "unshift":
-		// Add to beginning (min order - 1.0)
-		task.Order = store.GetMinTaskOrder() - 1.0
-	}
+	task.Order = newTaskOrder(store, mode)
 
 	// Add task to store
 	store.AddTask(task)
 
+	// Mention the new task in the related tasks too
+	if *bidirectionalFlag {
+		for _, related := range relatedTasks {
+			related.Description = appendRelatedLine(related.Description, task)
+			related.Touch()
+		}
+	}
+
 	// Save store
 	if err := s.Save(store); err != nil {
 		return fmt.Errorf("failed to save data: %w", err)
 	}
 
-	fmt.Printf("Task added with ID: %s\n", id)
+	c.printAddedID("Task added with ID", id)
+	if *autoSuffixFlag {
+		c.printf("Title: %s\n", title)
+	}
 	return nil
 }
 
-// executePush handles the 'push task' command (add to end)
-func (c *CLI) executePush(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("missing subcommand: 'task'")
+// suffixedTitle returns the title numbered after the tasks already titled "<title>" or "<title> (N)":
+// the title itself if there are none, and "<title> (N)" with the next number otherwise
+func suffixedTitle(store *model.Store, title string) string {
+	last := 0
+	for _, task := range store.Tasks {
+		if task.Title == title {
+			last = max(last, 1)
+			continue
+		}
+		rest, ok := strings.CutPrefix(task.Title, title+" (")
+		if !ok || !strings.HasSuffix(rest, ")") {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(rest, ")")); err == nil && n > 0 {
+			last = max(last, n)
+		}
 	}
-
-	subCmd := args[0]
-	if subCmd != "task" {
-		return fmt.Errorf("unknown subcommand: %s", subCmd)
+	if last == 0 {
+		return title
 	}
+	return fmt.Sprintf("%s (%d)", title, last+1)
+}
 
-	return c.executeAddTask(args[1:], "push")
+// resolveDependencies expands the task IDs given as dependencies to full IDs, dropping duplicates
+func resolveDependencies(store *model.Store, ids []string) ([]string, error) {
+	var deps []string
+	for _, id := range ids {
+		dep, err := resolveTask(store, id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency: %w", err)
+		}
+		if !containsString(deps, dep.ID) {
+			deps = append(deps, dep.ID)
+		}
+	}
+	return deps, nil
 }
 
-// executeUnshift handles the 'unshift task' command (add to beginning)
-func (c *CLI) executeUnshift(args []string) error {
-	if len(args) == 0 {
-		return fmt.Errorf("missing subcommand: 'task'")
+// dependencyList describes tasks as "1a2b3c4d Title, 5e6f7a8b Other"
+func dependencyList(tasks []*model.Task) string {
+	items := make([]string, len(tasks))
+	for i, task := range tasks {
+		items[i] = fmt.Sprintf("%s %s", task.ID[:8], task.Title)
 	}
+	return strings.Join(items, ", ")
+}
 
-	subCmd := args[0]
-	if subCmd != "task" {
-		return fmt.Errorf("unknown subcommand: %s", subCmd)
+// shortIDs describes IDs as "1a2b3c4d, 5e6f7a8b"
+func shortIDs(ids []string) string {
+	short := make([]string, len(ids))
+	for i, id := range ids {
+		short[i] = id[:8]
 	}
+	return strings.Join(short, ", ")
+}
 
-	return c.executeAddTask(args[1:], "unshift")
+// shortID returns the first 8 characters of an ID, or the whole ID if it is shorter,
+// for messages about data that may not have been checked yet
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
 }
 
-// executeList handles the 'list' command
-func (c *CLI) executeList(args []string) error {
-	// Create flag set
-	listCmd := flag.NewFlagSet("list", flag.ExitOnError)
+// appendRelatedLine adds a "Related: <id> <title>" line for the task to the end of the description
+func appendRelatedLine(description string, task *model.Task) string {
+	line := fmt.Sprintf("Related: %s %s", task.ID[:8], task.Title)
+	if strings.TrimSpace(description) == "" {
+		return line
+	}
+	return strings.TrimRight(description, "\n") + "\n" + line
+}
 
-	// Define flags
-	doneFlag := listCmd.Bool("done", false, "Show only completed tasks")
+// containsTask checks if a task slice contains a task
+func containsTask(tasks []*model.Task, task *model.Task) bool {
+	for _, t := range tasks {
+		if t == task {
+			return true
+		}
+	}
+	return false
+}
+
+// flagsSet returns the names of the flags that were set on the command line
+func flagsSet(cmd *flag.FlagSet) map[string]bool {
+	isSet := make(map[string]bool)
+	cmd.Visit(func(f *flag.Flag) {
+		isSet[f.Name] = true
+	})
+	return isSet
+}
+
+// copyLikeLastTag copies the tags, priority, and description of the latest task with the given tag,
+// except those set by flags. Without such a task, the tag itself is used.
+func copyLikeLastTag(store *model.Store, tag string, isSet map[string]bool, tags *[]string, priority, description *string) {
+	latest := store.FindLatestTaskWithTag(tag)
+	if latest == nil {
+		fmt.Fprintf(os.Stderr, "No task with tag '%s' found, creating task without copied attributes\n", tag)
+	}
+
+	if !isSet["tag"] {
+		if latest != nil {
+			*tags = append(*tags, latest.Tags...)
+		} else {
+			*tags = append(*tags, tag)
+		}
+	}
+	if latest != nil {
+		if !isSet["priority"] {
+			*priority = latest.Priority
+		}
+		if !isSet["d"] {
+			*description = latest.Description
+		}
+	}
+}
+
+// interactiveTaskDefaults holds the flags given with 'add task --interactive', used as the defaults of the prompts
+type interactiveTaskDefaults struct {
+	description     string
+	memoRefs        []string
+	tags            []string
+	priority        string
+	likeLastTag     string
+	includeArchived bool
+	isSet           map[string]bool
+}
+
+// executeAddTaskInteractive handles the 'add task --interactive' command
+func (c *CLI) executeAddTaskInteractive(args []string, mode string, defaults interactiveTaskDefaults) error {
+	// Use the title argument as the default title, if given
+	defaultTitle := ""
+	if len(args) > 0 {
+		defaultTitle = args[0]
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Resolve the defaults given by flags before prompting
+	defaultMemoRefs, err := resolveMemoRefs(store, defaults.memoRefs, defaults.includeArchived)
+	if err != nil {
+		return err
+	}
+	defaultDescription := defaults.description
+	defaultTags := defaults.tags
+	priority := defaults.priority
+	if defaults.likeLastTag != "" {
+		copyLikeLastTag(store, defaults.likeLastTag, defaults.isSet, &defaultTags, &priority, &defaultDescription)
+		defaultTags = parseTags(defaultTags)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+
+	// Prompt for title (required)
+	var title string
+	for title == "" {
+		if defaultTitle != "" {
+			fmt.Fprintf(os.Stderr, "Title [%s]: ", defaultTitle)
+		} else {
+			fmt.Fprint(os.Stderr, "Title: ")
+		}
+		line, err := readLineFrom(reader)
+		if err != nil {
+			return fmt.Errorf("task creation aborted: %w", err)
+		}
+		title = line
+		if title == "" {
+			title = defaultTitle
+		}
+		if title == "" {
+			fmt.Fprintln(os.Stderr, "Title is required")
+		}
+	}
+
+	// Prompt for description (optional)
+	if defaultDescription != "" {
+		fmt.Fprintf(os.Stderr, "Description [%s]: ", defaultDescription)
+	} else {
+		fmt.Fprint(os.Stderr, "Description (press Enter to skip): ")
+	}
+	description, err := readLineFrom(reader)
+	if err != nil {
+		return fmt.Errorf("task creation aborted: %w", err)
+	}
+	if description == "" {
+		description = defaultDescription
+	}
+
+	// Prompt for memo references (optional)
+	memoRefs := defaultMemoRefs
+	for {
+		if len(defaultMemoRefs) > 0 {
+			fmt.Fprintf(os.Stderr, "Memo References (comma-separated) [%s]: ", shortIDs(defaultMemoRefs))
+		} else {
+			fmt.Fprint(os.Stderr, "Memo References (comma-separated, press Enter to skip): ")
+		}
+		refsStr, err := readLineFrom(reader)
+		if err != nil {
+			return fmt.Errorf("task creation aborted: %w", err)
+		}
+		if refsStr == "" {
+			break
+		}
+
+		var inputRefs []string
+		for _, ref := range strings.Split(refsStr, ",") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				inputRefs = append(inputRefs, ref)
+			}
+		}
+
+		memoRefs, err = resolveMemoRefs(store, inputRefs, true)
+		if err == nil {
+			break
+		}
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	// Prompt for tags (optional)
+	if len(defaultTags) > 0 {
+		fmt.Fprintf(os.Stderr, "Tags (comma-separated) [%s]: ", strings.Join(defaultTags, ", "))
+	} else {
+		fmt.Fprint(os.Stderr, "Tags (comma-separated, press Enter to skip): ")
+	}
+	tagsStr, err := readLineFrom(reader)
+	if err != nil {
+		return fmt.Errorf("task creation aborted: %w", err)
+	}
+	tags := defaultTags
+	if tagsStr != "" {
+		tags = parseTags([]string{tagsStr})
+	}
+
+	// Show summary and ask for confirmation
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "Title: %s\n", title)
+	if description != "" {
+		fmt.Fprintf(os.Stderr, "Description: %s\n", description)
+	}
+	if len(tags) > 0 {
+		fmt.Fprintf(os.Stderr, "Tags: %s\n", strings.Join(tags, ", "))
+	}
+	if priority != "" {
+		fmt.Fprintf(os.Stderr, "Priority: %s\n", priority)
+	}
+	if len(memoRefs) > 0 {
+		fmt.Fprintln(os.Stderr, "Memo References:")
+		for _, memoID := range memoRefs {
+			titleStr := "<no title>"
+			if memo := store.FindMemoByID(memoID); memo != nil && memo.Title != nil {
+				titleStr = *memo.Title
+			}
+			fmt.Fprintf(os.Stderr, "  %s  %s\n", memoID[:8], titleStr)
+		}
+	}
+	fmt.Fprint(os.Stderr, "Save this task? (Y/n): ")
+	confirmation, err := readLineFrom(reader)
+	if err != nil {
+		return fmt.Errorf("task creation aborted: %w", err)
+	}
+	if answer := strings.ToLower(confirmation); answer != "" && answer != "y" && answer != "yes" {
+		fmt.Fprintln(os.Stderr, "Task creation aborted")
+		return nil
+	}
+
+	// Generate UUID
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	// Create new task
+	task := model.NewTask(id, title, description, memoRefs)
+	task.Tags = tags
+	task.Priority = priority
+	task.Order = newTaskOrder(store, mode)
+
+	// Add task to store
+	store.AddTask(task)
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printAddedID("Task added with ID", id)
+	return nil
+}
+
+// parseTags splits comma-separated tag values, trimming whitespace and dropping empty and duplicate tags
+func parseTags(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag != "" && !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// newTaskOrder returns the order for a new task added with the given mode
+func newTaskOrder(store *model.Store, mode string) float64 {
+	switch mode {
+	case "unshift":
+		// Add to beginning (min order - 1.0)
+		return store.GetMinTaskOrder() - 1.0
+	default:
+		// Add to end (max order + 1.0)
+		return store.GetMaxTaskOrder() + 1.0
+	}
+}
+
+// resolveMemoRefs converts memo IDs or ID prefixes to full memo IDs, failing if any memo is not found.
+// Active memos are preferred over archived ones. A reference that only matches an archived memo
+// is resolved if includeArchived is set, and otherwise only after confirmation.
+func resolveMemoRefs(store *model.Store, refs []string, includeArchived bool) ([]string, error) {
+	resolved := make([]string, 0, len(refs))
+	for _, refID := range refs {
+		var active, archivedMatches []*model.Memo
+		for _, m := range store.Memos {
+			if !strings.HasPrefix(m.ID, refID) {
+				continue
+			}
+			if m.ID == refID {
+				// A full ID matches only its memo
+				active, archivedMatches = nil, nil
+			}
+			if m.Archived {
+				archivedMatches = append(archivedMatches, m)
+			} else {
+				active = append(active, m)
+			}
+			if m.ID == refID {
+				break
+			}
+		}
+		if len(active) > 1 || (len(active) == 0 && len(archivedMatches) > 1) {
+			return nil, fmt.Errorf("%w: %s matches %d memos", errAmbiguousID, refID, len(active)+len(archivedMatches))
+		}
+
+		var memo, archived *model.Memo
+		if len(active) == 1 {
+			memo = active[0]
+		} else if len(archivedMatches) == 1 {
+			archived = archivedMatches[0]
+		}
+
+		if memo == nil && archived != nil {
+			if !includeArchived && !confirm(fmt.Sprintf("Warning: memo %s (%s) is archived. Reference it anyway?", archived.ID[:8], memoTitle(archived))) {
+				return nil, fmt.Errorf("memo with ID %s is archived (use --include-archived to reference it)", refID)
+			}
+			memo = archived
+		}
+		if memo == nil {
+			return nil, notFoundErrorf("memo with ID %s not found", refID)
+		}
+		resolved = append(resolved, memo.ID)
+	}
+	return resolved, nil
+}
+
+// executePush handles the 'push task' command (add to end)
+func (c *CLI) executePush(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand: 'task'")
+	}
+
+	subCmd := args[0]
+	if subCmd != "task" {
+		return fmt.Errorf("unknown subcommand: %s", subCmd)
+	}
+
+	return c.executeAddTask(args[1:], "push")
+}
+
+// executeUnshift handles the 'unshift task' command (add to beginning)
+func (c *CLI) executeUnshift(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand: 'task'")
+	}
+
+	subCmd := args[0]
+	if subCmd != "task" {
+		return fmt.Errorf("unknown subcommand: %s", subCmd)
+	}
+
+	return c.executeAddTask(args[1:], "unshift")
+}
+
+// executeList handles the 'list' command
+func (c *CLI) executeList(args []string) error {
+	// Create flag set
+	listCmd := flag.NewFlagSet("list", flag.ContinueOnError)
+
+	// Define flags
+	doneFlag := listCmd.Bool("done", false, "Show only completed tasks")
 	undoneFlag := listCmd.Bool("undone", false, "Show only uncompleted tasks")
 	refsFlag := listCmd.String("refs", "", "Show tasks referencing the specified memo ID")
+	timelineFlag := listCmd.Bool("timeline", false, "Show tasks and memos mixed, most recently updated first")
+	limitFlag := listCmd.Int("limit", 0, "Show at most this many items (0 for no limit)")
+	checkRefsFlag := listCmd.Bool("check-refs", false, "Mark tasks that reference memos which don't exist")
+	showGapsFlag := listCmd.Bool("show-gaps", false, "Insert a separator where the order gap between tasks exceeds the threshold")
+	gapThresholdFlag := listCmd.Float64("gap-threshold", 2.0, "Order gap above which --show-gaps inserts a separator")
+	refsCountFlag := listCmd.Bool("refs-count", false, "Same as --sort usage")
+	orphansFlag := listCmd.Bool("orphans", false, "Show only memos that no task references")
+	noHiddenCountFlag := listCmd.Bool("no-hidden-count", false, "Don't show how many completed tasks --undone or --hide-done-except-recent hid")
+	showTagsFlag := listCmd.Bool("show-tags", false, "Show the tags of each task as badges")
+	sortFlag := listCmd.String("sort", "", "Sort by 'order' (tasks by order, memos by creation), 'created', 'updated', 'title', or 'usage' (memos by the number of referencing tasks)")
+	reverseFlag := listCmd.Bool("reverse", false, "Show the items in reverse order, e.g. tasks by descending order")
+	pendingFirstFlag := listCmd.Bool("pending-first", false, "Show uncompleted tasks before completed ones, each in order (default: the list.pending_first setting)")
+	showFullIDFlag := listCmd.Bool("show-full-id", false, "Show full IDs instead of the first 8 characters")
+	inlineTagFlag := listCmd.String("inline-tag", "", "Show only items tagged with the tag, or with #tag in the description or content")
+	duplicateTitlesFlag := listCmd.Bool("duplicate-titles", false, "Show only memos whose title another memo also has, grouped by title")
+	staleFlag := listCmd.Int("stale", 0, "Show only uncompleted tasks created at least this many days ago")
+	hideDoneExceptRecentFlag := listCmd.String("hide-done-except-recent", "", "Hide completed tasks except those completed within this duration (e.g. 24h)")
+	activityFlag := listCmd.Int("activity", 0, "Show the number of tasks created each day of the last n days as a sparkline instead of the list")
+	activityCompletedFlag := listCmd.Bool("activity-completed", false, "With --activity, also show the number of tasks completed each day")
 
 	// Set usage
 	listCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo list [tasks|memos|all] [--done|--undone] [--refs <memo_id>]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: tamo list [tasks|memos|all] [--done|--undone] [--refs <memo_id>] [--timeline] [--limit <n>] [--check-refs]\n")
+		fmt.Fprintf(os.Stderr, "                 [--show-gaps [--gap-threshold <n>]] [--orphans] [--no-hidden-count] [--show-tags]\n")
+		fmt.Fprintf(os.Stderr, "                 [--pending-first] [--sort order|created|updated|title|usage] [--reverse] [--duplicate-titles]\n")
+		fmt.Fprintf(os.Stderr, "                 [--inline-tag <tag>] [--show-full-id] [--stale <days>] [--hide-done-except-recent <duration>]\n")
+		fmt.Fprintf(os.Stderr, "       tamo list --activity <days> [--activity-completed]\n\n")
 		fmt.Fprintf(os.Stderr, "List tasks and/or memos\n\n")
 		listCmd.PrintDefaults()
 	}
@@ -528,177 +2029,4858 @@ func (c *CLI) executeList(args []string) error {
 		return err
 	}
 
-	// Get subcommand (default to "tasks")
-	subCmd := "tasks"
+	// Load config
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Get subcommand (default to the configured target, "tasks" unless set)
+	subCmd := cfg.Get("list.default_target")
 	if listCmd.NArg() > 0 {
 		subCmd = listCmd.Arg(0)
 		if subCmd != "tasks" && subCmd != "memos" && subCmd != "all" {
 			return fmt.Errorf("unknown subcommand: %s", subCmd)
 		}
+
+		// Parse flags given after the subcommand
+		if err := listCmd.Parse(listCmd.Args()[1:]); err != nil {
+			return err
+		}
 	}
 
 	// Check for conflicting flags
 	if *doneFlag && *undoneFlag {
 		return fmt.Errorf("--done and --undone flags cannot be used together")
 	}
+	if *limitFlag < 0 {
+		return fmt.Errorf("--limit must not be negative")
+	}
+	if *gapThresholdFlag <= 0 {
+		return fmt.Errorf("--gap-threshold must be positive")
+	}
+	if *orphansFlag && subCmd == "tasks" {
+		return fmt.Errorf("--orphans can only be used with memos or all")
+	}
+	*inlineTagFlag = strings.TrimPrefix(*inlineTagFlag, "#")
+	if *duplicateTitlesFlag && subCmd == "tasks" {
+		return fmt.Errorf("--duplicate-titles can only be used with memos or all")
+	}
+	if *refsCountFlag {
+		if *sortFlag != "" && *sortFlag != "usage" {
+			return fmt.Errorf("--refs-count cannot be used with --sort %s", *sortFlag)
+		}
+		*sortFlag = "usage"
+	}
+	if *sortFlag != "" && !containsString(listSortKeys, *sortFlag) {
+		return fmt.Errorf("invalid sort key: %s (expected %s)", *sortFlag, strings.Join(listSortKeys, ", "))
+	}
+	if *sortFlag == "usage" && subCmd == "tasks" {
+		return fmt.Errorf("--sort usage can only be used with memos or all")
+	}
+	if *sortFlag == "" && cfg.Get("list.sort") == "usage" {
+		*sortFlag = "usage"
+	}
+	stale := false
+	listCmd.Visit(func(f *flag.Flag) {
+		if f.Name == "stale" {
+			stale = true
+		}
+	})
+	if stale && *staleFlag <= 0 {
+		return fmt.Errorf("--stale must be a positive number of days")
+	}
+	if stale && *doneFlag {
+		return fmt.Errorf("--stale and --done flags cannot be used together")
+	}
+	activity := false
+	listCmd.Visit(func(f *flag.Flag) {
+		if f.Name == "activity" {
+			activity = true
+		}
+	})
+	if activity && *activityFlag <= 0 {
+		return fmt.Errorf("--activity must be a positive number of days")
+	}
+	if *activityCompletedFlag && !activity {
+		return fmt.Errorf("--activity-completed requires --activity")
+	}
+	if activity && subCmd == "memos" {
+		return fmt.Errorf("--activity can only be used with tasks")
+	}
+	var recentWindow time.Duration
+	if *hideDoneExceptRecentFlag != "" {
+		if *doneFlag || *undoneFlag {
+			return fmt.Errorf("--hide-done-except-recent cannot be used with --done or --undone")
+		}
+		recentWindow, err = time.ParseDuration(*hideDoneExceptRecentFlag)
+		if err != nil || recentWindow <= 0 {
+			return fmt.Errorf("invalid --hide-done-except-recent: %s (expected a positive duration such as 24h)", *hideDoneExceptRecentFlag)
+		}
+	}
+
+	// Use the configured sort unless --pending-first is given
+	pendingFirst := cfg.Get("list.pending_first") == "true"
+	listCmd.Visit(func(f *flag.Flag) {
+		if f.Name == "pending-first" {
+			pendingFirst = *pendingFirstFlag
+		}
+	})
 
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// List items based on subcommand
-	switch subCmd {
-	case "tasks", "all":
-		// Filter tasks
-		var filteredTasks []*model.Task
+	if activity {
+		printActivity(store.Tasks, *activityFlag, *activityCompletedFlag, time.Now(), stdoutIsTerminal())
+		return nil
+	}
+
+	// Filter tasks
+	var filteredTasks []*model.Task
+	hiddenDone := 0
+	now := time.Now()
+	if subCmd == "tasks" || subCmd == "all" {
 		for _, task := range store.Tasks {
+			// Filter by memo reference
+			if *refsFlag != "" && !containsString(task.MemoRefs, *refsFlag) {
+				continue
+			}
+
+			// Filter by tag, including #tags in the description
+			if *inlineTagFlag != "" && !containsString(taskTags(task), *inlineTagFlag) {
+				continue
+			}
+
 			// Filter by done/undone
 			if *doneFlag && !task.Done {
 				continue
 			}
 			if *undoneFlag && task.Done {
+				hiddenDone++
 				continue
 			}
 
-			// Filter by memo reference
-			if *refsFlag != "" && !containsString(task.MemoRefs, *refsFlag) {
+			// Hide tasks completed before the recent window
+			if recentWindow > 0 && task.Done && now.Sub(taskCompletedAt(task)) > recentWindow {
+				hiddenDone++
 				continue
 			}
 
-			filteredTasks = append(filteredTasks, task)
-		}
-
-		// Sort tasks by order
-		sortTasksByOrder(filteredTasks)
-
-		// Print tasks
-		if len(filteredTasks) > 0 {
-			fmt.Println("Tasks:")
-			for _, task := range filteredTasks {
-				doneStr := "[ ]"
-				if task.Done {
-					doneStr = "[x]"
-				}
-				fmt.Printf("  %s  %.1f  %s  %s\n", task.ID[:8], task.Order, doneStr, task.Title)
+			// Filter by the time the task has been left uncompleted
+			if stale && (task.Done || taskAgeDays(task, now) < *staleFlag) {
+				continue
 			}
-		} else {
-			fmt.Println("No tasks found")
+
+			filteredTasks = append(filteredTasks, task)
 		}
 	}
 
+	// Filter memos
+	var filteredMemos []*model.Memo
 	if subCmd == "memos" || subCmd == "all" {
-		// Filter memos
-		var filteredMemos []*model.Memo
-		for _, memo := range store.Memos {
+		memos := store.Memos
+		if *orphansFlag {
+			memos = store.OrphanMemos()
+		}
+		duplicates := duplicateMemoTitles(store.Memos)
+		for _, memo := range memos {
 			// Filter by reference
 			if *refsFlag != "" {
 				// Skip this memo if we're filtering by refs (memos don't reference other memos)
 				continue
 			}
 
-			filteredMemos = append(filteredMemos, memo)
-		}
-
-		// Print memos
-		if len(filteredMemos) > 0 {
-			if subCmd == "all" {
-				fmt.Println() // Add a newline if we're listing both tasks and memos
+			// Filter by duplicate title
+			if *duplicateTitlesFlag && !duplicates[memoTitle(memo)] {
+				continue
 			}
-			fmt.Println("Memos:")
-			for _, memo := range filteredMemos {
-				titleStr := "<no title>"
-				if memo.Title != nil {
-					titleStr = *memo.Title
-				}
-
-				// Get first line of content
-				contentLines := strings.SplitN(memo.Content, "\n", 2)
-				contentPreview := contentLines[0]
-				if len(contentPreview) > 50 {
-					contentPreview = contentPreview[:47] + "..."
-				}
 
-				fmt.Printf("  %s  %s  %s\n", memo.ID[:8], titleStr, contentPreview)
+			// Filter by #tags in the content
+			if *inlineTagFlag != "" && !containsString(inlineTags(memo.Content), *inlineTagFlag) {
+				continue
 			}
-		} else {
-			fmt.Println("No memos found")
+
+			filteredMemos = append(filteredMemos, memo)
 		}
 	}
 
-	return nil
-}
-
-// executeShow handles the 'show' command
-func (c *CLI) executeShow(args []string) error {
-	// Create flag set
-	showCmd := flag.NewFlagSet("show", flag.ExitOnError)
+	if *timelineFlag {
+		printTimeline(filteredTasks, filteredMemos, *limitFlag, *showFullIDFlag, *reverseFlag)
+		return nil
+	}
 
-	// Set usage
-	showCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo show <id>\n\n")
+	// List items based on subcommand
+	if subCmd == "tasks" || subCmd == "all" {
+		// Sort tasks by order, or by the --sort key with ties broken by order
+		if pendingFirst {
+			sortTasksPendingFirst(filteredTasks)
+		} else {
+			sortTasksByOrder(filteredTasks)
+		}
+		if less := taskSortLess(*sortFlag); less != nil {
+			sort.SliceStable(filteredTasks, func(i, j int) bool {
+				a, b := filteredTasks[i], filteredTasks[j]
+				if pendingFirst && a.Done != b.Done {
+					return !a.Done
+				}
+				return less(a, b)
+			})
+		}
+		// Reverse the sorted list as a whole, so tasks with equal keys are reversed too
+		if *reverseFlag {
+			slices.Reverse(filteredTasks)
+		}
+		// Show subtasks indented beneath their parents
+		var depths map[string]int
+		filteredTasks, depths = taskTree(filteredTasks)
+		if *limitFlag > 0 && len(filteredTasks) > *limitFlag {
+			filteredTasks = filteredTasks[:*limitFlag]
+		}
+
+		// Print tasks
+		if len(filteredTasks) > 0 {
+			r := c.renderer()
+
+			countWidth := memoRefCountWidth(filteredTasks)
+
+			// Show the position of each task among all tasks, which can be given as %N instead of an ID
+			positions := taskPositions(store)
+			positionWidth := len(strconv.Itoa(len(store.Tasks))) + 1
+
+			fmt.Println("Tasks:")
+			for i, task := range filteredTasks {
+				// Separate groups of tasks with large order gaps
+				if *showGapsFlag && i > 0 && math.Abs(task.Order-filteredTasks[i-1].Order) > *gapThresholdFlag {
+					fmt.Println("  - - -")
+				}
+
+				line := fmt.Sprintf("  %-*s", positionWidth, "%"+strconv.Itoa(positions[task.ID])) + strings.Repeat("  ", depths[task.ID]) + taskListLine(r, task, countWidth, *showFullIDFlag)
+				if *checkRefsFlag {
+					line = markBrokenRefs(r, store, task, line)
+				}
+				if !task.Done && store.IsBlocked(task) {
+					line += "  ⛔"
+				}
+				if tags := taskTags(task); *showTagsFlag && len(tags) > 0 {
+					line += "  " + tagBadges(tags, r.color)
+				}
+				if stale {
+					line += fmt.Sprintf("  (stale: %d days)", taskAgeDays(task, now))
+				}
+				fmt.Println(line)
+			}
+		} else {
+			fmt.Println("No tasks found")
+		}
+
+		// Tell how many completed tasks were hidden
+		if hiddenDone > 0 && !*noHiddenCountFlag && !c.quiet {
+			fmt.Printf("(%d completed tasks hidden)\n", hiddenDone)
+		}
+	}
+
+	if subCmd == "memos" || subCmd == "all" {
+		// Count the tasks referencing each memo in one pass over the tasks
+		refCounts := countMemoRefs(store)
+		countWidth := 1
+		for _, memo := range filteredMemos {
+			countWidth = max(countWidth, len(strconv.Itoa(refCounts[memo.ID])))
+		}
+
+		// Sort memos by the --sort key, with ties broken by creation time
+		if less := memoSortLess(*sortFlag, refCounts); less != nil {
+			sort.SliceStable(filteredMemos, func(i, j int) bool {
+				return less(filteredMemos[i], filteredMemos[j])
+			})
+		}
+
+		// Group memos with the same title, keeping the order within each group
+		if *duplicateTitlesFlag {
+			sort.SliceStable(filteredMemos, func(i, j int) bool {
+				return memoTitle(filteredMemos[i]) < memoTitle(filteredMemos[j])
+			})
+		}
+
+		if *reverseFlag {
+			slices.Reverse(filteredMemos)
+		}
+		if *limitFlag > 0 && len(filteredMemos) > *limitFlag {
+			filteredMemos = filteredMemos[:*limitFlag]
+		}
+
+		// Print memos
+		if len(filteredMemos) > 0 {
+			if subCmd == "all" {
+				fmt.Println() // Add a newline if we're listing both tasks and memos
+			}
+			r := c.renderer()
+			fmt.Println("Memos:")
+			for _, memo := range filteredMemos {
+				fmt.Printf("  %s  [%*dt]  %s  %s\n", r.id(displayID(memo.ID, *showFullIDFlag)), countWidth, refCounts[memo.ID], memoTitle(memo), memoPreview(memo))
+			}
+		} else {
+			fmt.Println("No memos found")
+		}
+	}
+
+	return nil
+}
+
+// memoRefCountWidth returns the width of the largest memo ref count of the tasks.
+// Counts are padded to this width so titles stay aligned.
+func memoRefCountWidth(tasks []*model.Task) int {
+	width := 1
+	for _, task := range tasks {
+		width = max(width, len(strconv.Itoa(len(task.MemoRefs))))
+	}
+	return width
+}
+
+// taskTree reorders the tasks so that subtasks follow their parent, keeping the given order among siblings,
+// and returns the depth of each task. Tasks whose parent is not in the list stay at the top level.
+func taskTree(tasks []*model.Task) ([]*model.Task, map[string]int) {
+	inList := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		inList[task.ID] = true
+	}
+	children := make(map[string][]*model.Task)
+	var roots []*model.Task
+	for _, task := range tasks {
+		if task.ParentID != "" && task.ParentID != task.ID && inList[task.ParentID] {
+			children[task.ParentID] = append(children[task.ParentID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	ordered := make([]*model.Task, 0, len(tasks))
+	depths := make(map[string]int, len(tasks))
+	var walk func(task *model.Task, depth int)
+	walk = func(task *model.Task, depth int) {
+		if _, seen := depths[task.ID]; seen {
+			return
+		}
+		depths[task.ID] = depth
+		ordered = append(ordered, task)
+		for _, child := range children[task.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, task := range roots {
+		walk(task, 0)
+	}
+	// Tasks in a parent cycle are never reached from a root, so list them at the top level
+	for _, task := range tasks {
+		walk(task, 0)
+	}
+	return ordered, depths
+}
+
+// taskListLine renders a task as a line of the task list
+func taskListLine(r renderer, task *model.Task, countWidth int, fullID bool) string {
+	return fmt.Sprintf("  %s  %.1f  %s  [%*dm]  %s", r.id(displayID(task.ID, fullID)), task.Order, r.doneMark(task), countWidth, len(task.MemoRefs), r.title(task, task.Title))
+}
+
+// pickTaskIDs returns the IDs given on the command line, or the ID of a task picked from a numbered list.
+// The list is shown with pick, or when no ID is given and stdout is a terminal. Otherwise the IDs are
+// returned as given, so scripts fail on a missing ID instead of waiting for input.
+func (c *CLI) pickTaskIDs(ids []string, pick bool) ([]string, error) {
+	if !pick && (len(ids) > 0 || !stdoutIsTerminal()) {
+		return ids, nil
+	}
+	if len(ids) > 0 {
+		return nil, fmt.Errorf("--pick cannot be used with IDs")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+	if len(store.Tasks) == 0 {
+		return nil, fmt.Errorf("no tasks to pick from")
+	}
+
+	// List the tasks, uncompleted first
+	tasks := append([]*model.Task(nil), store.Tasks...)
+	sortTasksPendingFirst(tasks)
+	numberWidth := len(strconv.Itoa(len(tasks)))
+	countWidth := memoRefCountWidth(tasks)
+	for i, task := range tasks {
+		fmt.Fprintf(os.Stderr, "%*d)%s\n", numberWidth, i+1, taskListLine(renderer{}, task, countWidth, false))
+	}
+
+	fmt.Fprint(os.Stderr, "Select a task by number: ")
+	answer := readLine()
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(tasks) {
+		return nil, fmt.Errorf("invalid selection: %q", answer)
+	}
+	return []string{tasks[n-1].ID}, nil
+}
+
+// taskPositionRegex matches a task position argument: %N for the Nth task in order,
+// or %uN for the Nth uncompleted task
+var taskPositionRegex = regexp.MustCompile(`^%(u?)(\d+)$`)
+
+// isTaskPosition reports whether the argument gives a task by its position, like %N or %uN,
+// rather than by its ID
+func isTaskPosition(arg string) bool {
+	return strings.HasPrefix(arg, "%")
+}
+
+// taskAtPosition returns the task at the position given by a %N or %uN argument
+func taskAtPosition(store *model.Store, arg string) (*model.Task, error) {
+	match := taskPositionRegex.FindStringSubmatch(arg)
+	if match == nil {
+		return nil, fmt.Errorf("invalid task position: %s (expected %%N or %%uN)", arg)
+	}
+
+	var tasks []*model.Task
+	for _, task := range store.Tasks {
+		if match[1] == "" || !task.Done {
+			tasks = append(tasks, task)
+		}
+	}
+	sortTasksByOrder(tasks)
+
+	n, err := strconv.Atoi(match[2])
+	if err != nil || n < 1 || n > len(tasks) {
+		kind := "tasks"
+		if match[1] != "" {
+			kind = "uncompleted tasks"
+		}
+		return nil, notFoundErrorf("task position out of range: %s (%d %s)", arg, len(tasks), kind)
+	}
+	return tasks[n-1], nil
+}
+
+// taskPositions returns the 1-based position of each task in order, by task ID
+func taskPositions(store *model.Store) map[string]int {
+	tasks := append([]*model.Task(nil), store.Tasks...)
+	sortTasksByOrder(tasks)
+	positions := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		positions[task.ID] = i + 1
+	}
+	return positions
+}
+
+// sparkBlocks are the blocks of a sparkline, from the lowest to the highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// printActivity prints the number of tasks created on each of the last days, and optionally
+// the number completed, as sparklines ending today. Without a terminal, which may not render
+// the blocks, the numbers are printed instead.
+func printActivity(tasks []*model.Task, days int, completed bool, now time.Time, terminal bool) {
+	created := make(map[string]int)
+	done := make(map[string]int)
+	for _, task := range tasks {
+		created[task.CreatedAt.Local().Format("2006-01-02")]++
+		if task.Done {
+			done[taskCompletedAt(task).Local().Format("2006-01-02")]++
+		}
+	}
+
+	printLine := func(label string, byDay map[string]int) {
+		counts := make([]int, days)
+		total := 0
+		for i := range counts {
+			counts[i] = byDay[now.AddDate(0, 0, i-days+1).Format("2006-01-02")]
+			total += counts[i]
+		}
+		line := sparkline(counts)
+		if !terminal {
+			numbers := make([]string, days)
+			for i, count := range counts {
+				numbers[i] = strconv.Itoa(count)
+			}
+			line = strings.Join(numbers, " ")
+		}
+		fmt.Printf("%-10s %s  (%d tasks)\n", label, line, total)
+	}
+
+	fmt.Printf("Activity of the last %d days:\n", days)
+	printLine("Created:", created)
+	if completed {
+		printLine("Completed:", done)
+	}
+}
+
+// sparkline renders counts as blocks scaled to the largest count. Zero is the lowest block,
+// and any other count is higher.
+func sparkline(counts []int) string {
+	largest := 0
+	for _, count := range counts {
+		largest = max(largest, count)
+	}
+
+	var b strings.Builder
+	top := len(sparkBlocks) - 1
+	for _, count := range counts {
+		level := 0
+		if count > 0 {
+			level = (count*top + largest - 1) / largest
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// taskCompletedAt returns the time the task was completed, or its update time for tasks
+// completed before completion times were recorded
+func taskCompletedAt(task *model.Task) time.Time {
+	if task.CompletedAt != nil {
+		return task.CompletedAt.Time
+	}
+	return task.UpdatedAt.Time
+}
+
+// taskAgeDays returns the number of whole days since the task was created
+func taskAgeDays(task *model.Task, now time.Time) int {
+	return int(now.Sub(task.CreatedAt.Time) / (24 * time.Hour))
+}
+
+// executeSearch handles the 'search' command
+func (c *CLI) executeSearch(args []string) error {
+	// Create flag set
+	searchCmd := flag.NewFlagSet("search", flag.ContinueOnError)
+
+	// Define flags
+	ignoreCaseFlag := searchCmd.Bool("i", false, "Ignore case when matching")
+	searchCmd.BoolVar(ignoreCaseFlag, "ignore-case", false, "Ignore case when matching")
+	highlightFlag := searchCmd.Bool("highlight", false, "Highlight the matches")
+
+	// Set usage
+	searchCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo search \"<keyword>\" [-i|--ignore-case] [--highlight]\n\n")
+		fmt.Fprintf(os.Stderr, "Search task titles and descriptions, and memo titles and contents\n\n")
+		searchCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(searchCmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if keyword is provided
+	if len(positional) != 1 || positional[0] == "" {
+		searchCmd.Usage()
+		return fmt.Errorf("missing keyword")
+	}
+	keyword := positional[0]
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Narrow down the items to search with the search index, if it can be used
+	candidates := searchCandidates(s, store, keyword)
+
+	// Emphasize matches with reverse video when coloring, and with markers otherwise
+	r := c.renderer()
+	mark := func(text string) string {
+		if !*highlightFlag {
+			return text
+		}
+		return highlightMatches(text, keyword, *ignoreCaseFlag, r.color)
+	}
+
+	// matchingLines returns the lines of text containing the keyword
+	matchingLines := func(text string) []string {
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			if len(findMatches(line, keyword, *ignoreCaseFlag)) > 0 {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+
+	// Search tasks
+	tasks := append([]*model.Task(nil), store.Tasks...)
+	sortTasksByOrder(tasks)
+	found := false
+	for _, task := range tasks {
+		if candidates != nil && !candidates[task.ID] {
+			continue
+		}
+		titleMatches := len(findMatches(task.Title, keyword, *ignoreCaseFlag)) > 0
+		lines := matchingLines(task.Description)
+		if !titleMatches && len(lines) == 0 {
+			continue
+		}
+
+		if !found {
+			fmt.Println("Tasks:")
+			found = true
+		}
+		fmt.Printf("  %s  %s  %s\n", r.id(task.ID[:8]), r.doneMark(task), r.title(task, mark(task.Title)))
+		for _, line := range lines {
+			fmt.Printf("      %s\n", mark(line))
+		}
+	}
+
+	// Search memos
+	foundMemos := false
+	for _, memo := range store.Memos {
+		if candidates != nil && !candidates[memo.ID] {
+			continue
+		}
+		titleMatches := memo.Title != nil && len(findMatches(*memo.Title, keyword, *ignoreCaseFlag)) > 0
+		lines := matchingLines(memo.Content)
+		if !titleMatches && len(lines) == 0 {
+			continue
+		}
+
+		if !foundMemos {
+			if found {
+				fmt.Println()
+			}
+			fmt.Println("Memos:")
+			foundMemos = true
+		}
+		fmt.Printf("  %s  %s\n", r.id(memo.ID[:8]), mark(memoTitle(memo)))
+		for _, line := range lines {
+			fmt.Printf("      %s\n", mark(line))
+		}
+	}
+
+	if !found && !foundMemos {
+		fmt.Println("No matches found")
+	}
+	return nil
+}
+
+// searchIndexMinItems is the number of tasks and memos from which search uses the search index.
+// Scanning fewer items is fast enough that reading the index doesn't pay off.
+var searchIndexMinItems = 500
+
+// searchCandidates returns the IDs of the tasks and memos that may contain the keyword according to
+// the search index, or nil if all items must be scanned: when the store is small, or the index
+// is missing, broken, or out of date with the store.
+func searchCandidates(s *storage.Storage, store *model.Store, keyword string) map[string]bool {
+	if len(store.Tasks)+len(store.Memos) < searchIndexMinItems {
+		return nil
+	}
+	idx, err := s.LoadIndex()
+	if err != nil {
+		return nil
+	}
+	revision, err := s.DataRevision()
+	if err != nil || !idx.Fresh(revision) {
+		return nil
+	}
+	candidates, ok := idx.Candidates(keyword)
+	if !ok {
+		return nil
+	}
+	return candidates
+}
+
+// executeReindex handles the 'reindex' command
+func (c *CLI) executeReindex(args []string) error {
+	// Create flag set
+	reindexCmd := flag.NewFlagSet("reindex", flag.ContinueOnError)
+
+	// Set usage
+	reindexCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo reindex\n\n")
+		fmt.Fprintf(os.Stderr, "Rebuild the search index used by search on large data files\n\n")
+		reindexCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	if err := reindexCmd.Parse(args); err != nil {
+		return err
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Build and save the index
+	if err := s.SaveIndex(storage.BuildIndex(store)); err != nil {
+		return err
+	}
+
+	c.printf("Indexed %d tasks and %d memos\n", len(store.Tasks), len(store.Memos))
+	return nil
+}
+
+// findMatches returns the byte ranges of the non-overlapping occurrences of query in text.
+// With ignoreCase, runes are compared with Unicode case folding, so the ranges always point
+// into text itself even when the folded forms differ in length.
+func findMatches(text, query string, ignoreCase bool) [][2]int {
+	if query == "" {
+		return nil
+	}
+
+	var matches [][2]int
+	for start := 0; start < len(text); {
+		end := matchAt(text, start, query, ignoreCase)
+		if end < 0 {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			start += size
+			continue
+		}
+		matches = append(matches, [2]int{start, end})
+		start = end
+	}
+	return matches
+}
+
+// matchAt returns the end of a match of query starting at byte offset start of text, or -1
+func matchAt(text string, start int, query string, ignoreCase bool) int {
+	pos := start
+	for _, q := range query {
+		if pos >= len(text) {
+			return -1
+		}
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if r != q && !(ignoreCase && strings.EqualFold(string(r), string(q))) {
+			return -1
+		}
+		pos += size
+	}
+	return pos
+}
+
+// highlightMatches emphasizes the occurrences of query in text with reverse video,
+// or with ** markers when not writing to a terminal
+func highlightMatches(text, query string, ignoreCase, terminal bool) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range findMatches(text, query, ignoreCase) {
+		b.WriteString(text[last:m[0]])
+		if terminal {
+			b.WriteString(colorize(text[m[0]:m[1]], colorReverse))
+		} else {
+			b.WriteString("**" + text[m[0]:m[1]] + "**")
+		}
+		last = m[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// countMemoRefs returns the number of tasks referencing each memo, counting a task once per memo
+func countMemoRefs(store *model.Store) map[string]int {
+	counts := make(map[string]int, len(store.Memos))
+	for _, task := range store.Tasks {
+		for i, memoID := range task.MemoRefs {
+			if !containsString(task.MemoRefs[:i], memoID) {
+				counts[memoID]++
+			}
+		}
+	}
+	return counts
+}
+
+// markBrokenRefs annotates a task list line if the task references memos that don't exist
+func markBrokenRefs(r renderer, store *model.Store, task *model.Task, line string) string {
+	broken := len(findDanglingMemoRefs(store, task))
+	if broken == 0 {
+		return line
+	}
+
+	noun := "broken refs"
+	if broken == 1 {
+		noun = "broken ref"
+	}
+	line = fmt.Sprintf("%s  ⚠ (%d %s)", line, broken, noun)
+	return r.paint(line, colorRed)
+}
+
+// timelineEntry is a task or memo shown in the timeline
+type timelineEntry struct {
+	updatedAt time.Time
+	kind      string
+	line      string
+}
+
+// printTimeline prints tasks and memos mixed together, most recently updated first
+func printTimeline(tasks []*model.Task, memos []*model.Memo, limit int, fullID, reverse bool) {
+	var entries []timelineEntry
+	for _, task := range tasks {
+		entries = append(entries, timelineEntry{
+			updatedAt: task.UpdatedAt.Time,
+			kind:      "task",
+			line:      fmt.Sprintf("%s  %s  %s", displayID(task.ID, fullID), taskDoneMark(task), task.Title),
+		})
+	}
+	for _, memo := range memos {
+		entries = append(entries, timelineEntry{
+			updatedAt: memo.UpdatedAt.Time,
+			kind:      "memo",
+			line:      fmt.Sprintf("%s  %s  %s", displayID(memo.ID, fullID), memoTitle(memo), memoPreview(memo)),
+		})
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No tasks or memos found")
+		return
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].updatedAt.After(entries[j].updatedAt)
+	})
+	if reverse {
+		slices.Reverse(entries)
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	fmt.Println("Timeline:")
+	for _, entry := range entries {
+		fmt.Printf("  %s  %s  %s\n", entry.updatedAt.Local().Format("2006-01-02 15:04:05"), entry.kind, entry.line)
+	}
+}
+
+// taskDoneMark returns the checkbox mark for a task's completion status
+func taskDoneMark(task *model.Task) string {
+	if task.Done {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// displayID returns the first 8 characters of an ID, or the full ID if full is set
+func displayID(id string, full bool) string {
+	if full || len(id) <= 8 {
+		return id
+	}
+	return id[:8]
+}
+
+// memoTitle returns the title of a memo, or a placeholder if it has none
+func memoTitle(memo *model.Memo) string {
+	if memo.Title != nil {
+		return *memo.Title
+	}
+	return "<no title>"
+}
+
+// memoPreview returns the first line of a memo's content, truncated for list output
+func memoPreview(memo *model.Memo) string {
+	contentLines := strings.SplitN(memo.Content, "\n", 2)
+	return utils.TruncateToWidth(contentLines[0], 50)
+}
+
+// duplicateMemoTitles returns the titles that more than one of the memos has
+func duplicateMemoTitles(memos []*model.Memo) map[string]bool {
+	counts := make(map[string]int)
+	for _, memo := range memos {
+		counts[memoTitle(memo)]++
+	}
+
+	duplicates := make(map[string]bool)
+	for title, count := range counts {
+		if count > 1 {
+			duplicates[title] = true
+		}
+	}
+	return duplicates
+}
+
+// Reading speeds used to estimate reading time
+const (
+	wordsPerMinute = 200
+	charsPerMinute = 500 // For Japanese, which isn't separated into words
+)
+
+// readingStats describes the length of text and the estimated time to read it, e.g. "350 words, 2 min to read".
+// Japanese text is measured in characters instead of words.
+func readingStats(text string) string {
+	count, unit, perMinute := len(strings.Fields(text)), "words", wordsPerMinute
+	if containsJapanese(text) {
+		count, unit, perMinute = 0, "characters", charsPerMinute
+		for _, r := range text {
+			if !unicode.IsSpace(r) {
+				count++
+			}
+		}
+	}
+
+	minutes := float64(count) / float64(perMinute)
+	if minutes < 1 {
+		return fmt.Sprintf("%d %s, <1 min to read", count, unit)
+	}
+	return fmt.Sprintf("%d %s, %d min to read", count, unit, int(math.Round(minutes)))
+}
+
+// containsJapanese reports whether text contains kana or kanji
+func containsJapanese(text string) bool {
+	for _, r := range text {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderCodeBlocks replaces the ``` fences in text with a vertical bar in front of each
+// code line. An unclosed block runs to the end of the text.
+func renderCodeBlocks(text string) string {
+	var lines []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			line = "│ " + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// executeShow handles the 'show' command
+func (c *CLI) executeShow(args []string) error {
+	// Create flag set
+	showCmd := flag.NewFlagSet("show", flag.ContinueOnError)
+
+	// Define flags
+	sortMemosFlag := showCmd.String("sort-memos", "", "Sort referenced memos by 'created' or 'title' (default: reference order)")
+	renderFlag := showCmd.Bool("render", false, "Mark fenced code blocks in descriptions and content with a vertical bar")
+	withMemosFlag := showCmd.Bool("with-memos", false, "Show the full content of the memos referenced by a task")
+	statsFlag := showCmd.Bool("stats", false, "Show the length and estimated reading time of the description and referenced memos, or of the memo content")
+	pickFlag := showCmd.Bool("pick", false, "Pick the task from a numbered list")
+	noHintsFlag := showCmd.Bool("no-hints", false, "Don't mark referenced memos updated after the task")
+
+	// Set usage
+	showCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo show <id> [--sort-memos created|title] [--render] [--stats] [--with-memos] [--no-hints]\n")
+		fmt.Fprintf(os.Stderr, "       tamo show [--pick] [flags]\n\n")
 		fmt.Fprintf(os.Stderr, "Show details of a task or memo\n\n")
 		showCmd.PrintDefaults()
 	}
 
 	// Parse flags
-	if err := showCmd.Parse(args); err != nil {
+	positional, err := parseFlagsAnywhere(showCmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Check sort key
+	if *sortMemosFlag != "" && *sortMemosFlag != "created" && *sortMemosFlag != "title" {
+		return fmt.Errorf("invalid sort key: %s (expected created or title)", *sortMemosFlag)
+	}
+
+	// Pick a task if no ID is given
+	positional, err = c.pickTaskIDs(positional, *pickFlag)
+	if err != nil {
+		return err
+	}
+
+	// Check if ID is provided
+	if len(positional) < 1 {
+		return fmt.Errorf("missing ID")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find task or memo by ID, prefix, or position
+	task, memo, err := resolveItem(store, positional[0])
+	if err != nil {
+		return err
+	}
+
+	r := c.renderer()
+	if task != nil {
+		// Print task details
+		doneStr := "[ ] Not completed"
+		if task.Done {
+			doneStr = r.doneMark(task) + " Completed"
+		}
+
+		fmt.Printf("Task ID: %s\n", r.id(task.ID))
+		fmt.Printf("Title: %s\n", r.title(task, task.Title))
+		fmt.Printf("Order: %.1f\n", task.Order)
+		fmt.Printf("Status: %s\n", doneStr)
+		if len(task.Tags) > 0 {
+			fmt.Printf("Tags: %s\n", strings.Join(task.Tags, ", "))
+		}
+		if task.Priority != "" {
+			fmt.Printf("Priority: %s\n", task.Priority)
+		}
+		if task.ParentID != "" {
+			if parent := store.FindTaskByID(task.ParentID); parent != nil {
+				fmt.Printf("Parent: %s  %s\n", r.id(parent.ID[:8]), r.title(parent, parent.Title))
+			} else {
+				fmt.Printf("Parent: %s  <task not found>\n", shortID(task.ParentID))
+			}
+		}
+		fmt.Printf("Created: %s\n", c.formatTime(task.CreatedAt.Time))
+		fmt.Printf("Updated: %s\n", c.formatTime(task.UpdatedAt.Time))
+		if *statsFlag {
+			texts := []string{task.Description}
+			for _, memoID := range task.MemoRefs {
+				if memo := store.FindMemoByID(memoID); memo != nil {
+					texts = append(texts, memo.Content)
+				}
+			}
+			fmt.Printf("Stats: %s\n", readingStats(strings.Join(texts, "\n")))
+		}
+
+		if task.Description != "" {
+			fmt.Println("\nDescription:")
+			if *renderFlag {
+				fmt.Println(renderCodeBlocks(task.Description))
+			} else {
+				fmt.Println(task.Description)
+			}
+		}
+
+		if len(task.DependsOn) > 0 {
+			fmt.Println("\nDepends on:")
+			for _, depID := range task.DependsOn {
+				if dep := store.FindTaskByID(depID); dep != nil {
+					fmt.Printf("  %s  %s  %s\n", r.id(depID[:8]), r.doneMark(dep), r.title(dep, dep.Title))
+				} else {
+					fmt.Printf("  %s  <task not found>\n", depID[:8])
+				}
+			}
+		}
+
+		if children := store.ChildTasks(task.ID); len(children) > 0 {
+			fmt.Println("\nSubtasks:")
+			for _, child := range children {
+				fmt.Printf("  %s  %s  %s\n", r.id(child.ID[:8]), r.doneMark(child), r.title(child, child.Title))
+			}
+		}
+
+		// Point out memos updated after the task, which may call for a review of the task
+		hint := func(memo *model.Memo) string {
+			if *noHintsFlag || !memo.UpdatedAt.After(task.UpdatedAt.Time) {
+				return ""
+			}
+			return "  (memo updated after task)"
+		}
+
+		if len(task.MemoRefs) > 0 && *withMemosFlag {
+			// Show each memo in a delimited section
+			fmt.Println("\nReferenced Memos:")
+			for _, memoID := range sortMemoRefs(store, task.MemoRefs, *sortMemosFlag) {
+				memo := store.FindMemoByID(memoID)
+				if memo == nil {
+					fmt.Printf("\n--- %s  <memo not found> ---\n", memoID[:8])
+					continue
+				}
+				fmt.Printf("\n--- %s  %s ---%s\n", r.id(memoID[:8]), memoTitle(memo), hint(memo))
+				content := strings.TrimRight(memo.Content, "\n")
+				if *renderFlag {
+					content = renderCodeBlocks(content)
+				}
+				fmt.Println(content)
+			}
+		} else if len(task.MemoRefs) > 0 {
+			// Add a preview of the content to memos whose title another referenced memo also has
+			var refMemos []*model.Memo
+			for _, memoID := range task.MemoRefs {
+				if memo := store.FindMemoByID(memoID); memo != nil {
+					refMemos = append(refMemos, memo)
+				}
+			}
+			duplicates := duplicateMemoTitles(refMemos)
+
+			fmt.Println("\nReferenced Memos:")
+			for _, memoID := range sortMemoRefs(store, task.MemoRefs, *sortMemosFlag) {
+				memo := store.FindMemoByID(memoID)
+				if memo != nil && duplicates[memoTitle(memo)] {
+					fmt.Printf("  %s  %s  %s%s\n", r.id(memoID[:8]), memoTitle(memo), memoPreview(memo), hint(memo))
+				} else if memo != nil {
+					fmt.Printf("  %s  %s%s\n", r.id(memoID[:8]), memoTitle(memo), hint(memo))
+				} else {
+					fmt.Printf("  %s  <memo not found>\n", memoID[:8])
+				}
+			}
+		}
+
+		return nil
+	}
+
+	// Print memo details
+	fmt.Printf("Memo ID: %s\n", r.id(memo.ID))
+	if memo.Title != nil {
+		fmt.Printf("Title: %s\n", *memo.Title)
+	}
+	if memo.Archived {
+		fmt.Println("Status: Archived")
+	}
+	fmt.Printf("Created: %s\n", c.formatTime(memo.CreatedAt.Time))
+	fmt.Printf("Updated: %s\n", c.formatTime(memo.UpdatedAt.Time))
+	if *statsFlag {
+		fmt.Printf("Stats: %s\n", readingStats(memo.Content))
+	}
+
+	if len(memo.Attachments) > 0 {
+		fmt.Println("\nAttachments:")
+		for _, path := range memo.Attachments {
+			fmt.Printf("  %s\n", path)
+		}
+	}
+
+	// Show the referencing tasks above and/or below the content, as configured
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
+	refsPosition := cfg.Get("show.refs_position")
+
+	referencingTasks := store.TasksReferencingMemo(memo.ID)
+	// Sort tasks for consistent display order
+	sortTasksByOrder(referencingTasks)
+	printReferencingTasks := func() {
+		if len(referencingTasks) == 0 {
+			return
+		}
+		fmt.Println("\nReference Tasks:")
+		for _, task := range referencingTasks {
+			fmt.Printf("%s %.1f %s %s\n", r.doneMark(task), task.Order, r.id(task.ID[:8]), r.title(task, task.Title))
+		}
+	}
+
+	if refsPosition != "bottom" {
+		printReferencingTasks()
+	}
+
+	fmt.Println("\nContent:")
+	if *renderFlag {
+		fmt.Println(renderCodeBlocks(memo.Content))
+	} else {
+		fmt.Println(memo.Content)
+	}
+
+	if refsPosition != "top" {
+		printReferencingTasks()
+	}
+
+	return nil
+}
+
+// sortMemoRefs returns memo references sorted by the given key ("created" or "title").
+// References to memos that don't exist are placed at the end. An empty key keeps the reference order.
+func sortMemoRefs(store *model.Store, refs []string, by string) []string {
+	sorted := append([]string{}, refs...)
+	if by == "" {
+		return sorted
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a := store.FindMemoByID(sorted[i])
+		b := store.FindMemoByID(sorted[j])
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		switch by {
+		case "created":
+			return a.CreatedAt.Before(b.CreatedAt.Time)
+		case "title":
+			return strings.ToLower(memoTitle(a)) < strings.ToLower(memoTitle(b))
+		}
+		return false
+	})
+	return sorted
+}
+
+// rmPreviewLines is the number of lines of memo content shown by 'rm' before removing a memo
+const rmPreviewLines = 3
+
+// executeRemove handles the 'rm' command
+func (c *CLI) executeRemove(args []string) error {
+	// Manual argument parsing
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo rm <id>... [-f|--force] [--show-content] [--cascade]\n")
+		fmt.Fprintf(os.Stderr, "       tamo rm [--pick] [-f|--force]\n\n")
+		fmt.Fprintf(os.Stderr, "Remove tasks or memos\n\n")
+		fmt.Fprintf(os.Stderr, "  -f, --force       Force removal without confirmation, and remove the found items even if some IDs are not found\n")
+		fmt.Fprintf(os.Stderr, "  --show-content    Show the whole content of memos to remove instead of the first lines\n")
+		fmt.Fprintf(os.Stderr, "  --cascade         Also remove the subtasks of the tasks, instead of making them top-level tasks\n")
+		fmt.Fprintf(os.Stderr, "  --pick            Pick the task from a numbered list\n")
+		fmt.Fprintf(os.Stderr, "\nSet TAMO_ASSUME_YES=1 to answer yes to confirmations.\n")
+	}
+
+	// Separate IDs and flags
+	var ids []string
+	force := false
+	showContent := false
+	pick := false
+	cascade := false
+	for _, arg := range args {
+		if arg == "-f" || arg == "--force" {
+			force = true
+		} else if arg == "--show-content" {
+			showContent = true
+		} else if arg == "--cascade" {
+			cascade = true
+		} else if arg == "--pick" {
+			pick = true
+		} else {
+			ids = append(ids, arg)
+		}
+	}
+
+	// Pick a task if no ID is given
+	ids, err := c.pickTaskIDs(ids, pick)
+	if err != nil {
+		return err
+	}
+
+	// Check if we have at least an ID
+	if len(ids) < 1 {
+		usage()
+		return fmt.Errorf("missing ID")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Resolve all IDs before removing anything
+	var tasks []*model.Task
+	var memos []*model.Memo
+	var notFound []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		task, memo, err := resolveItem(store, id)
+		var notFoundErr *notFoundError
+		switch {
+		case errors.As(err, &notFoundErr):
+			notFound = append(notFound, id)
+		case err != nil:
+			return err
+		case task != nil:
+			if !seen[task.ID] {
+				seen[task.ID] = true
+				tasks = append(tasks, task)
+			}
+		default:
+			if !seen[memo.ID] {
+				seen[memo.ID] = true
+				memos = append(memos, memo)
+			}
+		}
+	}
+
+	if len(ids) == 1 && len(notFound) == 1 {
+		return itemNotFoundError(store, ids[0])
+	}
+	for _, id := range notFound {
+		fmt.Fprintf(os.Stderr, "%s\n", capitalize(itemNotFoundError(store, id).Error()))
+	}
+	if len(notFound) > 0 && !force {
+		return notFoundErrorf("%d of %d IDs not found, nothing removed. Use -f or --force to remove the others anyway", len(notFound), len(ids))
+	}
+
+	// Check if memos are referenced by any tasks. Tasks removed together don't count.
+	for _, memo := range memos {
+		var referencingTasks []*model.Task
+		for _, task := range store.TasksReferencingMemo(memo.ID) {
+			if !seen[task.ID] {
+				referencingTasks = append(referencingTasks, task)
+			}
+		}
+		if len(referencingTasks) > 0 {
+			if !force {
+				fmt.Fprintf(os.Stderr, "Memo '%s' is referenced by %d tasks. Use -f or --force to remove anyway.\n", memoTitle(memo), len(referencingTasks))
+				for _, task := range referencingTasks {
+					fmt.Fprintf(os.Stderr, "  %s  %s\n", task.ID[:8], task.Title)
+				}
+				return fmt.Errorf("memo removal aborted")
+			} else {
+				fmt.Fprintf(os.Stderr, "Forcing removal of memo referenced by %d tasks\n", len(referencingTasks))
+			}
+		}
+	}
+
+	// Remove the subtasks with their parents, or make them top-level tasks
+	orphans := make(map[string][]*model.Task)
+	if cascade {
+		for _, task := range tasks {
+			for _, descendant := range store.DescendantTasks(task.ID) {
+				if !seen[descendant.ID] {
+					seen[descendant.ID] = true
+					tasks = append(tasks, descendant)
+				}
+			}
+		}
+	} else {
+		for _, task := range tasks {
+			for _, child := range store.ChildTasks(task.ID) {
+				if !seen[child.ID] {
+					orphans[task.ID] = append(orphans[task.ID], child)
+				}
+			}
+		}
+	}
+
+	// Check if other tasks depend on the tasks. Tasks removed together don't count.
+	dependents := make(map[string][]*model.Task)
+	for _, task := range tasks {
+		for _, dependent := range store.TasksDependingOn(task.ID) {
+			if !seen[dependent.ID] {
+				dependents[task.ID] = append(dependents[task.ID], dependent)
+			}
+		}
+	}
+	for _, task := range tasks {
+		if len(dependents[task.ID]) == 0 {
+			continue
+		}
+		if !force {
+			fmt.Fprintf(os.Stderr, "Task '%s' is a dependency of %d tasks. Use -f or --force to remove anyway.\n", task.Title, len(dependents[task.ID]))
+			for _, dependent := range dependents[task.ID] {
+				fmt.Fprintf(os.Stderr, "  %s  %s\n", dependent.ID[:8], dependent.Title)
+			}
+			return fmt.Errorf("task removal aborted")
+		}
+		fmt.Fprintf(os.Stderr, "Forcing removal of task that %d tasks depend on, removing the dependencies:\n", len(dependents[task.ID]))
+		for _, dependent := range dependents[task.ID] {
+			fmt.Fprintf(os.Stderr, "  %s  %s\n", dependent.ID[:8], dependent.Title)
+		}
+	}
+
+	// Ask for confirmation before removing tasks, unless turned off with rm.confirm
+	askConfirmation := !force && c.removalNeedsConfirmation()
+	if len(tasks) > 0 && askConfirmation {
+		fmt.Fprintln(os.Stderr, "The following tasks will be removed:")
+		for _, task := range tasks {
+			fmt.Fprintf(os.Stderr, "  %s  %s\n", task.ID[:8], task.Title)
+			if task.Description != "" {
+				fmt.Fprintf(os.Stderr, "      %s\n", strings.SplitN(task.Description, "\n", 2)[0])
+			}
+			if len(task.MemoRefs) > 0 {
+				fmt.Fprintf(os.Stderr, "      References %d memos\n", len(task.MemoRefs))
+			}
+		}
+		if !confirm(fmt.Sprintf("Are you sure you want to remove %d tasks?", len(tasks))) {
+			fmt.Fprintln(os.Stderr, "Task removal aborted")
+			return nil
+		}
+	}
+
+	// Show the content of memos before removing them, and ask for confirmation unless forced
+	if len(memos) > 0 {
+		fmt.Fprintln(os.Stderr, "The following memos will be removed:")
+		for _, memo := range memos {
+			fmt.Fprintf(os.Stderr, "  %s  %s\n", memo.ID[:8], memoTitle(memo))
+			lines := strings.Split(strings.TrimRight(memo.Content, "\n"), "\n")
+			shown := lines
+			if !showContent && len(lines) > rmPreviewLines {
+				shown = lines[:rmPreviewLines]
+			}
+			for _, line := range shown {
+				fmt.Fprintf(os.Stderr, "      %s\n", line)
+			}
+			if len(shown) < len(lines) {
+				fmt.Fprintf(os.Stderr, "      ... (%d more lines, use --show-content to see all)\n", len(lines)-len(shown))
+			}
+		}
+		if askConfirmation && !confirm(fmt.Sprintf("Are you sure you want to remove %d memos?", len(memos))) {
+			fmt.Fprintln(os.Stderr, "Memo removal aborted")
+			return nil
+		}
+	}
+
+	// Remove tasks and memos, and the dependencies on the removed tasks
+	for _, task := range tasks {
+		store.RemoveTask(task.ID)
+		for _, dependent := range dependents[task.ID] {
+			dependent.DependsOn = removeString(dependent.DependsOn, task.ID)
+			dependent.Touch()
+		}
+		for _, child := range orphans[task.ID] {
+			child.ParentID = ""
+			child.Touch()
+		}
+	}
+	for _, memo := range memos {
+		store.RemoveMemo(memo.ID)
+	}
+
+	// Save store
+	if len(tasks) > 0 || len(memos) > 0 {
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+	}
+
+	for _, task := range tasks {
+		c.printf("Task '%s' removed\n", task.Title)
+		if len(orphans[task.ID]) > 0 {
+			c.printf("%d subtasks of task '%s' are now top-level tasks\n", len(orphans[task.ID]), task.Title)
+		}
+	}
+	for _, memo := range memos {
+		c.printf("Memo '%s' removed\n", memoTitle(memo))
+	}
+
+	if len(notFound) > 0 {
+		return notFoundErrorf("%d of %d IDs not found", len(notFound), len(ids))
+	}
+	return nil
+}
+
+// executeArchive handles the 'archive' command
+func (c *CLI) executeArchive(args []string) error {
+	// Create flag set
+	archiveCmd := flag.NewFlagSet("archive", flag.ContinueOnError)
+
+	// Define flags
+	completedBeforeFlag := archiveCmd.String("completed-before", "", "Archive only tasks completed before this date (YYYY-MM-DD)")
+	forceFlag := archiveCmd.Bool("f", false, "Archive without confirmation")
+	archiveCmd.BoolVar(forceFlag, "force", false, "Archive without confirmation")
+
+	// Set usage
+	archiveCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo archive [--completed-before <date>] [-f|--force]\n\n")
+		fmt.Fprintf(os.Stderr, "Move completed tasks out of the task list\n\n")
+		archiveCmd.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nSet TAMO_ASSUME_YES=1 to answer yes to confirmations.\n")
+	}
+
+	// Parse flags
+	if err := archiveCmd.Parse(args); err != nil {
+		return err
+	}
+
+	// Parse date
+	var before time.Time
+	if *completedBeforeFlag != "" {
+		var err error
+		before, err = utils.ParseDate(*completedBeforeFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find completed tasks, using the update time for tasks completed before completion times were recorded
+	var targets []*model.Task
+	for _, task := range store.Tasks {
+		if !task.Done {
+			continue
+		}
+		if !before.IsZero() && !taskCompletedAt(task).Before(before) {
+			continue
+		}
+		targets = append(targets, task)
+	}
+
+	if len(targets) == 0 {
+		c.printf("No completed tasks to archive\n")
+		return nil
+	}
+
+	// Ask for confirmation
+	sortTasksByOrder(targets)
+	if !*forceFlag {
+		fmt.Fprintln(os.Stderr, "The following tasks will be archived:")
+		for _, task := range targets {
+			fmt.Fprintf(os.Stderr, "  %s  %s\n", task.ID[:8], task.Title)
+		}
+		if !confirm(fmt.Sprintf("Archive %d completed tasks?", len(targets))) {
+			fmt.Fprintln(os.Stderr, "Archiving aborted")
+			return nil
+		}
+	}
+
+	// Move tasks to the archive
+	archived := make(map[string]bool, len(targets))
+	for _, task := range targets {
+		archived[task.ID] = true
+	}
+	remaining := make([]*model.Task, 0, len(store.Tasks)-len(targets))
+	for _, task := range store.Tasks {
+		if archived[task.ID] {
+			store.ArchivedTasks = append(store.ArchivedTasks, task)
+		} else {
+			remaining = append(remaining, task)
+		}
+	}
+	store.Tasks = remaining
+	store.Reindex()
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("%d tasks archived\n", len(targets))
+	return nil
+}
+
+// executeBackup handles the 'backup' command
+func (c *CLI) executeBackup(args []string) error {
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo backup list\n")
+		fmt.Fprintf(os.Stderr, "       tamo backup restore <timestamp>\n\n")
+		fmt.Fprintf(os.Stderr, "List or restore the backups made before each save\n")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing subcommand: 'list' or 'restore'")
+	}
+	if isHelpArg(args[0]) {
+		usage()
+		return flag.ErrHelp
+	}
+
+	s := c.newStorage()
+
+	switch args[0] {
+	case "list":
+		timestamps, err := s.ListBackups()
+		if err != nil {
+			return err
+		}
+		if len(timestamps) == 0 {
+			fmt.Println("No backups found")
+			return nil
+		}
+
+		// Show the newest backup first
+		for i := len(timestamps) - 1; i >= 0; i-- {
+			line := "  " + timestamps[i]
+			if t, err := storage.BackupTime(timestamps[i]); err == nil {
+				line += "  " + t.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Println(line)
+		}
+		return nil
+	case "restore":
+		if len(args) < 2 {
+			usage()
+			return fmt.Errorf("missing timestamp")
+		}
+		if !s.Exists() {
+			return fmt.Errorf("%w: %s", storage.ErrNotInitialized, s.FilePath)
+		}
+
+		restored, err := s.RestoreBackup(args[1])
+		if err != nil {
+			return err
+		}
+
+		c.printf("Backup %s restored\n", restored)
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// executeTrash handles the 'trash' command
+func (c *CLI) executeTrash(args []string) error {
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo trash list\n")
+		fmt.Fprintf(os.Stderr, "       tamo trash empty [-f|--force]\n\n")
+		fmt.Fprintf(os.Stderr, "List or permanently delete removed tasks and memos\n\n")
+		fmt.Fprintf(os.Stderr, "  -f, --force    Empty the trash without confirmation\n")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing subcommand: 'list' or 'empty'")
+	}
+	if isHelpArg(args[0]) {
+		usage()
+		return flag.ErrHelp
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(store.Trash.Tasks) == 0 && len(store.Trash.Memos) == 0 {
+			fmt.Println("Trash is empty")
+			return nil
+		}
+
+		if len(store.Trash.Tasks) > 0 {
+			fmt.Println("Tasks:")
+			for _, task := range store.Trash.Tasks {
+				fmt.Printf("  %s  %s  %s\n", task.ID[:8], c.formatTime(task.DeletedAt.Local()), task.Title)
+			}
+		}
+		if len(store.Trash.Memos) > 0 {
+			if len(store.Trash.Tasks) > 0 {
+				fmt.Println()
+			}
+			fmt.Println("Memos:")
+			for _, memo := range store.Trash.Memos {
+				fmt.Printf("  %s  %s  %s\n", memo.ID[:8], c.formatTime(memo.DeletedAt.Local()), memoTitle(&memo.Memo))
+			}
+		}
+		return nil
+	case "empty":
+		force := len(args) > 1 && (args[1] == "-f" || args[1] == "--force")
+
+		count := len(store.Trash.Tasks) + len(store.Trash.Memos)
+		if count == 0 {
+			fmt.Println("Trash is empty")
+			return nil
+		}
+
+		if !force && !confirm(fmt.Sprintf("Permanently delete %d items in the trash?", count)) {
+			fmt.Fprintln(os.Stderr, "Emptying trash aborted")
+			return nil
+		}
+
+		store.Trash.Tasks = make([]*model.TrashedTask, 0)
+		store.Trash.Memos = make([]*model.TrashedMemo, 0)
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("%d items permanently deleted\n", count)
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// executeGC handles the 'gc' command
+func (c *CLI) executeGC(args []string) error {
+	// Create flag set
+	gcCmd := flag.NewFlagSet("gc", flag.ContinueOnError)
+
+	// Define flags
+	memosFlag := gcCmd.Bool("memos", false, "Remove memos that no task references")
+	forceFlag := gcCmd.Bool("f", false, "Remove without confirmation")
+	gcCmd.BoolVar(forceFlag, "force", false, "Remove without confirmation")
+
+	// Set usage
+	gcCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo gc --memos [-f|--force]\n\n")
+		fmt.Fprintf(os.Stderr, "Move unreferenced items to the trash\n\n")
+		gcCmd.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nSet TAMO_ASSUME_YES=1 to answer yes to confirmations.\n")
+	}
+
+	// Parse flags
+	if err := gcCmd.Parse(args); err != nil {
+		return err
+	}
+
+	if !*memosFlag {
+		gcCmd.Usage()
+		return fmt.Errorf("nothing to collect: specify --memos")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find orphan memos
+	orphans := store.OrphanMemos()
+	if len(orphans) == 0 {
+		fmt.Println("No orphan memos found")
+		return nil
+	}
+
+	// Ask for confirmation
+	if !*forceFlag {
+		fmt.Fprintln(os.Stderr, "The following memos are not referenced by any task:")
+		for _, memo := range orphans {
+			fmt.Fprintf(os.Stderr, "  %s  %s  %s\n", memo.ID[:8], memoTitle(memo), memoPreview(memo))
+		}
+		if !confirm(fmt.Sprintf("Remove %d orphan memos?", len(orphans))) {
+			fmt.Fprintln(os.Stderr, "Memo removal aborted")
+			return nil
+		}
+	}
+
+	// Remove memos
+	for _, memo := range orphans {
+		store.RemoveMemo(memo.ID)
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("%d orphan memos removed\n", len(orphans))
+	return nil
+}
+
+// executeRestore handles the 'restore' command
+func (c *CLI) executeRestore(args []string) error {
+	// Create flag set
+	restoreCmd := flag.NewFlagSet("restore", flag.ContinueOnError)
+
+	// Set usage
+	restoreCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo restore <id>\n\n")
+		fmt.Fprintf(os.Stderr, "Restore a removed task or memo from the trash\n\n")
+		restoreCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	if err := restoreCmd.Parse(args); err != nil {
+		return err
+	}
+
+	// Check if ID is provided
+	if restoreCmd.NArg() < 1 {
+		return fmt.Errorf("missing ID")
+	}
+
+	// Get ID
+	id := restoreCmd.Arg(0)
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task or memo in the trash by ID or a unique ID prefix
+	taskIndex, memoIndex, err := resolveTrashItem(store, id)
+	if err != nil {
+		return err
+	}
+
+	if taskIndex >= 0 {
+		trashed := store.Trash.Tasks[taskIndex]
+
+		// Remove from trash
+		store.Trash.Tasks = append(store.Trash.Tasks[:taskIndex], store.Trash.Tasks[taskIndex+1:]...)
+
+		// Put the task back at the end of the list
+		task := trashed.Task
+		task.Order = store.GetMaxTaskOrder() + 1.0
+		task.Touch()
+		store.AddTask(&task)
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' restored\n", task.Title)
+		return nil
+	}
+
+	trashed := store.Trash.Memos[memoIndex]
+
+	// Remove from trash
+	store.Trash.Memos = append(store.Trash.Memos[:memoIndex], store.Trash.Memos[memoIndex+1:]...)
+
+	// Put the memo back with its original ID
+	memo := trashed.Memo
+	store.AddMemo(&memo)
+
+	// Restore the references of tasks that still exist, including archived ones
+	restoredRefs := 0
+	for _, taskID := range trashed.RefTaskIDs {
+		task := store.FindTaskByID(taskID)
+		if task == nil {
+			task = store.FindArchivedTaskByID(taskID)
+		}
+		if task != nil && store.AddMemoRef(task, memo.ID) {
+			restoredRefs++
+		}
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("Memo '%s' restored\n", memoTitle(&memo))
+	if restoredRefs > 0 {
+		c.printf("Restored references from %d tasks\n", restoredRefs)
+	}
+	return nil
+}
+
+// resolveTrashItem finds a task or memo in the trash by its full ID or a unique ID prefix,
+// and returns its index in Trash.Tasks or Trash.Memos, the other index being -1.
+// It reports an error if the prefix matches more than one item.
+func resolveTrashItem(store *model.Store, id string) (taskIndex, memoIndex int, err error) {
+	var tasks, memos []int
+	for i, trashed := range store.Trash.Tasks {
+		if trashed.ID == id {
+			return i, -1, nil
+		}
+		if strings.HasPrefix(trashed.ID, id) {
+			tasks = append(tasks, i)
+		}
+	}
+	for i, trashed := range store.Trash.Memos {
+		if trashed.ID == id {
+			return -1, i, nil
+		}
+		if strings.HasPrefix(trashed.ID, id) {
+			memos = append(memos, i)
+		}
+	}
+
+	switch {
+	case len(tasks) == 1 && len(memos) == 0:
+		return tasks[0], -1, nil
+	case len(tasks) == 0 && len(memos) == 1:
+		return -1, memos[0], nil
+	case len(tasks)+len(memos) > 1:
+		return -1, -1, fmt.Errorf("%w: %s matches %d tasks and %d memos in the trash", errAmbiguousID, id, len(tasks), len(memos))
+	}
+	return -1, -1, notFoundErrorf("no task or memo found in the trash with ID: %s", id)
+}
+
+// Helper functions
+
+// tagColors are the ANSI colors assigned to tags
+var tagColors = []string{"31", "32", "33", "34", "35", "36", "91", "92", "93", "94", "95", "96"}
+
+// tagBadges renders tags as badges like "[backend]". With color, each tag gets a color
+// picked from the hash of its name, so a tag always has the same color.
+func tagBadges(tags []string, color bool) string {
+	badges := make([]string, len(tags))
+	for i, tag := range tags {
+		badges[i] = "[" + tag + "]"
+		if color {
+			h := fnv.New32a()
+			h.Write([]byte(tag))
+			badges[i] = colorize(badges[i], tagColors[h.Sum32()%uint32(len(tagColors))])
+		}
+	}
+	return strings.Join(badges, " ")
+}
+
+// inlineTagRegex matches #tag in text. The # must start the text or follow a space or
+// an opening parenthesis, so anchors in URLs and Markdown headings ("# Title") don't match.
+var inlineTagRegex = regexp.MustCompile(`(?:^|[\s(])#([\p{L}\p{N}_-]+)`)
+
+// inlineCodeRegex matches inline code spans
+var inlineCodeRegex = regexp.MustCompile("`[^`]*`")
+
+// inlineTags returns the #tags written in text, in order of appearance without duplicates.
+// Tags in fenced code blocks and inline code spans are ignored.
+func inlineTags(text string) []string {
+	var tags []string
+	inCode := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		line = inlineCodeRegex.ReplaceAllString(line, "")
+		for _, match := range inlineTagRegex.FindAllStringSubmatch(line, -1) {
+			if !containsString(tags, match[1]) {
+				tags = append(tags, match[1])
+			}
+		}
+	}
+	return tags
+}
+
+// taskTags returns the tags of a task merged with the #tags written in its description
+func taskTags(task *model.Task) []string {
+	tags := append([]string{}, task.Tags...)
+	for _, tag := range inlineTags(task.Description) {
+		if !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// stringListFlag is a flag.Value that collects the values of a repeated flag
+type stringListFlag []string
+
+// String implements the flag.Value interface
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set implements the flag.Value interface
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// isHelpArg reports whether the argument asks for usage, so commands with subcommands
+// can show it and exit successfully, as flag sets do for -h
+func isHelpArg(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// parseFlagsAnywhere parses flags that may appear before or after positional arguments
+// and returns the positional arguments. Arguments after "--" are always positional.
+func parseFlagsAnywhere(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+
+		// The flag package consumes "--" and stops, so everything after it is positional
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// maxSuggestions is the number of similar items suggested when an ID is not found
+const maxSuggestions = 3
+
+// idCandidate is a task or memo that may be suggested for an ID that was not found
+type idCandidate struct {
+	id    string
+	title string
+}
+
+// taskCandidates returns the tasks as candidates for suggestions
+func taskCandidates(tasks []*model.Task) []idCandidate {
+	candidates := make([]idCandidate, len(tasks))
+	for i, task := range tasks {
+		candidates[i] = idCandidate{task.ID, task.Title}
+	}
+	return candidates
+}
+
+// memoCandidates returns the memos as candidates for suggestions
+func memoCandidates(memos []*model.Memo) []idCandidate {
+	candidates := make([]idCandidate, len(memos))
+	for i, memo := range memos {
+		candidates[i] = idCandidate{memo.ID, memoTitle(memo)}
+	}
+	return candidates
+}
+
+// minSuggestionIDLength is the shortest ID for which similar items are suggested,
+// as nearly every ID is close to a very short one
+const minSuggestionIDLength = 4
+
+// didYouMean returns a hint listing the candidates whose IDs start with something closest to id,
+// or "" if none is close. The hint starts with a newline, to be appended to an error.
+func didYouMean(id string, candidates []idCandidate) string {
+	if len(id) < minSuggestionIDLength {
+		return ""
+	}
+	id = strings.ToLower(id)
+	maxDistance := max(1, len(id)/4)
+
+	type suggestion struct {
+		candidate idCandidate
+		distance  int
+	}
+	var suggestions []suggestion
+	for _, c := range candidates {
+		prefix := c.id
+		if len(prefix) > len(id) {
+			prefix = prefix[:len(id)]
+		}
+		if distance := utils.EditDistance(id, prefix); distance <= maxDistance {
+			suggestions = append(suggestions, suggestion{c, distance})
+		}
+	}
+	if len(suggestions) == 0 {
+		return ""
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+	for i, s := range suggestions {
+		if s.distance > suggestions[0].distance {
+			suggestions = suggestions[:i]
+			break
+		}
+	}
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	if len(suggestions) == 1 {
+		c := suggestions[0].candidate
+		return fmt.Sprintf("\nDid you mean: %s %s", c.id[:8], c.title)
+	}
+	var hint strings.Builder
+	hint.WriteString("\nDid you mean one of these?")
+	for _, s := range suggestions {
+		fmt.Fprintf(&hint, "\n  %s  %s", s.candidate.id[:8], s.candidate.title)
+	}
+	return hint.String()
+}
+
+// taskNotFoundError returns the error for a task ID that was not found, suggesting similar tasks
+func taskNotFoundError(store *model.Store, id string) error {
+	return notFoundErrorf("no task found with ID: %s%s", id, didYouMean(id, taskCandidates(store.Tasks)))
+}
+
+// memoNotFoundError returns the error for an ID that matches no memo, suggesting similar memos
+func memoNotFoundError(store *model.Store, id string) error {
+	return notFoundErrorf("no memo found with ID: %s%s", id, didYouMean(id, memoCandidates(store.Memos)))
+}
+
+// itemNotFoundError returns the error for an ID that matches neither a task nor a memo,
+// suggesting similar tasks and memos
+func itemNotFoundError(store *model.Store, id string) error {
+	candidates := append(taskCandidates(store.Tasks), memoCandidates(store.Memos)...)
+	return notFoundErrorf("no task or memo found with ID: %s%s", id, didYouMean(id, candidates))
+}
+
+// errAmbiguousID is returned when an ID prefix matches more than one item
+var errAmbiguousID = errors.New("ambiguous ID")
+
+// notFoundError is returned when an ID or name matches no item
+type notFoundError struct {
+	message string
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+// notFoundErrorf formats a notFoundError
+func notFoundErrorf(format string, args ...any) error {
+	return &notFoundError{fmt.Sprintf(format, args...)}
+}
+
+// resolveTask finds a task by its full ID, a unique ID prefix, or its position, like %2 or %u1.
+// It reports an error if the prefix matches more than one task.
+func resolveTask(store *model.Store, id string) (*model.Task, error) {
+	if isTaskPosition(id) {
+		return taskAtPosition(store, id)
+	}
+	if task := store.FindTaskByID(id); task != nil {
+		return task, nil
+	}
+
+	// Only a shorter ID can be the prefix of other IDs
+	var matches []*model.Task
+	if !utils.IsUUID(id) {
+		for _, t := range store.Tasks {
+			if strings.HasPrefix(t.ID, id) {
+				matches = append(matches, t)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, taskNotFoundError(store, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %s matches %d tasks", errAmbiguousID, id, len(matches))
+	}
+}
+
+// resolveItem finds a task or memo by its full ID or a unique ID prefix, or a task by its position.
+// It reports an error if the prefix matches more than one task or memo.
+func resolveItem(store *model.Store, id string) (*model.Task, *model.Memo, error) {
+	if isTaskPosition(id) {
+		task, err := taskAtPosition(store, id)
+		return task, nil, err
+	}
+	if task := store.FindTaskByID(id); task != nil {
+		return task, nil, nil
+	}
+	if memo := store.FindMemoByID(id); memo != nil {
+		return nil, memo, nil
+	}
+
+	// Only a shorter ID can be the prefix of other IDs
+	var tasks []*model.Task
+	var memos []*model.Memo
+	if !utils.IsUUID(id) {
+		for _, t := range store.Tasks {
+			if strings.HasPrefix(t.ID, id) {
+				tasks = append(tasks, t)
+			}
+		}
+		for _, m := range store.Memos {
+			if strings.HasPrefix(m.ID, id) {
+				memos = append(memos, m)
+			}
+		}
+	}
+
+	switch {
+	case len(tasks)+len(memos) == 0:
+		return nil, nil, itemNotFoundError(store, id)
+	case len(tasks) == 1 && len(memos) == 0:
+		return tasks[0], nil, nil
+	case len(tasks) == 0 && len(memos) == 1:
+		return nil, memos[0], nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s matches %d tasks and %d memos", errAmbiguousID, id, len(tasks), len(memos))
+	}
+}
+
+// capitalize returns s with its first letter in upper case
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// resolveMemo finds a memo by its full ID or a unique ID prefix.
+// It reports an error if the prefix matches more than one memo.
+func resolveMemo(store *model.Store, id string) (*model.Memo, error) {
+	if memo := store.FindMemoByID(id); memo != nil {
+		return memo, nil
+	}
+
+	// Only a shorter ID can be the prefix of other IDs
+	var matches []*model.Memo
+	if !utils.IsUUID(id) {
+		for _, m := range store.Memos {
+			if strings.HasPrefix(m.ID, id) {
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, memoNotFoundError(store, id)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %s matches %d memos", errAmbiguousID, id, len(matches))
+	}
+}
+
+// sortTasksByOrder sorts tasks by their order field, and tasks with the same order by creation time,
+// so that list, %N positions, and renumber agree on the order
+func sortTasksByOrder(tasks []*model.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return taskOrderLess(tasks[i], tasks[j])
+	})
+}
+
+// taskOrderLess reports whether task a comes before task b, by order and then by creation time
+func taskOrderLess(a, b *model.Task) bool {
+	if a.Order != b.Order {
+		return a.Order < b.Order
+	}
+	return a.CreatedAt.Before(b.CreatedAt.Time)
+}
+
+// listSortKeys are the keys accepted by list --sort
+var listSortKeys = []string{"order", "created", "updated", "title", "usage"}
+
+// taskSortLess returns the comparison of tasks for a list --sort key,
+// or nil if the tasks are listed by order
+func taskSortLess(key string) func(a, b *model.Task) bool {
+	switch key {
+	case "created":
+		return func(a, b *model.Task) bool { return a.CreatedAt.Before(b.CreatedAt.Time) }
+	case "updated":
+		return func(a, b *model.Task) bool { return a.UpdatedAt.Before(b.UpdatedAt.Time) }
+	case "title":
+		return func(a, b *model.Task) bool { return a.Title < b.Title }
+	}
+	return nil
+}
+
+// memoSortLess returns the comparison of memos for a list --sort key, breaking ties by creation time,
+// or nil if the memos are listed in the order they were added
+func memoSortLess(key string, refCounts map[string]int) func(a, b *model.Memo) bool {
+	var less func(a, b *model.Memo) bool
+	switch key {
+	case "order", "created":
+		// Memos have no order, so they are sorted by creation time
+		return func(a, b *model.Memo) bool { return a.CreatedAt.Before(b.CreatedAt.Time) }
+	case "updated":
+		less = func(a, b *model.Memo) bool { return a.UpdatedAt.Before(b.UpdatedAt.Time) }
+	case "title":
+		less = func(a, b *model.Memo) bool { return memoTitle(a) < memoTitle(b) }
+	case "usage":
+		// Most referenced first
+		less = func(a, b *model.Memo) bool { return refCounts[a.ID] > refCounts[b.ID] }
+	default:
+		return nil
+	}
+	return func(a, b *model.Memo) bool {
+		if less(a, b) || less(b, a) {
+			return less(a, b)
+		}
+		return a.CreatedAt.Before(b.CreatedAt.Time)
+	}
+}
+
+// sortTasksPendingFirst sorts uncompleted tasks before completed ones, each like sortTasksByOrder
+func sortTasksPendingFirst(tasks []*model.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Done != tasks[j].Done {
+			return !tasks[i].Done
+		}
+		return taskOrderLess(tasks[i], tasks[j])
+	})
+}
+
+// containsString checks if a string slice contains a string
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns the slice without the occurrences of a string
+func removeString(slice []string, s string) []string {
+	var kept []string
+	for _, item := range slice {
+		if item != s {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// findDanglingMemoRefs returns the memo references of a task that point to memos not in the store
+func findDanglingMemoRefs(store *model.Store, task *model.Task) []string {
+	var refs []string
+	for _, memoID := range task.MemoRefs {
+		if store.FindMemoByID(memoID) == nil {
+			refs = append(refs, memoID)
+		}
+	}
+	return refs
+}
+
+// readText reads all of r as text without a limit on the line length. Like reading line by line,
+// it turns CRLF line endings into LF and ends non-empty text with a newline.
+func readText(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text, nil
+}
+
+// readLine reads a line from stdin. It reads one byte at a time so that the input
+// after the line is left for the next prompt when stdin is a pipe or a file.
+func readLine() string {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			break
+		}
+	}
+	return strings.TrimSpace(string(line))
+}
+
+// confirm asks a yes/no question and reports whether the answer is yes.
+// It returns true without asking if TAMO_ASSUME_YES is set to 1.
+func confirm(question string) bool {
+	if os.Getenv("TAMO_ASSUME_YES") == "1" {
+		return true
+	}
+
+	fmt.Fprintf(os.Stderr, "%s (y/N): ", question)
+	answer := strings.ToLower(readLine())
+	return answer == "y" || answer == "yes"
+}
+
+// readLineFrom reads a line from the given reader, returning io.EOF if no input is left
+func readLineFrom(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// executeEdit handles the 'edit' command
+func (c *CLI) executeEdit(args []string) error {
+	// Create flag set
+	editCmd := flag.NewFlagSet("edit", flag.ContinueOnError)
+
+	// Define flags
+	editorFlag := editCmd.Bool("editor", false, "Use editor to edit content")
+	titleFlag := editCmd.String("title", "", "Set the title of the task or memo")
+	descriptionFlag := editCmd.String("description", "", "Set the description of the task")
+	editCmd.StringVar(descriptionFlag, "d", "", "Shorthand for --description")
+	contentFlag := editCmd.String("content", "", "Set the content of the memo")
+	editCmd.StringVar(contentFlag, "c", "", "Shorthand for --content")
+	var addMemoFlag, removeMemoFlag stringListFlag
+	editCmd.Var(&addMemoFlag, "add-memo", "Add a memo reference to the task (can be repeated)")
+	editCmd.Var(&removeMemoFlag, "remove-memo", "Remove a memo reference from the task (can be repeated)")
+	var addDependsOnFlag, removeDependsOnFlag stringListFlag
+	editCmd.Var(&addDependsOnFlag, "add-depends-on", "Add a task the task depends on (can be repeated)")
+	editCmd.Var(&removeDependsOnFlag, "remove-depends-on", "Remove a task the task depends on (can be repeated)")
+	doneFlag := editCmd.Bool("done", false, "Mark the task as done")
+	undoneFlag := editCmd.Bool("undone", false, "Mark the task as not done")
+	archiveFlag := editCmd.Bool("archive", false, "Archive the memo")
+	unarchiveFlag := editCmd.Bool("unarchive", false, "Unarchive the memo")
+	pickFlag := editCmd.Bool("pick", false, "Pick the task from a numbered list")
+
+	// Set usage
+	editCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo edit <id>... [--editor]\n")
+		fmt.Fprintf(os.Stderr, "       tamo edit <id>... [--title \"<title>\"] [--description \"<description>\"] [--content \"<content>\"]\n")
+		fmt.Fprintf(os.Stderr, "                         [--add-memo <memo_id>]... [--remove-memo <memo_id>]... [--done|--undone]\n")
+		fmt.Fprintf(os.Stderr, "                         [--add-depends-on <task_id>]... [--remove-depends-on <task_id>]... [--archive|--unarchive]\n")
+		fmt.Fprintf(os.Stderr, "       tamo edit [--pick] [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Edit tasks or memos one after another, saving after each\n\n")
+		editCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(editCmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Pick a task if no ID is given
+	positional, err = c.pickTaskIDs(positional, *pickFlag)
+	if err != nil {
+		return err
+	}
+
+	// Check if ID is provided
+	if len(positional) < 1 {
+		return fmt.Errorf("missing ID")
+	}
+
+	// Collect the modifications given as flags
+	changes := editChanges{
+		addMemos:        addMemoFlag,
+		removeMemos:     removeMemoFlag,
+		addDependsOn:    addDependsOnFlag,
+		removeDependsOn: removeDependsOnFlag,
+		done:            *doneFlag,
+		undone:          *undoneFlag,
+		archive:         *archiveFlag,
+		unarchive:       *unarchiveFlag,
+	}
+	editCmd.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			changes.title = titleFlag
+		case "description", "d":
+			changes.description = descriptionFlag
+		case "content", "c":
+			changes.content = contentFlag
+		}
+	})
+
+	if changes.done && changes.undone {
+		return fmt.Errorf("--done and --undone flags cannot be used together")
+	}
+	if changes.archive && changes.unarchive {
+		return fmt.Errorf("--archive and --unarchive flags cannot be used together")
+	}
+	if changes.any() && *editorFlag {
+		return fmt.Errorf("--editor cannot be used together with modification flags")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Resolve all IDs before editing anything
+	var tasks []*model.Task
+	var memos []*model.Memo
+	for _, id := range positional {
+		task, memo, err := resolveItem(store, id)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, task)
+		memos = append(memos, memo)
+	}
+
+	// Apply the modifications given as flags to every item, saving once only if all of them succeed
+	if changes.any() {
+		for i := range positional {
+			var err error
+			if tasks[i] != nil {
+				err = applyTaskChanges(tasks[i], store, changes)
+			} else {
+				err = applyMemoChanges(memos[i], changes)
+			}
+			if err != nil && len(positional) > 1 {
+				return fmt.Errorf("%s: %w (nothing changed)", positional[i], err)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		for i := range positional {
+			if len(positional) > 1 {
+				c.printf("[%d/%d] ", i+1, len(positional))
+			}
+			if tasks[i] != nil {
+				c.printf("Task '%s' updated\n", tasks[i].Title)
+			} else {
+				c.printf("Memo '%s' updated\n", memoTitle(memos[i]))
+			}
+		}
+		return nil
+	}
+
+	// Edit each item in turn, saving after each
+	for i := range positional {
+		if len(positional) > 1 {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", i+1, len(positional), positional[i])
+		}
+
+		var err error
+		if tasks[i] != nil {
+			err = c.editTask(tasks[i], store, s, *editorFlag)
+		} else {
+			err = c.editMemo(memos[i], store, s, *editorFlag)
+		}
+
+		if errors.Is(err, errEditAborted) {
+			fmt.Fprintln(os.Stderr, "Edit aborted, no changes saved")
+			remaining := len(positional) - i - 1
+			if remaining > 0 && confirm(fmt.Sprintf("Skip the remaining %d items?", remaining)) {
+				return nil
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// executeAppend handles the 'append' command
+func (c *CLI) executeAppend(args []string) error {
+	// Create flag set
+	appendCmd := flag.NewFlagSet("append", flag.ContinueOnError)
+
+	// Define flags
+	fromStdinFlag := appendCmd.Bool("from-stdin", false, "Read the text to append from stdin")
+	timestampFlag := appendCmd.Bool("timestamp", false, "Prefix the text with the current time")
+
+	// Set usage
+	appendCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo append <id> \"<text>\" [--timestamp]\n")
+		fmt.Fprintf(os.Stderr, "       tamo append <id> --from-stdin [--timestamp]\n\n")
+		fmt.Fprintf(os.Stderr, "Append text on a new line to the content of a memo or the description of a task\n\n")
+		appendCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(appendCmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Check arguments
+	if len(positional) < 1 {
+		appendCmd.Usage()
+		return fmt.Errorf("missing ID")
+	}
+	var text string
+	if *fromStdinFlag {
+		if len(positional) > 1 {
+			return fmt.Errorf("text cannot be given with --from-stdin")
+		}
+		text, err = readText(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("error reading from stdin: %w", err)
+		}
+	} else {
+		if len(positional) < 2 {
+			appendCmd.Usage()
+			return fmt.Errorf("missing text")
+		}
+		if len(positional) > 2 {
+			return fmt.Errorf("unexpected arguments: %s (quote the text if it contains spaces)", strings.Join(positional[2:], " "))
+		}
+		text = positional[1]
+	}
+	text = strings.TrimRight(text, "\n")
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("nothing to append: the text is empty")
+	}
+	if *timestampFlag {
+		text = time.Now().Format("2006-01-02 15:04:05") + " " + text
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task or memo
+	task, memo, err := resolveItem(store, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if task != nil {
+		task.Description = appendLine(task.Description, text)
+		task.Touch()
+	} else {
+		memo.Content = appendLine(memo.Content, text)
+		memo.Touch()
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	if task != nil {
+		fmt.Printf("Appended to task '%s'\n", task.Title)
+	} else {
+		fmt.Printf("Appended to memo '%s'\n", memoTitle(memo))
+	}
+	return nil
+}
+
+// appendLine adds text to the end of the content on a new line
+func appendLine(content, text string) string {
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return text
+	}
+	return content + "\n" + text
+}
+
+// errEditAborted is returned when the user saves an empty file in the editor
+var errEditAborted = errors.New("edit aborted")
+
+// editChanges holds the modifications given to the 'edit' command as flags
+type editChanges struct {
+	title           *string
+	description     *string
+	content         *string
+	addMemos        []string
+	removeMemos     []string
+	addDependsOn    []string
+	removeDependsOn []string
+	done            bool
+	undone          bool
+	archive         bool
+	unarchive       bool
+}
+
+// any reports whether any modification was given
+func (c editChanges) any() bool {
+	return c.title != nil || c.description != nil || c.content != nil ||
+		len(c.addMemos) > 0 || len(c.removeMemos) > 0 || len(c.addDependsOn) > 0 || len(c.removeDependsOn) > 0 ||
+		c.done || c.undone || c.archive || c.unarchive
+}
+
+// applyTaskChanges applies the modifications given as flags to a task without prompting.
+// The store is not saved, so that nothing is changed if an error is returned.
+func applyTaskChanges(task *model.Task, store *model.Store, changes editChanges) error {
+	if changes.content != nil {
+		return fmt.Errorf("--content can only be used with memos")
+	}
+	if changes.archive || changes.unarchive {
+		return fmt.Errorf("--archive and --unarchive can only be used with memos")
+	}
+
+	// Validate memo references before changing anything
+	addMemos, err := resolveMemoRefs(store, changes.addMemos, true)
+	if err != nil {
+		return err
+	}
+	addDependsOn, err := resolveDependencies(store, changes.addDependsOn)
+	if err != nil {
+		return err
+	}
+	for _, depID := range addDependsOn {
+		if depID == task.ID {
+			return fmt.Errorf("a task cannot depend on itself")
+		}
+		if store.WouldCreateDependencyCycle(task.ID, depID) {
+			dep := store.FindTaskByID(depID)
+			return fmt.Errorf("task '%s' already depends on task '%s', depending on each other would create a cycle", dep.Title, task.Title)
+		}
+	}
+
+	if changes.title != nil {
+		if *changes.title == "" {
+			return fmt.Errorf("task title cannot be empty")
+		}
+		task.Title = *changes.title
+	}
+	if changes.description != nil {
+		task.Description = *changes.description
+	}
+	for _, memoID := range addMemos {
+		store.AddMemoRef(task, memoID)
+	}
+	for _, refID := range changes.removeMemos {
+		var matches []string
+		for _, memoID := range task.MemoRefs {
+			if strings.HasPrefix(memoID, refID) && !containsString(matches, memoID) {
+				matches = append(matches, memoID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("task does not reference memo %s", refID)
+		case 1:
+			store.RemoveMemoRef(task, matches[0])
+		default:
+			return fmt.Errorf("%w: %s matches %d referenced memos", errAmbiguousID, refID, len(matches))
+		}
+	}
+	for _, depID := range addDependsOn {
+		if !containsString(task.DependsOn, depID) {
+			task.DependsOn = append(task.DependsOn, depID)
+		}
+	}
+	for _, refID := range changes.removeDependsOn {
+		removed := false
+		for i := 0; i < len(task.DependsOn); i++ {
+			if strings.HasPrefix(task.DependsOn[i], refID) {
+				task.DependsOn = append(task.DependsOn[:i], task.DependsOn[i+1:]...)
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			return fmt.Errorf("task does not depend on task %s", refID)
+		}
+	}
+	if changes.done {
+		task.MarkDone()
+	}
+	if changes.undone {
+		task.MarkUndone()
+	}
+
+	// Update timestamp
+	task.Touch()
+	return nil
+}
+
+// executeAttach handles the 'attach' command
+func (c *CLI) executeAttach(args []string) error {
+	// Create flag set
+	attachCmd := flag.NewFlagSet("attach", flag.ContinueOnError)
+
+	// Define flags
+	includeArchivedFlag := attachCmd.Bool("include-archived", false, "Reference archived memos without asking")
+
+	// Set usage
+	attachCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo attach <task_id> <memo_id>... [--include-archived]\n\n")
+		fmt.Fprintf(os.Stderr, "Add memo references to the end of a task's references\n\n")
+		attachCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(attachCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		attachCmd.Usage()
+		return fmt.Errorf("missing task ID or memo ID")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task and the memos before changing anything
+	task, err := resolveTask(store, positional[0])
+	if err != nil {
+		return err
+	}
+	memoRefs, err := resolveMemoRefs(store, positional[1:], *includeArchivedFlag)
+	if err != nil {
+		return err
+	}
+
+	// Append the memos not referenced yet
+	changed := false
+	for _, memoID := range memoRefs {
+		if store.AddMemoRef(task, memoID) {
+			changed = true
+		}
+	}
+
+	if changed {
+		task.Touch()
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+	}
+
+	printTaskMemoRefs(store, task)
+	return nil
+}
+
+// executeDetach handles the 'detach' command
+func (c *CLI) executeDetach(args []string) error {
+	// Create flag set
+	detachCmd := flag.NewFlagSet("detach", flag.ContinueOnError)
+
+	// Define flags
+	allFlag := detachCmd.Bool("all", false, "Remove all memo references")
+
+	// Set usage
+	detachCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo detach <task_id> <memo_id>...\n")
+		fmt.Fprintf(os.Stderr, "       tamo detach <task_id> --all\n\n")
+		fmt.Fprintf(os.Stderr, "Remove memo references from a task\n\n")
+		detachCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(detachCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		detachCmd.Usage()
+		return fmt.Errorf("missing task ID")
+	}
+	if *allFlag && len(positional) > 1 {
+		return fmt.Errorf("--all cannot be used with memo IDs")
+	}
+	if !*allFlag && len(positional) < 2 {
+		detachCmd.Usage()
+		return fmt.Errorf("missing memo ID (or --all)")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task
+	task, err := resolveTask(store, positional[0])
+	if err != nil {
+		return err
+	}
+
+	// Match the memo IDs against the references of the task, which may include memos that no longer exist,
+	// before changing anything
+	remove := make(map[string]bool)
+	for _, refID := range positional[1:] {
+		var matches []string
+		for _, memoID := range task.MemoRefs {
+			if strings.HasPrefix(memoID, refID) {
+				matches = append(matches, memoID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("task does not reference memo %s", refID)
+		case 1:
+			remove[matches[0]] = true
+		default:
+			return fmt.Errorf("%w: %s matches %d referenced memos", errAmbiguousID, refID, len(matches))
+		}
+	}
+
+	var memoRefs []string
+	for _, memoID := range task.MemoRefs {
+		if !*allFlag && !remove[memoID] {
+			memoRefs = append(memoRefs, memoID)
+		}
+	}
+
+	if len(memoRefs) != len(task.MemoRefs) {
+		store.SetMemoRefs(task, memoRefs)
+		task.Touch()
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+	}
+
+	printTaskMemoRefs(store, task)
+	return nil
+}
+
+// executeBlock handles the 'block' command
+func (c *CLI) executeBlock(args []string) error {
+	// Create flag set
+	blockCmd := flag.NewFlagSet("block", flag.ContinueOnError)
+
+	// Define flags
+	var byFlag stringListFlag
+	blockCmd.Var(&byFlag, "by", "ID of a task that blocks the task (can be repeated)")
+
+	// Set usage
+	blockCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo block <task_id> --by <other_task_id>...\n\n")
+		fmt.Fprintf(os.Stderr, "Record that a task can't start until other tasks are done\n\n")
+		blockCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(blockCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		blockCmd.Usage()
+		return fmt.Errorf("missing task ID")
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("unexpected arguments: %s (give the blocking tasks with --by)", strings.Join(positional[1:], " "))
+	}
+	if len(byFlag) == 0 {
+		blockCmd.Usage()
+		return fmt.Errorf("missing --by")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task and the blocking tasks before changing anything
+	task, err := resolveTask(store, positional[0])
+	if err != nil {
+		return err
+	}
+	blockerIDs, err := resolveDependencies(store, byFlag)
+	if err != nil {
+		return err
+	}
+	for _, blockerID := range blockerIDs {
+		if blockerID == task.ID {
+			return fmt.Errorf("task '%s' cannot block itself", task.Title)
+		}
+		if store.WouldCreateDependencyCycle(task.ID, blockerID) {
+			blocker := store.FindTaskByID(blockerID)
+			return fmt.Errorf("task '%s' is already blocked by task '%s', blocking each other would create a cycle", blocker.Title, task.Title)
+		}
+	}
+
+	changed := false
+	for _, blockerID := range blockerIDs {
+		if !containsString(task.DependsOn, blockerID) {
+			task.DependsOn = append(task.DependsOn, blockerID)
+			changed = true
+		}
+	}
+	if changed {
+		task.Touch()
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+	}
+
+	printTaskBlockers(store, task)
+	return nil
+}
+
+// executeUnblock handles the 'unblock' command
+func (c *CLI) executeUnblock(args []string) error {
+	// Create flag set
+	unblockCmd := flag.NewFlagSet("unblock", flag.ContinueOnError)
+
+	// Define flags
+	var byFlag stringListFlag
+	unblockCmd.Var(&byFlag, "by", "ID of a blocking task to remove (can be repeated)")
+	allFlag := unblockCmd.Bool("all", false, "Remove all blocking tasks")
+
+	// Set usage
+	unblockCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo unblock <task_id> --by <other_task_id>...\n")
+		fmt.Fprintf(os.Stderr, "       tamo unblock <task_id> --all\n\n")
+		fmt.Fprintf(os.Stderr, "Remove the tasks blocking a task\n\n")
+		unblockCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(unblockCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		unblockCmd.Usage()
+		return fmt.Errorf("missing task ID")
+	}
+	if len(positional) > 1 {
+		return fmt.Errorf("unexpected arguments: %s (give the blocking tasks with --by)", strings.Join(positional[1:], " "))
+	}
+	if *allFlag && len(byFlag) > 0 {
+		return fmt.Errorf("--all cannot be used with --by")
+	}
+	if !*allFlag && len(byFlag) == 0 {
+		unblockCmd.Usage()
+		return fmt.Errorf("missing --by (or --all)")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task
+	task, err := resolveTask(store, positional[0])
+	if err != nil {
+		return err
+	}
+
+	// Match the IDs against the blockers of the task, which may include tasks that no longer exist,
+	// before changing anything
+	remove := make(map[string]bool)
+	for _, refID := range byFlag {
+		// A position gives a task that exists, so its full ID is matched
+		if isTaskPosition(refID) {
+			blocker, err := taskAtPosition(store, refID)
+			if err != nil {
+				return err
+			}
+			refID = blocker.ID
+		}
+
+		var matches []string
+		for _, depID := range task.DependsOn {
+			if strings.HasPrefix(depID, refID) {
+				matches = append(matches, depID)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return fmt.Errorf("task is not blocked by task %s", refID)
+		case 1:
+			remove[matches[0]] = true
+		default:
+			return fmt.Errorf("%w: %s matches %d blocking tasks", errAmbiguousID, refID, len(matches))
+		}
+	}
+
+	var dependsOn []string
+	for _, depID := range task.DependsOn {
+		if !*allFlag && !remove[depID] {
+			dependsOn = append(dependsOn, depID)
+		}
+	}
+
+	if len(dependsOn) != len(task.DependsOn) {
+		task.DependsOn = dependsOn
+		task.Touch()
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+	}
+
+	printTaskBlockers(store, task)
+	return nil
+}
+
+// printTaskBlockers prints the tasks a task depends on, in order, with their status
+func printTaskBlockers(store *model.Store, task *model.Task) {
+	if len(task.DependsOn) == 0 {
+		fmt.Printf("Task '%s' is not blocked by any task\n", task.Title)
+		return
+	}
+	fmt.Printf("Task '%s' is blocked by %d tasks:\n", task.Title, len(task.DependsOn))
+	for _, depID := range task.DependsOn {
+		if dep := store.FindTaskByID(depID); dep != nil {
+			fmt.Printf("  %s  %s  %s\n", depID[:8], taskDoneMark(dep), dep.Title)
+		} else {
+			fmt.Printf("  %s  <task not found>\n", depID[:8])
+		}
+	}
+}
+
+// printTaskMemoRefs prints the memos a task references, in order
+func printTaskMemoRefs(store *model.Store, task *model.Task) {
+	if len(task.MemoRefs) == 0 {
+		fmt.Printf("Task '%s' references no memos\n", task.Title)
+		return
+	}
+	fmt.Printf("Task '%s' references %d memos:\n", task.Title, len(task.MemoRefs))
+	for _, memoID := range task.MemoRefs {
+		if memo := store.FindMemoByID(memoID); memo != nil {
+			fmt.Printf("  %s  %s\n", memoID[:8], memoTitle(memo))
+		} else {
+			fmt.Printf("  %s  <memo not found>\n", memoID[:8])
+		}
+	}
+}
+
+// applyMemoChanges applies the modifications given as flags to a memo without prompting.
+// Like applyTaskChanges, it doesn't save the store.
+func applyMemoChanges(memo *model.Memo, changes editChanges) error {
+	if changes.description != nil || len(changes.addMemos) > 0 || len(changes.removeMemos) > 0 ||
+		len(changes.addDependsOn) > 0 || len(changes.removeDependsOn) > 0 || changes.done || changes.undone {
+		return fmt.Errorf("only --title, --content, --archive, and --unarchive can be used with memos")
+	}
+
+	if changes.title != nil {
+		if *changes.title == "" {
+			memo.Title = nil
+		} else {
+			title := *changes.title
+			memo.Title = &title
+		}
+	}
+	if changes.content != nil {
+		memo.Content = *changes.content
+	}
+	if changes.archive {
+		memo.Archived = true
+	}
+	if changes.unarchive {
+		memo.Archived = false
+	}
+
+	// Update timestamp
+	memo.Touch()
+	return nil
+}
+
+// Markers separating the sections of the task edit template. They are matched as whole lines,
+// so the description can contain any Markdown, including headings.
+const (
+	taskDescriptionMarker = "---TAMO-DESCRIPTION---"
+	taskMemoRefsMarker    = "---TAMO-MEMO-REFS---"
+)
+
+// legacyMemoRefsHeading starts the memo references in the template used before the marker lines
+const legacyMemoRefsHeading = "# Memo References"
+
+// taskEditTemplate renders a task as the content edited with --editor
+func taskEditTemplate(task *model.Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", task.Title)
+	fmt.Fprintf(&b, "%s\n", taskDescriptionMarker)
+	if task.Description != "" {
+		fmt.Fprintf(&b, "%s\n", task.Description)
+	}
+	fmt.Fprintf(&b, "%s (one memo ID per line)\n", taskMemoRefsMarker)
+	for _, ref := range task.MemoRefs {
+		fmt.Fprintf(&b, "%s\n", ref)
+	}
+	return b.String()
+}
+
+// parseTaskEditTemplate extracts the title, description, and memo references from a task edit template.
+// The title is the first "# " line before the description marker, the description is everything
+// between the markers, and the memo references are the non-empty lines after the last references marker.
+func parseTaskEditTemplate(content string) (title, description string, memoRefs []string, err error) {
+	lines := strings.Split(content, "\n")
+
+	descStart, refsStart := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if descStart < 0 && trimmed == taskDescriptionMarker {
+			descStart = i
+		} else if descStart >= 0 && strings.HasPrefix(trimmed, taskMemoRefsMarker) {
+			refsStart = i
+		}
+	}
+	if descStart < 0 && !strings.Contains(content, taskMemoRefsMarker) {
+		if t, d, refs, ok := parseLegacyTaskEditTemplate(lines); ok {
+			return t, d, refs, nil
+		}
+	}
+	if descStart < 0 {
+		return "", "", nil, fmt.Errorf("the %s line is missing", taskDescriptionMarker)
+	}
+	if refsStart < 0 {
+		return "", "", nil, fmt.Errorf("the %s line is missing", taskMemoRefsMarker)
+	}
+
+	titleFound := false
+	for _, line := range lines[:descStart] {
+		if strings.HasPrefix(line, "# ") {
+			title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			titleFound = true
+			break
+		}
+	}
+	if !titleFound {
+		return "", "", nil, fmt.Errorf("the title line (\"# <title>\") is missing")
+	}
+	if title == "" {
+		return "", "", nil, fmt.Errorf("task title cannot be empty")
+	}
+
+	description = strings.TrimSpace(strings.Join(lines[descStart+1:refsStart], "\n"))
+
+	for _, line := range lines[refsStart+1:] {
+		if ref := strings.TrimSpace(line); ref != "" {
+			memoRefs = append(memoRefs, ref)
+		}
+	}
+
+	return title, description, memoRefs, nil
+}
+
+// parseLegacyTaskEditTemplate parses content in the old template format, where the title is the
+// first "# " line and the memo references follow the last "# Memo References" line, so content
+// written before the marker lines were introduced is still accepted.
+func parseLegacyTaskEditTemplate(lines []string) (title, description string, memoRefs []string, ok bool) {
+	titleLine, refsStart := -1, -1
+	for i, line := range lines {
+		if titleLine < 0 && strings.HasPrefix(line, "# ") {
+			titleLine = i
+		} else if titleLine >= 0 && strings.HasPrefix(line, legacyMemoRefsHeading) {
+			refsStart = i
+		}
+	}
+	if titleLine < 0 || refsStart < 0 {
+		return "", "", nil, false
+	}
+
+	title = strings.TrimSpace(strings.TrimPrefix(lines[titleLine], "# "))
+	if title == "" {
+		return "", "", nil, false
+	}
+	description = strings.TrimSpace(strings.Join(lines[titleLine+1:refsStart], "\n"))
+	for _, line := range lines[refsStart+1:] {
+		if ref := strings.TrimSpace(line); ref != "" && !strings.HasPrefix(ref, "# ") {
+			memoRefs = append(memoRefs, ref)
+		}
+	}
+	return title, description, memoRefs, true
+}
+
+// editTask edits a task using an editor or simple prompts
+func (c *CLI) editTask(task *model.Task, store *model.Store, s *storage.Storage, useEditor bool) error {
+	if useEditor {
+		// Write task content to temporary file
+		content := taskEditTemplate(task)
+
+		var title, description string
+		var memoRefs []string
+		for {
+			// Open editor
+			editedContent, err := c.editInEditor("tamo-task-*.md", content)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(editedContent) == "" {
+				return errEditAborted
+			}
+
+			// Parse edited content, then expand ID prefixes to full IDs,
+			// re-opening the edited content if either fails.
+			// Archived memos are accepted without asking, as the task may already reference them.
+			title, description, memoRefs, err = parseTaskEditTemplate(editedContent)
+			if err == nil {
+				memoRefs, err = resolveMemoRefs(store, memoRefs, true)
+			}
+			if err == nil {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if !confirm("Re-open the editor to fix it?") {
+				return fmt.Errorf("invalid task content, no changes saved: %w", err)
+			}
+			content = editedContent
+		}
+
+		// Update task
+		task.Title = title
+		task.Description = description
+		store.SetMemoRefs(task, memoRefs)
+		task.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' updated\n", task.Title)
+		return nil
+	} else {
+		// Simple prompt-based editing
+		fmt.Fprintf(os.Stderr, "Editing task: %s\n", task.ID)
+
+		// Edit title
+		fmt.Fprintf(os.Stderr, "Title [%s]: ", task.Title)
+		title := readLine()
+		if title != "" {
+			task.Title = title
+		}
+
+		// Edit description
+		fmt.Fprintf(os.Stderr, "Description [Press Enter to keep, 'edit' to edit]:\n")
+		descAction := readLine()
+		if descAction == "edit" {
+			fmt.Fprintln(os.Stderr, "Enter new description (press Ctrl+D when finished):")
+			text, err := readText(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("error reading description: %w", err)
+			}
+			task.Description = strings.TrimSpace(text)
+		}
+
+		// Edit memo refs
+		fmt.Fprintf(os.Stderr, "Memo References [%s] (comma-separated): ", strings.Join(task.MemoRefs, ","))
+		for refsStr := readLine(); refsStr != ""; refsStr = readLine() {
+			var memoRefs []string
+			for _, ref := range strings.Split(refsStr, ",") {
+				// Trim whitespace from each memo ID
+				if ref = strings.TrimSpace(ref); ref != "" {
+					memoRefs = append(memoRefs, ref)
+				}
+			}
+
+			// Expand ID prefixes to full IDs, asking again if a memo is not found
+			resolved, err := resolveMemoRefs(store, memoRefs, true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Memo References [%s] (comma-separated): ", strings.Join(task.MemoRefs, ","))
+				continue
+			}
+			store.SetMemoRefs(task, resolved)
+			break
+		}
+
+		// Update timestamp
+		task.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' updated\n", task.Title)
+		return nil
+	}
+}
+
+// editMemo edits a memo using an editor or simple prompts
+func (c *CLI) editMemo(memo *model.Memo, store *model.Store, s *storage.Storage, useEditor bool) error {
+	if useEditor {
+		// Write memo content to temporary file
+		var content string
+		if memo.Title != nil {
+			content = fmt.Sprintf("# %s\n\n%s\n", *memo.Title, memo.Content)
+		} else {
+			content = fmt.Sprintf("# \n\n%s\n", memo.Content)
+		}
+
+		var title *string
+		for {
+			// Open editor
+			editedContent, err := c.editInEditor("tamo-memo-*.md", content)
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(editedContent) == "" {
+				return errEditAborted
+			}
+
+			// Extract title and content, re-opening the edited content if the title line was deleted,
+			// which would otherwise drop the title and keep its text as content
+			title, content, err = parseMemoEditTemplate(editedContent)
+			if err == nil {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if !confirm("Re-open the editor to fix it?") {
+				return fmt.Errorf("invalid memo content, no changes saved: %w", err)
+			}
+			content = editedContent
+		}
+
+		// Update memo
+		memo.Title = title
+		memo.Content = content
+		memo.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		titleStr := "<no title>"
+		if memo.Title != nil {
+			titleStr = *memo.Title
+		}
+		c.printf("Memo '%s' updated\n", titleStr)
+		return nil
+	} else {
+		// Simple prompt-based editing
+		fmt.Fprintf(os.Stderr, "Editing memo: %s\n", memo.ID)
+
+		// Edit title
+		titleStr := "<no title>"
+		if memo.Title != nil {
+			titleStr = *memo.Title
+		}
+		fmt.Fprintf(os.Stderr, "Title [%s]: ", titleStr)
+		title := readLine()
+		if title != "" {
+			memo.Title = &title
+		} else if title == "<no title>" {
+			memo.Title = nil
+		}
+
+		// Edit content
+		fmt.Fprintf(os.Stderr, "Content [Press Enter to keep, 'edit' to edit]:\n")
+		contentAction := readLine()
+		if contentAction == "edit" {
+			fmt.Fprintln(os.Stderr, "Enter new content (press Ctrl+D when finished):")
+			text, err := readText(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("error reading content: %w", err)
+			}
+			memo.Content = strings.TrimSpace(text)
+		}
+
+		// Update timestamp
+		memo.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		titleStr = "<no title>"
+		if memo.Title != nil {
+			titleStr = *memo.Title
+		}
+		c.printf("Memo '%s' updated\n", titleStr)
+		return nil
+	}
+}
+
+// editorCommand returns the editor command line, split into the program and its arguments.
+// TAMO_EDITOR takes precedence over the configured editor, which takes precedence over EDITOR.
+func editorCommand(configured string) []string {
+	editor := os.Getenv("TAMO_EDITOR")
+	if strings.TrimSpace(editor) == "" {
+		editor = configured
+	}
+	if strings.TrimSpace(editor) == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		// Default to a simple editor if not set
+		editor = "nano"
+	}
+	return strings.Fields(editor)
+}
+
+// editInEditor writes content to a temporary file, opens it in the user's editor, and returns the edited content
+func (c *CLI) editInEditor(pattern, content string) (string, error) {
+	// Create temporary file
+	tmpFile, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	// Write content to temporary file
+	if _, err := tmpFile.Write([]byte(content)); err != nil {
+		tmpFile.Close()
+		return "", fmt.Errorf("failed to write to temporary file: %w", err)
+	}
+	tmpFile.Close()
+
+	// Open editor
+	editor := editorCommand(c.configValue("editor"))
+	cmd := exec.Command(editor[0], append(editor[1:], tmpFile.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor command failed: %w", err)
+	}
+
+	// Read edited content
+	editedContent, err := ioutil.ReadFile(tmpFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited content: %w", err)
+	}
+
+	return string(editedContent), nil
+}
+
+// parseMemoEditTemplate parses a memo edited with --editor. Unlike a new memo, whose title line
+// is optional, the edited text must keep the title line, even if it is an empty "# " line.
+func parseMemoEditTemplate(text string) (*string, string, error) {
+	firstLine, _, _ := strings.Cut(strings.TrimLeft(text, "\r\n"), "\n")
+	if firstLine := strings.TrimSpace(firstLine); firstLine != "#" && !strings.HasPrefix(firstLine, "# ") {
+		return nil, "", fmt.Errorf("the title line (\"# <title>\", or \"# \" for no title) is missing")
+	}
+	title, content := parseMemoEditorContent(strings.TrimLeft(text, "\r\n"))
+	return title, content, nil
+}
+
+// parseMemoEditorContent parses memo text written in an editor.
+// A first line starting with "# " is the title and the rest is the content.
+func parseMemoEditorContent(text string) (*string, string) {
+	lines := strings.Split(text, "\n")
+
+	var title *string
+	if len(lines) > 0 && (strings.HasPrefix(lines[0], "# ") || strings.TrimSpace(lines[0]) == "#") {
+		if t := strings.TrimSpace(strings.TrimPrefix(lines[0], "#")); t != "" {
+			title = &t
+		}
+		lines = lines[1:]
+	}
+
+	return title, strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// executeDone handles the 'done' command
+func (c *CLI) executeDone(args []string) error {
+	// Create flag set
+	doneCmd := flag.NewFlagSet("done", flag.ContinueOnError)
+
+	// Define flags
+	strictFlag := doneCmd.Bool("strict", false, "Refuse to mark tasks that depend on incomplete tasks as done")
+	pickFlag := doneCmd.Bool("pick", false, "Pick the task from a numbered list")
+
+	// Set usage
+	doneCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo done <task_id>... [--strict]\n")
+		fmt.Fprintf(os.Stderr, "       tamo done [--pick] [--strict]\n\n")
+		fmt.Fprintf(os.Stderr, "Mark tasks as done\n\n")
+		doneCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(doneCmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Pick a task if no ID is given
+	positional, err = c.pickTaskIDs(positional, *pickFlag)
+	if err != nil {
+		return err
+	}
+
+	return c.setTasksDone(positional, true, *strictFlag)
+}
+
+// executeUndone handles the 'undone' command
+func (c *CLI) executeUndone(args []string) error {
+	// Create flag set
+	undoneCmd := flag.NewFlagSet("undone", flag.ContinueOnError)
+
+	// Set usage
+	undoneCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo undone <task_id>...\n\n")
+		fmt.Fprintf(os.Stderr, "Mark tasks as not done\n\n")
+		undoneCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	if err := undoneCmd.Parse(args); err != nil {
+		return err
+	}
+
+	return c.setTasksDone(undoneCmd.Args(), false, false)
+}
+
+// setTasksDone marks the tasks with the given IDs as done or not done and saves once.
+// Tasks that can be resolved are updated even if other IDs are unknown or ambiguous.
+func (c *CLI) setTasksDone(taskIDs []string, done, strict bool) error {
+	// Check if task ID is provided
+	if len(taskIDs) < 1 {
+		return fmt.Errorf("missing task ID")
+	}
+
+	status := "done"
+	if !done {
+		status = "not done"
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Resolve task IDs
+	var tasks []*model.Task
+	notFound := 0
+	ambiguous := 0
+	for _, taskID := range taskIDs {
+		task, err := resolveTask(store, taskID)
+		if err != nil {
+			// A single ID keeps the plain error
+			if len(taskIDs) == 1 {
+				return err
+			}
+			if errors.Is(err, errAmbiguousID) {
+				ambiguous++
+			} else {
+				notFound++
+			}
+			fmt.Fprintln(os.Stderr, capitalize(err.Error()))
+			continue
+		}
+
+		// The same task may be given twice, e.g. by its full ID and a prefix
+		if !containsTask(tasks, task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	// Check the dependencies of the tasks to complete. Dependencies completed by the same command count as done.
+	if done {
+		var blocked []string
+		for _, task := range tasks {
+			var incomplete []*model.Task
+			for _, dep := range store.IncompleteDependencies(task) {
+				if !containsTask(tasks, dep) {
+					incomplete = append(incomplete, dep)
+				}
+			}
+			if len(incomplete) > 0 {
+				blocked = append(blocked, fmt.Sprintf("task '%s' depends on incomplete tasks: %s", task.Title, dependencyList(incomplete)))
+			}
+		}
+		if strict && len(blocked) == 1 {
+			return fmt.Errorf("%s", blocked[0])
+		}
+		for _, message := range blocked {
+			if strict {
+				fmt.Fprintf(os.Stderr, "%s\n", capitalize(message))
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", message)
+			}
+		}
+		if strict && len(blocked) > 0 {
+			return fmt.Errorf("%d tasks depend on incomplete tasks, nothing marked as done", len(blocked))
+		}
+
+		// Warn about subtasks left undone. Subtasks completed by the same command count as done.
+		for _, task := range tasks {
+			var undone []*model.Task
+			for _, child := range store.ChildTasks(task.ID) {
+				if !child.Done && !containsTask(tasks, child) {
+					undone = append(undone, child)
+				}
+			}
+			if len(undone) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: task '%s' has %d undone subtasks: %s\n", task.Title, len(undone), dependencyList(undone))
+			}
+		}
+	}
+
+	// Mark tasks, keeping the tasks newly completed
+	var completed []*model.Task
+	for _, task := range tasks {
+		if done {
+			if !task.Done {
+				completed = append(completed, task)
+			}
+			task.MarkDone()
+		} else {
+			task.MarkUndone()
+		}
+	}
+
+	// Save store
+	if len(tasks) > 0 {
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+	}
+
+	// Tell how many tasks are left, on the line of a single task or after the summary of several
+	pending := store.PendingCount()
+	noun := "tasks"
+	if pending == 1 {
+		noun = "task"
+	}
+	remaining := fmt.Sprintf(" (%d %s remaining)", pending, noun)
+	for _, task := range tasks {
+		line := fmt.Sprintf("Task '%s' (order %.1f) marked as %s", task.Title, task.Order, status)
+		if len(taskIDs) == 1 {
+			line += remaining
+		}
+		c.printf("%s\n", line)
+	}
+
+	// Print summary for multiple tasks
+	if len(taskIDs) > 1 {
+		marked := "tasks"
+		if len(tasks) == 1 {
+			marked = "task"
+		}
+		summary := fmt.Sprintf("%d %s marked as %s", len(tasks), marked, status)
+		if notFound > 0 {
+			summary += fmt.Sprintf(", %d not found", notFound)
+		}
+		if ambiguous > 0 {
+			summary += fmt.Sprintf(", %d ambiguous", ambiguous)
+		}
+		c.printf("%s\n", summary+remaining)
+	}
+	c.printUnblockedTasks(store, completed)
+	if done && len(tasks) > 0 && store.PendingCount() == 0 {
+		c.printf("All tasks done! 🎉\n")
+	}
+
+	if failed := notFound + ambiguous; failed > 0 {
+		return notFoundErrorf("%d of %d task IDs could not be resolved", failed, len(taskIDs))
+	}
+	return nil
+}
+
+// orderNextTo returns the order that places a task right before or after the target task,
+// halfway to its neighbor in the sorted tasks. It returns false if the halfway order can't be
+// told apart from the neighbors, because their orders are equal or too close for a float64.
+func orderNextTo(tasks []*model.Task, target *model.Task, before bool) (float64, bool) {
+	for i, t := range tasks {
+		if t.ID != target.ID {
+			continue
+		}
+		var neighbor *model.Task
+		if before && i > 0 {
+			neighbor = tasks[i-1]
+		} else if !before && i < len(tasks)-1 {
+			neighbor = tasks[i+1]
+		}
+
+		if neighbor == nil {
+			// Place before the first task or after the last task
+			if before {
+				return target.Order - 1.0, true
+			}
+			return target.Order + 1.0, true
+		}
+
+		// Place between the target and its neighbor
+		order := (neighbor.Order + target.Order) / 2.0
+		return order, order != neighbor.Order && order != target.Order
+	}
+	return 0, false
+}
+
+// renumberTasks reassigns the orders of the tasks as 1.0, 2.0, 3.0, ... keeping their current order.
+// Tasks with the same order are ordered by creation time. It returns the number of tasks whose order changed.
+func renumberTasks(tasks []*model.Task) int {
+	sorted := append([]*model.Task(nil), tasks...)
+	sortTasksByOrder(sorted)
+
+	changed := 0
+	now := model.CustomTime{Time: time.Now().UTC()}
+	for i, task := range sorted {
+		if task.Order != float64(i+1) {
+			task.Order = float64(i + 1)
+			task.UpdatedAt = now
+			changed++
+		}
+	}
+	return changed
+}
+
+// executeRenumber handles the 'renumber' command
+func (c *CLI) executeRenumber(args []string) error {
+	// Create flag set
+	renumberCmd := flag.NewFlagSet("renumber", flag.ContinueOnError)
+
+	// Set usage
+	renumberCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo renumber\n\n")
+		fmt.Fprintf(os.Stderr, "Reassign task orders 1.0, 2.0, ... keeping the current order\n\n")
+		renumberCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	if err := renumberCmd.Parse(args); err != nil {
+		return err
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	changed := renumberTasks(store.Tasks)
+	if changed == 0 {
+		c.printf("Task orders are already renumbered\n")
+		return nil
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("%d tasks renumbered\n", changed)
+	return nil
+}
+
+// executeCopy handles the 'cp' command
+func (c *CLI) executeCopy(args []string) error {
+	// Create flag set
+	cpCmd := flag.NewFlagSet("cp", flag.ContinueOnError)
+
+	// Define flags
+	deepFlag := cpCmd.Bool("deep", false, "Also copy the referenced memos, and reference the copies")
+
+	// Set usage
+	cpCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo cp <task_id> [\"<new title>\"] [--deep]\n\n")
+		fmt.Fprintf(os.Stderr, "Copy a task to the end of the list as an uncompleted task\n\n")
+		cpCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(cpCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		cpCmd.Usage()
+		return fmt.Errorf("missing task ID")
+	}
+	if len(positional) > 2 {
+		return fmt.Errorf("unexpected arguments: %s (quote the title if it contains spaces)", strings.Join(positional[2:], " "))
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task
+	source, err := resolveTask(store, positional[0])
+	if err != nil {
+		return err
+	}
+
+	title := source.Title + " (copy)"
+	if len(positional) > 1 {
+		title = positional[1]
+		if strings.TrimSpace(title) == "" {
+			return fmt.Errorf("task title cannot be empty")
+		}
+	}
+
+	task, copiedMemos, err := copyTask(store, source, title, *deepFlag)
+	if err != nil {
+		return err
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printAddedID("Task copied with ID", task.ID)
+	if *deepFlag {
+		c.printf("Copied %d memos\n", copiedMemos)
+	}
+	return nil
+}
+
+// copyTask adds an uncompleted copy of the task with the title to the end of the list, and returns it
+// with the number of memos copied. The copy references the same memos, or with deep, copies of them.
+// References to memos that don't exist are kept as they are.
+func copyTask(store *model.Store, source *model.Task, title string, deep bool) (*model.Task, int, error) {
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	memoRefs := append([]string(nil), source.MemoRefs...)
+	copiedMemos := 0
+	if deep {
+		for i, memoID := range memoRefs {
+			memo := store.FindMemoByID(memoID)
+			if memo == nil {
+				continue
+			}
+			memoCopy, err := copyMemo(memo)
+			if err != nil {
+				return nil, 0, err
+			}
+			store.AddMemo(memoCopy)
+			memoRefs[i] = memoCopy.ID
+			copiedMemos++
+		}
+	}
+
+	task := model.NewTask(id, title, source.Description, memoRefs)
+	task.Tags = append([]string(nil), source.Tags...)
+	task.Priority = source.Priority
+	task.ParentID = source.ParentID
+	task.DependsOn = append([]string(nil), source.DependsOn...)
+	task.Order = newTaskOrder(store, "push")
+	store.AddTask(task)
+	return task, copiedMemos, nil
+}
+
+// copyMemo returns a copy of the memo with a new ID and new timestamps
+func copyMemo(memo *model.Memo) (*model.Memo, error) {
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	var title *string
+	if memo.Title != nil {
+		t := *memo.Title
+		title = &t
+	}
+	memoCopy := model.NewMemo(id, title, memo.Content)
+	memoCopy.Attachments = append([]string(nil), memo.Attachments...)
+	return memoCopy, nil
+}
+
+// executeTemplate handles the 'template' command
+func (c *CLI) executeTemplate(args []string) error {
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo template save <task_id> <name> [--own-memos] [-f|--force]\n")
+		fmt.Fprintf(os.Stderr, "       tamo template list\n")
+		fmt.Fprintf(os.Stderr, "       tamo template use <name> [--title \"<title>\"]\n")
+		fmt.Fprintf(os.Stderr, "       tamo template rm <name>\n\n")
+		fmt.Fprintf(os.Stderr, "Save tasks as templates and create tasks from them\n")
+	}
+
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("missing subcommand: 'save', 'list', 'use', or 'rm'")
+	}
+	if isHelpArg(args[0]) {
+		usage()
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "save":
+		return c.executeTemplateSave(args[1:])
+	case "list":
+		return c.executeTemplateList(args[1:])
+	case "use":
+		return c.executeTemplateUse(args[1:])
+	case "rm":
+		return c.executeTemplateRemove(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown subcommand: %s", args[0])
+	}
+}
+
+// executeTemplateSave handles the 'template save' command
+func (c *CLI) executeTemplateSave(args []string) error {
+	// Create flag set
+	saveCmd := flag.NewFlagSet("template save", flag.ContinueOnError)
+
+	// Define flags
+	ownMemosFlag := saveCmd.Bool("own-memos", false, "Store copies of the referenced memos in the template, copied again for each task")
+	forceFlag := saveCmd.Bool("force", false, "Replace a template with the same name")
+	saveCmd.BoolVar(forceFlag, "f", false, "Shorthand for --force")
+
+	// Set usage
+	saveCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo template save <task_id> <name> [--own-memos] [-f|--force]\n\n")
+		fmt.Fprintf(os.Stderr, "Save a task with its memo references as a named template\n\n")
+		saveCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(saveCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		saveCmd.Usage()
+		return fmt.Errorf("expected a task ID and a template name")
+	}
+	name := strings.TrimSpace(positional[1])
+	if name == "" {
+		return fmt.Errorf("template name cannot be empty")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the task
+	task, err := resolveTask(store, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if store.FindTemplate(name) != nil {
+		if !*forceFlag {
+			return fmt.Errorf("template '%s' already exists (use -f or --force to replace it)", name)
+		}
+		store.RemoveTemplate(name)
+	}
+
+	template := &model.TaskTemplate{
+		Name:        name,
+		Title:       task.Title,
+		Description: task.Description,
+		Tags:        append([]string(nil), task.Tags...),
+		Priority:    task.Priority,
+		CreatedAt:   model.CustomTime{Time: time.Now().UTC()},
+	}
+	for _, memoID := range task.MemoRefs {
+		memo := store.FindMemoByID(memoID)
+		if *ownMemosFlag && memo != nil {
+			owned, err := copyMemo(memo)
+			if err != nil {
+				return err
+			}
+			template.Memos = append(template.Memos, model.TemplateMemo{Memo: owned})
+		} else {
+			template.Memos = append(template.Memos, model.TemplateMemo{MemoID: memoID})
+		}
+	}
+	store.Templates = append(store.Templates, template)
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("Template '%s' saved from task '%s'\n", name, task.Title)
+	return nil
+}
+
+// executeTemplateList handles the 'template list' command
+func (c *CLI) executeTemplateList(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	if len(store.Templates) == 0 {
+		fmt.Println("No templates found")
+		return nil
+	}
+
+	nameWidth := 0
+	for _, template := range store.Templates {
+		nameWidth = max(nameWidth, len(template.Name))
+	}
+	fmt.Println("Templates:")
+	for _, template := range store.Templates {
+		fmt.Printf("  %-*s  [%dm]  %s\n", nameWidth, template.Name, len(template.Memos), template.Title)
+	}
+	return nil
+}
+
+// executeTemplateUse handles the 'template use' command
+func (c *CLI) executeTemplateUse(args []string) error {
+	// Create flag set
+	useCmd := flag.NewFlagSet("template use", flag.ContinueOnError)
+
+	// Define flags
+	titleFlag := useCmd.String("title", "", "Title of the new task instead of the template's")
+
+	// Set usage
+	useCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo template use <name> [--title \"<title>\"]\n\n")
+		fmt.Fprintf(os.Stderr, "Create a task from a template at the end of the list\n\n")
+		useCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(useCmd, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		useCmd.Usage()
+		return fmt.Errorf("expected a template name")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	template := store.FindTemplate(positional[0])
+	if template == nil {
+		return notFoundErrorf("no template found with name: %s", positional[0])
+	}
+
+	title := template.Title
+	if strings.TrimSpace(*titleFlag) != "" {
+		title = *titleFlag
+	}
+
+	// Reference the shared memos, and copies of the template-owned memos
+	var memoRefs []string
+	copiedMemos := 0
+	for _, templateMemo := range template.Memos {
+		if templateMemo.Memo == nil {
+			if store.FindMemoByID(templateMemo.MemoID) == nil {
+				fmt.Fprintf(os.Stderr, "Warning: memo %s of the template no longer exists, not referenced\n", templateMemo.MemoID[:min(8, len(templateMemo.MemoID))])
+				continue
+			}
+			memoRefs = append(memoRefs, templateMemo.MemoID)
+			continue
+		}
+		memo, err := copyMemo(templateMemo.Memo)
+		if err != nil {
+			return err
+		}
+		store.AddMemo(memo)
+		memoRefs = append(memoRefs, memo.ID)
+		copiedMemos++
+	}
+
+	// Generate UUID
+	id, err := utils.GenerateUUID()
+	if err != nil {
+		return fmt.Errorf("failed to generate UUID: %w", err)
+	}
+
+	task := model.NewTask(id, title, template.Description, memoRefs)
+	task.Tags = append([]string(nil), template.Tags...)
+	task.Priority = template.Priority
+	task.Order = newTaskOrder(store, "push")
+	store.AddTask(task)
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printAddedID("Task added with ID", id)
+	if copiedMemos > 0 {
+		c.printf("Copied %d memos from the template\n", copiedMemos)
+	}
+	return nil
+}
+
+// executeTemplateRemove handles the 'template rm' command.
+// Tasks created from the template, and the memos they reference, are kept.
+func (c *CLI) executeTemplateRemove(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a template name")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	if store.RemoveTemplate(args[0]) == nil {
+		return notFoundErrorf("no template found with name: %s", args[0])
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("Template '%s' removed\n", args[0])
+	return nil
+}
+
+// executeSwap handles the 'swap' command
+func (c *CLI) executeSwap(args []string) error {
+	// Create flag set
+	swapCmd := flag.NewFlagSet("swap", flag.ContinueOnError)
+
+	// Set usage
+	swapCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo swap <task_id> <other_task_id>\n\n")
+		fmt.Fprintf(os.Stderr, "Exchange the orders of two tasks\n\n")
+		swapCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	if err := swapCmd.Parse(args); err != nil {
+		return err
+	}
+	if swapCmd.NArg() != 2 {
+		swapCmd.Usage()
+		return fmt.Errorf("expected two task IDs")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Resolve both tasks
+	ids := swapCmd.Args()
+	a, err := resolveTask(store, ids[0])
+	if err != nil {
+		return err
+	}
+	b, err := resolveTask(store, ids[1])
+	if err != nil {
+		return err
+	}
+	if a == b {
+		return fmt.Errorf("cannot swap task '%s' with itself", a.Title)
+	}
+
+	if a.Order != b.Order {
+		a.Order, b.Order = b.Order, a.Order
+	} else {
+		// Tasks with the same order have no positions to exchange, so the second task is put
+		// right before the first one: the first moves halfway to the next larger order
+		next := a.Order + 2.0
+		for _, t := range store.Tasks {
+			if t.Order > a.Order && t.Order < next {
+				next = t.Order
+			}
+		}
+		order := (a.Order + next) / 2.0
+		if order != a.Order && order != next {
+			a.Order = order
+		} else {
+			// No room left between the orders
+			renumberTasks(store.Tasks)
+			if a.Order < b.Order {
+				a.Order, b.Order = b.Order, a.Order
+			}
+		}
+	}
+	a.Touch()
+	b.Touch()
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	// Show the two tasks in their new order
+	swapped := []*model.Task{a, b}
+	sortTasksByOrder(swapped)
+	c.printf("Tasks swapped:\n")
+	for _, task := range swapped {
+		c.printf("  %s  %.1f  %s\n", task.ID[:8], task.Order, task.Title)
+	}
+	return nil
+}
+
+// listEnds maps the targets of 'mv' that move a task to either end of the list to that end
+var listEnds = map[string]string{
+	"top":    "top",
+	"first":  "top",
+	"bottom": "bottom",
+	"last":   "bottom",
+}
+
+// moveTaskUnder makes the task a subtask of the given parent, or a top-level task if the parent is "none"
+func (c *CLI) moveTaskUnder(s *storage.Storage, store *model.Store, task *model.Task, parentArg string) error {
+	if parentArg == "none" {
+		if task.ParentID == "" {
+			c.printf("Task '%s' is already a top-level task\n", task.Title)
+			return nil
+		}
+		task.ParentID = ""
+		task.Touch()
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+		c.printf("Task '%s' is now a top-level task\n", task.Title)
+		return nil
+	}
+
+	// Find the parent task
+	parent, err := resolveTask(store, parentArg)
+	if err != nil {
+		return fmt.Errorf("invalid parent: %w", err)
+	}
+	if task.ParentID == parent.ID {
+		c.printf("Task '%s' is already a subtask of task '%s'\n", task.Title, parent.Title)
+		return nil
+	}
+
+	// Reject cycles, including a task under itself
+	if store.WouldCreateParentCycle(task.ID, parent.ID) {
+		if parent.ID == task.ID {
+			return fmt.Errorf("task '%s' cannot be a subtask of itself", task.Title)
+		}
+		return fmt.Errorf("task '%s' cannot be moved under its own subtask '%s'", task.Title, parent.Title)
+	}
+
+	task.ParentID = parent.ID
+	task.Touch()
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+	c.printf("Task '%s' moved under task '%s'\n", task.Title, parent.Title)
+	return nil
+}
+
+// executeMove handles the 'mv' command
+func (c *CLI) executeMove(args []string) error {
+	// Manual argument parsing
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo mv <task_id> --order <order>\n")
+		fmt.Fprintf(os.Stderr, "       tamo mv <task_id> before|after <other_task_id>\n")
+		fmt.Fprintf(os.Stderr, "       tamo mv <task_id> top|bottom (or first|last)\n")
+		fmt.Fprintf(os.Stderr, "       tamo mv <task_id> under <parent_task_id>|none\n\n")
+		fmt.Fprintf(os.Stderr, "Move a task to a specific order or relative to another task\n")
+		fmt.Fprintf(os.Stderr, "The order may also be given without --order, as in 'tamo mv <task_id> <order>'\n")
+		fmt.Fprintf(os.Stderr, "'under' makes the task a subtask of the parent task, or a top-level task with 'none'\n")
+	}
+
+	// Separate the --order flag from the other arguments
+	var orderArg string
+	hasOrder := false
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--order":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--order requires a value")
+			}
+			orderArg, hasOrder = args[i+1], true
+			i++
+		case strings.HasPrefix(args[i], "--order="):
+			orderArg, hasOrder = strings.TrimPrefix(args[i], "--order="), true
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	args = rest
+
+	// Check if we have a task ID and a target
+	if len(args) < 1 || (len(args) < 2 && !hasOrder) {
+		usage()
+		return fmt.Errorf("missing arguments")
+	}
+	if hasOrder && len(args) > 1 {
+		usage()
+		return fmt.Errorf("--order cannot be used with another target: %s", args[1])
+	}
+	target := ""
+	if len(args) > 1 {
+		target = args[1]
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find task by ID, prefix, or position
+	task, err := resolveTask(store, args[0])
+	if err != nil {
+		return err
+	}
+
+	// Move the task under another task
+	if target == "under" {
+		if len(args) < 3 {
+			usage()
+			return fmt.Errorf("missing parent task ID")
+		}
+		return c.moveTaskUnder(s, store, task, args[2])
+	}
+
+	// Sort tasks by order
+	var tasks []*model.Task
+	tasks = append(tasks, store.Tasks...)
+	sortTasksByOrder(tasks)
+
+	// Handle different move types
+	if end, ok := listEnds[target]; ok {
+		// Move to the top or bottom of the list, unless the task is already there
+		var neighbor *model.Task
+		if end == "top" {
+			if tasks[0].ID == task.ID {
+				c.printf("Task '%s' is already at the top\n", task.Title)
+				return nil
+			}
+			neighbor = tasks[0]
+			task.Order = store.GetMinTaskOrder() - 1.0
+		} else {
+			if tasks[len(tasks)-1].ID == task.ID {
+				c.printf("Task '%s' is already at the bottom\n", task.Title)
+				return nil
+			}
+			neighbor = tasks[len(tasks)-1]
+			task.Order = store.GetMaxTaskOrder() + 1.0
+		}
+		task.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		if end == "top" {
+			c.printf("Task '%s' moved to the top (order %.1f), before task '%s'\n", task.Title, task.Order, neighbor.Title)
+		} else {
+			c.printf("Task '%s' moved to the bottom (order %.1f), after task '%s'\n", task.Title, task.Order, neighbor.Title)
+		}
+		return nil
+	} else if target == "before" || target == "after" {
+		// Relative move
+		if len(args) < 3 {
+			usage()
+			return fmt.Errorf("missing target task ID")
+		}
+
+		// Find target task
+		targetTaskID := args[2]
+		targetTask, err := resolveTask(store, targetTaskID)
+		var notFound *notFoundError
+		if errors.As(err, &notFound) && !isTaskPosition(targetTaskID) {
+			return notFoundErrorf("no target task found with ID: %s%s", targetTaskID, didYouMean(targetTaskID, taskCandidates(store.Tasks)))
+		}
+		if err != nil {
+			return err
+		}
+
+		// Calculate new order, renumbering all tasks first if there is no room left between the neighbors
+		before := target == "before"
+		newOrder, ok := orderNextTo(tasks, targetTask, before)
+		if !ok {
+			renumberTasks(store.Tasks)
+			tasks = append(tasks[:0], store.Tasks...)
+			sortTasksByOrder(tasks)
+			newOrder, _ = orderNextTo(tasks, targetTask, before)
+			c.printf("Task orders renumbered to make room for the move\n")
+		}
+
+		// Update task order
+		task.Order = newOrder
+		task.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' moved %s task '%s'\n", task.Title, target, targetTask.Title)
+		return nil
+	} else {
+		// Absolute move
+		orderText := orderArg
+		if !hasOrder {
+			orderText = target
+		}
+		targetOrder, err := strconv.ParseFloat(orderText, 64)
+		if err != nil || math.IsInf(targetOrder, 0) || math.IsNaN(targetOrder) {
+			if hasOrder {
+				return fmt.Errorf("invalid order: %s (expected a finite number)", orderText)
+			}
+			usage()
+			hint := ""
+			if other, err := resolveTask(store, target); err == nil {
+				hint = fmt.Sprintf(". To move next to task '%s', use 'before %s' or 'after %s'", other.Title, target, target)
+			}
+			return fmt.Errorf("invalid target: %s (expected before|after <task_id>, top|bottom, or --order <order>)%s", target, hint)
+		}
+
+		// A bare number can also be the ID prefix of a task, which is easy to mistake for an order
+		if !hasOrder {
+			if other, err := resolveTask(store, target); err == nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s is also the ID prefix of task '%s'. Moving to order %s; use --order %s to move to an order, or 'before %s' or 'after %s' to move next to the task\n",
+					target, other.Title, target, target, target, target)
+			}
+		}
+
+		// Update task order
+		task.Order = targetOrder
+		task.Touch()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' moved to order %.1f\n", task.Title, targetOrder)
+		return nil
+	}
+}
+
+// executeReorder handles the 'reorder' command
+func (c *CLI) executeReorder(args []string) error {
+	// Create flag set
+	reorderCmd := flag.NewFlagSet("reorder", flag.ContinueOnError)
+
+	// Define flags
+	byFlag := reorderCmd.String("by", "", "Sort key: created, updated, or title")
+	yesFlag := reorderCmd.Bool("yes", false, "Reorder without confirmation")
+
+	// Set usage
+	reorderCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo reorder --by created|updated|title [--yes]\n\n")
+		fmt.Fprintf(os.Stderr, "Reassign task orders 1.0, 2.0, ... sorted by the given key\n\n")
+		reorderCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	if err := reorderCmd.Parse(args); err != nil {
 		return err
 	}
 
-	// Check if ID is provided
-	if showCmd.NArg() < 1 {
-		return fmt.Errorf("missing ID")
+	// Get sort function
+	var less func(a, b *model.Task) bool
+	switch *byFlag {
+	case "created":
+		less = func(a, b *model.Task) bool { return a.CreatedAt.Before(b.CreatedAt.Time) }
+	case "updated":
+		less = func(a, b *model.Task) bool { return a.UpdatedAt.Before(b.UpdatedAt.Time) }
+	case "title":
+		less = func(a, b *model.Task) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case "":
+		reorderCmd.Usage()
+		return fmt.Errorf("missing --by flag")
+	default:
+		return fmt.Errorf("invalid sort key: %s (expected created, updated, or title)", *byFlag)
 	}
 
-	// Get ID
-	id := showCmd.Arg(0)
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	if len(store.Tasks) == 0 {
+		return fmt.Errorf("no tasks found")
+	}
+
+	// Sort tasks, keeping the current order for ties
+	var tasks []*model.Task
+	tasks = append(tasks, store.Tasks...)
+	sortTasksByOrder(tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return less(tasks[i], tasks[j])
+	})
+
+	// Count tasks whose order changes
+	changed := 0
+	for i, task := range tasks {
+		if task.Order != float64(i+1) {
+			changed++
+		}
+	}
+
+	if changed == 0 {
+		fmt.Println("Tasks are already in order")
+		return nil
+	}
+
+	if !*yesFlag {
+		// Ask for confirmation
+		if !confirm(fmt.Sprintf("Reorder %d tasks by %s? This changes the order of %d tasks.", len(tasks), *byFlag, changed)) {
+			fmt.Fprintln(os.Stderr, "Reorder aborted")
+			return nil
+		}
+	}
+
+	// Assign new orders
+	now := model.CustomTime{Time: time.Now().UTC()}
+	for i, task := range tasks {
+		if task.Order != float64(i+1) {
+			task.Order = float64(i + 1)
+			task.UpdatedAt = now
+		}
+	}
+
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+
+	c.printf("%d tasks reordered by %s\n", changed, *byFlag)
+	return nil
+}
+
+// executePop handles the 'pop task' command
+func (c *CLI) executePop(args []string) error {
+	// Manual argument parsing
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo pop task [--undone] [--done | --rm [-f]]\n\n")
+		fmt.Fprintf(os.Stderr, "Show, mark as done, or remove the last task\n\n")
+		fmt.Fprintf(os.Stderr, "  --undone  Only consider undone tasks\n")
+		fmt.Fprintf(os.Stderr, "  --done    Mark the last task as done\n")
+		fmt.Fprintf(os.Stderr, "  --rm      Remove the last task\n")
+		fmt.Fprintf(os.Stderr, "  -f        Force removal without confirmation\n")
+	}
+
+	// Check if we have at least the 'task' subcommand
+	if len(args) < 1 || args[0] != "task" {
+		usage()
+		return fmt.Errorf("missing or invalid subcommand: expected 'task'")
+	}
+
+	// Parse options
+	doneFlag := false
+	rmFlag := false
+	forceFlag := false
+	undoneFlag := false
+
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--done" {
+			doneFlag = true
+		} else if args[i] == "--undone" {
+			undoneFlag = true
+		} else if args[i] == "--rm" {
+			rmFlag = true
+		} else if args[i] == "-f" {
+			forceFlag = true
+		}
+	}
+
+	// Check for conflicting flags
+	if doneFlag && rmFlag {
+		return fmt.Errorf("--done and --rm flags cannot be used together")
+	}
 
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Try to find task by ID or prefix
-	var task *model.Task
-	if len(id) == 36 { // Full UUID
-		task = store.FindTaskByID(id)
+	// Find the last task (highest order)
+	var lastTask *model.Task
+	maxOrder := -1.0
+
+	for _, task := range store.Tasks {
+		if undoneFlag && task.Done {
+			continue
+		}
+		if task.Order > maxOrder {
+			lastTask = task
+			maxOrder = task.Order
+		}
+	}
+
+	if lastTask == nil {
+		if undoneFlag {
+			return fmt.Errorf("no undone tasks found")
+		}
+		return fmt.Errorf("no tasks found")
+	}
+
+	// Handle different actions
+	if doneFlag {
+		// Mark as done
+		lastTask.MarkDone()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' marked as done\n", lastTask.Title)
+	} else if rmFlag {
+		// Remove task
+		if !forceFlag && c.removalNeedsConfirmation() {
+			// Ask for confirmation
+			if !confirm(fmt.Sprintf("Are you sure you want to remove task '%s'?", lastTask.Title)) {
+				fmt.Fprintln(os.Stderr, "Task removal aborted")
+				return nil
+			}
+		}
+
+		// Remove task
+		store.RemoveTask(lastTask.ID)
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' removed\n", lastTask.Title)
 	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, id) {
-				task = t
-				break
+		// Show task details
+		doneStr := "[ ] Not completed"
+		if lastTask.Done {
+			doneStr = "[x] Completed"
+		}
+
+		fmt.Printf("Task ID: %s\n", lastTask.ID)
+		fmt.Printf("Title: %s\n", lastTask.Title)
+		fmt.Printf("Order: %.1f\n", lastTask.Order)
+		fmt.Printf("Status: %s\n", doneStr)
+		fmt.Printf("Created: %s\n", c.formatTime(lastTask.CreatedAt.Time))
+		fmt.Printf("Updated: %s\n", c.formatTime(lastTask.UpdatedAt.Time))
+
+		if lastTask.Description != "" {
+			fmt.Println("\nDescription:")
+			fmt.Println(lastTask.Description)
+		}
+
+		if len(lastTask.MemoRefs) > 0 {
+			fmt.Println("\nReferenced Memos:")
+			for _, memoID := range lastTask.MemoRefs {
+				memo := store.FindMemoByID(memoID)
+				if memo != nil {
+					titleStr := "<no title>"
+					if memo.Title != nil {
+						titleStr = *memo.Title
+					}
+					fmt.Printf("  %s  %s\n", memoID[:8], titleStr)
+				} else {
+					fmt.Printf("  %s  <memo not found>\n", memoID[:8])
+				}
 			}
 		}
 	}
 
-	if task != nil {
-		// Print task details
+	return nil
+}
+
+// executeShift handles the 'shift task' command
+func (c *CLI) executeShift(args []string) error {
+	// Manual argument parsing
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo shift task [--undone [--include-blocked]] [--done | --rm [-f]]\n\n")
+		fmt.Fprintf(os.Stderr, "Show, mark as done, or remove the first task\n\n")
+		fmt.Fprintf(os.Stderr, "  --undone  Only consider undone tasks that are not blocked by undone tasks\n")
+		fmt.Fprintf(os.Stderr, "  --include-blocked  With --undone, also consider blocked tasks\n")
+		fmt.Fprintf(os.Stderr, "  --done    Mark the first task as done\n")
+		fmt.Fprintf(os.Stderr, "  --rm      Remove the first task\n")
+		fmt.Fprintf(os.Stderr, "  -f        Force removal without confirmation\n")
+	}
+
+	// Check if we have at least the 'task' subcommand
+	if len(args) < 1 || args[0] != "task" {
+		usage()
+		return fmt.Errorf("missing or invalid subcommand: expected 'task'")
+	}
+
+	// Parse options
+	doneFlag := false
+	rmFlag := false
+	forceFlag := false
+	undoneFlag := false
+	includeBlockedFlag := false
+
+	for i := 1; i < len(args); i++ {
+		if args[i] == "--done" {
+			doneFlag = true
+		} else if args[i] == "--undone" {
+			undoneFlag = true
+		} else if args[i] == "--include-blocked" {
+			includeBlockedFlag = true
+		} else if args[i] == "--rm" {
+			rmFlag = true
+		} else if args[i] == "-f" {
+			forceFlag = true
+		}
+	}
+
+	// Check for conflicting flags
+	if doneFlag && rmFlag {
+		return fmt.Errorf("--done and --rm flags cannot be used together")
+	}
+	if includeBlockedFlag && !undoneFlag {
+		return fmt.Errorf("--include-blocked requires --undone")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find the first task (lowest order)
+	var firstTask *model.Task
+	minOrder := math.MaxFloat64
+	blocked := 0
+
+	for _, task := range store.Tasks {
+		if undoneFlag && task.Done {
+			continue
+		}
+		if undoneFlag && !includeBlockedFlag && store.IsBlocked(task) {
+			blocked++
+			continue
+		}
+		if task.Order < minOrder {
+			firstTask = task
+			minOrder = task.Order
+		}
+	}
+
+	if firstTask == nil {
+		if undoneFlag {
+			return noUndoneTasksError(blocked)
+		}
+		return fmt.Errorf("no tasks found")
+	}
+
+	// Handle different actions
+	if doneFlag {
+		// Mark as done
+		wasDone := firstTask.Done
+		firstTask.MarkDone()
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' marked as done\n", firstTask.Title)
+		if !wasDone {
+			c.printUnblockedTasks(store, []*model.Task{firstTask})
+		}
+	} else if rmFlag {
+		// Remove task
+		if !forceFlag && c.removalNeedsConfirmation() {
+			// Ask for confirmation
+			if !confirm(fmt.Sprintf("Are you sure you want to remove task '%s'?", firstTask.Title)) {
+				fmt.Fprintln(os.Stderr, "Task removal aborted")
+				return nil
+			}
+		}
+
+		// Remove task
+		store.RemoveTask(firstTask.ID)
+
+		// Save store
+		if err := s.Save(store); err != nil {
+			return fmt.Errorf("failed to save data: %w", err)
+		}
+
+		c.printf("Task '%s' removed\n", firstTask.Title)
+	} else {
+		// Show task details
 		doneStr := "[ ] Not completed"
-		if task.Done {
+		if firstTask.Done {
 			doneStr = "[x] Completed"
 		}
 
-		fmt.Printf("Task ID: %s\n", task.ID)
-		fmt.Printf("Title: %s\n", task.Title)
-		fmt.Printf("Order: %.1f\n", task.Order)
+		fmt.Printf("Task ID: %s\n", firstTask.ID)
+		fmt.Printf("Title: %s\n", firstTask.Title)
+		fmt.Printf("Order: %.1f\n", firstTask.Order)
 		fmt.Printf("Status: %s\n", doneStr)
-		fmt.Printf("Created: %s\n", task.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", task.UpdatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("Created: %s\n", c.formatTime(firstTask.CreatedAt.Time))
+		fmt.Printf("Updated: %s\n", c.formatTime(firstTask.UpdatedAt.Time))
 
-		if task.Description != "" {
+		if firstTask.Description != "" {
 			fmt.Println("\nDescription:")
-			fmt.Println(task.Description)
+			fmt.Println(firstTask.Description)
 		}
 
-		if len(task.MemoRefs) > 0 {
+		if len(firstTask.MemoRefs) > 0 {
 			fmt.Println("\nReferenced Memos:")
-			for _, memoID := range task.MemoRefs {
+			for _, memoID := range firstTask.MemoRefs {
 				memo := store.FindMemoByID(memoID)
 				if memo != nil {
 					titleStr := "<no title>"
@@ -711,1337 +6893,1572 @@ func (c *CLI) executeShow(args []string) error {
 				}
 			}
 		}
-
-		return nil
-	}
-
-	// Try to find memo by ID or prefix
-	var memo *model.Memo
-	if len(id) == 36 { // Full UUID
-		memo = store.FindMemoByID(id)
-	} else {
-		// Try to find by prefix
-		for _, m := range store.Memos {
-			if strings.HasPrefix(m.ID, id) {
-				memo = m
-				break
-			}
-		}
 	}
 
-	if memo != nil {
-		// Print memo details
-		fmt.Printf("Memo ID: %s\n", memo.ID)
-		if memo.Title != nil {
-			fmt.Printf("Title: %s\n", *memo.Title)
-		}
-		fmt.Printf("Created: %s\n", memo.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", memo.UpdatedAt.Format("2006-01-02 15:04:05"))
-
-		referencingTasks := findTasksReferencingMemo(store, memo.ID)
-		if len(referencingTasks) > 0 {
-			// Sort tasks for consistent display order
-			sortTasksByOrder(referencingTasks)
-			fmt.Println("\nReference Tasks:")
-			for _, task := range referencingTasks {
-				fmt.Printf("%s %s\n", task.ID[:8], task.Title)
-			}
-		}
-
-		fmt.Println("\nContent:")
-		fmt.Println(memo.Content)
+	return nil
+}
 
-		return nil
-	}
+// executeNext handles the 'next' command (alias for shift task with focus on undone tasks)
+func (c *CLI) executeNext(args []string) error {
+	// Create flag set
+	nextCmd := flag.NewFlagSet("next", flag.ContinueOnError)
 
-	return fmt.Errorf("no task or memo found with ID: %s", id)
-}
+	// Define flags
+	countFlag := nextCmd.Int("count", 0, "Show the first n undone tasks in one line each")
+	doneFlag := nextCmd.Bool("done", false, "Mark the next undone task as done")
+	rmFlag := nextCmd.Bool("rm", false, "Remove the next undone task")
+	forceFlag := nextCmd.Bool("f", false, "Force removal without confirmation")
+	nFlag := nextCmd.Int("n", 1, "Show the first n undone tasks as a compact list")
+	compactFlag := nextCmd.Bool("compact", false, "Print only the short ID and title of the next undone task")
+	includeBlockedFlag := nextCmd.Bool("include-blocked", false, "Don't skip tasks blocked by undone tasks")
 
-// executeRemove handles the 'rm' command
-func (c *CLI) executeRemove(args []string) error {
-	// Manual argument parsing
 	// Set usage
-	usage := func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo rm <id> [-f|--force]\n\n")
-		fmt.Fprintf(os.Stderr, "Remove a task or memo\n\n")
-		fmt.Fprintf(os.Stderr, "  -f, --force    Force removal without confirmation\n")
+	nextCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo next [-n <n> | --count <n> | --compact | --done | --rm [-f]] [--include-blocked]\n\n")
+		fmt.Fprintf(os.Stderr, "Show, mark as done, or remove the next undone task\n\n")
+		nextCmd.PrintDefaults()
 	}
 
-	// Check if we have at least an ID
-	if len(args) < 1 {
-		usage()
-		return fmt.Errorf("missing ID")
+	// Parse flags
+	if err := nextCmd.Parse(args); err != nil {
+		return err
 	}
 
-	// Get ID
-	id := args[0]
-
-	// Check for force flag
-	force := false
-	for i := 1; i < len(args); i++ {
-		if args[i] == "-f" || args[i] == "--force" {
-			force = true
-			break
-		}
+	countSet := false
+	nSet := false
+	nextCmd.Visit(func(f *flag.Flag) {
+		countSet = countSet || f.Name == "count"
+		nSet = nSet || f.Name == "n"
+	})
+	if countSet && *countFlag < 1 {
+		return fmt.Errorf("--count must be at least 1")
+	}
+	if *nFlag < 1 {
+		return fmt.Errorf("-n must be at least 1")
+	}
+	if countSet && nSet {
+		return fmt.Errorf("-n and --count cannot be used together")
+	}
+	if *doneFlag && *rmFlag {
+		return fmt.Errorf("--done and --rm flags cannot be used together")
+	}
+	if countSet && (*doneFlag || *rmFlag) {
+		return fmt.Errorf("--count cannot be combined with --done or --rm")
+	}
+	if *nFlag > 1 && (*doneFlag || *rmFlag) {
+		return fmt.Errorf("-n cannot be combined with --done or --rm")
+	}
+	if *compactFlag && (countSet || *nFlag > 1 || *doneFlag || *rmFlag) {
+		return fmt.Errorf("--compact only applies to showing a single task")
 	}
 
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Try to find task by ID or prefix
-	var task *model.Task
-	if len(id) == 36 { // Full UUID
-		task = store.FindTaskByID(id)
-	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, id) {
-				task = t
-				break
-			}
-		}
+	r := c.renderer()
+	if countSet {
+		return printNextTasks(r, store, *countFlag, *includeBlockedFlag)
+	}
+	if *nFlag > 1 {
+		return printNextTasksCompact(r, store, *nFlag, *includeBlockedFlag)
 	}
 
-	if task != nil {
-		// Remove task
-		removeTask(store, task.ID)
+	// Find the first undone task (lowest order)
+	undoneTasks, err := nextUndoneTasks(store, 1, *includeBlockedFlag)
+	if err != nil {
+		return err
+	}
+	firstUndoneTask := undoneTasks[0]
+
+	if *doneFlag {
+		firstUndoneTask.MarkDone()
 
-		// Save store
 		if err := s.Save(store); err != nil {
 			return fmt.Errorf("failed to save data: %w", err)
 		}
 
-		fmt.Printf("Task '%s' removed\n", task.Title)
+		c.printf("Task '%s' marked as done\n", firstUndoneTask.Title)
+		c.printUnblockedTasks(store, []*model.Task{firstUndoneTask})
 		return nil
 	}
 
-	// Try to find memo by ID or prefix
-	var memo *model.Memo
-	if len(id) == 36 { // Full UUID
-		memo = store.FindMemoByID(id)
-	} else {
-		// Try to find by prefix
-		for _, m := range store.Memos {
-			if strings.HasPrefix(m.ID, id) {
-				memo = m
-				break
-			}
-		}
-	}
-
-	if memo != nil {
-		// Check if memo is referenced by any tasks
-		referencingTasks := findTasksReferencingMemo(store, memo.ID)
-		if len(referencingTasks) > 0 {
-			if !force {
-				fmt.Printf("Memo is referenced by %d tasks. Use -f or --force to remove anyway.\n", len(referencingTasks))
-				for _, task := range referencingTasks {
-					fmt.Printf("  %s  %s\n", task.ID[:8], task.Title)
-				}
-				return fmt.Errorf("memo removal aborted")
-			} else {
-				fmt.Printf("Forcing removal of memo referenced by %d tasks\n", len(referencingTasks))
+	if *rmFlag {
+		if !*forceFlag && c.removalNeedsConfirmation() {
+			if !confirm(fmt.Sprintf("Are you sure you want to remove task '%s'?", firstUndoneTask.Title)) {
+				fmt.Fprintln(os.Stderr, "Task removal aborted")
+				return nil
 			}
 		}
 
-		// Remove memo
-		removeMemo(store, memo.ID)
+		store.RemoveTask(firstUndoneTask.ID)
 
-		// Save store
 		if err := s.Save(store); err != nil {
 			return fmt.Errorf("failed to save data: %w", err)
 		}
 
-		titleStr := "<no title>"
-		if memo.Title != nil {
-			titleStr = *memo.Title
-		}
-		fmt.Printf("Memo '%s' removed\n", titleStr)
+		c.printf("Task '%s' removed\n", firstUndoneTask.Title)
 		return nil
 	}
 
-	return fmt.Errorf("no task or memo found with ID: %s", id)
-}
+	if *compactFlag {
+		fmt.Printf("%s  %s\n", r.id(firstUndoneTask.ID[:8]), r.title(firstUndoneTask, firstUndoneTask.Title))
+		return nil
+	}
 
-// Helper functions
+	// Show task details
+	fmt.Printf("Task ID: %s\n", r.id(firstUndoneTask.ID))
+	fmt.Printf("Title: %s\n", r.title(firstUndoneTask, firstUndoneTask.Title))
+	fmt.Printf("Order: %.1f\n", firstUndoneTask.Order)
+	fmt.Printf("Status: [ ] Not completed\n")
+	fmt.Printf("Created: %s\n", c.formatTime(firstUndoneTask.CreatedAt.Time))
+	fmt.Printf("Updated: %s\n", c.formatTime(firstUndoneTask.UpdatedAt.Time))
 
-// sortTasksByOrder sorts tasks by their order field
-func sortTasksByOrder(tasks []*model.Task) {
-	// Simple bubble sort for now
-	for i := 0; i < len(tasks); i++ {
-		for j := i + 1; j < len(tasks); j++ {
-			if tasks[i].Order > tasks[j].Order {
-				tasks[i], tasks[j] = tasks[j], tasks[i]
+	if firstUndoneTask.Description != "" {
+		fmt.Println("\nDescription:")
+		fmt.Println(firstUndoneTask.Description)
+	}
+
+	if len(firstUndoneTask.MemoRefs) > 0 {
+		fmt.Println("\nReferenced Memos:")
+		for _, memoID := range firstUndoneTask.MemoRefs {
+			memo := store.FindMemoByID(memoID)
+			if memo != nil {
+				titleStr := "<no title>"
+				if memo.Title != nil {
+					titleStr = *memo.Title
+				}
+				fmt.Printf("  %s  %s\n", r.id(memoID[:8]), titleStr)
+			} else {
+				fmt.Printf("  %s  <memo not found>\n", r.id(memoID[:8]))
 			}
 		}
 	}
+
+	return nil
 }
 
-// containsString checks if a string slice contains a string
-func containsString(slice []string, s string) bool {
-	for _, item := range slice {
-		if item == s {
-			return true
+// printNextTasks prints up to count undone tasks in order, one line each with a short summary
+func printNextTasks(r renderer, store *model.Store, count int, includeBlocked bool) error {
+	undoneTasks, err := nextUndoneTasks(store, count, includeBlocked)
+	if err != nil {
+		return err
+	}
+
+	for i, task := range undoneTasks {
+		fmt.Printf("%d. %s  %.1f  %s\n", i+1, r.id(task.ID[:8]), task.Order, r.title(task, task.Title))
+		if task.Description != "" {
+			fmt.Printf("      %s\n", strings.SplitN(task.Description, "\n", 2)[0])
+		}
+		if len(task.MemoRefs) > 0 {
+			fmt.Printf("      References %d memos\n", len(task.MemoRefs))
 		}
 	}
-	return false
+
+	return nil
 }
 
-// removeTask removes a task from the store
-func removeTask(store *model.Store, id string) {
-	for i, task := range store.Tasks {
-		if task.ID == id {
-			// Remove task from slice
-			store.Tasks = append(store.Tasks[:i], store.Tasks[i+1:]...)
-			break
+// printNextTasksCompact prints the first count undone tasks one line each, with the number
+// of referenced memos and the tags of each task
+func printNextTasksCompact(r renderer, store *model.Store, count int, includeBlocked bool) error {
+	undoneTasks, err := nextUndoneTasks(store, count, includeBlocked)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range undoneTasks {
+		line := fmt.Sprintf("%s  %.1f  %s", r.id(task.ID[:8]), task.Order, r.title(task, task.Title))
+		if len(task.MemoRefs) > 0 {
+			line += fmt.Sprintf("  [%dm]", len(task.MemoRefs))
+		}
+		for _, tag := range task.Tags {
+			line += "  #" + tag
 		}
+		fmt.Println(line)
 	}
+
+	return nil
 }
 
-// removeMemo removes a memo from the store
-func removeMemo(store *model.Store, id string) {
-	for i, memo := range store.Memos {
-		if memo.ID == id {
-			// Remove memo from slice
-			store.Memos = append(store.Memos[:i], store.Memos[i+1:]...)
-			break
+// noUndoneTasksError tells that no task is left to work on, pointing to --include-blocked if tasks were skipped as blocked
+func noUndoneTasksError(blocked int) error {
+	if blocked > 0 {
+		return fmt.Errorf("no unblocked undone tasks found (%d blocked tasks, use --include-blocked to include them)", blocked)
+	}
+	return fmt.Errorf("no undone tasks found")
+}
+
+// printUnblockedTasks prints the undone tasks that depended on the newly completed tasks and no longer
+// depend on tasks that are not done
+func (c *CLI) printUnblockedTasks(store *model.Store, completed []*model.Task) {
+	var unblocked []*model.Task
+	for _, task := range completed {
+		for _, dependent := range store.TasksDependingOn(task.ID) {
+			if !dependent.Done && !store.IsBlocked(dependent) && !containsTask(unblocked, dependent) {
+				unblocked = append(unblocked, dependent)
+			}
 		}
 	}
+	for _, task := range unblocked {
+		c.printf("Task '%s' is now unblocked\n", task.Title)
+	}
+}
 
-	// Also remove references to this memo from all tasks
+// nextUndoneTasks returns the first count undone tasks in order, skipping blocked tasks unless includeBlocked
+func nextUndoneTasks(store *model.Store, count int, includeBlocked bool) ([]*model.Task, error) {
+	var undoneTasks []*model.Task
+	blocked := 0
 	for _, task := range store.Tasks {
-		for i, memoID := range task.MemoRefs {
-			if memoID == id {
-				// Remove reference from slice
-				task.MemoRefs = append(task.MemoRefs[:i], task.MemoRefs[i+1:]...)
-				break
-			}
+		if task.Done {
+			continue
+		}
+		if !includeBlocked && store.IsBlocked(task) {
+			blocked++
+			continue
 		}
+		undoneTasks = append(undoneTasks, task)
+	}
+	if len(undoneTasks) == 0 {
+		return nil, noUndoneTasksError(blocked)
+	}
+
+	sortTasksByOrder(undoneTasks)
+	if len(undoneTasks) > count {
+		undoneTasks = undoneTasks[:count]
 	}
+	return undoneTasks, nil
 }
 
-// findTasksReferencingMemo finds all tasks that reference a memo
-func findTasksReferencingMemo(store *model.Store, memoID string) []*model.Task {
+// executeFlattask handles the 'flattask' command
+func (c *CLI) executeFlattask(args []string) error {
+	// Create flag set
+	flattaskCmd := flag.NewFlagSet("flattask", flag.ContinueOnError)
+
+	// Define flags
+	outputFlag := flattaskCmd.String("o", "", "Write the document to the file instead of stdout")
+	allUndoneFlag := flattaskCmd.Bool("all-undone", false, "Flatten every undone task into one document")
+
+	// Set usage
+	flattaskCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo flattask <task_id>... [-o <file>]\n")
+		fmt.Fprintf(os.Stderr, "       tamo flattask --all-undone [-o <file>]\n\n")
+		fmt.Fprintf(os.Stderr, "Flatten tasks by expanding all memo references\n\n")
+		flattaskCmd.PrintDefaults()
+	}
+
+	// Parse flags
+	positional, err := parseFlagsAnywhere(flattaskCmd, args)
+	if err != nil {
+		return err
+	}
+
+	// Check if task IDs are provided
+	if len(positional) < 1 && !*allUndoneFlag {
+		return fmt.Errorf("missing task ID")
+	}
+	if len(positional) > 0 && *allUndoneFlag {
+		return fmt.Errorf("task IDs cannot be used with --all-undone")
+	}
+
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	// Find tasks
 	var tasks []*model.Task
-	for _, task := range store.Tasks {
-		if containsString(task.MemoRefs, memoID) {
+	if *allUndoneFlag {
+		for _, task := range store.Tasks {
+			if !task.Done {
+				tasks = append(tasks, task)
+			}
+		}
+		sortTasksByOrder(tasks)
+		if len(tasks) == 0 {
+			return fmt.Errorf("no undone tasks found")
+		}
+	}
+	for _, taskID := range positional {
+		task, err := resolveTask(store, taskID)
+		if err != nil {
+			return err
+		}
+		if !containsTask(tasks, task) {
 			tasks = append(tasks, task)
 		}
 	}
-	return tasks
+
+	// Render the document, separating tasks with horizontal rules
+	sections := make([]string, len(tasks))
+	for i, task := range tasks {
+		sections[i] = flattenTask(store, task, tasks)
+	}
+	doc := strings.Join(sections, "---\n\n")
+
+	// Print the document
+	if *outputFlag == "" {
+		fmt.Println(doc)
+		return nil
+	}
+	if err := storage.WriteFileAtomic(*outputFlag, []byte(doc)); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *outputFlag, err)
+	}
+	c.printf("Flattened %d tasks to %s\n", len(tasks), *outputFlag)
+
+	return nil
 }
 
-// readLine reads a line from stdin
-func readLine() string {
-	reader := bufio.NewReader(os.Stdin)
-	line, _ := reader.ReadString('\n')
-	return strings.TrimSpace(line)
+// expandMemoLinks replaces the [memo](id) links in a description with the memo contents as blockquotes,
+// and records the IDs of the expanded memos. Links to missing memos are replaced with a warning.
+func expandMemoLinks(store *model.Store, description string, expanded map[string]bool) string {
+	return memoLinkRegex.ReplaceAllStringFunc(description, func(link string) string {
+		id := memoLinkRegex.FindStringSubmatch(link)[1]
+		memo, err := resolveMemo(store, id)
+		var notFound *notFoundError
+		if errors.As(err, &notFound) {
+			return fmt.Sprintf("> **Warning:** memo %s not found", id)
+		}
+		if err != nil {
+			return fmt.Sprintf("> **Warning:** %s", err)
+		}
+		expanded[memo.ID] = true
+
+		var quote strings.Builder
+		if memo.Title != nil {
+			fmt.Fprintf(&quote, "> **%s** (%s)\n>", *memo.Title, memo.ID[:8])
+		} else {
+			fmt.Fprintf(&quote, "> **Memo %s**\n>", memo.ID[:8])
+		}
+		for _, line := range strings.Split(strings.TrimRight(memo.Content, "\n"), "\n") {
+			quote.WriteString("\n>")
+			if line != "" {
+				quote.WriteString(" " + line)
+			}
+		}
+		return quote.String()
+	})
+}
+
+// flattenTask renders a task as a Markdown document with the contents of its referenced memos.
+// Memos that other tasks in rendered also reference are noted as such.
+func flattenTask(store *model.Store, task *model.Task, rendered []*model.Task) string {
+	var doc strings.Builder
+
+	// Add task title and status
+	doc.WriteString(fmt.Sprintf("# %s\n\n", task.Title))
+
+	if task.Done {
+		doc.WriteString("**Status:** Completed\n\n")
+	} else {
+		doc.WriteString("**Status:** Not completed\n\n")
+	}
+
+	// Add task description if available, expanding [memo](id) links in place
+	expanded := make(map[string]bool)
+	if task.Description != "" {
+		doc.WriteString("## Description\n\n")
+		doc.WriteString(expandMemoLinks(store, task.Description, expanded))
+		doc.WriteString("\n\n")
+	}
+
+	// Add referenced memos that were not expanded in the description
+	var memoRefs []string
+	for _, memoID := range task.MemoRefs {
+		if !expanded[memoID] {
+			memoRefs = append(memoRefs, memoID)
+		}
+	}
+	if len(memoRefs) > 0 {
+		doc.WriteString("## Referenced Memos\n\n")
+
+		for _, memoID := range memoRefs {
+			memo := store.FindMemoByID(memoID)
+			if memo != nil {
+				// Add memo title
+				if memo.Title != nil {
+					doc.WriteString(fmt.Sprintf("### %s\n\n", *memo.Title))
+				} else {
+					doc.WriteString(fmt.Sprintf("### Memo %s\n\n", memoID[:8]))
+				}
+
+				// Note the other rendered tasks that reference the memo
+				var others []string
+				for _, other := range rendered {
+					if other != task && containsString(other.MemoRefs, memoID) {
+						others = append(others, fmt.Sprintf("%s %s", other.ID[:8], other.Title))
+					}
+				}
+				if len(others) > 0 {
+					doc.WriteString(fmt.Sprintf("_(also referenced by %s)_\n\n", strings.Join(others, ", ")))
+				}
+
+				// Add memo content
+				doc.WriteString(memo.Content)
+				doc.WriteString("\n\n")
+			} else {
+				doc.WriteString(fmt.Sprintf("### Memo %s (not found)\n\n", memoID[:8]))
+			}
+
+		}
+	}
+
+	return doc.String()
 }
 
-// executeEdit handles the 'edit' command
-func (c *CLI) executeEdit(args []string) error {
+// executeExport handles the 'export' command
+func (c *CLI) executeExport(args []string) error {
 	// Create flag set
-	editCmd := flag.NewFlagSet("edit", flag.ExitOnError)
+	exportCmd := flag.NewFlagSet("export", flag.ContinueOnError)
 
 	// Define flags
-	editorFlag := editCmd.Bool("editor", false, "Use editor to edit content")
+	formatFlag := exportCmd.String("format", "json", "Output format: 'json' or 'markdown'")
+	outputFlag := exportCmd.String("o", "", "Write to the file instead of stdout")
+	doneFlag := exportCmd.Bool("done", false, "Export only completed tasks")
+	undoneFlag := exportCmd.Bool("undone", false, "Export only uncompleted tasks")
+	tasksFlag := exportCmd.Bool("tasks", false, "Export only tasks")
+	memosFlag := exportCmd.Bool("memos", false, "Export only memos")
 
 	// Set usage
-	editCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo edit <id> [--editor]\n\n")
-		fmt.Fprintf(os.Stderr, "Edit a task or memo\n\n")
-		editCmd.PrintDefaults()
+	exportCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo export [--format json|markdown] [-o <file>] [--done|--undone] [--tasks|--memos]\n\n")
+		fmt.Fprintf(os.Stderr, "Export tasks and memos\n\n")
+		exportCmd.PrintDefaults()
 	}
 
 	// Parse flags
-	if err := editCmd.Parse(args); err != nil {
+	if err := exportCmd.Parse(args); err != nil {
 		return err
 	}
 
-	// Check if ID is provided
-	if editCmd.NArg() < 1 {
-		return fmt.Errorf("missing ID")
+	// Check for conflicting flags
+	if *formatFlag != "json" && *formatFlag != "markdown" {
+		return fmt.Errorf("invalid format: %s (expected json or markdown)", *formatFlag)
+	}
+	if *doneFlag && *undoneFlag {
+		return fmt.Errorf("--done and --undone flags cannot be used together")
+	}
+	if *tasksFlag && *memosFlag {
+		return fmt.Errorf("--tasks and --memos flags cannot be used together")
 	}
-
-	// Get ID
-	id := editCmd.Arg(0)
 
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Try to find task by ID or prefix
-	var task *model.Task
-	if len(id) == 36 { // Full UUID
-		task = store.FindTaskByID(id)
-	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, id) {
-				task = t
-				break
+	// Filter tasks, exporting only undone tasks as Markdown by default
+	var tasks []*model.Task
+	if !*memosFlag {
+		for _, task := range store.Tasks {
+			if (*doneFlag && !task.Done) || (*undoneFlag && task.Done) {
+				continue
+			}
+			if *formatFlag == "markdown" && !*doneFlag && !*undoneFlag && task.Done {
+				continue
 			}
+			tasks = append(tasks, task)
 		}
 	}
+	sortTasksForExport(tasks)
 
-	if task != nil {
-		// Edit task
-		return editTask(task, store, s, *editorFlag)
-	}
-
-	// Try to find memo by ID or prefix
-	var memo *model.Memo
-	if len(id) == 36 { // Full UUID
-		memo = store.FindMemoByID(id)
-	} else {
-		// Try to find by prefix
-		for _, m := range store.Memos {
-			if strings.HasPrefix(m.ID, id) {
-				memo = m
-				break
-			}
+	// Render output
+	var data []byte
+	if *formatFlag == "json" {
+		data, err = exportJSON(store, tasks, !*tasksFlag, !*tasksFlag && !*memosFlag && !*doneFlag && !*undoneFlag)
+		if err != nil {
+			return err
 		}
+	} else {
+		data = []byte(exportMarkdown(store, tasks, !*tasksFlag, *memosFlag))
 	}
 
-	if memo != nil {
-		// Edit memo
-		return editMemo(memo, store, s, *editorFlag)
+	// Write output
+	if *outputFlag == "" {
+		_, err := os.Stdout.Write(data)
+		return err
 	}
-
-	return fmt.Errorf("no task or memo found with ID: %s", id)
+	if err := os.WriteFile(*outputFlag, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	c.printf("Exported to %s\n", *outputFlag)
+	return nil
 }
 
-// editTask edits a task using an editor or simple prompts
-func editTask(task *model.Task, store *model.Store, s *storage.Storage, useEditor bool) error {
-	if useEditor {
-		// Get editor from environment
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			// Default to a simple editor if not set
-			editor = "nano"
-		}
-
-		// Create temporary file
-		tmpFile, err := ioutil.TempFile("", "tamo-task-*.md")
-		if err != nil {
-			return fmt.Errorf("failed to create temporary file: %w", err)
-		}
-		defer os.Remove(tmpFile.Name())
-
-		// Write task content to temporary file
-		content := fmt.Sprintf("# %s\n\n%s\n\n# Memo References (one ID per line):\n%s\n",
-			task.Title,
-			task.Description,
-			strings.Join(task.MemoRefs, "\n"))
+// executeImport handles the 'import' command
+func (c *CLI) executeImport(args []string) error {
+	// Create flag set
+	importCmd := flag.NewFlagSet("import", flag.ContinueOnError)
 
-		if _, err := tmpFile.Write([]byte(content)); err != nil {
-			tmpFile.Close()
-			return fmt.Errorf("failed to write to temporary file: %w", err)
-		}
-		tmpFile.Close()
+	// Define flags
+	overwriteFlag := importCmd.Bool("overwrite", false, "Overwrite existing items when the imported item was updated more recently")
+	keepOrderFlag := importCmd.Bool("keep-order", false, "Keep the order values of imported tasks instead of appending them")
 
-		// Open editor
-		cmd := exec.Command(editor, tmpFile.Name())
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+	// Set usage
+	importCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo import <file.json> [--overwrite] [--keep-order]\n\n")
+		fmt.Fprintf(os.Stderr, "Merge tasks and memos from a data.json file or a JSON export\n\n")
+		importCmd.PrintDefaults()
+	}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("editor command failed: %w", err)
-		}
+	// Parse flags
+	positional, err := parseFlagsAnywhere(importCmd, args)
+	if err != nil {
+		return err
+	}
 
-		// Read edited content
-		editedContent, err := ioutil.ReadFile(tmpFile.Name())
-		if err != nil {
-			return fmt.Errorf("failed to read edited content: %w", err)
-		}
+	// Check if file is provided
+	if len(positional) != 1 {
+		importCmd.Usage()
+		return fmt.Errorf("missing file to import")
+	}
 
-		// Parse edited content
-		lines := strings.Split(string(editedContent), "\n")
+	// Read the file to import
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return fmt.Errorf("failed to read import file: %w", err)
+	}
+	var incoming model.Store
+	if err := json.Unmarshal(data, &incoming); err != nil {
+		return fmt.Errorf("failed to parse import file: %w", err)
+	}
+	if _, err := storage.Migrate(&incoming); err != nil {
+		return fmt.Errorf("failed to import file: %w", err)
+	}
 
-		// Extract title, description, and memo refs
-		var title string
-		var description strings.Builder
-		var memoRefs []string
+	// Load store
+	s := c.newStorage()
+	store, err := s.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
 
-		mode := "title"
-		for _, line := range lines {
-			if mode == "title" && strings.HasPrefix(line, "# ") {
-				title = strings.TrimPrefix(line, "# ")
-				mode = "description"
-			} else if mode == "description" && strings.HasPrefix(line, "# Memo References") {
-				mode = "refs"
-			} else if mode == "description" {
-				description.WriteString(line)
-				description.WriteString("\n")
-			} else if mode == "refs" && line != "" && !strings.HasPrefix(line, "# ") {
-				// Add memo ref if it's not empty and not a heading
-				memoRefs = append(memoRefs, strings.TrimSpace(line))
-			}
-		}
+	// Check the IDs before merging anything, as commands rely on them being UUIDs
+	if err := checkImportIDs(store, &incoming); err != nil {
+		return fmt.Errorf("failed to import file: %w", err)
+	}
 
-		// Update task
-		task.Title = title
-		task.Description = strings.TrimSpace(description.String())
-		task.MemoRefs = memoRefs
-		task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
+	tasksAdded, memosAdded, overwritten, skipped := 0, 0, 0, 0
 
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
+	// Merge memos first so that imported task references resolve
+	for _, memo := range incoming.Memos {
+		existing := store.FindMemoByID(memo.ID)
+		if existing == nil {
+			store.AddMemo(memo)
+			memosAdded++
+		} else if *overwriteFlag && memo.UpdatedAt.After(existing.UpdatedAt.Time) {
+			*existing = *memo
+			overwritten++
+		} else {
+			skipped++
 		}
+	}
 
-		fmt.Printf("Task '%s' updated\n", task.Title)
-		return nil
-	} else {
-		// Simple prompt-based editing
-		fmt.Printf("Editing task: %s\n", task.ID)
-
-		// Edit title
-		fmt.Printf("Title [%s]: ", task.Title)
-		title := readLine()
-		if title != "" {
-			task.Title = title
+	// Merge tasks, appending new ones after the existing tasks in their imported order
+	incomingTasks := append([]*model.Task(nil), incoming.Tasks...)
+	sortTasksByOrder(incomingTasks)
+	nextOrder := store.GetMaxTaskOrder() + 1.0
+	for _, task := range incomingTasks {
+		if task.MemoRefs == nil {
+			task.MemoRefs = []string{}
 		}
 
-		// Edit description
-		fmt.Printf("Description [Press Enter to keep, 'edit' to edit]:\n")
-		descAction := readLine()
-		if descAction == "edit" {
-			fmt.Println("Enter new description (press Ctrl+D when finished):")
-			scanner := bufio.NewScanner(os.Stdin)
-			var descBuilder strings.Builder
-			for scanner.Scan() {
-				descBuilder.WriteString(scanner.Text())
-				descBuilder.WriteString("\n")
-			}
-			if err := scanner.Err(); err != nil {
-				return fmt.Errorf("error reading description: %w", err)
+		existing := store.FindTaskByID(task.ID)
+		if existing == nil {
+			if !*keepOrderFlag {
+				task.Order = nextOrder
+				nextOrder += 1.0
 			}
-			task.Description = strings.TrimSpace(descBuilder.String())
-		}
-
-		// Edit memo refs
-		fmt.Printf("Memo References [%s] (comma-separated): ", strings.Join(task.MemoRefs, ","))
-		refsStr := readLine()
-		if refsStr != "" {
-			task.MemoRefs = strings.Split(refsStr, ",")
-			// Trim whitespace from each memo ID
-			for i, ref := range task.MemoRefs {
-				task.MemoRefs[i] = strings.TrimSpace(ref)
+			store.AddTask(task)
+			tasksAdded++
+		} else if *overwriteFlag && task.UpdatedAt.After(existing.UpdatedAt.Time) {
+			if !*keepOrderFlag {
+				task.Order = existing.Order
 			}
+			store.ReplaceTask(existing, task)
+			overwritten++
+		} else {
+			skipped++
 		}
+	}
 
-		// Update timestamp
-		task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
-
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
-		}
+	// Save store
+	if err := s.Save(store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
 
-		fmt.Printf("Task '%s' updated\n", task.Title)
-		return nil
+	c.printf("%d tasks added, %d memos added, %d skipped\n", tasksAdded, memosAdded, skipped)
+	if overwritten > 0 {
+		c.printf("%d existing items overwritten\n", overwritten)
 	}
+	return nil
 }
 
-// editMemo edits a memo using an editor or simple prompts
-func editMemo(memo *model.Memo, store *model.Store, s *storage.Storage, useEditor bool) error {
-	if useEditor {
-		// Get editor from environment
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			// Default to a simple editor if not set
-			editor = "nano"
-		}
-
-		// Create temporary file
-		tmpFile, err := ioutil.TempFile("", "tamo-memo-*.md")
-		if err != nil {
-			return fmt.Errorf("failed to create temporary file: %w", err)
-		}
-		defer os.Remove(tmpFile.Name())
-
-		// Write memo content to temporary file
-		var content string
-		if memo.Title != nil {
-			content = fmt.Sprintf("# %s\n\n%s\n", *memo.Title, memo.Content)
-		} else {
-			content = fmt.Sprintf("# \n\n%s\n", memo.Content)
-		}
-
-		if _, err := tmpFile.Write([]byte(content)); err != nil {
-			tmpFile.Close()
-			return fmt.Errorf("failed to write to temporary file: %w", err)
+// checkImportIDs reports an error unless every task and memo to import has a UUID as its ID,
+// used by no other item in the file, and by no item of another kind in the store.
+// Items with the ID of an existing item of the same kind are merged with it.
+func checkImportIDs(store *model.Store, incoming *model.Store) error {
+	seen := make(map[string]bool)
+	check := func(kind, id string, clashes bool) error {
+		if !utils.IsUUID(id) {
+			return fmt.Errorf("%s has an invalid ID %q (expected a UUID)", kind, id)
 		}
-		tmpFile.Close()
-
-		// Open editor
-		cmd := exec.Command(editor, tmpFile.Name())
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("editor command failed: %w", err)
-		}
-
-		// Read edited content
-		editedContent, err := ioutil.ReadFile(tmpFile.Name())
-		if err != nil {
-			return fmt.Errorf("failed to read edited content: %w", err)
-		}
-
-		// Parse edited content
-		lines := strings.Split(string(editedContent), "\n")
-
-		// Extract title and content
-		var title string
-		var contentBuilder strings.Builder
-
-		mode := "title"
-		for i, line := range lines {
-			if i == 0 && strings.HasPrefix(line, "# ") {
-				title = strings.TrimPrefix(line, "# ")
-				mode = "content"
-			} else if mode == "content" {
-				contentBuilder.WriteString(line)
-				contentBuilder.WriteString("\n")
-			}
-		}
-
-		// Update memo
-		if title != "" {
-			memo.Title = &title
-		} else {
-			memo.Title = nil
-		}
-		memo.Content = strings.TrimSpace(contentBuilder.String())
-		memo.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
-
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
+		if seen[id] {
+			return fmt.Errorf("ID %s is used by more than one item in the file", id)
 		}
-
-		titleStr := "<no title>"
-		if memo.Title != nil {
-			titleStr = *memo.Title
+		if clashes {
+			return fmt.Errorf("%s ID %s is already used by another item", kind, id)
 		}
-		fmt.Printf("Memo '%s' updated\n", titleStr)
+		seen[id] = true
 		return nil
-	} else {
-		// Simple prompt-based editing
-		fmt.Printf("Editing memo: %s\n", memo.ID)
-
-		// Edit title
-		titleStr := "<no title>"
-		if memo.Title != nil {
-			titleStr = *memo.Title
-		}
-		fmt.Printf("Title [%s]: ", titleStr)
-		title := readLine()
-		if title != "" {
-			memo.Title = &title
-		} else if title == "<no title>" {
-			memo.Title = nil
-		}
-
-		// Edit content
-		fmt.Printf("Content [Press Enter to keep, 'edit' to edit]:\n")
-		contentAction := readLine()
-		if contentAction == "edit" {
-			fmt.Println("Enter new content (press Ctrl+D when finished):")
-			scanner := bufio.NewScanner(os.Stdin)
-			var contentBuilder strings.Builder
-			for scanner.Scan() {
-				contentBuilder.WriteString(scanner.Text())
-				contentBuilder.WriteString("\n")
-			}
-			if err := scanner.Err(); err != nil {
-				return fmt.Errorf("error reading content: %w", err)
-			}
-			memo.Content = strings.TrimSpace(contentBuilder.String())
-		}
-
-		// Update timestamp
-		memo.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
+	}
 
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
+	for _, memo := range incoming.Memos {
+		clashes := store.FindTaskByID(memo.ID) != nil || store.FindArchivedTaskByID(memo.ID) != nil
+		if err := check("memo", memo.ID, clashes); err != nil {
+			return err
 		}
-
-		titleStr = "<no title>"
-		if memo.Title != nil {
-			titleStr = *memo.Title
+	}
+	for _, task := range incoming.Tasks {
+		clashes := store.FindMemoByID(task.ID) != nil || store.FindArchivedTaskByID(task.ID) != nil
+		if err := check("task", task.ID, clashes); err != nil {
+			return err
 		}
-		fmt.Printf("Memo '%s' updated\n", titleStr)
-		return nil
 	}
+	return nil
 }
 
-// executeDone handles the 'done' command
-func (c *CLI) executeDone(args []string) error {
-	// Create flag set
-	doneCmd := flag.NewFlagSet("done", flag.ExitOnError)
-
-	// Set usage
-	doneCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo done <task_id>\n\n")
-		fmt.Fprintf(os.Stderr, "Mark a task as done\n\n")
-		doneCmd.PrintDefaults()
-	}
-
-	// Parse flags
-	if err := doneCmd.Parse(args); err != nil {
-		return err
+// exportJSON renders the given tasks, and the memos and trash if requested, as indented JSON.
+// The archived tasks are exported with the trash, so that the memos they reference are kept.
+// Items are sorted so that exporting the same data always gives the same bytes.
+func exportJSON(store *model.Store, tasks []*model.Task, withMemos, withTrash bool) ([]byte, error) {
+	export := &model.Store{
+		Version: store.Version,
+		Tasks:   tasks,
+		Memos:   make([]*model.Memo, 0),
+		Trash: model.Trash{
+			Tasks: make([]*model.TrashedTask, 0),
+			Memos: make([]*model.TrashedMemo, 0),
+		},
+	}
+	if export.Tasks == nil {
+		export.Tasks = make([]*model.Task, 0)
+	}
+	if withMemos {
+		export.Memos = append(export.Memos, store.Memos...)
+		sortMemosForExport(export.Memos)
+	}
+	if withTrash {
+		export.ArchivedTasks = append(export.ArchivedTasks, store.ArchivedTasks...)
+		sortTasksForExport(export.ArchivedTasks)
+		export.Trash.Tasks = append(export.Trash.Tasks, store.Trash.Tasks...)
+		export.Trash.Memos = append(export.Trash.Memos, store.Trash.Memos...)
+		sort.SliceStable(export.Trash.Tasks, func(i, j int) bool {
+			return export.Trash.Tasks[i].ID < export.Trash.Tasks[j].ID
+		})
+		sort.SliceStable(export.Trash.Memos, func(i, j int) bool {
+			return export.Trash.Memos[i].ID < export.Trash.Memos[j].ID
+		})
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
 	}
+	return append(data, '\n'), nil
+}
 
-	// Check if task ID is provided
-	if doneCmd.NArg() < 1 {
-		return fmt.Errorf("missing task ID")
+// exportMarkdown renders the given tasks in the flattask layout separated by horizontal rules,
+// followed by the memos no task references. With allMemos, every memo is rendered instead.
+func exportMarkdown(store *model.Store, tasks []*model.Task, withMemos, allMemos bool) string {
+	var sections []string
+	for _, task := range tasks {
+		sections = append(sections, flattenTask(store, task, nil))
 	}
 
-	// Get task ID
-	taskID := doneCmd.Arg(0)
-
-	// Load store
-	s := storage.NewStorage()
-	store, err := s.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load data: %w", err)
+	var memos []*model.Memo
+	heading := "# Unreferenced Memos"
+	if allMemos {
+		memos = append(memos, store.Memos...)
+		heading = "# Memos"
+	} else if withMemos {
+		memos = store.OrphanMemos()
 	}
+	sortMemosForExport(memos)
 
-	// Find task by ID or prefix
-	var task *model.Task
-	if len(taskID) == 36 { // Full UUID
-		task = store.FindTaskByID(taskID)
-	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, taskID) {
-				task = t
-				break
+	if len(memos) > 0 {
+		var doc strings.Builder
+		doc.WriteString(heading + "\n\n")
+		for _, memo := range memos {
+			if memo.Title != nil {
+				doc.WriteString(fmt.Sprintf("## %s\n\n", *memo.Title))
+			} else {
+				doc.WriteString(fmt.Sprintf("## Memo %s\n\n", memo.ID[:8]))
 			}
+			doc.WriteString(memo.Content)
+			doc.WriteString("\n\n")
 		}
+		sections = append(sections, doc.String())
 	}
 
-	if task == nil {
-		return fmt.Errorf("no task found with ID: %s", taskID)
-	}
-
-	// Mark task as done
-	task.Done = true
-	task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
+	return strings.Join(sections, "---\n\n")
+}
 
-	// Save store
-	if err := s.Save(store); err != nil {
-		return fmt.Errorf("failed to save data: %w", err)
-	}
+// sortTasksForExport sorts tasks by order, breaking ties by ID
+func sortTasksForExport(tasks []*model.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Order != tasks[j].Order {
+			return tasks[i].Order < tasks[j].Order
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+}
 
-	fmt.Printf("Task '%s' marked as done\n", task.Title)
-	return nil
+// sortMemosForExport sorts memos by creation time, breaking ties by ID
+func sortMemosForExport(memos []*model.Memo) {
+	sort.SliceStable(memos, func(i, j int) bool {
+		if !memos[i].CreatedAt.Equal(memos[j].CreatedAt.Time) {
+			return memos[i].CreatedAt.Before(memos[j].CreatedAt.Time)
+		}
+		return memos[i].ID < memos[j].ID
+	})
 }
 
-// executeUndone handles the 'undone' command
-func (c *CLI) executeUndone(args []string) error {
+// executeAddTasks handles the 'add tasks' command, which creates a task for each checklist item in Markdown
+func (c *CLI) executeAddTasks(args []string) error {
 	// Create flag set
-	undoneCmd := flag.NewFlagSet("undone", flag.ExitOnError)
+	tasksCmd := flag.NewFlagSet("add tasks", flag.ContinueOnError)
+
+	// Define flags
+	fileFlag := tasksCmd.String("f", "", "Create tasks from the checklist in a Markdown file")
+	fromStdinFlag := tasksCmd.Bool("from-stdin", false, "Create tasks from the checklist in Markdown input on stdin")
+	h1AsPrefixFlag := tasksCmd.Bool("h1-as-prefix", false, "Prefix the task titles with the H1 heading instead of tagging the tasks with it")
 
 	// Set usage
-	undoneCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo undone <task_id>\n\n")
-		fmt.Fprintf(os.Stderr, "Mark a task as not done\n\n")
-		undoneCmd.PrintDefaults()
+	tasksCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo add tasks -f <filepath> | --from-stdin [--h1-as-prefix]\n\n")
+		fmt.Fprintf(os.Stderr, "Add a task for each '- [ ]' or '- [x]' item of a Markdown checklist\n\n")
+		tasksCmd.PrintDefaults()
 	}
 
 	// Parse flags
-	if err := undoneCmd.Parse(args); err != nil {
+	if err := tasksCmd.Parse(args); err != nil {
 		return err
 	}
-
-	// Check if task ID is provided
-	if undoneCmd.NArg() < 1 {
-		return fmt.Errorf("missing task ID")
+	if (*fileFlag == "") == !*fromStdinFlag {
+		tasksCmd.Usage()
+		return fmt.Errorf("either -f or --from-stdin is required")
 	}
 
-	// Get task ID
-	taskID := undoneCmd.Arg(0)
-
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Find task by ID or prefix
-	var task *model.Task
-	if len(taskID) == 36 { // Full UUID
-		task = store.FindTaskByID(taskID)
+	// Parse Markdown
+	parser := NewMarkdownParser(store)
+	var tasks []*model.Task
+	if *fromStdinFlag {
+		tasks, err = parser.ParseChecklistFromStdin(*h1AsPrefixFlag)
 	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, taskID) {
-				task = t
-				break
-			}
-		}
-	}
-
-	if task == nil {
-		return fmt.Errorf("no task found with ID: %s", taskID)
+		tasks, err = parser.ParseChecklistFromFile(*fileFlag, *h1AsPrefixFlag)
 	}
-
-	// Mark task as not done
-	task.Done = false
-	task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
-
-	// Save store
-	if err := s.Save(store); err != nil {
-		return fmt.Errorf("failed to save data: %w", err)
+	if err != nil {
+		return fmt.Errorf("failed to parse Markdown: %w", err)
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("no checklist items found")
 	}
 
-	fmt.Printf("Task '%s' marked as not done\n", task.Title)
-	return nil
-}
-
-// executeMove handles the 'mv' command
-func (c *CLI) executeMove(args []string) error {
-	// Manual argument parsing
-	// Set usage
-	usage := func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo mv <task_id> <target_order>\n")
-		fmt.Fprintf(os.Stderr, "       tamo mv <task_id> before|after <other_task_id>\n\n")
-		fmt.Fprintf(os.Stderr, "Move a task to a specific order or relative to another task\n")
+	// Save tasks
+	if err := parser.SaveTasksAndMemos(tasks, nil, s); err != nil {
+		return fmt.Errorf("failed to save tasks: %w", err)
 	}
 
-	// Check if we have at least a task ID and a target
-	if len(args) < 2 {
-		usage()
-		return fmt.Errorf("missing arguments")
+	// Print summary
+	fmt.Printf("Created %d tasks:\n", len(tasks))
+	for _, task := range tasks {
+		fmt.Printf("  %s  %s %s\n", task.ID[:8], taskDoneMark(task), task.Title)
 	}
 
-	// Get task ID
-	taskID := args[0]
+	return nil
+}
 
+// executeAddTaskFromMarkdown handles the 'add task' command with Markdown parsing
+func (c *CLI) executeAddTaskFromMarkdown(filePath string, fromStdin, h2AsSubtasks bool) error {
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Find task by ID or prefix
-	var task *model.Task
-	if len(taskID) == 36 { // Full UUID
-		task = store.FindTaskByID(taskID)
+	// Create parser
+	parser := NewMarkdownParser(store)
+
+	// Parse Markdown
+	var tasks []*model.Task
+	var memos []*model.Memo
+
+	if h2AsSubtasks {
+		if fromStdin {
+			tasks, memos, err = parser.ParseSubtasksFromStdin()
+		} else {
+			tasks, memos, err = parser.ParseSubtasksFromFile(filePath)
+		}
 	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, taskID) {
-				task = t
-				break
-			}
+		var task *model.Task
+		if fromStdin {
+			task, memos, err = parser.ParseFromStdin()
+		} else {
+			task, memos, err = parser.ParseFromFile(filePath)
 		}
+		tasks = []*model.Task{task}
 	}
 
-	if task == nil {
-		return fmt.Errorf("no task found with ID: %s", taskID)
+	if err != nil {
+		return fmt.Errorf("failed to parse Markdown: %w", err)
+	}
+	for _, warning := range parser.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
 	}
 
-	// Sort tasks by order
-	var tasks []*model.Task
-	tasks = append(tasks, store.Tasks...)
-	sortTasksByOrder(tasks)
-
-	// Handle different move types
-	if args[1] == "before" || args[1] == "after" {
-		// Relative move
-		if len(args) < 3 {
-			usage()
-			return fmt.Errorf("missing target task ID")
-		}
-
-		// Get target task ID
-		targetTaskID := args[2]
+	// Save tasks and memos
+	if err := parser.SaveTasksAndMemos(tasks, memos, s); err != nil {
+		return fmt.Errorf("failed to save task and memos: %w", err)
+	}
 
-		// Find target task
-		var targetTask *model.Task
-		if len(targetTaskID) == 36 { // Full UUID
-			targetTask = store.FindTaskByID(targetTaskID)
-		} else {
-			// Try to find by prefix
-			for _, t := range store.Tasks {
-				if strings.HasPrefix(t.ID, targetTaskID) {
-					targetTask = t
-					break
-				}
+	// Print success message, with the subtasks as a tree under the parent
+	c.printAddedID("Task added with ID", tasks[0].ID)
+	if h2AsSubtasks {
+		fmt.Printf("Created %d subtasks:\n", len(tasks)-1)
+		fmt.Printf("  %s  %s\n", tasks[0].ID[:8], tasks[0].Title)
+		for i, task := range tasks[1:] {
+			branch := "├─"
+			if i == len(tasks)-2 {
+				branch = "└─"
 			}
+			fmt.Printf("  %s %s  %s\n", branch, task.ID[:8], task.Title)
 		}
-
-		if targetTask == nil {
-			return fmt.Errorf("no target task found with ID: %s", targetTaskID)
+	}
+	if len(memos) > 0 {
+		fmt.Printf("Created %d memos:\n", len(memos))
+		for _, memo := range memos {
+			fmt.Printf("  Memo ID: %s\n", memo.ID[:8])
 		}
+	}
 
-		// Calculate new order
-		var newOrder float64
+	return nil
+}
 
-		if args[1] == "before" {
-			// Find the task before the target task
-			var prevTask *model.Task
-			for i, t := range tasks {
-				if t.ID == targetTask.ID && i > 0 {
-					prevTask = tasks[i-1]
-					break
-				}
-			}
+// Stats holds summary counts of a store
+type Stats struct {
+	Tasks            int `json:"tasks"`
+	DoneTasks        int `json:"done_tasks"`
+	UndoneTasks      int `json:"undone_tasks"`
+	DoneThisWeek     int `json:"done_this_week"`
+	Memos            int `json:"memos"`
+	OrphanMemos      int `json:"orphan_memos"`
+	DanglingMemoRefs int `json:"dangling_memo_refs"`
+}
 
-			if prevTask != nil {
-				// Place between prev and target
-				newOrder = (prevTask.Order + targetTask.Order) / 2.0
-			} else {
-				// Place before the first task
-				newOrder = targetTask.Order - 1.0
-			}
-		} else { // after
-			// Find the task after the target task
-			var nextTask *model.Task
-			for i, t := range tasks {
-				if t.ID == targetTask.ID && i < len(tasks)-1 {
-					nextTask = tasks[i+1]
-					break
-				}
-			}
+// computeStats computes summary counts of the store relative to now
+func computeStats(store *model.Store, now time.Time) Stats {
+	// The week starts on Monday at midnight
+	weekday := (int(now.Weekday()) + 6) % 7
+	year, month, day := now.Date()
+	weekStart := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).AddDate(0, 0, -weekday)
 
-			if nextTask != nil {
-				// Place between target and next
-				newOrder = (targetTask.Order + nextTask.Order) / 2.0
-			} else {
-				// Place after the last task
-				newOrder = targetTask.Order + 1.0
+	stats := Stats{
+		Tasks: len(store.Tasks),
+		Memos: len(store.Memos),
+	}
+
+	for _, task := range store.Tasks {
+		if task.Done {
+			stats.DoneTasks++
+			// Editing a done task doesn't make it done this week
+			if !taskCompletedAt(task).Before(weekStart) {
+				stats.DoneThisWeek++
 			}
+		} else {
+			stats.UndoneTasks++
 		}
+		stats.DanglingMemoRefs += len(findDanglingMemoRefs(store, task))
+	}
 
-		// Update task order
-		task.Order = newOrder
-		task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
+	stats.OrphanMemos = len(store.OrphanMemos())
 
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
-		}
+	return stats
+}
 
-		fmt.Printf("Task '%s' moved %s task '%s'\n", task.Title, args[1], targetTask.Title)
-		return nil
-	} else {
-		// Absolute move
-		targetOrder, err := strconv.ParseFloat(args[1], 64)
-		if err != nil {
-			usage()
-			return fmt.Errorf("invalid target order: %s", args[1])
-		}
+// executeDoctor handles the 'doctor' command
+func (c *CLI) executeDoctor(args []string) error {
+	// Create flag set
+	doctorCmd := flag.NewFlagSet("doctor", flag.ContinueOnError)
 
-		// Update task order
-		task.Order = targetOrder
-		task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
+	// Define flags
+	fixFlag := doctorCmd.Bool("fix", false, "Repair the problems that can be repaired safely, after writing a backup")
 
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
-		}
+	// Set usage
+	doctorCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo doctor [--fix]\n\n")
+		fmt.Fprintf(os.Stderr, "Check the data file for problems and repair them\n\n")
+		doctorCmd.PrintDefaults()
+	}
 
-		fmt.Printf("Task '%s' moved to order %.1f\n", task.Title, targetOrder)
-		return nil
+	// Parse flags
+	if err := doctorCmd.Parse(args); err != nil {
+		return err
 	}
-}
 
-// executePop handles the 'pop task' command
-func (c *CLI) executePop(args []string) error {
-	// Manual argument parsing
-	// Set usage
-	usage := func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo pop task [--done | --rm [-f]]\n\n")
-		fmt.Fprintf(os.Stderr, "Show, mark as done, or remove the last task\n\n")
-		fmt.Fprintf(os.Stderr, "  --done    Mark the last task as done\n")
-		fmt.Fprintf(os.Stderr, "  --rm      Remove the last task\n")
-		fmt.Fprintf(os.Stderr, "  -f        Force removal without confirmation\n")
+	// Read data file
+	s := c.newStorage()
+	data, err := ioutil.ReadFile(s.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read data file: %w", err)
 	}
 
-	// Check if we have at least the 'task' subcommand
-	if len(args) < 1 || args[0] != "task" {
-		usage()
-		return fmt.Errorf("missing or invalid subcommand: expected 'task'")
+	// Check JSON syntax
+	var store model.Store
+	if err := json.Unmarshal(data, &store); err != nil {
+		if line, col, ok := storage.ErrorPosition(data, err); ok {
+			fmt.Printf("%s: invalid JSON at line %d, column %d: %v\n", s.FilePath, line, col, err)
+		} else {
+			fmt.Printf("%s: invalid JSON: %v\n", s.FilePath, err)
+		}
+		fmt.Println("This can't be repaired automatically. Restore a backup with 'tamo backup list' and 'tamo backup restore <timestamp>'.")
+		return fmt.Errorf("data file is not valid JSON")
 	}
 
-	// Parse options
-	doneFlag := false
-	rmFlag := false
-	forceFlag := false
+	// Check version, since repairing a newer data file would drop the fields this tamo doesn't know
+	if err := storage.CheckVersion(store.Version); err != nil {
+		return err
+	}
 
-	for i := 1; i < len(args); i++ {
-		if args[i] == "--done" {
-			doneFlag = true
-		} else if args[i] == "--rm" {
-			rmFlag = true
-		} else if args[i] == "-f" {
-			forceFlag = true
-		}
+	// Check contents
+	problems := checkStore(&store, *fixFlag)
+	if len(problems) == 0 {
+		fmt.Println("No problems found")
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Printf("  %s\n", problem)
 	}
 
-	// Check for conflicting flags
-	if doneFlag && rmFlag {
-		return fmt.Errorf("--done and --rm flags cannot be used together")
+	if !*fixFlag {
+		return fmt.Errorf("%d problems found, run 'tamo doctor --fix' to repair them", len(problems))
 	}
 
-	// Load store
-	s := storage.NewStorage()
-	store, err := s.Load()
+	// Back up the data file before saving the repaired store
+	timestamp, err := s.Backup()
 	if err != nil {
-		return fmt.Errorf("failed to load data: %w", err)
+		return err
+	}
+	s.NoBackup = true
+	if err := s.Save(&store); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
 	}
 
-	// Find the last task (highest order)
-	var lastTask *model.Task
-	maxOrder := -1.0
+	c.printf("%d problems fixed (backup %s)\n", len(problems), timestamp)
+	return nil
+}
 
-	for _, task := range store.Tasks {
-		if task.Order > maxOrder {
-			lastTask = task
-			maxOrder = task.Order
+// checkStore returns the problems found in the store: IDs that are not UUIDs, duplicate IDs, orders that are
+// not finite numbers, memo references that are duplicated or point to missing memos, dependencies on missing
+// tasks or in cycles, and parents that are missing or in cycles.
+// With fix, the problems are repaired by giving new IDs to the items with invalid ones, keeping the first of
+// each duplicate, moving tasks with invalid orders to the end, dropping the references and the dependencies
+// that close the cycles, and making the subtasks whose parent is missing or closes a cycle top-level tasks.
+func checkStore(store *model.Store, fix bool) []string {
+	problems := checkIDs(store, fix)
+
+	// Check for duplicate memo IDs
+	memoIDs := make(map[string]bool)
+	memos := make([]*model.Memo, 0, len(store.Memos))
+	for _, memo := range store.Memos {
+		if memoIDs[memo.ID] {
+			problems = append(problems, fmt.Sprintf("Duplicate memo ID %s (%s)", memo.ID, memoTitle(memo)))
+			continue
 		}
+		memoIDs[memo.ID] = true
+		memos = append(memos, memo)
 	}
 
-	if lastTask == nil {
-		return fmt.Errorf("no tasks found")
+	// Check for duplicate task IDs
+	taskIDs := make(map[string]bool)
+	tasks := make([]*model.Task, 0, len(store.Tasks))
+	for _, task := range store.Tasks {
+		if taskIDs[task.ID] {
+			problems = append(problems, fmt.Sprintf("Duplicate task ID %s (%s)", task.ID, task.Title))
+			continue
+		}
+		taskIDs[task.ID] = true
+		tasks = append(tasks, task)
 	}
 
-	// Handle different actions
-	if doneFlag {
-		// Mark as done
-		lastTask.Done = true
-		lastTask.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
-
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
+	// Check orders
+	maxOrder := 0.0
+	for _, task := range tasks {
+		if !math.IsNaN(task.Order) && !math.IsInf(task.Order, 0) && task.Order > maxOrder {
+			maxOrder = task.Order
 		}
-
-		fmt.Printf("Task '%s' marked as done\n", lastTask.Title)
-	} else if rmFlag {
-		// Remove task
-		if !forceFlag {
-			// Ask for confirmation
-			fmt.Printf("Are you sure you want to remove task '%s'? (y/N): ", lastTask.Title)
-			confirmation := readLine()
-			if strings.ToLower(confirmation) != "y" {
-				fmt.Println("Task removal aborted")
-				return nil
+	}
+	for _, task := range tasks {
+		if math.IsNaN(task.Order) || math.IsInf(task.Order, 0) {
+			problems = append(problems, fmt.Sprintf("Task %s (%s) has an invalid order: %v", task.ID, task.Title, task.Order))
+			if fix {
+				maxOrder += 1.0
+				task.Order = maxOrder
 			}
 		}
+	}
 
-		// Remove task
-		removeTask(store, lastTask.ID)
-
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
-		}
-
-		fmt.Printf("Task '%s' removed\n", lastTask.Title)
-	} else {
-		// Show task details
-		doneStr := "[ ] Not completed"
-		if lastTask.Done {
-			doneStr = "[x] Completed"
+	// Check memo references
+	for _, task := range tasks {
+		refs := make([]string, 0, len(task.MemoRefs))
+		seen := make(map[string]bool)
+		for _, memoID := range task.MemoRefs {
+			switch {
+			case !memoIDs[memoID]:
+				problems = append(problems, fmt.Sprintf("Task %s (%s) references missing memo %s", task.ID, task.Title, memoID))
+			case seen[memoID]:
+				problems = append(problems, fmt.Sprintf("Task %s (%s) references memo %s more than once", task.ID, task.Title, memoID))
+			default:
+				seen[memoID] = true
+				refs = append(refs, memoID)
+			}
 		}
-
-		fmt.Printf("Task ID: %s\n", lastTask.ID)
-		fmt.Printf("Title: %s\n", lastTask.Title)
-		fmt.Printf("Order: %.1f\n", lastTask.Order)
-		fmt.Printf("Status: %s\n", doneStr)
-		fmt.Printf("Created: %s\n", lastTask.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", lastTask.UpdatedAt.Format("2006-01-02 15:04:05"))
-
-		if lastTask.Description != "" {
-			fmt.Println("\nDescription:")
-			fmt.Println(lastTask.Description)
+		if fix {
+			store.SetMemoRefs(task, refs)
 		}
+	}
 
-		if len(lastTask.MemoRefs) > 0 {
-			fmt.Println("\nReferenced Memos:")
-			for _, memoID := range lastTask.MemoRefs {
-				memo := store.FindMemoByID(memoID)
-				if memo != nil {
-					titleStr := "<no title>"
-					if memo.Title != nil {
-						titleStr = *memo.Title
-					}
-					fmt.Printf("  %s  %s\n", memoID[:8], titleStr)
-				} else {
-					fmt.Printf("  %s  <memo not found>\n", memoID[:8])
-				}
+	// Check memo references of archived tasks, which keep their memos from being collected
+	for _, task := range store.ArchivedTasks {
+		refs := make([]string, 0, len(task.MemoRefs))
+		for _, memoID := range task.MemoRefs {
+			if !memoIDs[memoID] {
+				problems = append(problems, fmt.Sprintf("Archived task %s (%s) references missing memo %s", task.ID, task.Title, memoID))
+				continue
 			}
+			refs = append(refs, memoID)
+		}
+		if fix {
+			task.SetMemoRefs(refs)
 		}
 	}
 
-	return nil
-}
-
-// executeShift handles the 'shift task' command
-func (c *CLI) executeShift(args []string) error {
-	// Manual argument parsing
-	// Set usage
-	usage := func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo shift task [--done | --rm [-f]]\n\n")
-		fmt.Fprintf(os.Stderr, "Show, mark as done, or remove the first task\n\n")
-		fmt.Fprintf(os.Stderr, "  --done    Mark the first task as done\n")
-		fmt.Fprintf(os.Stderr, "  --rm      Remove the first task\n")
-		fmt.Fprintf(os.Stderr, "  -f        Force removal without confirmation\n")
-	}
-
-	// Check if we have at least the 'task' subcommand
-	if len(args) < 1 || args[0] != "task" {
-		usage()
-		return fmt.Errorf("missing or invalid subcommand: expected 'task'")
+	// Check parents. A subtask may stay under a parent that has been archived.
+	tasksByID := make(map[string]*model.Task, len(tasks))
+	for _, task := range tasks {
+		tasksByID[task.ID] = task
 	}
-
-	// Parse options
-	doneFlag := false
-	rmFlag := false
-	forceFlag := false
-
-	for i := 1; i < len(args); i++ {
-		if args[i] == "--done" {
-			doneFlag = true
-		} else if args[i] == "--rm" {
-			rmFlag = true
-		} else if args[i] == "-f" {
-			forceFlag = true
+	for _, task := range tasks {
+		if task.ParentID != "" && tasksByID[task.ParentID] == nil && store.FindArchivedTaskByID(task.ParentID) == nil {
+			problems = append(problems, fmt.Sprintf("Task %s (%s) is a subtask of missing task %s", task.ID, task.Title, task.ParentID))
+			if fix {
+				task.ParentID = ""
+			}
 		}
 	}
+	problems = append(problems, checkParentCycles(tasks, tasksByID, fix)...)
 
-	// Check for conflicting flags
-	if doneFlag && rmFlag {
-		return fmt.Errorf("--done and --rm flags cannot be used together")
-	}
-
-	// Load store
-	s := storage.NewStorage()
-	store, err := s.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load data: %w", err)
+	// Check dependencies
+	for _, task := range tasks {
+		deps := make([]string, 0, len(task.DependsOn))
+		for _, depID := range task.DependsOn {
+			if tasksByID[depID] == nil {
+				problems = append(problems, fmt.Sprintf("Task %s (%s) depends on missing task %s", task.ID, task.Title, depID))
+				continue
+			}
+			deps = append(deps, depID)
+		}
+		if fix {
+			task.DependsOn = deps
+		}
 	}
+	problems = append(problems, checkDependencyCycles(tasks, tasksByID, fix)...)
 
-	// Find the first task (lowest order)
-	var firstTask *model.Task
-	minOrder := math.MaxFloat64
+	if fix {
+		store.Memos = memos
+		store.Tasks = tasks
+		store.Reindex()
+	}
+	return problems
+}
 
-	for _, task := range store.Tasks {
-		if task.Order < minOrder {
-			firstTask = task
-			minOrder = task.Order
+// checkIDs returns a problem for each task, archived task, and memo whose ID is not a UUID, as commands show
+// and match the first 8 characters of IDs. With fix, such items get new IDs, and the memo references,
+// dependencies, and parents naming the old IDs are updated.
+func checkIDs(store *model.Store, fix bool) []string {
+	var problems []string
+	newTaskIDs := make(map[string]string)
+	newMemoIDs := make(map[string]string)
+	checkID := func(kind, id, title string, newIDs map[string]string) string {
+		if utils.IsUUID(id) {
+			return id
+		}
+		problems = append(problems, fmt.Sprintf("%s %q (%s) has an invalid ID", kind, id, title))
+		if !fix {
+			return id
+		}
+		newID, err := utils.GenerateUUID()
+		if err != nil {
+			return id
 		}
+		if _, ok := newIDs[id]; !ok {
+			newIDs[id] = newID
+		}
+		return newID
 	}
 
-	if firstTask == nil {
-		return fmt.Errorf("no tasks found")
+	for _, memo := range store.Memos {
+		memo.ID = checkID("Memo", memo.ID, memoTitle(memo), newMemoIDs)
+	}
+	for _, task := range store.Tasks {
+		task.ID = checkID("Task", task.ID, task.Title, newTaskIDs)
+	}
+	for _, task := range store.ArchivedTasks {
+		task.ID = checkID("Archived task", task.ID, task.Title, newTaskIDs)
+	}
+	if len(newTaskIDs) == 0 && len(newMemoIDs) == 0 {
+		return problems
 	}
 
-	// Handle different actions
-	if doneFlag {
-		// Mark as done
-		firstTask.Done = true
-		firstTask.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
-
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
+	// Update the references to the old IDs
+	rename := func(ids []string, newIDs map[string]string) {
+		for i, id := range ids {
+			if newID, ok := newIDs[id]; ok {
+				ids[i] = newID
+			}
 		}
-
-		fmt.Printf("Task '%s' marked as done\n", firstTask.Title)
-	} else if rmFlag {
-		// Remove task
-		if !forceFlag {
-			// Ask for confirmation
-			fmt.Printf("Are you sure you want to remove task '%s'? (y/N): ", firstTask.Title)
-			confirmation := readLine()
-			if strings.ToLower(confirmation) != "y" {
-				fmt.Println("Task removal aborted")
-				return nil
+	}
+	for _, tasks := range [][]*model.Task{store.Tasks, store.ArchivedTasks} {
+		for _, task := range tasks {
+			rename(task.MemoRefs, newMemoIDs)
+			rename(task.DependsOn, newTaskIDs)
+			if newID, ok := newTaskIDs[task.ParentID]; ok && task.ParentID != "" {
+				task.ParentID = newID
 			}
 		}
-
-		// Remove task
-		removeTask(store, firstTask.ID)
-
-		// Save store
-		if err := s.Save(store); err != nil {
-			return fmt.Errorf("failed to save data: %w", err)
+	}
+	for _, template := range store.Templates {
+		for i := range template.Memos {
+			if newID, ok := newMemoIDs[template.Memos[i].MemoID]; ok && template.Memos[i].MemoID != "" {
+				template.Memos[i].MemoID = newID
+			}
 		}
+	}
+	store.Reindex()
+	return problems
+}
 
-		fmt.Printf("Task '%s' removed\n", firstTask.Title)
-	} else {
-		// Show task details
-		doneStr := "[ ] Not completed"
-		if firstTask.Done {
-			doneStr = "[x] Completed"
+// checkParentCycles returns a problem for each parent that closes a cycle, found by following the parents
+// from the tasks in order. With fix, the subtasks closing the cycles become top-level tasks.
+func checkParentCycles(tasks []*model.Task, tasksByID map[string]*model.Task, fix bool) []string {
+	var problems []string
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(tasks))
+
+	for _, start := range tasks {
+		var path []*model.Task
+		task := start
+		for task != nil && state[task.ID] == unvisited {
+			state[task.ID] = visiting
+			path = append(path, task)
+			task = tasksByID[task.ParentID]
 		}
 
-		fmt.Printf("Task ID: %s\n", firstTask.ID)
-		fmt.Printf("Title: %s\n", firstTask.Title)
-		fmt.Printf("Order: %.1f\n", firstTask.Order)
-		fmt.Printf("Status: %s\n", doneStr)
-		fmt.Printf("Created: %s\n", firstTask.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", firstTask.UpdatedAt.Format("2006-01-02 15:04:05"))
+		if task != nil && state[task.ID] == visiting {
+			// Describe the cycle from the parent closing it back to itself
+			var ids []string
+			for i := len(path) - 1; i >= 0; i-- {
+				ids = append([]string{shortID(path[i].ID)}, ids...)
+				if path[i] == task {
+					break
+				}
+			}
+			ids = append(ids, shortID(task.ID))
+			last := path[len(path)-1]
+			problems = append(problems, fmt.Sprintf("Parent cycle: %s (task %s (%s) is a subtask of task %s)", strings.Join(ids, " -> "), last.ID, last.Title, task.ID))
+			if fix {
+				last.ParentID = ""
+			}
+		}
 
-		if firstTask.Description != "" {
-			fmt.Println("\nDescription:")
-			fmt.Println(firstTask.Description)
+		for _, visitedTask := range path {
+			state[visitedTask.ID] = visited
 		}
+	}
+	return problems
+}
 
-		if len(firstTask.MemoRefs) > 0 {
-			fmt.Println("\nReferenced Memos:")
-			for _, memoID := range firstTask.MemoRefs {
-				memo := store.FindMemoByID(memoID)
-				if memo != nil {
-					titleStr := "<no title>"
-					if memo.Title != nil {
-						titleStr = *memo.Title
+// checkDependencyCycles returns a problem for each dependency that closes a cycle, found by a depth-first
+// search from the tasks in order. With fix, those dependencies are dropped.
+func checkDependencyCycles(tasks []*model.Task, tasksByID map[string]*model.Task, fix bool) []string {
+	var problems []string
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(tasks))
+	var path []*model.Task
+
+	var visit func(task *model.Task)
+	visit = func(task *model.Task) {
+		state[task.ID] = visiting
+		path = append(path, task)
+
+		deps := make([]string, 0, len(task.DependsOn))
+		for _, depID := range task.DependsOn {
+			dep := tasksByID[depID]
+			if dep != nil && state[depID] == visiting {
+				// Describe the cycle from the dependency back to itself
+				var ids []string
+				for i := len(path) - 1; i >= 0; i-- {
+					ids = append([]string{shortID(path[i].ID)}, ids...)
+					if path[i] == dep {
+						break
 					}
-					fmt.Printf("  %s  %s\n", memoID[:8], titleStr)
-				} else {
-					fmt.Printf("  %s  <memo not found>\n", memoID[:8])
 				}
+				ids = append(ids, shortID(dep.ID))
+				problems = append(problems, fmt.Sprintf("Dependency cycle: %s (task %s (%s) depends on task %s)", strings.Join(ids, " -> "), task.ID, task.Title, dep.ID))
+				continue
 			}
+			if dep != nil && state[depID] == unvisited {
+				visit(dep)
+			}
+			deps = append(deps, depID)
+		}
+		if fix {
+			task.DependsOn = deps
 		}
-	}
-
-	return nil
-}
 
-// executeNext handles the 'next' command (alias for shift task with focus on undone tasks)
-func (c *CLI) executeNext(args []string) error {
-	// Load store
-	s := storage.NewStorage()
-	store, err := s.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load data: %w", err)
+		path = path[:len(path)-1]
+		state[task.ID] = visited
 	}
 
-	// Find the first undone task (lowest order)
-	var firstUndoneTask *model.Task
-	minOrder := math.MaxFloat64
+	for _, task := range tasks {
+		if state[task.ID] == unvisited {
+			visit(task)
+		}
+	}
+	return problems
+}
 
-	for _, task := range store.Tasks {
-		if !task.Done && task.Order < minOrder {
-			firstUndoneTask = task
-			minOrder = task.Order
+// executeConfig handles the 'config' command
+func (c *CLI) executeConfig(args []string) error {
+	// Set usage
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo config [list]\n")
+		fmt.Fprintf(os.Stderr, "       tamo config get <key>\n")
+		fmt.Fprintf(os.Stderr, "       tamo config set <key> <value>\n")
+		fmt.Fprintf(os.Stderr, "       tamo config unset <key>\n\n")
+		fmt.Fprintf(os.Stderr, "Show or change settings\n\n")
+		fmt.Fprintf(os.Stderr, "Keys:\n")
+		for _, key := range config.Keys {
+			values := strings.Join(key.Values, "|")
+			if key.Values == nil {
+				values = "any value"
+			}
+			fmt.Fprintf(os.Stderr, "  %s  %s (%s; default: %q)\n", key.Name, key.Description, values, key.Default)
 		}
 	}
+	if len(args) > 0 && isHelpArg(args[0]) {
+		usage()
+		return flag.ErrHelp
+	}
 
-	if firstUndoneTask == nil {
-		return fmt.Errorf("no undone tasks found")
+	// Check if tamo is initialized
+	s := c.newStorage()
+	if !s.Exists() {
+		return fmt.Errorf("%w: %s", storage.ErrNotInitialized, s.FilePath)
 	}
 
-	// Show task details
-	fmt.Printf("Task ID: %s\n", firstUndoneTask.ID)
-	fmt.Printf("Title: %s\n", firstUndoneTask.Title)
-	fmt.Printf("Order: %.1f\n", firstUndoneTask.Order)
-	fmt.Printf("Status: [ ] Not completed\n")
-	fmt.Printf("Created: %s\n", firstUndoneTask.CreatedAt.Format("2006-01-02 15:04:05"))
-	fmt.Printf("Updated: %s\n", firstUndoneTask.UpdatedAt.Format("2006-01-02 15:04:05"))
+	// Load config
+	cfg, err := c.loadConfig()
+	if err != nil {
+		return err
+	}
 
-	if firstUndoneTask.Description != "" {
-		fmt.Println("\nDescription:")
-		fmt.Println(firstUndoneTask.Description)
+	subCmd := "list"
+	if len(args) > 0 {
+		subCmd = args[0]
 	}
 
-	if len(firstUndoneTask.MemoRefs) > 0 {
-		fmt.Println("\nReferenced Memos:")
-		for _, memoID := range firstUndoneTask.MemoRefs {
-			memo := store.FindMemoByID(memoID)
-			if memo != nil {
-				titleStr := "<no title>"
-				if memo.Title != nil {
-					titleStr = *memo.Title
-				}
-				fmt.Printf("  %s  %s\n", memoID[:8], titleStr)
-			} else {
-				fmt.Printf("  %s  <memo not found>\n", memoID[:8])
+	switch subCmd {
+	case "list":
+		for _, key := range config.Keys {
+			suffix := ""
+			if !cfg.IsSet(key.Name) {
+				suffix = " (default)"
 			}
+			fmt.Printf("%s = %s%s\n", key.Name, cfg.Get(key.Name), suffix)
+		}
+		return nil
+	case "get":
+		if len(args) != 2 {
+			usage()
+			return fmt.Errorf("expected a key")
+		}
+		if config.FindKey(args[1]) == nil {
+			return fmt.Errorf("unknown config key: %s", args[1])
+		}
+		fmt.Println(cfg.Get(args[1]))
+		return nil
+	case "set":
+		if len(args) != 3 {
+			usage()
+			return fmt.Errorf("expected a key and a value")
+		}
+		if err := cfg.Set(args[1], args[2]); err != nil {
+			return err
+		}
+	case "unset":
+		if len(args) != 2 {
+			usage()
+			return fmt.Errorf("expected a key")
+		}
+		if config.FindKey(args[1]) == nil {
+			return fmt.Errorf("unknown config key: %s", args[1])
 		}
+		cfg.Unset(args[1])
+	default:
+		usage()
+		return fmt.Errorf("unknown subcommand: %s", subCmd)
+	}
+
+	// Save config
+	if err := cfg.Save(); err != nil {
+		return err
 	}
 
+	fmt.Printf("%s = %s\n", args[1], cfg.Get(args[1]))
 	return nil
 }
 
-// executeFlattask handles the 'flattask' command
-func (c *CLI) executeFlattask(args []string) error {
+// executeStats handles the 'stats' command
+func (c *CLI) executeStats(args []string) error {
 	// Create flag set
-	flattaskCmd := flag.NewFlagSet("flattask", flag.ExitOnError)
+	statsCmd := flag.NewFlagSet("stats", flag.ContinueOnError)
+
+	// Define flags
+	jsonFlag := statsCmd.Bool("json", false, "Output statistics as JSON")
 
 	// Set usage
-	flattaskCmd.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: tamo flattask <task_id>\n\n")
-		fmt.Fprintf(os.Stderr, "Flatten a task by expanding all memo references\n\n")
-		flattaskCmd.PrintDefaults()
+	statsCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo stats [--json]\n\n")
+		fmt.Fprintf(os.Stderr, "Show a summary of tasks and memos\n\n")
+		statsCmd.PrintDefaults()
 	}
 
 	// Parse flags
-	if err := flattaskCmd.Parse(args); err != nil {
+	if err := statsCmd.Parse(args); err != nil {
 		return err
 	}
 
-	// Check if task ID is provided
-	if flattaskCmd.NArg() < 1 {
-		return fmt.Errorf("missing task ID")
-	}
-
-	// Get task ID
-	taskID := flattaskCmd.Arg(0)
-
 	// Load store
-	s := storage.NewStorage()
+	s := c.newStorage()
 	store, err := s.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Find task by ID or prefix
-	var task *model.Task
-	if len(taskID) == 36 { // Full UUID
-		task = store.FindTaskByID(taskID)
-	} else {
-		// Try to find by prefix
-		for _, t := range store.Tasks {
-			if strings.HasPrefix(t.ID, taskID) {
-				task = t
-				break
-			}
+	stats := computeStats(store, time.Now())
+
+	if *jsonFlag {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal stats: %w", err)
 		}
+		fmt.Println(string(data))
+		return nil
 	}
 
-	if task == nil {
-		return fmt.Errorf("no task found with ID: %s", taskID)
-	}
+	fmt.Printf("%-20s %5d\n", "Tasks:", stats.Tasks)
+	fmt.Printf("%-20s %5d\n", "  Done:", stats.DoneTasks)
+	fmt.Printf("%-20s %5d\n", "  Undone:", stats.UndoneTasks)
+	fmt.Printf("%-20s %5d\n", "  Done this week:", stats.DoneThisWeek)
+	fmt.Printf("%-20s %5d\n", "Memos:", stats.Memos)
+	fmt.Printf("%-20s %5d\n", "  Orphan memos:", stats.OrphanMemos)
+	fmt.Printf("%-20s %5d\n", "Dangling memo refs:", stats.DanglingMemoRefs)
 
-	// Generate Markdown document
-	var doc strings.Builder
+	return nil
+}
 
-	// Add task title and status
-	doc.WriteString(fmt.Sprintf("# %s\n\n", task.Title))
+// Status is an overview of the data file in use
+type Status struct {
+	Initialized bool       `json:"initialized"`
+	DataFile    string     `json:"data_file"`
+	Tasks       int        `json:"tasks"`
+	UndoneTasks int        `json:"undone_tasks"`
+	Memos       int        `json:"memos"`
+	LastUpdated *time.Time `json:"last_updated"` // nil if nothing has been added yet
+}
 
-	if task.Done {
-		doc.WriteString("**Status:** Completed\n\n")
-	} else {
-		doc.WriteString("**Status:** Not completed\n\n")
+// executeStatus handles the 'status' command
+func (c *CLI) executeStatus(args []string) error {
+	// Create flag set
+	statusCmd := flag.NewFlagSet("status", flag.ContinueOnError)
+
+	// Define flags
+	jsonFlag := statusCmd.Bool("json", false, "Output the status as JSON")
+
+	// Set usage
+	statusCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo status [--json]\n\n")
+		fmt.Fprintf(os.Stderr, "Show which data file is used and an overview of it\n\n")
+		statusCmd.PrintDefaults()
 	}
 
-	// Add task description if available
-	if task.Description != "" {
-		doc.WriteString("## Description\n\n")
-		doc.WriteString(task.Description)
-		doc.WriteString("\n\n")
+	// Parse flags
+	if err := statusCmd.Parse(args); err != nil {
+		return err
 	}
 
-	// Add referenced memos
-	if len(task.MemoRefs) > 0 {
-		doc.WriteString("## Referenced Memos\n\n")
+	s := c.newStorage()
+	status := Status{DataFile: s.FilePath}
+	if abs, err := filepath.Abs(s.FilePath); err == nil {
+		status.DataFile = abs
+	}
 
-		for _, memoID := range task.MemoRefs {
-			memo := store.FindMemoByID(memoID)
-			if memo != nil {
-				// Add memo title
-				if memo.Title != nil {
-					doc.WriteString(fmt.Sprintf("### %s\n\n", *memo.Title))
-				} else {
-					doc.WriteString(fmt.Sprintf("### Memo %s\n\n", memoID[:8]))
-				}
+	// Load store if tamo is initialized
+	if s.Exists() {
+		store, err := s.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load data: %w", err)
+		}
+		status.Initialized = true
 
-				// Add memo content
-				doc.WriteString(memo.Content)
-				doc.WriteString("\n\n")
-			} else {
-				doc.WriteString(fmt.Sprintf("### Memo %s (not found)\n\n", memoID[:8]))
+		// Count items and find the latest update
+		var lastUpdated time.Time
+		for _, task := range store.Tasks {
+			status.Tasks++
+			if !task.Done {
+				status.UndoneTasks++
 			}
-
+			if task.UpdatedAt.After(lastUpdated) {
+				lastUpdated = task.UpdatedAt.Time
+			}
+		}
+		for _, memo := range store.Memos {
+			status.Memos++
+			if memo.UpdatedAt.After(lastUpdated) {
+				lastUpdated = memo.UpdatedAt.Time
+			}
+		}
+		if !lastUpdated.IsZero() {
+			status.LastUpdated = &lastUpdated
 		}
 	}
 
-	// Print the document
-	fmt.Println(doc.String())
+	if *jsonFlag {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal status: %w", err)
+		}
+		fmt.Println(string(data))
+	} else if status.Initialized {
+		lastUpdated := "never"
+		if status.LastUpdated != nil {
+			lastUpdated = status.LastUpdated.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-14s %s\n", "Data file:", status.DataFile)
+		fmt.Printf("%-14s %d (%d undone)\n", "Tasks:", status.Tasks, status.UndoneTasks)
+		fmt.Printf("%-14s %d\n", "Memos:", status.Memos)
+		fmt.Printf("%-14s %s\n", "Last updated:", lastUpdated)
+	}
 
+	if !status.Initialized {
+		return &ExitError{Code: exitNotInitialized, Err: fmt.Errorf("not initialized: %s not found (run 'tamo init' to start, or use --dir or TAMO_DIR to point to existing data)", status.DataFile)}
+	}
 	return nil
 }
 
-// executeAddTaskFromMarkdown handles the 'add task' command with Markdown parsing
-func (c *CLI) executeAddTaskFromMarkdown(args []string) error {
-	// Check if we have the right arguments
-	if len(args) == 0 {
-		return fmt.Errorf("missing arguments for Markdown parsing")
-	}
+// executeCount handles the 'count' command
+func (c *CLI) executeCount(args []string) error {
+	// Create flag set
+	countCmd := flag.NewFlagSet("count", flag.ContinueOnError)
 
-	// Parse options
-	var filePath string
-	fromStdin := false
+	// Define flags
+	doneFlag := countCmd.Bool("done", false, "Count only completed tasks")
+	undoneFlag := countCmd.Bool("undone", false, "Count only uncompleted tasks")
+	tagFlag := countCmd.String("tag", "", "Count only tasks with the tag")
+	overdueFlag := countCmd.Bool("overdue", false, "Count only overdue tasks")
+	memosFlag := countCmd.Bool("memos", false, "Count memos instead of tasks")
 
-	if args[0] == "-f" {
-		if len(args) < 2 {
-			return fmt.Errorf("missing file path after -f")
-		}
-		filePath = args[1]
-	} else if args[0] == "--from-stdin" {
-		fromStdin = true
-	} else {
-		return fmt.Errorf("invalid option: %s", args[0])
+	// Set usage
+	countCmd.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo count [--done|--undone] [--tag <tag>] [--overdue]\n")
+		fmt.Fprintf(os.Stderr, "       tamo count --memos\n\n")
+		fmt.Fprintf(os.Stderr, "Print the number of tasks or memos, e.g. for shell prompts and scripts\n\n")
+		countCmd.PrintDefaults()
 	}
 
-	// Load store
-	s := storage.NewStorage()
-	store, err := s.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load data: %w", err)
+	// Parse flags
+	if err := countCmd.Parse(args); err != nil {
+		return err
 	}
 
-	// Create parser
-	parser := NewMarkdownParser(store)
-
-	// Parse Markdown
-	var task *model.Task
-	var memos []*model.Memo
+	// Check for conflicting flags
+	if countCmd.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(countCmd.Args(), " "))
+	}
+	if *doneFlag && *undoneFlag {
+		return fmt.Errorf("--done and --undone flags cannot be used together")
+	}
+	if *memosFlag && (*doneFlag || *undoneFlag || *tagFlag != "" || *overdueFlag) {
+		return fmt.Errorf("--memos cannot be combined with task filters")
+	}
+	if *overdueFlag {
+		return fmt.Errorf("--overdue is not supported: tasks have no due dates")
+	}
 
-	if fromStdin {
-		task, memos, err = parser.ParseFromStdin()
-	} else {
-		task, memos, err = parser.ParseFromFile(filePath)
+	// Tell scripts apart an uninitialized directory from an empty one
+	s := c.newStorage()
+	if !s.Exists() {
+		return &ExitError{Code: exitNotInitialized, Err: fmt.Errorf("not initialized: %s not found", s.FilePath)}
 	}
 
+	// Load store
+	store, err := s.Load()
 	if err != nil {
-		return fmt.Errorf("failed to parse Markdown: %w", err)
+		return fmt.Errorf("failed to load data: %w", err)
 	}
 
-	// Save task and memos
-	if err := parser.SaveTaskAndMemos(task, memos, s); err != nil {
-		return fmt.Errorf("failed to save task and memos: %w", err)
+	if *memosFlag {
+		fmt.Println(len(store.Memos))
+		return nil
 	}
 
-	// Print success message
-	fmt.Printf("Task added with ID: %s\n", task.ID)
-	if len(memos) > 0 {
-		fmt.Printf("Created %d memos:\n", len(memos))
-		for _, memo := range memos {
-			fmt.Printf("  Memo ID: %s\n", memo.ID[:8])
+	count := 0
+	tag := strings.TrimPrefix(*tagFlag, "#")
+	for _, task := range store.Tasks {
+		if *doneFlag && !task.Done {
+			continue
+		}
+		if *undoneFlag && task.Done {
+			continue
 		}
+		if tag != "" && !containsString(taskTags(task), tag) {
+			continue
+		}
+		count++
 	}
-
+	fmt.Println(count)
 	return nil
 }
@@ -2,12 +2,33 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zishida/tamo/internal/config"
+	"github.com/zishida/tamo/internal/model"
+	"github.com/zishida/tamo/internal/storage"
+	"github.com/zishida/tamo/internal/utils"
+	"github.com/zishida/tamo/internal/version"
 )
 
+// TestMain runs the tests with colors disabled, so output can be compared as plain text
+// even when the tests run on a terminal
+func TestMain(m *testing.M) {
+	os.Setenv("NO_COLOR", "1")
+	os.Exit(m.Run())
+}
+
 // Helper function to capture stdout for testing
 func captureOutput(f func() error) (string, error) {
 	old := os.Stdout
@@ -24,6 +45,33 @@ func captureOutput(f func() error) (string, error) {
 	return buf.String(), err
 }
 
+// Helper function to capture stderr for testing
+func captureStderr(f func() error) (string, error) {
+	old := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	err := f()
+
+	w.Close()
+	os.Stderr = old
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	return buf.String(), err
+}
+
+// Helper function to capture stdout and stderr separately for testing
+func captureOutputAndStderr(f func() error) (string, string, error) {
+	var stdout string
+	stderr, err := captureStderr(func() error {
+		var err error
+		stdout, err = captureOutput(f)
+		return err
+	})
+	return stdout, stderr, err
+}
+
 // TestExecuteHelp tests the help command
 func TestExecuteHelp(t *testing.T) {
 	cli := NewCLI()
@@ -44,6 +92,150 @@ func TestExecuteHelp(t *testing.T) {
 	if !strings.Contains(output, "Available commands:") {
 		t.Errorf("Expected help output to list available commands, got: %s", output)
 	}
+
+	// Check that commands are sorted within their categories, and that every command is listed
+	if !strings.Contains(output, "Tasks:\n  archive ") || strings.Index(output, "  mv ") > strings.Index(output, "  next ") {
+		t.Errorf("Expected commands sorted by category and name, got: %s", output)
+	}
+	for name, cmd := range cli.commands {
+		if !slices.Contains(commandCategories, cmd.Category) {
+			t.Errorf("Expected command %s to have a known category, got %q", name, cmd.Category)
+		}
+		if cmd.Usage == "" || len(cmd.Examples) == 0 {
+			t.Errorf("Expected command %s to have usage and examples", name)
+		}
+	}
+}
+
+// TestExecuteHelpCommand tests the help of a single command
+func TestExecuteHelpCommand(t *testing.T) {
+	cli := NewCLI()
+
+	output, err := captureOutput(func() error {
+		return cli.executeHelp([]string{"mv"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"tamo mv - " + cli.commands["mv"].Description,
+		"Usage:\n  tamo mv <task_id> --order <order>\n",
+		"Examples:\n  tamo mv 1a2b after 3c4d\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected help of mv to contain %q, got: %s", expected, output)
+		}
+	}
+
+	if _, err := captureOutput(func() error {
+		return cli.executeHelp([]string{"nope"})
+	}); err == nil || !strings.Contains(err.Error(), "unknown command 'nope'") {
+		t.Errorf("Expected an unknown command error, got: %v", err)
+	}
+}
+
+// TestRunExitCodes tests the exit codes returned for errors of each kind
+func TestRunExitCodes(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	run := func(args ...string) (int, string) {
+		var code int
+		stderr, _ := captureStderr(func() error {
+			_, err := captureOutput(func() error {
+				code = Run(args)
+				return nil
+			})
+			return err
+		})
+		return code, stderr
+	}
+
+	// Test unknown commands, with a suggestion for a close one
+	code, stderr := run("lisst")
+	if code != exitError || !strings.Contains(stderr, "Error: unknown command 'lisst'. Did you mean 'list'?") {
+		t.Errorf("Expected exit code %d with a suggestion, got %d: %s", exitError, code, stderr)
+	}
+	code, stderr = run("frobnicate")
+	if code != exitError || strings.Contains(stderr, "Did you mean") {
+		t.Errorf("Expected exit code %d without a suggestion, got %d: %s", exitError, code, stderr)
+	}
+
+	// Test a store that is not initialized
+	if code, stderr := run("list"); code != exitNotInitialized {
+		t.Errorf("Expected exit code %d before init, got %d: %s", exitNotInitialized, code, stderr)
+	}
+
+	if code, stderr := run("init"); code != exitOK {
+		t.Fatalf("Expected exit code %d for init, got %d: %s", exitOK, code, stderr)
+	}
+	if code, stderr := run("list", "-h"); code != exitOK {
+		t.Errorf("Expected exit code %d for -h, got %d: %s", exitOK, code, stderr)
+	}
+	for _, args := range [][]string{{"template", "-h"}, {"template", "--help"}, {"backup", "-h"}, {"trash", "-h"}, {"config", "--help"}} {
+		if code, stderr := run(args...); code != exitOK || !strings.Contains(stderr, "Usage: tamo "+args[0]) {
+			t.Errorf("Expected exit code %d with the usage for %v, got %d: %s", exitOK, args, code, stderr)
+		}
+	}
+
+	// Test IDs that match nothing, and ID prefixes that match several tasks
+	if code, stderr := run("show", "ffffffff"); code != exitNotFound {
+		t.Errorf("Expected exit code %d for an unknown ID, got %d: %s", exitNotFound, code, stderr)
+	}
+	if code, stderr := run("done", "%9"); code != exitNotFound || !strings.Contains(stderr, "task position out of range") {
+		t.Errorf("Expected exit code %d for a position out of range, got %d: %s", exitNotFound, code, stderr)
+	}
+	if code, stderr := run("done", "%x"); code != exitError {
+		t.Errorf("Expected exit code %d for an invalid position, got %d: %s", exitError, code, stderr)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	for _, id := range []string{"abcd0001-0000-0000-0000-000000000000", "abcd0002-0000-0000-0000-000000000000"} {
+		store.AddTask(model.NewTask(id, "Task", "", nil))
+		store.AddMemo(model.NewMemo("ef"+id[2:], nil, "Memo"))
+		store.Trash.Tasks = append(store.Trash.Tasks, &model.TrashedTask{Task: *model.NewTask("cc"+id[2:], "Removed", "", nil)})
+	}
+	if err := storage.NewStorage().Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	for _, args := range [][]string{
+		{"done", "abcd"},
+		{"rm", "-f", "abcd"},
+		{"show", "abcd"},
+		{"show", "efcd"},
+		{"edit", "abcd", "--title", "Renamed"},
+		{"edit", "abcd0001", "--add-memo", "efcd"},
+		{"mv", "abcd", "top"},
+		{"mv", "abcd0001", "before", "abcd"},
+		{"flattask", "abcd"},
+		{"restore", "cccd"},
+		{"add", "memo", "Memo", "-c", "Content", "--to-task", "abcd"},
+	} {
+		if code, stderr := run(args...); code != exitNotFound || !strings.Contains(stderr, "ambiguous ID") {
+			t.Errorf("Expected exit code %d for an ambiguous ID with %v, got %d: %s", exitNotFound, args, code, stderr)
+		}
+	}
+
+	// Test other errors
+	if code, stderr := run("mv"); code != exitError {
+		t.Errorf("Expected exit code %d for missing arguments, got %d: %s", exitError, code, stderr)
+	}
 }
 
 // TestExecuteInit tests the init command
@@ -263,8 +455,8 @@ func TestExecuteDone(t *testing.T) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 
-	if !strings.Contains(output, "marked as done") {
-		t.Errorf("Expected output to contain 'marked as done', got: %s", output)
+	if output != "Task 'Test Task' (order 1.0) marked as done (0 tasks remaining)\nAll tasks done! 🎉\n" {
+		t.Errorf("Expected the order, the remaining count, and the completion message, got: %s", output)
 	}
 
 	// Test marking non-existent task as done
@@ -333,8 +525,8 @@ func TestExecuteUndone(t *testing.T) {
 		t.Errorf("Unexpected error: %v", err)
 	}
 
-	if !strings.Contains(output, "marked as not done") {
-		t.Errorf("Expected output to contain 'marked as not done', got: %s", output)
+	if output != "Task 'Test Task' (order 1.0) marked as not done (1 task remaining)\n" {
+		t.Errorf("Expected the order and the remaining count, got: %s", output)
 	}
 }
 
@@ -420,6 +612,28 @@ func TestExecuteMove(t *testing.T) {
 	if !strings.Contains(output, "moved after") {
 		t.Errorf("Expected output to contain 'moved after', got: %s", output)
 	}
+
+	// Test moving tasks to either end of the list, and to the end they are already at
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{taskID1, "last"}, "Task 'Task 1' is already at the bottom"},
+		{[]string{taskID1, "top"}, "Task 'Task 1' moved to the top (order 1.0), before task 'Task 2'"},
+		{[]string{taskID1, "first"}, "Task 'Task 1' is already at the top"},
+		{[]string{taskID2, "bottom"}, "Task 'Task 2' is already at the bottom"},
+		{[]string{taskID1, "bottom"}, "Task 'Task 1' moved to the bottom (order 3.0), after task 'Task 2'"},
+	} {
+		output, err = captureOutput(func() error {
+			return cli.executeMove(tc.args)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tc.args, err)
+		}
+		if strings.TrimSpace(output) != tc.want {
+			t.Errorf("Expected %q for %v, got: %s", tc.want, tc.args, output)
+		}
+	}
 }
 
 // TestExecuteFlattask tests the flattask command
@@ -576,6 +790,35 @@ func TestExecuteShow(t *testing.T) {
 		t.Errorf("Expected output to contain task ID and title, got: %s", output)
 	}
 
+	// Check that the reference shows the task status and order
+	if !strings.Contains(output, "[ ] 1.0 "+taskID[:8]+" Test Task") {
+		t.Errorf("Expected output to contain task status and order, got: %s", output)
+	}
+
+	// Check that the reference tasks are listed above and below the content by default
+	if strings.Count(output, "Reference Tasks:") != 2 {
+		t.Errorf("Expected reference tasks above and below the content, got: %s", output)
+	}
+
+	// Test the configured position of the reference tasks
+	for _, position := range []string{"top", "bottom"} {
+		if _, err := captureOutput(func() error {
+			return cli.executeConfig([]string{"set", "show.refs_position", position})
+		}); err != nil {
+			t.Fatalf("Failed to set config: %v", err)
+		}
+		output, err = captureOutput(func() error {
+			return cli.executeShow([]string{memoID})
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		below := strings.Index(output, "Reference Tasks:") > strings.Index(output, "Test Memo Content")
+		if strings.Count(output, "Reference Tasks:") != 1 || below != (position == "bottom") {
+			t.Errorf("Expected reference tasks only at the %s, got: %s", position, output)
+		}
+	}
+
 	// Test show task command
 	output, err = captureOutput(func() error {
 		return cli.executeShow([]string{taskID})
@@ -593,3 +836,7376 @@ func TestExecuteShow(t *testing.T) {
 		t.Errorf("Expected output to contain memo reference, got: %s", output)
 	}
 }
+
+// TestComputeStats tests the statistics computed for the stats command
+func TestComputeStats(t *testing.T) {
+	store := model.NewStore()
+
+	memo1 := model.NewMemo("memo-1", nil, "Referenced memo")
+	memo2 := model.NewMemo("memo-2", nil, "Orphan memo")
+	store.AddMemo(memo1)
+	store.AddMemo(memo2)
+
+	task1 := model.NewTask("task-1", "Task 1", "", []string{"memo-1"})
+	task2 := model.NewTask("task-2", "Task 2", "", []string{"missing-memo"})
+	task2.Done = true
+	task3 := model.NewTask("task-3", "Task 3", "", nil)
+	task3.Done = true
+	task3.UpdatedAt = model.CustomTime{Time: time.Now().AddDate(0, 0, -30)}
+	// Completed a month ago but edited today
+	task4 := model.NewTask("task-4", "Task 4", "", nil)
+	task4.Done = true
+	task4.CompletedAt = &model.CustomTime{Time: time.Now().AddDate(0, 0, -30)}
+	store.AddTask(task1)
+	store.AddTask(task2)
+	store.AddTask(task3)
+	store.AddTask(task4)
+
+	stats := computeStats(store, time.Now())
+
+	expected := Stats{
+		Tasks:            4,
+		DoneTasks:        3,
+		UndoneTasks:      1,
+		DoneThisWeek:     1,
+		Memos:            2,
+		OrphanMemos:      1,
+		DanglingMemoRefs: 1,
+	}
+	if stats != expected {
+		t.Errorf("Expected stats %+v, got %+v", expected, stats)
+	}
+}
+
+// withStdin runs f with os.Stdin replaced by the given input
+func withStdin(t *testing.T, input string, f func() error) error {
+	t.Helper()
+
+	tmpFile, err := os.CreateTemp("", "tamo-stdin")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(input); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	if _, err := tmpFile.Seek(0, 0); err != nil {
+		t.Fatalf("Failed to seek temp file: %v", err)
+	}
+	defer tmpFile.Close()
+
+	old := os.Stdin
+	os.Stdin = tmpFile
+	defer func() { os.Stdin = old }()
+
+	return f()
+}
+
+// TestExecuteAddTaskInteractive tests the add task command in interactive mode
+func TestExecuteAddTaskInteractive(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Test Memo", "-c", "Test Memo Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(output[strings.Index(output, "Memo added with ID: ")+len("Memo added with ID: "):])
+
+	// Empty title is asked again, unknown memo is asked again, then confirmed
+	input := "\nWizard Task\nWizard Description\nunknown\n" + memoID[:8] + "\nwizard, cli\n\n"
+	output, prompts, err := captureOutputAndStderr(func() error {
+		return withStdin(t, input, func() error {
+			return cli.executeAddTask([]string{"--interactive"}, "add")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(prompts, "Title is required") {
+		t.Errorf("Expected prompts to ask for the title again, got: %s", prompts)
+	}
+	if !strings.Contains(prompts, "memo with ID unknown not found") {
+		t.Errorf("Expected prompts to report the unknown memo, got: %s", prompts)
+	}
+	if !strings.HasPrefix(output, "Task added with ID") {
+		t.Errorf("Expected output to contain only the task added message, got: %s", output)
+	}
+
+	// Declining the confirmation doesn't create a task
+	output, err = captureStderr(func() error {
+		return withStdin(t, "\n\n\n\nn\n", func() error {
+			return cli.executeAddTask([]string{"Declined Task", "--interactive"}, "add")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task creation aborted") {
+		t.Errorf("Expected output to contain abort message, got: %s", output)
+	}
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(store.Tasks))
+	}
+	task := store.Tasks[0]
+	if task.Title != "Wizard Task" || task.Description != "Wizard Description" {
+		t.Errorf("Expected task 'Wizard Task' with description, got %q / %q", task.Title, task.Description)
+	}
+	if len(task.MemoRefs) != 1 || task.MemoRefs[0] != memoID {
+		t.Errorf("Expected task to reference memo %s, got %v", memoID, task.MemoRefs)
+	}
+	if len(task.Tags) != 2 || task.Tags[0] != "wizard" || task.Tags[1] != "cli" {
+		t.Errorf("Expected tags [wizard cli], got %v", task.Tags)
+	}
+
+	// Flags give the defaults of the prompts
+	_, _, err = captureOutputAndStderr(func() error {
+		return withStdin(t, "\n\n\n\n\n", func() error {
+			return cli.executeAddTask([]string{"Flag Task", "--interactive", "-d", "Flag Description", "-m", memoID[:8], "--tag", "flag", "--priority", "high"}, "add")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task = store.Tasks[len(store.Tasks)-1]
+	if task.Title != "Flag Task" || task.Description != "Flag Description" || task.Priority != "high" {
+		t.Errorf("Expected the flags as defaults, got %q / %q / %q", task.Title, task.Description, task.Priority)
+	}
+	if len(task.MemoRefs) != 1 || task.MemoRefs[0] != memoID || len(task.Tags) != 1 || task.Tags[0] != "flag" {
+		t.Errorf("Expected the memo and tag flags as defaults, got %v / %v", task.MemoRefs, task.Tags)
+	}
+}
+
+// writeFakeEditor writes a shell script that replaces the edited file with the given content
+func writeFakeEditor(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	contentPath := filepath.Join(dir, "editor-content.txt")
+	if err := os.WriteFile(contentPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write editor content: %v", err)
+	}
+
+	scriptPath := filepath.Join(dir, "fake-editor.sh")
+	script := fmt.Sprintf("#!/bin/sh\ncat '%s' > \"$1\"\n", contentPath)
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake editor: %v", err)
+	}
+
+	return scriptPath
+}
+
+// TestExecuteAddMemoEditor tests the add memo command with the --editor flag
+func TestExecuteAddMemoEditor(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Editor command with arguments
+	editor := writeFakeEditor(t, tempDir, "# Editor Memo\n\nEditor content\n")
+	t.Setenv("EDITOR", "sh "+editor)
+
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"--editor"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Memo added with ID") {
+		t.Errorf("Expected output to contain memo added message, got: %s", output)
+	}
+
+	// Saving an empty file aborts
+	editor = writeFakeEditor(t, tempDir, "")
+	t.Setenv("EDITOR", "sh "+editor)
+
+	output, err = captureOutput(func() error {
+		return cli.executeAddMemo([]string{"--editor"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "memo not created") {
+		t.Errorf("Expected output to contain abort message, got: %s", output)
+	}
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Memos) != 1 {
+		t.Fatalf("Expected 1 memo, got %d", len(store.Memos))
+	}
+	memo := store.Memos[0]
+	if memo.Title == nil || *memo.Title != "Editor Memo" {
+		t.Errorf("Expected memo title 'Editor Memo', got %v", memo.Title)
+	}
+	if memo.Content != "Editor content" {
+		t.Errorf("Expected memo content 'Editor content', got %q", memo.Content)
+	}
+}
+
+// TestExecuteListTimeline tests the list command with the --timeline flag
+func TestExecuteListTimeline(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task and a memo
+	if err := cli.executeAddTask([]string{"Old Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddMemo([]string{"New Memo", "-c", "Memo Content"}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	// Make the task older than the memo
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	store.Tasks[0].UpdatedAt = model.CustomTime{Time: time.Now().Add(-time.Hour).UTC()}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test timeline lists the memo before the task
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"all", "--timeline"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	memoIndex := strings.Index(output, "memo  ")
+	taskIndex := strings.Index(output, "task  ")
+	if memoIndex == -1 || taskIndex == -1 || memoIndex > taskIndex {
+		t.Errorf("Expected memo to be listed before task, got: %s", output)
+	}
+
+	// Test limit keeps only the most recent item
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"all", "--timeline", "--limit", "1"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "New Memo") || strings.Contains(output, "Old Task") {
+		t.Errorf("Expected only the memo in limited timeline, got: %s", output)
+	}
+}
+
+// TestEditorCommand tests how the editor command is read from the environment and the editor setting
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamoEditor string
+		configured string
+		editor     string
+		expected   []string
+	}{
+		{"default", "", "", "", []string{"nano"}},
+		{"editor only", "", "", "vim", []string{"vim"}},
+		{"editor with arguments", "", "", "code --wait", []string{"code", "--wait"}},
+		{"extra whitespace", "", "", "  vim   -u NONE ", []string{"vim", "-u", "NONE"}},
+		{"tamo editor takes precedence", "emacs -nw", "", "vim", []string{"emacs", "-nw"}},
+		{"configured editor takes precedence over editor", "", "micro", "vim", []string{"micro"}},
+		{"tamo editor takes precedence over configured editor", "emacs -nw", "micro", "vim", []string{"emacs", "-nw"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TAMO_EDITOR", tt.tamoEditor)
+			t.Setenv("EDITOR", tt.editor)
+
+			got := editorCommand(tt.configured)
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestExecuteEditTaskEditor tests the edit command on a task with the --editor flag
+func TestExecuteEditTaskEditor(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Test Task", "-d", "Test Description"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+
+	// Editor command with arguments from TAMO_EDITOR
+	editor := writeFakeEditor(t, tempDir, "# Edited Task\n\n---TAMO-DESCRIPTION---\nEdited Description\n---TAMO-MEMO-REFS---\n")
+	t.Setenv("TAMO_EDITOR", "sh "+editor)
+	t.Setenv("EDITOR", "false")
+
+	output, err = captureOutput(func() error {
+		return cli.executeEdit([]string{"--editor", taskID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Edited Task' updated") {
+		t.Errorf("Expected output to contain task updated message, got: %s", output)
+	}
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(taskID)
+	if task.Title != "Edited Task" || task.Description != "Edited Description" {
+		t.Errorf("Expected edited title and description, got %q / %q", task.Title, task.Description)
+	}
+}
+
+// TestExecuteAddMemoToTask tests the add memo command with the --to-task flag
+func TestExecuteAddMemoToTask(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two tasks
+	var taskIDs []string
+	for _, title := range []string{"Task 1", "Task 2"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):]))
+	}
+
+	// Test unknown task doesn't create a memo
+	_, err = captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Content", "--to-task", "nonexistent"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no task found") {
+		t.Errorf("Expected error about task not found, got: %v", err)
+	}
+
+	// Test linking to both tasks
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Content", "--to-task", taskIDs[0][:8], "--to-task", taskIDs[1][:8]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Linked to tasks:") || !strings.Contains(output, "Task 2") {
+		t.Errorf("Expected output to list linked tasks, got: %s", output)
+	}
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Memos) != 1 {
+		t.Fatalf("Expected 1 memo, got %d", len(store.Memos))
+	}
+	if store.Memos[0].Content != "Content" {
+		t.Errorf("Expected memo content 'Content', got %q", store.Memos[0].Content)
+	}
+	for _, taskID := range taskIDs {
+		task := store.FindTaskByID(taskID)
+		if !containsString(task.MemoRefs, store.Memos[0].ID) {
+			t.Errorf("Expected task %s to reference the memo, got %v", taskID, task.MemoRefs)
+		}
+	}
+
+	// Test that a prefix matching both tasks doesn't create a memo
+	if _, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Content", "--task", ""})
+	}); !errors.Is(err, errAmbiguousID) {
+		t.Errorf("Expected ambiguous ID error, got: %v", err)
+	}
+
+	// Test --task with content from stdin appends the memo to the references
+	output, err = captureOutput(func() error {
+		return withStdin(t, "Note\n", func() error {
+			return cli.executeAddMemo([]string{"Note", "--from-stdin", "--task", taskIDs[1][:8]})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Memo added with ID: ") || !strings.Contains(output, taskIDs[1][:8]+"  Task 2") {
+		t.Errorf("Expected the memo ID and the linked task, got: %s", output)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Memos) != 2 {
+		t.Fatalf("Expected 2 memos, got %d", len(store.Memos))
+	}
+	if refs := store.FindTaskByID(taskIDs[1]).MemoRefs; len(refs) != 2 || refs[1] != store.Memos[1].ID {
+		t.Errorf("Expected the new memo at the end of the references, got %v", refs)
+	}
+}
+
+// TestExecuteAddTaskFlagPositions tests that add task accepts flags before and after the title
+func TestExecuteAddTaskFlagPositions(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		title       string
+		description string
+	}{
+		{"title first", []string{"My title", "-d", "desc"}, "My title", "desc"},
+		{"flags first", []string{"-d", "desc", "My title"}, "My title", "desc"},
+		{"title between flags", []string{"-d", "desc", "My title", "-m", ""}, "My title", "desc"},
+		{"double dash", []string{"-d", "desc", "--", "-d"}, "-d", "desc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := captureOutput(func() error {
+				return cli.executeAddTask(tt.args, "add")
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			taskID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+
+			store, err := storage.NewStorage().Load()
+			if err != nil {
+				t.Fatalf("Failed to load data: %v", err)
+			}
+			task := store.FindTaskByID(taskID)
+			if task == nil {
+				t.Fatalf("Task %s not found", taskID)
+			}
+			if task.Title != tt.title || task.Description != tt.description {
+				t.Errorf("Expected %q / %q, got %q / %q", tt.title, tt.description, task.Title, task.Description)
+			}
+		})
+	}
+
+	// Test unknown flag is a usage error
+	_, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"--unknown", "My title"}, "add")
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid arguments") {
+		t.Errorf("Expected usage error for unknown flag, got: %v", err)
+	}
+
+	// Test extra positional arguments are rejected
+	_, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"My", "title"}, "add")
+	})
+	if err == nil || !strings.Contains(err.Error(), "unexpected arguments") {
+		t.Errorf("Expected error for unexpected arguments, got: %v", err)
+	}
+}
+
+// TestExecuteListCheckRefs tests the list command with the --check-refs flag
+func TestExecuteListCheckRefs(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a healthy task and a task with a broken memo reference
+	if err := cli.executeAddTask([]string{"Healthy Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Broken Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	store.Tasks[1].MemoRefs = []string{"00000000-0000-4000-8000-000000000000"}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--check-refs"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "Healthy Task") && strings.Contains(line, "⚠") {
+			t.Errorf("Expected healthy task not to be marked, got: %s", line)
+		}
+		if strings.Contains(line, "Broken Task") && !strings.Contains(line, "⚠ (1 broken ref)") {
+			t.Errorf("Expected broken task to be marked, got: %s", line)
+		}
+	}
+
+	// Test markers are not shown without the flag
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "broken ref") {
+		t.Errorf("Expected no broken ref markers without --check-refs, got: %s", output)
+	}
+}
+
+// TestExecuteEditFlags tests the edit command with non-interactive flags
+func TestExecuteEditFlags(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo and a task
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Test Memo", "-c", "Test Memo Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(output[strings.Index(output, "Memo added with ID: ")+len("Memo added with ID: "):])
+
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Test Task", "-d", "Test Description"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+
+	// Test editing a task with flags
+	output, err = captureOutput(func() error {
+		return cli.executeEdit([]string{taskID[:8], "--title", "New Title", "--description", "New Description", "--add-memo", memoID[:8], "--done"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 'New Title' updated") {
+		t.Errorf("Expected output to contain task updated message, got: %s", output)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(taskID)
+	if task.Title != "New Title" || task.Description != "New Description" || !task.Done {
+		t.Errorf("Expected task to be updated, got %+v", task)
+	}
+	if len(task.MemoRefs) != 1 || task.MemoRefs[0] != memoID {
+		t.Errorf("Expected task to reference memo %s, got %v", memoID, task.MemoRefs)
+	}
+
+	// Test removing the memo reference and marking as undone
+	if _, err := captureOutput(func() error {
+		return cli.executeEdit([]string{taskID[:8], "--remove-memo", memoID[:8], "--undone"})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task = store.FindTaskByID(taskID)
+	if len(task.MemoRefs) != 0 || task.Done {
+		t.Errorf("Expected memo reference removed and task undone, got %+v", task)
+	}
+
+	// Test unknown memo is rejected
+	_, err = captureOutput(func() error {
+		return cli.executeEdit([]string{taskID[:8], "--add-memo", "nonexistent"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected error about memo not found, got: %v", err)
+	}
+
+	// Test editing a memo with flags
+	output, err = captureOutput(func() error {
+		return cli.executeEdit([]string{memoID[:8], "--content", "New Content"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if memo := store.FindMemoByID(memoID); memo.Content != "New Content" || memo.Title == nil || *memo.Title != "Test Memo" {
+		t.Errorf("Expected memo content to be updated, got %+v", memo)
+	}
+}
+
+// TestExecuteReorder tests the reorder command
+func TestExecuteReorder(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks out of title order
+	for _, title := range []string{"Charlie", "alpha", "Bravo"} {
+		if err := cli.executeAddTask([]string{title}, "add"); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+
+	// Test missing sort key
+	_, err = captureOutput(func() error {
+		return cli.executeReorder([]string{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "missing --by") {
+		t.Errorf("Expected error about missing --by flag, got: %v", err)
+	}
+
+	// Test declining the confirmation
+	output, err := captureStderr(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeReorder([]string{"--by", "title"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Reorder aborted") {
+		t.Errorf("Expected output to contain abort message, got: %s", output)
+	}
+
+	// Test reordering by title
+	output, err = captureOutput(func() error {
+		return cli.executeReorder([]string{"--by", "title", "--yes"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "3 tasks reordered by title") {
+		t.Errorf("Expected output to report reordered tasks, got: %s", output)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	tasks := append([]*model.Task{}, store.Tasks...)
+	sortTasksByOrder(tasks)
+	for i, expected := range []string{"alpha", "Bravo", "Charlie"} {
+		if tasks[i].Title != expected || tasks[i].Order != float64(i+1) {
+			t.Errorf("Expected %s at order %d, got %s at order %.1f", expected, i+1, tasks[i].Title, tasks[i].Order)
+		}
+	}
+}
+
+// TestExecuteRemoveMultiple tests the rm command with multiple IDs
+func TestExecuteRemoveMultiple(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two tasks and a memo
+	var ids []string
+	for _, title := range []string{"Task 1", "Task 2"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])[:8])
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Test Memo", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	ids = append(ids, strings.TrimSpace(output[strings.Index(output, "Memo added with ID: ")+len("Memo added with ID: "):])[:8])
+
+	// Test that an unknown ID aborts the whole removal
+	_, err = captureOutput(func() error {
+		return cli.executeRemove([]string{ids[0], "nonexistent"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "nothing removed") {
+		t.Errorf("Expected error about nothing removed, got: %v", err)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 2 {
+		t.Errorf("Expected no tasks removed, got %d tasks", len(store.Tasks))
+	}
+
+	// Test that --force removes the found items but still fails
+	output, err = captureOutput(func() error {
+		return cli.executeRemove([]string{ids[0], "nonexistent", ids[2], "-f"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 IDs not found") {
+		t.Errorf("Expected error about IDs not found, got: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Task 1' removed") || !strings.Contains(output, "Memo 'Test Memo' removed") {
+		t.Errorf("Expected output to report removed items, got: %s", output)
+	}
+
+	// Test removing the remaining task after confirmation
+	if _, err := captureOutput(func() error {
+		return withStdin(t, "y\n", func() error {
+			return cli.executeRemove([]string{ids[1]})
+		})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 0 || len(store.Memos) != 0 {
+		t.Errorf("Expected all items removed, got %d tasks and %d memos", len(store.Tasks), len(store.Memos))
+	}
+
+	// Test that a memo referenced only by a task removed together is removed without --force
+	output, err = captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Task Memo", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(output[strings.Index(output, "Memo added with ID: ")+len("Memo added with ID: "):])
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task 3", "-m", memoID}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	if _, _, err := captureOutputAndStderr(func() error {
+		return withStdin(t, "y\ny\n", func() error {
+			return cli.executeRemove([]string{memoID[:8], taskID[:8]})
+		})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 0 || len(store.Memos) != 0 {
+		t.Errorf("Expected the task and its memo removed, got %d tasks and %d memos", len(store.Tasks), len(store.Memos))
+	}
+}
+
+// TestSortMemoRefs tests sorting of referenced memos for the show command
+func TestSortMemoRefs(t *testing.T) {
+	store := model.NewStore()
+
+	titleB := "Bravo"
+	titleA := "alpha"
+	older := model.NewMemo("memo-older", &titleB, "Older memo")
+	older.CreatedAt = model.CustomTime{Time: time.Now().Add(-time.Hour)}
+	newer := model.NewMemo("memo-newer", &titleA, "Newer memo")
+	store.AddMemo(older)
+	store.AddMemo(newer)
+
+	refs := []string{"memo-missing", "memo-newer", "memo-older"}
+
+	tests := []struct {
+		by       string
+		expected []string
+	}{
+		{"", []string{"memo-missing", "memo-newer", "memo-older"}},
+		{"created", []string{"memo-older", "memo-newer", "memo-missing"}},
+		{"title", []string{"memo-newer", "memo-older", "memo-missing"}},
+	}
+
+	for _, tt := range tests {
+		got := sortMemoRefs(store, refs, tt.by)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("sortMemoRefs(%q): expected %v, got %v", tt.by, tt.expected, got)
+		}
+	}
+
+	// The original references must not be modified
+	if refs[0] != "memo-missing" {
+		t.Errorf("Expected original references to be unchanged, got %v", refs)
+	}
+}
+
+// TestResolveTask tests resolving tasks by full ID or unique prefix
+func TestResolveTask(t *testing.T) {
+	store := model.NewStore()
+	store.AddTask(model.NewTask("aaaa1111-0000-4000-8000-000000000000", "Task 1", "", nil))
+	store.AddTask(model.NewTask("aaaa2222-0000-4000-8000-000000000000", "Task 2", "", nil))
+
+	if task, err := resolveTask(store, "aaaa1"); err != nil || task.Title != "Task 1" {
+		t.Errorf("Expected unique prefix to resolve to Task 1, got %v, %v", task, err)
+	}
+	if task, err := resolveTask(store, "aaaa2222-0000-4000-8000-000000000000"); err != nil || task.Title != "Task 2" {
+		t.Errorf("Expected full ID to resolve to Task 2, got %v, %v", task, err)
+	}
+	if _, err := resolveTask(store, "aaaa"); !errors.Is(err, errAmbiguousID) {
+		t.Errorf("Expected ambiguous ID error, got %v", err)
+	}
+	if _, err := resolveTask(store, "bbbb"); err == nil || !strings.Contains(err.Error(), "no task found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+// TestResolveMemo tests resolving memos by full ID or unique prefix
+func TestResolveMemo(t *testing.T) {
+	store := model.NewStore()
+	store.AddMemo(model.NewMemo("aaaa1111-0000-4000-8000-000000000000", nil, "Memo 1"))
+	store.AddMemo(model.NewMemo("aaaa2222-0000-4000-8000-000000000000", nil, "Memo 2"))
+
+	if memo, err := resolveMemo(store, "aaaa1"); err != nil || memo.Content != "Memo 1" {
+		t.Errorf("Expected unique prefix to resolve to Memo 1, got %v, %v", memo, err)
+	}
+	if memo, err := resolveMemo(store, "aaaa2222-0000-4000-8000-000000000000"); err != nil || memo.Content != "Memo 2" {
+		t.Errorf("Expected full ID to resolve to Memo 2, got %v, %v", memo, err)
+	}
+	if _, err := resolveMemo(store, "aaaa"); !errors.Is(err, errAmbiguousID) {
+		t.Errorf("Expected ambiguous ID error, got %v", err)
+	}
+	if _, err := resolveMemo(store, "bbbb"); err == nil || !strings.Contains(err.Error(), "no memo found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if _, err := resolveMemoRefs(store, []string{"aaaa"}, false); !errors.Is(err, errAmbiguousID) {
+		t.Errorf("Expected ambiguous ID error for memo references, got %v", err)
+	}
+}
+
+// TestExecuteDoneMultiple tests the done and undone commands with multiple IDs
+func TestExecuteDoneMultiple(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add three tasks
+	var ids []string
+	for _, title := range []string{"Task 1", "Task 2", "Task 3"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])[:8])
+	}
+
+	// Test marking multiple tasks with an unknown ID
+	output, err := captureOutput(func() error {
+		return cli.executeDone([]string{ids[0], ids[1], "nonexistent"})
+	})
+	if err == nil {
+		t.Errorf("Expected error for unknown ID")
+	}
+	if !strings.Contains(output, "2 tasks marked as done, 1 not found (") {
+		t.Errorf("Expected summary in output, got: %s", output)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	doneCount := 0
+	for _, task := range store.Tasks {
+		if task.Done {
+			doneCount++
+		}
+	}
+	if doneCount != 2 {
+		t.Errorf("Expected 2 done tasks, got %d", doneCount)
+	}
+
+	// Test marking multiple tasks as undone
+	output, err = captureOutput(func() error {
+		return cli.executeUndone([]string{ids[0], ids[1]})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "2 tasks marked as not done") {
+		t.Errorf("Expected summary in output, got: %s", output)
+	}
+
+	// Test that a task given twice, by a prefix and a position, is marked once
+	output, err = captureOutput(func() error {
+		return cli.executeDone([]string{ids[2], ids[2][:4], "%3"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Count(output, "Task 'Task 3'") != 1 || !strings.Contains(output, "1 task marked as done (2 tasks remaining)") {
+		t.Errorf("Expected the task to be marked and counted once, got: %s", output)
+	}
+}
+
+// TestExecuteAddTaskLikeLastTag tests the add task command with the --like-last-tag flag
+func TestExecuteAddTaskLikeLastTag(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	addTask := func(args ...string) *model.Task {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+		store, err := storage.NewStorage().Load()
+		if err != nil {
+			t.Fatalf("Failed to load data: %v", err)
+		}
+		return store.FindTaskByID(taskID)
+	}
+
+	addTask("Template Task", "--tag", "backend,api", "--priority", "high", "-d", "Template Description")
+
+	// Test copying attributes
+	task := addTask("Copied Task", "--like-last-tag", "backend")
+	if strings.Join(task.Tags, ",") != "backend,api" || task.Priority != "high" || task.Description != "Template Description" {
+		t.Errorf("Expected attributes to be copied, got %+v", task)
+	}
+
+	// Test overriding copied attributes
+	task = addTask("Overridden Task", "--like-last-tag", "backend", "--priority", "low", "-d", "Own Description")
+	if strings.Join(task.Tags, ",") != "backend,api" || task.Priority != "low" || task.Description != "Own Description" {
+		t.Errorf("Expected flags to override copied attributes, got %+v", task)
+	}
+
+	// Test falling back when no task has the tag
+	task = addTask("New Tag Task", "--like-last-tag", "frontend")
+	if strings.Join(task.Tags, ",") != "frontend" || task.Priority != "" || task.Description != "" {
+		t.Errorf("Expected normal task with the tag, got %+v", task)
+	}
+}
+
+// TestExecuteRemoveTaskConfirmation tests the confirmation prompt when removing a task
+func TestExecuteRemoveTaskConfirmation(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Test Task", "-d", "First line\nSecond line"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+
+	// Test aborting the removal
+	output, err = captureStderr(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeRemove([]string{taskID})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "First line") || strings.Contains(output, "Second line") {
+		t.Errorf("Expected output to show the first description line, got: %s", output)
+	}
+	if !strings.Contains(output, "Task removal aborted") {
+		t.Errorf("Expected output to contain abort message, got: %s", output)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 1 {
+		t.Fatalf("Expected task not to be removed, got %d tasks", len(store.Tasks))
+	}
+
+	// Test TAMO_ASSUME_YES skips the confirmation
+	t.Setenv("TAMO_ASSUME_YES", "1")
+	output, err = captureOutput(func() error {
+		return cli.executeRemove([]string{taskID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Test Task' removed") {
+		t.Errorf("Expected output to contain removal message, got: %s", output)
+	}
+}
+
+// TestExecuteListShowGaps tests the list command with the --show-gaps flag
+func TestExecuteListShowGaps(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks with orders 1, 2, and 10
+	for _, title := range []string{"Task 1", "Task 2", "Task 3"} {
+		if err := cli.executeAddTask([]string{title}, "add"); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	store.Tasks[2].Order = 10.0
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test separator between Task 2 and Task 3 only
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--show-gaps"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Count(output, "- - -") != 1 {
+		t.Errorf("Expected one separator, got: %s", output)
+	}
+	separator := strings.Index(output, "- - -")
+	if separator < strings.Index(output, "Task 2") || separator > strings.Index(output, "Task 3") {
+		t.Errorf("Expected separator between Task 2 and Task 3, got: %s", output)
+	}
+
+	// Test a larger threshold removes the separator
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"--show-gaps", "--gap-threshold", "10"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "- - -") {
+		t.Errorf("Expected no separator, got: %s", output)
+	}
+}
+
+// TestExecuteTrashAndRestore tests the trash and restore commands
+func TestExecuteTrashAndRestore(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo, a task referencing it, and another task
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Test Memo", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(output[strings.Index(output, "Memo added with ID: ")+len("Memo added with ID: "):])
+
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Removed Task", "-m", memoID}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Referencing Task", "-m", memoID}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	referencingID := strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):])
+
+	// Remove the first task and the memo
+	if _, err := captureOutput(func() error {
+		return cli.executeRemove([]string{taskID, memoID, "-f"})
+	}); err != nil {
+		t.Fatalf("Failed to remove items: %v", err)
+	}
+
+	// Test listing the trash
+	output, err = captureOutput(func() error {
+		return cli.executeTrash([]string{"list"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Removed Task") || !strings.Contains(output, "Test Memo") {
+		t.Errorf("Expected trash to list removed items, got: %s", output)
+	}
+
+	// Test restoring the memo restores references of existing tasks
+	if _, err := captureOutput(func() error {
+		return cli.executeRestore([]string{memoID[:8]})
+	}); err != nil {
+		t.Fatalf("Failed to restore memo: %v", err)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if store.FindMemoByID(memoID) == nil {
+		t.Errorf("Expected memo to be restored with its original ID")
+	}
+	if task := store.FindTaskByID(referencingID); !containsString(task.MemoRefs, memoID) {
+		t.Errorf("Expected memo reference to be restored, got %v", task.MemoRefs)
+	}
+
+	// Test restoring the task puts it at the end
+	if _, err := captureOutput(func() error {
+		return cli.executeRestore([]string{taskID[:8]})
+	}); err != nil {
+		t.Fatalf("Failed to restore task: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	restored := store.FindTaskByID(taskID)
+	if restored == nil || restored.Order != store.GetMaxTaskOrder() || restored.Order <= store.FindTaskByID(referencingID).Order {
+		t.Errorf("Expected task to be restored at the end, got %+v", restored)
+	}
+	if len(store.Trash.Tasks) != 0 || len(store.Trash.Memos) != 0 {
+		t.Errorf("Expected trash to be empty after restoring")
+	}
+
+	// Test emptying the trash
+	if _, err := captureOutput(func() error {
+		return cli.executeRemove([]string{taskID, "-f"})
+	}); err != nil {
+		t.Fatalf("Failed to remove task: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeTrash([]string{"empty", "-f"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "1 items permanently deleted") {
+		t.Errorf("Expected output to report deleted items, got: %s", output)
+	}
+	_, err = captureOutput(func() error {
+		return cli.executeRestore([]string{taskID[:8]})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no task or memo found in the trash") {
+		t.Errorf("Expected error about item not in trash, got: %v", err)
+	}
+}
+
+// TestExecuteEditMultiple tests the edit command with multiple IDs
+func TestExecuteEditMultiple(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two tasks
+	var ids []string
+	for _, title := range []string{"Task 1", "Task 2"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(output[strings.Index(output, "Task added with ID: ")+len("Task added with ID: "):]))
+	}
+
+	// Test applying flags to all tasks
+	output, err := captureOutput(func() error {
+		return cli.executeEdit([]string{ids[0][:8], ids[1][:8], "-d", "Shared Description"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "[2/2]") {
+		t.Errorf("Expected output to show progress, got: %s", output)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	for _, id := range ids {
+		if task := store.FindTaskByID(id); task.Description != "Shared Description" {
+			t.Errorf("Expected description to be applied to %s, got %q", id, task.Description)
+		}
+	}
+
+	// Test that nothing is changed if the changes can't be applied to one of the items
+	_, err = captureOutput(func() error {
+		return cli.executeEdit([]string{ids[0][:8], ids[1][:8], "--title", "Renamed", "--remove-depends-on", ids[0][:8]})
+	})
+	if err == nil || !strings.Contains(err.Error(), "nothing changed") {
+		t.Errorf("Expected error for the changes that can't be applied, got %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	for _, id := range ids {
+		if task := store.FindTaskByID(id); task.Title == "Renamed" {
+			t.Errorf("Expected task %s not to be renamed", id)
+		}
+	}
+
+	// Test skipping the remaining items after an aborted edit
+	editor := writeFakeEditor(t, tempDir, "")
+	t.Setenv("TAMO_EDITOR", "sh "+editor)
+	output, prompts, err := captureOutputAndStderr(func() error {
+		return withStdin(t, "y\n", func() error {
+			return cli.executeEdit([]string{ids[0][:8], ids[1][:8], "--editor"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(prompts, "Edit aborted") || !strings.Contains(prompts, "Skip the remaining 1 items?") {
+		t.Errorf("Expected prompts to ask about skipping, got: %s", prompts)
+	}
+	if !strings.Contains(prompts, "[1/2] "+ids[0][:8]+"\n") || strings.Contains(prompts, "[2/2]") || strings.Contains(output, "[1/2]") {
+		t.Errorf("Expected progress on stderr and the second task to be skipped, got: %s / %s", output, prompts)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if task := store.FindTaskByID(ids[0]); task.Title != "Task 1" {
+		t.Errorf("Expected aborted edit not to change the task, got %q", task.Title)
+	}
+}
+
+func TestExecuteListMemosMultibytePreview(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add memos with long Japanese and emoji content
+	japanese := strings.Repeat("これは重要な情報です。", 5)
+	emoji := strings.Repeat("🎉🚀✨", 10)
+	if err := cli.executeAddMemo([]string{"Japanese", "-c", japanese}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	if err := cli.executeAddMemo([]string{"Emoji", "-c", emoji}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	// Test the previews are valid UTF-8 and fit in 50 columns
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"memos"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !utf8.ValidString(output) {
+		t.Errorf("Expected valid UTF-8 output, got: %q", output)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasSuffix(line, "...") {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimSpace(line), "  ", 4)
+		if len(fields) != 4 {
+			t.Fatalf("Unexpected memo line: %q", line)
+		}
+		if width := utils.DisplayWidth(fields[3]); width > 50 {
+			t.Errorf("Expected preview to fit in 50 columns, got %d: %q", width, fields[3])
+		}
+	}
+	if !strings.Contains(output, "これは重要な情報です。これは") {
+		t.Errorf("Expected Japanese preview in output, got: %s", output)
+	}
+	if !strings.Contains(output, "🎉🚀✨") {
+		t.Errorf("Expected emoji preview in output, got: %s", output)
+	}
+}
+
+func TestExecuteListRefCounts(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two memos
+	var memoIDs []string
+	for _, title := range []string{"Lonely Memo", "Popular Memo"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{title, "-c", "Content"})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+
+	// Add tasks referencing the second memo
+	if err := cli.executeAddTask([]string{"Task With Memo", "-m", memoIDs[1]}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Another Task With Memo", "-m", memoIDs[1]}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Task Without Memo"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test tasks show their memo ref counts
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"tasks"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "[1m]  Task With Memo") {
+		t.Errorf("Expected memo ref count for task, got: %s", output)
+	}
+	if !strings.Contains(output, "[0m]  Task Without Memo") {
+		t.Errorf("Expected zero memo ref count for task, got: %s", output)
+	}
+
+	// Test memos show how many tasks reference them
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"memos"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "[0t]  Lonely Memo") || !strings.Contains(output, "[2t]  Popular Memo") {
+		t.Errorf("Expected task reference counts for memos, got: %s", output)
+	}
+	if strings.Index(output, "Lonely Memo") > strings.Index(output, "Popular Memo") {
+		t.Errorf("Expected memos in creation order by default, got: %s", output)
+	}
+
+	// Test --refs-count puts the most referenced memo first
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"memos", "--refs-count"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Index(output, "Popular Memo") > strings.Index(output, "Lonely Memo") {
+		t.Errorf("Expected most referenced memo first, got: %s", output)
+	}
+}
+
+func TestRenderCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "no code block",
+			text: "Plain text\nMore text",
+			want: "Plain text\nMore text",
+		},
+		{
+			name: "closed code block with language",
+			text: "Run this:\n```go\nfmt.Println(1)\n```\nDone",
+			want: "Run this:\n│ fmt.Println(1)\nDone",
+		},
+		{
+			name: "unclosed code block",
+			text: "Start\n```\nline 1\nline 2",
+			want: "Start\n│ line 1\n│ line 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderCodeBlocks(tt.text); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteOrphanMemos(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a referenced memo and an orphan memo
+	var memoIDs []string
+	for _, title := range []string{"Used Memo", "Orphan Memo"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{title, "-c", "Content"})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+	if err := cli.executeAddTask([]string{"Task", "-m", memoIDs[0]}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test list --orphans shows only the orphan memo
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"memos", "--orphans"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Orphan Memo") || strings.Contains(output, "Used Memo") {
+		t.Errorf("Expected only the orphan memo, got: %s", output)
+	}
+
+	// Test --orphans is rejected for tasks
+	if err := cli.executeList([]string{"tasks", "--orphans"}); err == nil {
+		t.Errorf("Expected error for --orphans with tasks, got nil")
+	}
+
+	// Test gc asks for confirmation
+	_, err = captureOutput(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeGC([]string{"--memos"})
+		})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Memos) != 2 {
+		t.Errorf("Expected 2 memos after declining, got %d", len(store.Memos))
+	}
+
+	// Test gc -f removes the orphan memo into the trash
+	output, err = captureOutput(func() error {
+		return cli.executeGC([]string{"--memos", "-f"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "1 orphan memos removed") {
+		t.Errorf("Expected removal message, got: %s", output)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Memos) != 1 || store.Memos[0].ID != memoIDs[0] {
+		t.Errorf("Expected only the referenced memo to remain, got %v", store.Memos)
+	}
+	if len(store.Trash.Memos) != 1 || store.Trash.Memos[0].ID != memoIDs[1] {
+		t.Errorf("Expected the orphan memo in the trash, got %v", store.Trash.Memos)
+	}
+
+	// Test gc without --memos fails
+	if err := cli.executeGC([]string{}); err == nil {
+		t.Errorf("Expected error without --memos, got nil")
+	}
+}
+
+func TestExecuteListUndoneHiddenCount(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add three tasks and complete two of them
+	var taskIDs []string
+	for _, title := range []string{"Task 1", "Task 2", "Task 3"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+
+	// Test nothing is reported while no task is completed
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--undone"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "hidden") {
+		t.Errorf("Expected no hidden count, got: %s", output)
+	}
+
+	if err := cli.executeDone([]string{taskIDs[0], taskIDs[1]}); err != nil {
+		t.Fatalf("Failed to mark tasks as done: %v", err)
+	}
+
+	// Test the hidden count is shown
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"--undone"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 3") || !strings.HasSuffix(output, "(2 completed tasks hidden)\n") {
+		t.Errorf("Expected hidden count at the end, got: %s", output)
+	}
+
+	// Test --no-hidden-count and the global --quiet suppress the hidden count
+	for _, args := range [][]string{{"list", "--undone", "--no-hidden-count"}, {"--quiet", "list", "--undone"}} {
+		output, err = captureOutput(func() error {
+			return NewCLI().run(args)
+		})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(output, "Task 3") || strings.Contains(output, "hidden") {
+			t.Errorf("Expected no hidden count with %v, got: %s", args, output)
+		}
+	}
+}
+
+// TestQuietMessages tests that --quiet suppresses the messages telling what a command did or didn't do
+func TestQuietMessages(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	for _, title := range []string{"Task", "Other"} {
+		if _, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		}); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+
+	// Test that only the ID of the task added with --auto-suffix is printed
+	output, err := captureOutput(func() error {
+		return NewCLI().run([]string{"--quiet", "add", "task", "Task", "--auto-suffix"})
+	})
+	if err != nil || strings.Contains(output, "Title") || !utils.IsUUID(strings.TrimSpace(output)) {
+		t.Errorf("Expected only the ID with --auto-suffix, got: %q (%v)", output, err)
+	}
+
+	// Test that the other messages are not printed
+	for _, args := range [][]string{
+		{"reindex"},
+		{"archive"},
+		{"renumber"},
+		{"renumber"},
+		{"mv", "%1", "top"},
+		{"mv", "%1", "under", "none"},
+		{"swap", "%1", "%2"},
+	} {
+		output, err := captureOutput(func() error {
+			return NewCLI().run(append([]string{"--quiet"}, args...))
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", args, err)
+		}
+		if output != "" {
+			t.Errorf("Expected no output with --quiet for %v, got: %q", args, output)
+		}
+	}
+}
+
+func TestExecuteWithDataDir(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to a working directory without .tamo
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.Mkdir(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work dir: %v", err)
+	}
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change to work dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	flagDir := filepath.Join(tempDir, "flag-notes")
+	envDir := filepath.Join(tempDir, "env-notes")
+
+	// Test --dir initializes and uses the given directory
+	_, err = captureOutput(func() error {
+		return NewCLI().run([]string{"--dir", flagDir, "init"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(flagDir, storage.DefaultFileName)); err != nil {
+		t.Errorf("Expected data file in --dir directory: %v", err)
+	}
+	if _, err := os.Stat(storage.DefaultDirName); !os.IsNotExist(err) {
+		t.Errorf("Expected no .tamo directory in the working directory")
+	}
+	_, err = captureOutput(func() error {
+		return NewCLI().run([]string{"--dir=" + flagDir, "add", "task", "Flag Task"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test TAMO_DIR is used when --dir is not given
+	t.Setenv("TAMO_DIR", envDir)
+	_, err = captureOutput(func() error {
+		return NewCLI().run([]string{"init"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	_, err = captureOutput(func() error {
+		return NewCLI().run([]string{"add", "task", "Env Task"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	output, err := captureOutput(func() error {
+		return NewCLI().run([]string{"list"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Env Task") || strings.Contains(output, "Flag Task") {
+		t.Errorf("Expected only the TAMO_DIR task, got: %s", output)
+	}
+
+	// Test --dir takes precedence over TAMO_DIR
+	output, err = captureOutput(func() error {
+		return NewCLI().run([]string{"--dir", flagDir, "list"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Flag Task") || strings.Contains(output, "Env Task") {
+		t.Errorf("Expected only the --dir task, got: %s", output)
+	}
+
+	// Test --dir without a path fails
+	if err := NewCLI().run([]string{"--dir"}); err == nil {
+		t.Errorf("Expected error for --dir without a path, got nil")
+	}
+}
+
+func TestExecuteAddTaskArchivedMemoRefs(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add an archived and an active memo sharing an ID prefix
+	archivedID := "abcd0001-0000-0000-0000-000000000000"
+	activeID := "abcd0002-0000-0000-0000-000000000000"
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	archivedTitle := "Old Memo"
+	activeTitle := "Current Memo"
+	store.AddMemo(model.NewMemo(archivedID, &archivedTitle, "Old"))
+	store.AddMemo(model.NewMemo(activeID, &activeTitle, "Current"))
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	if err := cli.executeEdit([]string{archivedID, "--archive"}); err != nil {
+		t.Fatalf("Failed to archive memo: %v", err)
+	}
+
+	// Test the active memo is preferred for a shared prefix
+	if err := cli.executeAddTask([]string{"Shared Prefix Task", "-m", "abcd"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test declining to reference an archived-only match fails
+	err = withStdin(t, "n\n", func() error {
+		return cli.executeAddTask([]string{"Declined Task", "-m", "abcd0001"}, "add")
+	})
+	if err == nil {
+		t.Errorf("Expected error when declining an archived memo, got nil")
+	}
+
+	// Test accepting the warning references the archived memo
+	err = withStdin(t, "y\n", func() error {
+		return cli.executeAddTask([]string{"Accepted Task", "-m", "abcd0001"}, "add")
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Test --include-archived references the archived memo without asking
+	if err := cli.executeAddTask([]string{"Included Task", "-m", "abcd0001", "--include-archived"}, "add"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 3 {
+		t.Fatalf("Expected 3 tasks, got %d", len(store.Tasks))
+	}
+	expected := map[string]string{
+		"Shared Prefix Task": activeID,
+		"Accepted Task":      archivedID,
+		"Included Task":      archivedID,
+	}
+	for _, task := range store.Tasks {
+		if len(task.MemoRefs) != 1 || task.MemoRefs[0] != expected[task.Title] {
+			t.Errorf("Expected task '%s' to reference %s, got %v", task.Title, expected[task.Title], task.MemoRefs)
+		}
+	}
+	if memo := store.FindMemoByID(archivedID); memo == nil || !memo.Archived {
+		t.Errorf("Expected memo to be archived")
+	}
+}
+
+func TestExecuteNextCount(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add four tasks and complete the first one
+	var taskIDs []string
+	for _, title := range []string{"Task 1", "Task 2", "Task 3", "Task 4"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title, "-d", title + " details"}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone([]string{taskIDs[0]}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+
+	// Test --count shows the first undone tasks in order
+	output, err := captureOutput(func() error {
+		return cli.executeNext([]string{"--count", "2"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "1. "+taskIDs[1][:8]) || !strings.Contains(output, "2. "+taskIDs[2][:8]) {
+		t.Errorf("Expected Task 2 and Task 3 in order, got: %s", output)
+	}
+	if strings.Contains(output, "Task 1") || strings.Contains(output, "Task 4") {
+		t.Errorf("Expected only two undone tasks, got: %s", output)
+	}
+	if !strings.Contains(output, "Task 2 details") {
+		t.Errorf("Expected description summary, got: %s", output)
+	}
+
+	// Test fewer candidates than requested shows what there is
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"--count", "10"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Count(output, ". ") != 3 {
+		t.Errorf("Expected 3 tasks, got: %s", output)
+	}
+
+	// Test no --count keeps the detailed view of one task
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task ID: "+taskIDs[1]) || strings.Contains(output, "Task 3") {
+		t.Errorf("Expected details of Task 2 only, got: %s", output)
+	}
+
+	// Test invalid count
+	if err := cli.executeNext([]string{"--count", "0"}); err == nil {
+		t.Errorf("Expected error for --count 0, got nil")
+	}
+	if err := cli.executeNext([]string{"--count", "2", "--done"}); err == nil {
+		t.Errorf("Expected error for --count with --done, got nil")
+	}
+
+	// Test -n lists the first undone tasks compactly, and -n 1 keeps the detailed view
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	store.FindTaskByID(taskIDs[2]).Tags = []string{"ops"}
+	if err := storage.NewStorage().Save(store); err != nil {
+		t.Fatalf("Failed to save store: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"-n", "2"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expected := taskIDs[1][:8] + "  2.0  Task 2\n" + taskIDs[2][:8] + "  3.0  Task 3  #ops\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"-n", "1"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task ID: "+taskIDs[1]) {
+		t.Errorf("Expected details of Task 2, got: %s", output)
+	}
+	if err := cli.executeNext([]string{"-n", "0"}); err == nil {
+		t.Errorf("Expected error for -n 0, got nil")
+	}
+
+	// Test --compact prints the short ID and title only
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"--compact"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if output != taskIDs[1][:8]+"  Task 2\n" {
+		t.Errorf("Expected compact line for Task 2, got %q", output)
+	}
+	if err := cli.executeNext([]string{"--compact", "-n", "3"}); err == nil {
+		t.Errorf("Expected error for --compact with -n 3, got nil")
+	}
+
+	// Test --done takes the next undone task off the queue
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"--done"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Task 2' marked as done") {
+		t.Errorf("Expected Task 2 marked as done, got: %s", output)
+	}
+
+	// Test --rm -f removes the following one
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"--rm", "-f"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Task 3' removed") {
+		t.Errorf("Expected Task 3 removed, got: %s", output)
+	}
+
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	if task := store.FindTaskByID(taskIDs[1]); task == nil || !task.Done {
+		t.Errorf("Expected Task 2 to be done")
+	}
+	if store.FindTaskByID(taskIDs[2]) != nil {
+		t.Errorf("Expected Task 3 to be removed")
+	}
+}
+
+func TestExecutePopShiftUndone(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add three tasks and complete the first and the last
+	var taskIDs []string
+	for _, title := range []string{"Task 1", "Task 2", "Task 3"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone([]string{taskIDs[0], taskIDs[2]}); err != nil {
+		t.Fatalf("Failed to mark tasks as done: %v", err)
+	}
+
+	// Test the default still picks done tasks
+	output, err := captureOutput(func() error {
+		return cli.executeShift([]string{"task"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task ID: "+taskIDs[0]) {
+		t.Errorf("Expected shift to show Task 1, got: %s", output)
+	}
+
+	// Test --undone skips done tasks at both ends
+	output, err = captureOutput(func() error {
+		return cli.executeShift([]string{"task", "--undone"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task ID: "+taskIDs[1]) {
+		t.Errorf("Expected shift --undone to show Task 2, got: %s", output)
+	}
+
+	output, err = captureOutput(func() error {
+		return cli.executePop([]string{"task", "--undone", "--done"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Task 2' marked as done") {
+		t.Errorf("Expected pop --undone to mark Task 2 as done, got: %s", output)
+	}
+
+	// Test no undone tasks left
+	if err := cli.executePop([]string{"task", "--undone"}); err == nil || !strings.Contains(err.Error(), "no undone tasks found") {
+		t.Errorf("Expected no undone tasks error, got: %v", err)
+	}
+}
+
+func TestExecuteExport(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add memos and tasks, completing one task
+	var memoIDs []string
+	for _, title := range []string{"Linked Memo", "Loose Memo"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{title, "-c", title + " content"})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+	var taskIDs []string
+	for _, args := range [][]string{{"Open Task", "-m", memoIDs[0]}, {"Second Open Task"}, {"Finished Task"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone([]string{taskIDs[2]}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+
+	// Test JSON export contains everything and is byte-stable
+	first, err := captureOutput(func() error {
+		return cli.executeExport([]string{"--format", "json"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, err := captureOutput(func() error {
+		return cli.executeExport([]string{})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected identical JSON exports")
+	}
+	var exported model.Store
+	if err := json.Unmarshal([]byte(first), &exported); err != nil {
+		t.Fatalf("Failed to parse JSON export: %v", err)
+	}
+	if len(exported.Tasks) != 3 || len(exported.Memos) != 2 {
+		t.Errorf("Expected 3 tasks and 2 memos, got %d and %d", len(exported.Tasks), len(exported.Memos))
+	}
+
+	// Test scoping the JSON export
+	output, err := captureOutput(func() error {
+		return cli.executeExport([]string{"--tasks", "--done"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exported = model.Store{}
+	if err := json.Unmarshal([]byte(output), &exported); err != nil {
+		t.Fatalf("Failed to parse JSON export: %v", err)
+	}
+	if len(exported.Tasks) != 1 || exported.Tasks[0].ID != taskIDs[2] || len(exported.Memos) != 0 {
+		t.Errorf("Expected only the finished task, got %d tasks and %d memos", len(exported.Tasks), len(exported.Memos))
+	}
+
+	// Test Markdown export renders undone tasks and unreferenced memos
+	output, err = captureOutput(func() error {
+		return cli.executeExport([]string{"--format", "markdown"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "# Open Task") || !strings.Contains(output, "# Second Open Task") {
+		t.Errorf("Expected undone tasks in Markdown export, got: %s", output)
+	}
+	if strings.Contains(output, "Finished Task") {
+		t.Errorf("Expected no completed task in Markdown export, got: %s", output)
+	}
+	if !strings.Contains(output, "Linked Memo content") {
+		t.Errorf("Expected referenced memo content under its task, got: %s", output)
+	}
+	if strings.Count(output, "---\n") != 2 {
+		t.Errorf("Expected sections separated by ---, got: %s", output)
+	}
+	appendix := output[strings.Index(output, "# Unreferenced Memos"):]
+	if !strings.Contains(appendix, "## Loose Memo") || strings.Contains(appendix, "Linked Memo") {
+		t.Errorf("Expected only the unreferenced memo in the appendix, got: %s", appendix)
+	}
+
+	// Test writing to a file
+	outFile := filepath.Join(tempDir, "export.json")
+	if _, err := captureOutput(func() error {
+		return cli.executeExport([]string{"-o", outFile})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("Failed to read export file: %v", err)
+	}
+	if string(data) != first {
+		t.Errorf("Expected export file to match stdout export")
+	}
+
+	// Test invalid format
+	if err := cli.executeExport([]string{"--format", "yaml"}); err == nil {
+		t.Errorf("Expected error for invalid format, got nil")
+	}
+}
+
+func TestHighlightMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		query      string
+		ignoreCase bool
+		terminal   bool
+		want       string
+	}{
+		{
+			name:  "case sensitive",
+			text:  "Go go GO",
+			query: "go",
+			want:  "Go **go** GO",
+		},
+		{
+			name:       "ignore case",
+			text:       "Go go GO",
+			query:      "go",
+			ignoreCase: true,
+			want:       "**Go** **go** **GO**",
+		},
+		{
+			name:       "multibyte text",
+			text:       "日本語のテスト、テストです",
+			query:      "テスト",
+			ignoreCase: true,
+			want:       "日本語の**テスト**、**テスト**です",
+		},
+		{
+			name:       "case folding with different byte lengths",
+			text:       "Kelvin sign \u212A and k",
+			query:      "k",
+			ignoreCase: true,
+			want:       "**K**elvin sign **\u212A** and **k**",
+		},
+		{
+			name:     "terminal",
+			text:     "find me",
+			query:    "me",
+			terminal: true,
+			want:     "find \033[7mme\033[0m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightMatches(tt.text, tt.query, tt.ignoreCase, tt.terminal); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteSearch(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks and memos
+	if err := cli.executeAddTask([]string{"Deploy server", "-d", "First line\nCheck the Server logs"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Write docs"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddMemo([]string{"Notes", "-c", "サーバーの設定\nserver port is 8080"}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	// Test case-sensitive search
+	output, err := captureOutput(func() error {
+		return cli.executeSearch([]string{"Server"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Deploy server") || !strings.Contains(output, "Check the Server logs") {
+		t.Errorf("Expected matching task and description line, got: %s", output)
+	}
+	if strings.Contains(output, "First line") || strings.Contains(output, "Write docs") || strings.Contains(output, "Memos:") {
+		t.Errorf("Expected only matching items and lines, got: %s", output)
+	}
+
+	// Test case-insensitive search with highlighting
+	output, err = captureOutput(func() error {
+		return cli.executeSearch([]string{"SERVER", "-i", "--highlight"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"Deploy **server**", "Check the **Server** logs", "**server** port is 8080"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+
+	// Test multibyte keywords
+	output, err = captureOutput(func() error {
+		return cli.executeSearch([]string{"--highlight", "サーバー"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "**サーバー**の設定") {
+		t.Errorf("Expected highlighted multibyte match, got: %s", output)
+	}
+
+	// Test no matches
+	output, err = captureOutput(func() error {
+		return cli.executeSearch([]string{"nothing"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "No matches found") {
+		t.Errorf("Expected no matches message, got: %s", output)
+	}
+}
+
+func TestExecuteImport(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add an existing task
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Local Task"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	localID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Write a file with a newer copy of the existing task, and a new task referencing a new memo
+	memoTitle := "Imported Memo"
+	memo := model.NewMemo("11111111-0000-0000-0000-000000000000", &memoTitle, "Imported content")
+	newTask := model.NewTask("22222222-0000-0000-0000-000000000000", "Imported Task", "", []string{memo.ID})
+	newTask.Order = 1.0
+	updated := model.NewTask(localID, "Updated Local Task", "", nil)
+	updated.Order = 5.0
+	updated.UpdatedAt = model.CustomTime{Time: time.Now().Add(time.Hour).UTC()}
+	incoming := model.NewStore()
+	incoming.AddMemo(memo)
+	incoming.AddTask(newTask)
+	incoming.AddTask(updated)
+	data, err := json.Marshal(incoming)
+	if err != nil {
+		t.Fatalf("Failed to marshal import data: %v", err)
+	}
+	importFile := filepath.Join(tempDir, "import.json")
+	if err := os.WriteFile(importFile, data, 0644); err != nil {
+		t.Fatalf("Failed to write import file: %v", err)
+	}
+
+	// Test import adds new items and skips existing ones
+	output, err = captureOutput(func() error {
+		return cli.executeImport([]string{importFile})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "1 tasks added, 1 memos added, 1 skipped") {
+		t.Errorf("Expected import summary, got: %s", output)
+	}
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	imported := store.FindTaskByID(newTask.ID)
+	if imported == nil {
+		t.Fatalf("Expected imported task to exist")
+	}
+	if imported.Order != 2.0 {
+		t.Errorf("Expected imported task to be appended with order 2.0, got %.1f", imported.Order)
+	}
+	if len(imported.MemoRefs) != 1 || store.FindMemoByID(imported.MemoRefs[0]) == nil {
+		t.Errorf("Expected imported task to keep its memo reference, got %v", imported.MemoRefs)
+	}
+	if task := store.FindTaskByID(localID); task.Title != "Local Task" {
+		t.Errorf("Expected existing task to be kept, got '%s'", task.Title)
+	}
+
+	// Test --overwrite replaces older items but keeps their order
+	output, err = captureOutput(func() error {
+		return cli.executeImport([]string{importFile, "--overwrite"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "0 tasks added, 0 memos added, 2 skipped") || !strings.Contains(output, "1 existing items overwritten") {
+		t.Errorf("Expected overwrite summary, got: %s", output)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(localID)
+	if task.Title != "Updated Local Task" || task.Order != 1.0 {
+		t.Errorf("Expected overwritten task with its local order, got '%s' with order %.1f", task.Title, task.Order)
+	}
+
+	// Test invalid file
+	if err := cli.executeImport([]string{filepath.Join(tempDir, "missing.json")}); err == nil {
+		t.Errorf("Expected error for missing file, got nil")
+	}
+
+	// Test files with IDs that are not UUIDs, or are used twice, are rejected without importing anything
+	for name, content := range map[string]string{
+		"short ID":      `{"tasks": [{"id": "abc", "title": "Short"}], "memos": []}`,
+		"empty ID":      `{"tasks": [], "memos": [{"id": "", "content": "Empty"}]}`,
+		"duplicate ID":  `{"tasks": [{"id": "33333333-0000-0000-0000-000000000000", "title": "A"}], "memos": [{"id": "33333333-0000-0000-0000-000000000000", "content": "B"}]}`,
+		"existing memo": `{"tasks": [{"id": "11111111-0000-0000-0000-000000000000", "title": "Clash"}], "memos": []}`,
+		"valid and bad": `{"tasks": [{"id": "44444444-0000-0000-0000-000000000000", "title": "Valid"}, {"id": "x", "title": "Bad"}], "memos": []}`,
+	} {
+		if err := os.WriteFile(importFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write import file: %v", err)
+		}
+		if err := cli.executeImport([]string{importFile}); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 2 || len(store.Memos) != 1 {
+		t.Errorf("Expected nothing to be imported from invalid files, got %d tasks and %d memos", len(store.Tasks), len(store.Memos))
+	}
+}
+
+func TestExecuteArchiveCompletedBefore(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks and complete three of them
+	var taskIDs []string
+	for _, title := range []string{"Old Task", "Legacy Task", "Recent Task", "Open Task"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone(taskIDs[:3]); err != nil {
+		t.Fatalf("Failed to mark tasks as done: %v", err)
+	}
+
+	// Backdate the completion of the old task, and the update of a task without a completion time
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	old := model.CustomTime{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	store.FindTaskByID(taskIDs[0]).CompletedAt = &old
+	store.AddMemo(model.NewMemo("memo-archived", nil, "Memo of an archived task"))
+	store.FindTaskByID(taskIDs[0]).MemoRefs = []string{"memo-archived"}
+	legacy := store.FindTaskByID(taskIDs[1])
+	legacy.CompletedAt = nil
+	legacy.UpdatedAt = old
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test declining keeps the tasks
+	_, err = captureOutput(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeArchive([]string{"--completed-before", "2025-01-01"})
+		})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 4 || len(store.ArchivedTasks) != 0 {
+		t.Errorf("Expected nothing archived after declining")
+	}
+
+	// Test only tasks completed before the date are archived
+	output, err := captureOutput(func() error {
+		return withStdin(t, "y\n", func() error {
+			return cli.executeArchive([]string{"--completed-before", "2025-01-01"})
+		})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "2 tasks archived") {
+		t.Errorf("Expected 2 tasks archived, got: %s", output)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 2 || store.FindTaskByID(taskIDs[2]) == nil || store.FindTaskByID(taskIDs[3]) == nil {
+		t.Errorf("Expected the recent and open tasks to remain, got %d tasks", len(store.Tasks))
+	}
+	if len(store.ArchivedTasks) != 2 {
+		t.Errorf("Expected 2 archived tasks, got %d", len(store.ArchivedTasks))
+	}
+
+	// Test the archived tasks are exported
+	output, err = captureOutput(func() error {
+		return cli.executeExport([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, `"archived_tasks"`) || !strings.Contains(output, "Old Task") {
+		t.Errorf("Expected the archived tasks in the export, got: %s", output)
+	}
+
+	// Test gc keeps the memo referenced by the archived task
+	output, err = captureOutput(func() error {
+		return cli.executeGC([]string{"--memos", "-f"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "No orphan memos found") {
+		t.Errorf("Expected the archived task's memo to be kept, got: %s", output)
+	}
+
+	// Test removing the memo removes the archived task's reference, and restoring it brings the reference back
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if refs := store.RemoveMemo("memo-archived"); refs != 1 {
+		t.Errorf("Expected 1 reference removed, got %d", refs)
+	}
+	if len(store.FindArchivedTaskByID(taskIDs[0]).MemoRefs) != 0 {
+		t.Errorf("Expected the archived task's reference to be removed")
+	}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeRestore([]string{"memo-archived"})
+	}); err != nil {
+		t.Errorf("Failed to restore memo: %v", err)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if refs := store.FindArchivedTaskByID(taskIDs[0]).MemoRefs; len(refs) != 1 || refs[0] != "memo-archived" {
+		t.Errorf("Expected the archived task's reference to be restored, got %v", refs)
+	}
+
+	// Test invalid date
+	if err := cli.executeArchive([]string{"--completed-before", "yesterday"}); err == nil {
+		t.Errorf("Expected error for invalid date, got nil")
+	}
+}
+
+func TestExecuteBackup(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test no backups exist yet
+	output, err := captureOutput(func() error {
+		return cli.executeBackup([]string{"list"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "No backups found") {
+		t.Errorf("Expected no backups, got: %s", output)
+	}
+
+	// Add tasks, which backs up the data file before each save
+	if err := cli.executeAddTask([]string{"First Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Second Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	s := storage.NewStorage()
+	backups, err := s.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(backups))
+	}
+
+	// Test list shows the backups
+	output, err = captureOutput(func() error {
+		return cli.executeBackup([]string{"list"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, backups[0]) || !strings.Contains(output, backups[1]) {
+		t.Errorf("Expected both backups in output, got: %s", output)
+	}
+
+	// Test restoring the backup made before the second task was added
+	output, err = captureOutput(func() error {
+		return cli.executeBackup([]string{"restore", backups[1]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Backup "+backups[1]+" restored") {
+		t.Errorf("Expected restore message, got: %s", output)
+	}
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 1 || store.Tasks[0].Title != "First Task" {
+		t.Errorf("Expected only the first task after restore, got %d tasks", len(store.Tasks))
+	}
+
+	// Test --no-backup skips backups
+	before, err := s.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	_, err = captureOutput(func() error {
+		return NewCLI().run([]string{"--no-backup", "add", "task", "Third Task"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	after, err := s.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(after) != len(before) || after[len(after)-1] != before[len(before)-1] {
+		t.Errorf("Expected no new backup with --no-backup")
+	}
+}
+
+func TestExecuteConfigListDefaultTarget(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task and a memo
+	if err := cli.executeAddTask([]string{"Some Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddMemo([]string{"Some Memo", "-c", "Content"}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	// Test list shows tasks by default
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Some Task") || strings.Contains(output, "Some Memo") {
+		t.Errorf("Expected only tasks by default, got: %s", output)
+	}
+
+	// Test setting the default target
+	output, err = captureOutput(func() error {
+		return cli.executeConfig([]string{"set", "list.default_target", "all"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if !strings.Contains(output, "list.default_target = all") {
+		t.Errorf("Expected the new value, got: %s", output)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Some Task") || !strings.Contains(output, "Some Memo") {
+		t.Errorf("Expected tasks and memos with the configured default, got: %s", output)
+	}
+
+	// Test an explicit subcommand takes precedence
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"memos"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "Some Task") {
+		t.Errorf("Expected only memos, got: %s", output)
+	}
+
+	// Test get and invalid values
+	output, err = captureOutput(func() error {
+		return cli.executeConfig([]string{"get", "list.default_target"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(output) != "all" {
+		t.Errorf("Expected 'all', got: %s", output)
+	}
+	if err := cli.executeConfig([]string{"set", "list.default_target", "everything"}); err == nil {
+		t.Errorf("Expected error for invalid value, got nil")
+	}
+
+	// Test unset restores the default
+	if _, err := captureOutput(func() error {
+		return cli.executeConfig([]string{"unset", "list.default_target"})
+	}); err != nil {
+		t.Fatalf("Failed to unset config: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeConfig([]string{"list"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "list.default_target = tasks (default)") {
+		t.Errorf("Expected default value, got: %s", output)
+	}
+}
+
+func TestExecuteDoctor(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test a healthy store
+	output, err := captureOutput(func() error {
+		return cli.executeDoctor([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "No problems found") {
+		t.Errorf("Expected no problems, got: %s", output)
+	}
+
+	// Write a store with a duplicate memo, a missing memo reference, and a repeated reference
+	memoID := "11111111-0000-0000-0000-000000000000"
+	missingID := "99999999-0000-0000-0000-000000000000"
+	title := "Memo"
+	store := model.NewStore()
+	store.AddMemo(model.NewMemo(memoID, &title, "First"))
+	store.AddMemo(model.NewMemo(memoID, &title, "Copy"))
+	task := model.NewTask("22222222-0000-0000-0000-000000000000", "Task", "", nil)
+	task.MemoRefs = []string{memoID, missingID, memoID}
+	task.Order = 1.0
+	store.AddTask(task)
+	s := storage.NewStorage()
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test the problems are reported
+	output, err = captureOutput(func() error {
+		return cli.executeDoctor([]string{})
+	})
+	if err == nil {
+		t.Errorf("Expected error for problems, got nil")
+	}
+	for _, want := range []string{"Duplicate memo ID " + memoID, "references missing memo " + missingID, "references memo " + memoID + " more than once"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+
+	// Test --fix repairs the problems after a backup
+	backupsBefore, err := s.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeDoctor([]string{"--fix"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "3 problems fixed") {
+		t.Errorf("Expected fix summary, got: %s", output)
+	}
+	backupsAfter, err := s.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backupsAfter) != len(backupsBefore)+1 {
+		t.Errorf("Expected one new backup, got %d before and %d after", len(backupsBefore), len(backupsAfter))
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Memos) != 1 || store.Memos[0].Content != "First" {
+		t.Errorf("Expected the first memo to be kept, got %v", store.Memos)
+	}
+	if len(store.Tasks[0].MemoRefs) != 1 || store.Tasks[0].MemoRefs[0] != memoID {
+		t.Errorf("Expected one valid reference, got %v", store.Tasks[0].MemoRefs)
+	}
+
+	// Test IDs that other commands can't handle are reported
+	store.AddTask(model.NewTask("abc", "Short ID", "", nil))
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeDoctor([]string{})
+	})
+	if err == nil || !strings.Contains(output, `Task "abc" (Short ID) has an invalid ID`) {
+		t.Errorf("Expected the invalid ID to be reported, got: %s (%v)", output, err)
+	}
+
+	// Test a truncated file is reported with its position
+	if err := os.WriteFile(s.FilePath, []byte("{\n  \"version\": 1,\n  \"tasks\": ["), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	_, err = s.Load()
+	if err == nil || !strings.Contains(err.Error(), "line 3") || !strings.Contains(err.Error(), "tamo doctor") {
+		t.Errorf("Expected load error with position and doctor hint, got: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeDoctor([]string{})
+	})
+	if err == nil {
+		t.Errorf("Expected error for invalid JSON, got nil")
+	}
+	if !strings.Contains(output, "invalid JSON at line 3, column 13") {
+		t.Errorf("Expected syntax error position, got: %s", output)
+	}
+}
+
+func TestCheckStoreInvalidOrder(t *testing.T) {
+	store := model.NewStore()
+	valid := model.NewTask("11111111-0000-0000-0000-000000000000", "Valid", "", []string{})
+	valid.Order = 3.0
+	invalid := model.NewTask("22222222-0000-0000-0000-000000000000", "Invalid", "", []string{})
+	invalid.Order = math.NaN()
+	store.AddTask(valid)
+	store.AddTask(invalid)
+
+	problems := checkStore(store, true)
+	if len(problems) != 1 || !strings.Contains(problems[0], "invalid order") {
+		t.Errorf("Expected one invalid order problem, got %v", problems)
+	}
+	if invalid.Order != 4.0 {
+		t.Errorf("Expected invalid order to be moved to the end, got %v", invalid.Order)
+	}
+}
+
+func TestExecuteAddTaskTop(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two tasks, then one at the top
+	if err := cli.executeAddTask([]string{"First Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Second Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Urgent Task", "--top"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	urgentID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Test the task comes next
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task ID: "+urgentID) || !strings.Contains(output, "Order: 0.0") {
+		t.Errorf("Expected the urgent task with order 0.0 next, got: %s", output)
+	}
+
+	// Test conflicting placement
+	if err := cli.executeAddTask([]string{"Conflicting Task", "--top"}, "push"); err == nil {
+		t.Errorf("Expected error for push with --top, got nil")
+	}
+	if err := cli.executeAddTask([]string{"--top", "-f", "tasks.md"}, "add"); err == nil {
+		t.Errorf("Expected error for --top with -f, got nil")
+	}
+}
+
+func TestExecuteListShowTags(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a tagged task and an untagged one
+	if err := cli.executeAddTask([]string{"Tagged Task", "--tag", "backend,urgent"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeAddTask([]string{"Untagged Task"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test listing with tags, which are plain when not writing to a terminal
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--show-tags"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got: %s", output)
+	}
+	if !strings.HasSuffix(lines[1], "Tagged Task  [backend] [urgent]") {
+		t.Errorf("Expected tag badges at the end of the tagged task, got: %s", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "Untagged Task") {
+		t.Errorf("Expected no badges for the untagged task, got: %s", lines[2])
+	}
+
+	// Test listing without tags
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "[backend]") {
+		t.Errorf("Expected no tag badges without --show-tags, got: %s", output)
+	}
+
+	// Test that a tag always gets the same color
+	colored := tagBadges([]string{"backend"}, true)
+	if colored != tagBadges([]string{"backend"}, true) || !strings.HasPrefix(colored, "\033[") {
+		t.Errorf("Expected a stable colored badge, got: %q", colored)
+	}
+}
+
+func TestExecuteShowStats(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo of 300 words and a task referencing it with a 100 word description
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Long Memo", "-c", strings.Repeat("word ", 300)})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Long Task", "-d", strings.Repeat("word ", 100), "-m", memoID}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Test stats of the description and the referenced memo
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID, "--stats"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Stats: 400 words, 2 min to read") {
+		t.Errorf("Expected stats line, got: %s", output)
+	}
+
+	// Test that stats are not shown by default
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "Stats:") {
+		t.Errorf("Expected no stats without --stats, got: %s", output)
+	}
+
+	// Test short and Japanese text
+	if got := readingStats("just a few words"); got != "4 words, <1 min to read" {
+		t.Errorf("Expected short text to take <1 min, got: %s", got)
+	}
+	if got := readingStats(strings.Repeat("日本語の文章。", 100)); got != "700 characters, 1 min to read" {
+		t.Errorf("Expected Japanese text to be counted in characters, got: %s", got)
+	}
+}
+
+func TestExecuteRemoveMemoPreview(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo with five lines
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Five Lines", "-c", "line 1\nline 2\nline 3\nline 4\nline 5"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+
+	// Test that the first lines are shown and declining keeps the memo
+	output, err = captureStderr(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeRemove([]string{memoID})
+		})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "      line 3\n") || strings.Contains(output, "line 4") || !strings.Contains(output, "2 more lines") {
+		t.Errorf("Expected a preview of the first 3 lines, got: %s", output)
+	}
+	if !strings.Contains(output, "Memo removal aborted") {
+		t.Errorf("Expected removal to be aborted, got: %s", output)
+	}
+
+	// Test showing the whole content
+	output, err = captureStderr(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeRemove([]string{memoID, "--show-content"})
+		})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "      line 5\n") || strings.Contains(output, "more lines") {
+		t.Errorf("Expected the whole content, got: %s", output)
+	}
+
+	// Test that -f still shows the preview but doesn't ask
+	output, prompts, err := captureOutputAndStderr(func() error {
+		return cli.executeRemove([]string{memoID, "-f"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(prompts, "      line 1\n") || output != "Memo 'Five Lines' removed\n" {
+		t.Errorf("Expected preview on stderr and removal on stdout, got: %q and %q", prompts, output)
+	}
+}
+
+func TestExecuteListPendingFirst(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add three tasks and complete the first
+	var taskIDs []string
+	for _, title := range []string{"Task A", "Task B", "Task C"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone([]string{taskIDs[0]}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+
+	// titles lists the task titles in the order shown by list
+	titles := func(args ...string) string {
+		output, err := captureOutput(func() error {
+			return cli.executeList(args)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var shown []string
+		for _, line := range strings.Split(output, "\n") {
+			if i := strings.Index(line, "Task "); i >= 0 {
+				shown = append(shown, line[i+5:])
+			}
+		}
+		return strings.Join(shown, ",")
+	}
+
+	// Test the default order
+	if got := titles(); got != "A,B,C" {
+		t.Errorf("Expected tasks in order A,B,C, got %s", got)
+	}
+
+	// Test uncompleted tasks first
+	if got := titles("--pending-first"); got != "B,C,A" {
+		t.Errorf("Expected tasks in order B,C,A, got %s", got)
+	}
+
+	// Test the reverse of each order
+	if got := titles("--reverse"); got != "C,B,A" {
+		t.Errorf("Expected tasks in order C,B,A, got %s", got)
+	}
+	if got := titles("--pending-first", "--reverse"); got != "A,C,B" {
+		t.Errorf("Expected tasks in order A,C,B, got %s", got)
+	}
+
+	// Test the setting and overriding it
+	if err := cli.executeConfig([]string{"set", "list.pending_first", "true"}); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if got := titles(); got != "B,C,A" {
+		t.Errorf("Expected tasks in order B,C,A with the setting, got %s", got)
+	}
+	if got := titles("--pending-first=false"); got != "A,B,C" {
+		t.Errorf("Expected tasks in order A,B,C with --pending-first=false, got %s", got)
+	}
+
+	// Test sorting by other keys than the order, ascending and descending
+	if _, err := captureOutput(func() error {
+		return cli.executeMove([]string{taskIDs[2], "top"})
+	}); err != nil {
+		t.Fatalf("Failed to move task: %v", err)
+	}
+	if got := titles("--pending-first=false"); got != "C,A,B" {
+		t.Errorf("Expected tasks in order C,A,B, got %s", got)
+	}
+	if got := titles("--pending-first=false", "--sort", "title"); got != "A,B,C" {
+		t.Errorf("Expected tasks in order A,B,C by title, got %s", got)
+	}
+	if got := titles("--pending-first=false", "--sort", "title", "--reverse"); got != "C,B,A" {
+		t.Errorf("Expected tasks in order C,B,A by title descending, got %s", got)
+	}
+	if got := titles("--sort", "title"); got != "B,C,A" {
+		t.Errorf("Expected uncompleted tasks first, each by title, got %s", got)
+	}
+}
+
+func TestExecuteEditTaskResolvesMemoRefs(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo and a task
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	loadTask := func() *model.Task {
+		store, err := storage.NewStorage().Load()
+		if err != nil {
+			t.Fatalf("Failed to load store: %v", err)
+		}
+		return store.FindTaskByID(taskID)
+	}
+
+	// Test the prompts, which ask again for an unknown memo and expand the prefix
+	output, err = captureStderr(func() error {
+		return withStdin(t, "Prompted Title\n\nbogus\n"+memoID[:8]+"\n", func() error {
+			return cli.executeEdit([]string{taskID})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "memo with ID bogus not found") {
+		t.Errorf("Expected unknown memo error, got: %s", output)
+	}
+	task := loadTask()
+	if task.Title != "Prompted Title" || len(task.MemoRefs) != 1 || task.MemoRefs[0] != memoID {
+		t.Errorf("Expected title and full memo ID to be saved, got %q %v", task.Title, task.MemoRefs)
+	}
+
+	// The editor writes an unknown memo first, then replaces it with a prefix when re-opened
+	contentPath := filepath.Join(tempDir, "editor-content.txt")
+	if err := os.WriteFile(contentPath, []byte("# Editor Title\n\n---TAMO-DESCRIPTION---\nKept description\n---TAMO-MEMO-REFS---\nbogus\n"), 0644); err != nil {
+		t.Fatalf("Failed to write editor content: %v", err)
+	}
+	scriptPath := filepath.Join(tempDir, "fake-editor.sh")
+	script := fmt.Sprintf("#!/bin/sh\nif grep -q '^bogus$' \"$1\"; then\n  sed 's/^bogus$/%s/' \"$1\" > \"$1.tmp\" && mv \"$1.tmp\" \"$1\"\nelse\n  cat '%s' > \"$1\"\nfi\n", memoID[:8], contentPath)
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake editor: %v", err)
+	}
+	t.Setenv("TAMO_EDITOR", "sh "+scriptPath)
+
+	// Test declining to re-open keeps the task unchanged
+	_, err = captureOutput(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeEdit([]string{taskID, "--editor"})
+		})
+	})
+	if err == nil {
+		t.Errorf("Expected error for unknown memo, got nil")
+	}
+	if task := loadTask(); task.Title != "Prompted Title" {
+		t.Errorf("Expected task to be unchanged, got title %q", task.Title)
+	}
+
+	// Test re-opening the edited content
+	_, err = captureOutput(func() error {
+		return withStdin(t, "y\n", func() error {
+			return cli.executeEdit([]string{taskID, "--editor"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	task = loadTask()
+	if task.Title != "Editor Title" || task.Description != "Kept description" {
+		t.Errorf("Expected edits to be kept after re-opening, got %q %q", task.Title, task.Description)
+	}
+	if len(task.MemoRefs) != 1 || task.MemoRefs[0] != memoID {
+		t.Errorf("Expected the prefix to be expanded to %s, got %v", memoID, task.MemoRefs)
+	}
+}
+
+func TestExecuteStatus(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Test before initialization
+	cli := NewCLI()
+	_, err = captureOutput(func() error {
+		return cli.executeStatus([]string{})
+	})
+	if err == nil || !strings.Contains(err.Error(), "not initialized") {
+		t.Errorf("Expected not initialized error, got %v", err)
+	}
+
+	// Initialize tamo
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test an empty store
+	output, err := captureOutput(func() error {
+		return cli.executeStatus([]string{})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{filepath.Join(".tamo", "data.json"), "Tasks:         0 (0 undone)", "Memos:         0", "Last updated:  never"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output, got: %s", want, output)
+		}
+	}
+
+	// Add two tasks, complete one, and add a memo
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task 1"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	if err := cli.executeAddTask([]string{"Task 2"}, "add"); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeDone([]string{taskID}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+	if err := cli.executeAddMemo([]string{"Memo", "-c", "Content"}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	// Test JSON output
+	output, err = captureOutput(func() error {
+		return cli.executeStatus([]string{"--json"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	var status Status
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+	}
+	if !status.Initialized || status.Tasks != 2 || status.UndoneTasks != 1 || status.Memos != 1 || status.LastUpdated == nil {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if !filepath.IsAbs(status.DataFile) {
+		t.Errorf("Expected an absolute data file path, got %s", status.DataFile)
+	}
+}
+
+func TestExecuteAddMemoFromStdinLongLine(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo with a 1MB single line, like minified JSON
+	payload := "{\"data\":\"" + strings.Repeat("x", 1<<20) + "\"}\n"
+	output, err := captureOutput(func() error {
+		return withStdin(t, payload, func() error {
+			return cli.executeAddMemo([]string{"Minified", "--from-stdin"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+
+	// Test that the content round-trips intact
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	memo := store.FindMemoByID(memoID)
+	if memo == nil {
+		t.Fatalf("Memo %s not found", memoID)
+	}
+	if memo.Content != payload {
+		t.Errorf("Expected content of %d bytes to round-trip, got %d bytes", len(payload), len(memo.Content))
+	}
+
+	// Test that a missing trailing newline is added, as before
+	text, err := readText(strings.NewReader("line 1\r\nline 2"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text != "line 1\nline 2\n" {
+		t.Errorf("Expected normalized text, got %q", text)
+	}
+}
+
+func TestExecuteAddTaskRelated(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two existing tasks
+	var ids []string
+	for _, args := range [][]string{{"Design API", "-d", "Draft the endpoints"}, {"Write docs"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+
+	// Test adding a task related to both, in both directions
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Implement API", "-d", "Use Go", "--related", ids[0][:8], "--related", ids[1], "--bidirectional"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	newID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	want := "Use Go\nRelated: " + ids[0][:8] + " Design API\nRelated: " + ids[1][:8] + " Write docs"
+	if got := store.FindTaskByID(newID).Description; got != want {
+		t.Errorf("Expected description %q, got %q", want, got)
+	}
+	if got := store.FindTaskByID(ids[0]).Description; got != "Draft the endpoints\nRelated: "+newID[:8]+" Implement API" {
+		t.Errorf("Expected the new task in the related description, got %q", got)
+	}
+	if got := store.FindTaskByID(ids[1]).Description; got != "Related: "+newID[:8]+" Implement API" {
+		t.Errorf("Expected the new task in the empty related description, got %q", got)
+	}
+
+	// Test that unknown tasks are rejected
+	if err := cli.executeAddTask([]string{"Orphan", "--related", "nonexistent"}, "add"); err == nil {
+		t.Errorf("Expected error for unknown related task, got nil")
+	}
+	if err := cli.executeAddTask([]string{"Alone", "--bidirectional"}, "add"); err == nil {
+		t.Errorf("Expected error for --bidirectional without --related, got nil")
+	}
+}
+
+func TestExecuteShowWithMemos(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two memos and a task referencing them in reverse order
+	var memoIDs []string
+	for _, args := range [][]string{{"First Memo", "-c", "First content\nsecond line"}, {"Second Memo", "-c", "Second content"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo(args)
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task", "-m", memoIDs[1] + "," + memoIDs[0]}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Remove the first memo so that its reference is missing
+	if err := cli.executeRemove([]string{memoIDs[0], "-f"}); err != nil {
+		t.Fatalf("Failed to remove memo: %v", err)
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	store.FindTaskByID(taskID).MemoRefs = append(store.FindTaskByID(taskID).MemoRefs, memoIDs[0])
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save store: %v", err)
+	}
+
+	// Test the memo contents are shown in reference order with the usual layout
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID, "--with-memos"})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	want := "Referenced Memos:\n\n--- " + memoIDs[1][:8] + "  Second Memo ---\nSecond content\n\n--- " + memoIDs[0][:8] + "  <memo not found> ---\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("Expected memo sections %q, got: %s", want, output)
+	}
+	if !strings.Contains(output, "Status: [ ] Not completed") {
+		t.Errorf("Expected the usual task details, got: %s", output)
+	}
+
+	// Test that contents are not shown by default
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "Second content") {
+		t.Errorf("Expected no memo content without --with-memos, got: %s", output)
+	}
+}
+
+func TestExecuteListMemosSortUsage(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add memos A to D, in order of creation
+	memoIDs := make(map[string]string)
+	for _, name := range []string{"A", "B", "C", "D"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{"Memo " + name, "-c", "Content"})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs[name] = strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	}
+
+	// Reference C twice, A and D once, and B never
+	for _, refs := range []string{memoIDs["C"] + "," + memoIDs["A"], memoIDs["C"] + "," + memoIDs["D"]} {
+		if err := cli.executeAddTask([]string{"Task", "-m", refs}, "add"); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+
+	// titles lists the memo names in the order shown by list
+	titles := func(args ...string) string {
+		output, err := captureOutput(func() error {
+			return cli.executeList(append([]string{"memos"}, args...))
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var shown []string
+		for _, line := range strings.Split(output, "\n") {
+			if i := strings.Index(line, "Memo "); i >= 0 {
+				shown = append(shown, line[i+5:i+6])
+			}
+		}
+		return strings.Join(shown, ",")
+	}
+
+	// Test most referenced first, oldest first among equals
+	if got := titles("--sort", "usage"); got != "C,A,D,B" {
+		t.Errorf("Expected memos in order C,A,D,B, got %s", got)
+	}
+
+	// Test least referenced first, the exact reverse of the usage order
+	if got := titles("--sort", "usage", "--reverse"); got != "B,D,A,C" {
+		t.Errorf("Expected memos in order B,D,A,C, got %s", got)
+	}
+
+	// Test --refs-count is the same as --sort usage
+	if got := titles("--refs-count"); got != "C,A,D,B" {
+		t.Errorf("Expected memos in order C,A,D,B with --refs-count, got %s", got)
+	}
+
+	// Test other keys in descending order
+	if got := titles("--sort", "title", "--reverse"); got != "D,C,B,A" {
+		t.Errorf("Expected memos in order D,C,B,A, got %s", got)
+	}
+
+	// Test newest first, and the limit applied after reversing
+	if got := titles("--reverse"); got != "D,C,B,A" {
+		t.Errorf("Expected memos in order D,C,B,A, got %s", got)
+	}
+	if got := titles("--reverse", "--limit", "2"); got != "D,C" {
+		t.Errorf("Expected memos D,C, got %s", got)
+	}
+
+	// Test invalid options
+	if err := cli.executeList([]string{"memos", "--sort", "size"}); err == nil || !strings.Contains(err.Error(), "expected order, created, updated, title, usage") {
+		t.Errorf("Expected error for unknown sort key, got %v", err)
+	}
+	if err := cli.executeList([]string{"tasks", "--sort", "usage"}); err == nil {
+		t.Errorf("Expected error for --sort usage with tasks, got nil")
+	}
+	if err := cli.executeList([]string{"memos", "--refs-count", "--sort", "title"}); err == nil {
+		t.Errorf("Expected error for --refs-count with another sort key, got nil")
+	}
+}
+
+func TestExecuteFlattaskMultiple(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a shared memo and three tasks referencing it, completing the last
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Shared Memo", "-c", "Shared content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	var taskIDs []string
+	for _, title := range []string{"Task A", "Task B", "Task C"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title, "-m", memoID}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone([]string{taskIDs[2]}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+
+	// Test writing every undone task to a file
+	output, err = captureOutput(func() error {
+		return cli.executeFlattask([]string{"--all-undone", "-o", "context.md"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Flattened 2 tasks to context.md") {
+		t.Errorf("Expected summary, got: %s", output)
+	}
+	data, err := os.ReadFile("context.md")
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	doc := string(data)
+	sections := strings.Split(doc, "---\n\n")
+	if len(sections) != 2 || !strings.HasPrefix(sections[0], "# Task A") || !strings.HasPrefix(sections[1], "# Task B") {
+		t.Fatalf("Expected sections for tasks A and B, got: %s", doc)
+	}
+	if strings.Count(doc, "Shared content") != 2 {
+		t.Errorf("Expected the shared memo under each task, got: %s", doc)
+	}
+	if !strings.Contains(sections[0], "(also referenced by "+taskIDs[1][:8]+" Task B)") || !strings.Contains(sections[1], "(also referenced by "+taskIDs[0][:8]+" Task A)") {
+		t.Errorf("Expected notes about the other task, got: %s", doc)
+	}
+
+	// Test a single task, which has no notes
+	output, err = captureOutput(func() error {
+		return cli.executeFlattask([]string{taskIDs[2]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "also referenced by") || !strings.Contains(output, "# Task C") {
+		t.Errorf("Expected task C without notes, got: %s", output)
+	}
+
+	// Test conflicting arguments
+	if err := cli.executeFlattask([]string{taskIDs[0], "--all-undone"}); err == nil {
+		t.Errorf("Expected error for task ID with --all-undone, got nil")
+	}
+}
+
+func TestParseTaskEditTemplate(t *testing.T) {
+	// Test that a description with headings and marker-like text survives a round trip
+	task := model.NewTask("task-id", "Task Title", "# Heading\n\nBody\n\n## Memo References\n- not a ref\n---", []string{"memo-1", "memo-2"})
+	title, description, memoRefs, err := parseTaskEditTemplate(taskEditTemplate(task))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if title != task.Title || description != task.Description {
+		t.Errorf("Expected %q / %q, got %q / %q", task.Title, task.Description, title, description)
+	}
+	if len(memoRefs) != 2 || memoRefs[0] != "memo-1" || memoRefs[1] != "memo-2" {
+		t.Errorf("Expected memo refs to be kept, got %v", memoRefs)
+	}
+
+	// Test an empty description and no memo refs
+	task = model.NewTask("task-id", "Empty", "", nil)
+	_, description, memoRefs, err = parseTaskEditTemplate(taskEditTemplate(task))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if description != "" || len(memoRefs) != 0 {
+		t.Errorf("Expected empty description and refs, got %q %v", description, memoRefs)
+	}
+
+	// Test that a description containing "# Memo References" literally is kept
+	task = model.NewTask("task-id", "Title", "# Memo References\n\nnot-a-ref", []string{"memo-1"})
+	_, description, memoRefs, err = parseTaskEditTemplate(taskEditTemplate(task))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if description != task.Description || len(memoRefs) != 1 || memoRefs[0] != "memo-1" {
+		t.Errorf("Expected %q [memo-1], got %q %v", task.Description, description, memoRefs)
+	}
+
+	// Test that content in the old template format is still accepted
+	title, description, memoRefs, err = parseTaskEditTemplate("# Old Title\n\n# Background\nOld description\n\n# Memo References (one ID per line):\nmemo-1\n\nmemo-2\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if title != "Old Title" || description != "# Background\nOld description" {
+		t.Errorf("Expected old title and description, got %q / %q", title, description)
+	}
+	if len(memoRefs) != 2 || memoRefs[0] != "memo-1" || memoRefs[1] != "memo-2" {
+		t.Errorf("Expected memo refs from the old format, got %v", memoRefs)
+	}
+
+	// Test invalid content
+	for _, content := range []string{
+		"# Title\n\nDescription\n",
+		"# Title\n\nDescription\n---TAMO-MEMO-REFS---\n",
+		"# Title\n\n---TAMO-DESCRIPTION---\nDescription\n",
+		"---TAMO-DESCRIPTION---\nDescription\n---TAMO-MEMO-REFS---\n",
+	} {
+		if _, _, _, err := parseTaskEditTemplate(content); err == nil {
+			t.Errorf("Expected error for %q, got nil", content)
+		}
+	}
+}
+
+func TestExecuteEditEditorKeepsHeadings(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo and a task whose description contains headings
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	description := "# Background\n\nSome context\n\n# Memo References\n\n## Steps\n1. first"
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task", "-d", description, "-m", memoID}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Test that saving the template unchanged keeps the task intact
+	scriptPath := filepath.Join(tempDir, "noop-editor.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\ntouch \"$1\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake editor: %v", err)
+	}
+	t.Setenv("TAMO_EDITOR", "sh "+scriptPath)
+
+	if _, err := captureOutput(func() error {
+		return cli.executeEdit([]string{"--editor", taskID})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(taskID)
+	if task.Title != "Task" || task.Description != description {
+		t.Errorf("Expected task to be unchanged, got %q / %q", task.Title, task.Description)
+	}
+	if len(task.MemoRefs) != 1 || task.MemoRefs[0] != memoID {
+		t.Errorf("Expected memo reference to be kept, got %v", task.MemoRefs)
+	}
+}
+
+func TestExecuteFlattaskInlineMemos(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task from Markdown, which replaces the memo block with a link
+	markdown := "# Inline Task\n\nBefore\n\n```memo\nFirst line\n\nThird line\n```\n\nAfter\n"
+	if err := os.WriteFile("task.md", []byte(markdown), 0644); err != nil {
+		t.Fatalf("Failed to write Markdown file: %v", err)
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"-f", "task.md"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.SplitN(strings.TrimPrefix(output, "Task added with ID: "), "\n", 2)[0])
+
+	// Link a memo that doesn't exist
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	store.FindTaskByID(taskID).Description += "\n\n[memo](deadbeef)"
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test that the memo is expanded in place and not appended again
+	output, err = captureOutput(func() error {
+		return cli.executeFlattask([]string{taskID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Before\n\n> **Memo ") || !strings.Contains(output, "\n>\n> First line\n>\n> Third line\n\nAfter") {
+		t.Errorf("Expected memo to be expanded in place, got: %s", output)
+	}
+	if strings.Contains(output, "## Referenced Memos") {
+		t.Errorf("Expected expanded memo not to be appended, got: %s", output)
+	}
+	if !strings.Contains(output, "> **Warning:** memo deadbeef not found") {
+		t.Errorf("Expected warning for missing memo, got: %s", output)
+	}
+}
+
+func TestExecuteShowDuplicateMemoTitles(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two memos with the same title and one with a unique title
+	var memoIDs []string
+	for _, memo := range [][]string{{"Notes", "Login flow"}, {"Notes", "Payment flow"}, {"Design", "Overview"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{memo[0], "-c", memo[1]})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+
+	// Test that only the duplicated titles get a preview
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task", "-m", strings.Join(memoIDs, ",")}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		memoIDs[0][:8] + "  Notes  Login flow\n",
+		memoIDs[1][:8] + "  Notes  Payment flow\n",
+		memoIDs[2][:8] + "  Design\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, output)
+		}
+	}
+
+	// Test listing only the memos with duplicate titles
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"memos", "--duplicate-titles"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, memoIDs[0][:8]) || !strings.Contains(output, memoIDs[1][:8]) || strings.Contains(output, memoIDs[2][:8]) {
+		t.Errorf("Expected only the memos titled Notes, got: %s", output)
+	}
+
+	if err := cli.executeList([]string{"tasks", "--duplicate-titles"}); err == nil {
+		t.Errorf("Expected error for --duplicate-titles with tasks, got nil")
+	}
+}
+
+func TestExecuteAddTaskH2AsSubtasks(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test creating a parent task with subtasks from stdin
+	output, err := captureOutput(func() error {
+		return withStdin(t, "# Plan\n\n## Design\n\nSketch it\n\n## Build\n", func() error {
+			return cli.executeAddTask([]string{"--from-stdin", "--h2-as-subtasks"}, "add")
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 5 || lines[1] != "Created 2 subtasks:" || !strings.HasSuffix(lines[2], "  Plan") ||
+		!strings.HasPrefix(lines[3], "  ├─ ") || !strings.HasSuffix(lines[3], "  Design") ||
+		!strings.HasPrefix(lines[4], "  └─ ") || !strings.HasSuffix(lines[4], "  Build") {
+		t.Fatalf("Expected a tree of the created tasks, got: %s", output)
+	}
+	parentID := strings.TrimPrefix(lines[0], "Task added with ID: ")
+	childID := strings.Fields(lines[3])[1]
+
+	// Test that show displays the parent
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{childID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Parent: "+parentID[:8]+"  Plan\n") || !strings.Contains(output, "Sketch it") {
+		t.Errorf("Expected the parent and description, got: %s", output)
+	}
+
+	// Test that the flag requires Markdown input
+	if err := cli.executeAddTask([]string{"Task", "--h2-as-subtasks"}, "add"); err == nil {
+		t.Errorf("Expected error for --h2-as-subtasks without -f, got nil")
+	}
+}
+
+func TestInlineTags(t *testing.T) {
+	text := "#urgent Fix the login (#backend) and #緊急\n\n# Heading\n\nSee https://example.com/page#anchor and `#not-a-tag`\n\n```\n#comment in code\n```\n#urgent again"
+	got := inlineTags(text)
+	want := []string{"urgent", "backend", "緊急"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestExecuteListInlineTag(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks tagged inline, explicitly, and in a code block, and a memo tagged inline
+	for _, args := range [][]string{
+		{"Inline Task", "-d", "This is #urgent"},
+		{"Explicit Task", "--tag", "urgent"},
+		{"Code Task", "-d", "```\n#urgent\n```"},
+	} {
+		if _, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		}); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Urgent Memo", "-c", "Needed by #urgent tasks"})
+	}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Other Memo", "-c", "Nothing here"})
+	}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	// Test filtering tasks and memos by the tag
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"all", "--inline-tag", "#urgent", "--show-tags"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"Inline Task  [urgent]", "Explicit Task  [urgent]", "Urgent Memo"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "Code Task") || strings.Contains(output, "Other Memo") {
+		t.Errorf("Expected untagged items to be filtered out, got: %s", output)
+	}
+}
+
+func TestExecuteAddTasks(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test creating tasks from a checklist file
+	if err := os.WriteFile("plan.md", []byte("# Plan\n\n- [ ] First\n- [x] Second\n"), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAdd([]string{"tasks", "-f", "plan.md"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Created 2 tasks:") || !strings.Contains(output, "[ ] First") || !strings.Contains(output, "[x] Second") {
+		t.Errorf("Expected summary of the created tasks, got: %s", output)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if len(store.Tasks) != 2 || store.Tasks[0].Title != "First" || !store.Tasks[1].Done || !store.Tasks[1].HasTag("Plan") {
+		t.Errorf("Expected the checklist items as tasks, got %+v", store.Tasks)
+	}
+
+	// Test input without checklist items and missing input
+	if err := os.WriteFile("empty.md", []byte("# Nothing\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := cli.executeAdd([]string{"tasks", "-f", "empty.md"}); err == nil {
+		t.Errorf("Expected error for a file without checklist items, got nil")
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeAdd([]string{"tasks"})
+	}); err == nil {
+		t.Errorf("Expected error without -f or --from-stdin, got nil")
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	candidates := []idCandidate{
+		{"1a2b3c4d-0000-4000-8000-000000000001", "First"},
+		{"1a2b3c5e-0000-4000-8000-000000000002", "Second"},
+		{"ffffffff-0000-4000-8000-000000000003", "Third"},
+	}
+
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"1a2b3c4x", "\nDid you mean: 1a2b3c4d First"},
+		{"1a2b3cxx", "\nDid you mean one of these?\n  1a2b3c4d  First\n  1a2b3c5e  Second"},
+		{"1A2B3C4X", "\nDid you mean: 1a2b3c4d First"},
+		{"abcdefab", ""},
+		{"1a2", ""},
+	}
+	for _, tt := range tests {
+		if got := didYouMean(tt.id, candidates); got != tt.want {
+			t.Errorf("didYouMean(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteMoveOrder(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task and a task whose ID starts with digits
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := model.NewTask("abcdef01-0000-0000-0000-000000000000", "Task", "", nil)
+	numeric := model.NewTask("3c4d5e6f-0000-0000-0000-000000000000", "Numeric", "", nil)
+	task.Order, numeric.Order = 1, 2
+	store.AddTask(task)
+	store.AddTask(numeric)
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test absolute moves with --order, which never warn
+	for _, args := range [][]string{{"abcd", "--order", "3"}, {"--order=3", "abcd"}} {
+		var output string
+		stderr, err := captureStderr(func() error {
+			output, err = captureOutput(func() error {
+				return cli.executeMove(args)
+			})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", args, err)
+		}
+		if output != "Task 'Task' moved to order 3.0\n" || stderr != "" {
+			t.Errorf("Expected a move without warnings for %v, got %q / %q", args, output, stderr)
+		}
+	}
+
+	// Test that a bare number that is also a task ID prefix moves with a warning
+	stderr, err := captureStderr(func() error {
+		_, err := captureOutput(func() error {
+			return cli.executeMove([]string{"abcd", "3"})
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(stderr, "Warning: 3 is also the ID prefix of task 'Numeric'") {
+		t.Errorf("Expected a warning about the task prefix, got: %q", stderr)
+	}
+
+	// Test that a task prefix given without a keyword suggests before and after
+	_, err = captureStderr(func() error {
+		return cli.executeMove([]string{"abcd", "3c"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "expected before|after <task_id>, top|bottom, or --order <order>") ||
+		!strings.Contains(err.Error(), "use 'before 3c' or 'after 3c'") {
+		t.Errorf("Expected an error suggesting the targets, got %v", err)
+	}
+
+	// Test invalid orders and conflicting targets
+	for _, args := range [][]string{{"abcd", "--order", "x"}, {"abcd", "--order", "inf"}, {"abcd", "NaN"}, {"abcd", "--order"}, {"abcd", "top", "--order", "1"}} {
+		if _, err := captureStderr(func() error {
+			return cli.executeMove(args)
+		}); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestExecuteMoveSuggestsTasks(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Target Task"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Mistype the last character of the ID prefix
+	typo := taskID[:7] + "z"
+	want := "no task found with ID: " + typo + "\nDid you mean: " + taskID[:8] + " Target Task"
+	if err := cli.executeMove([]string{typo, "5"}); err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+	if err := cli.executeDone([]string{typo}); err == nil || !strings.Contains(err.Error(), "Did you mean: "+taskID[:8]) {
+		t.Errorf("Expected suggestion from done, got %v", err)
+	}
+	if err := cli.executeShow([]string{typo}); err == nil || !strings.Contains(err.Error(), "Did you mean: "+taskID[:8]) {
+		t.Errorf("Expected suggestion from show, got %v", err)
+	}
+
+	// Test that unrelated IDs keep the plain error
+	if err := cli.executeMove([]string{"zzzzzzzz", "5"}); err == nil || err.Error() != "no task found with ID: zzzzzzzz" {
+		t.Errorf("Expected plain error, got %v", err)
+	}
+}
+
+func TestExecuteListShowFullID(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task and a memo
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+
+	// Test the default short IDs and the full IDs in each layout
+	for _, args := range [][]string{{"all"}, {"all", "--timeline"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeList(args)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Contains(output, taskID) || strings.Contains(output, memoID) || !strings.Contains(output, taskID[:8]) {
+			t.Errorf("Expected short IDs by default for %v, got: %s", args, output)
+		}
+
+		output, err = captureOutput(func() error {
+			return cli.executeList(append(args, "--show-full-id"))
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(output, taskID) || !strings.Contains(output, memoID) {
+			t.Errorf("Expected full IDs with --show-full-id for %v, got: %s", args, output)
+		}
+	}
+}
+
+func TestExecuteListStale(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks created 10 days ago, 3 days ago, and 10 days ago but completed
+	for _, title := range []string{"Old", "Recent", "Old done"} {
+		if _, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		}); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, task := range store.Tasks {
+		switch task.Title {
+		case "Old":
+			task.CreatedAt = model.CustomTime{Time: now.Add(-10*24*time.Hour - time.Hour)}
+		case "Recent":
+			task.CreatedAt = model.CustomTime{Time: now.Add(-3 * 24 * time.Hour)}
+		case "Old done":
+			task.CreatedAt = model.CustomTime{Time: now.Add(-10*24*time.Hour - time.Hour)}
+			task.Done = true
+		}
+	}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test that only uncompleted tasks at least 7 days old are shown, with their age
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--stale", "7"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Old  (stale: 10 days)") {
+		t.Errorf("Expected the old task with its age, got: %s", output)
+	}
+	if strings.Contains(output, "Recent") || strings.Contains(output, "Old done") {
+		t.Errorf("Expected recent and completed tasks to be hidden, got: %s", output)
+	}
+
+	// Test that a lower threshold includes the recent task
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"--stale", "3"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Recent  (stale: 3 days)") {
+		t.Errorf("Expected the recent task with its age, got: %s", output)
+	}
+
+	// Test invalid thresholds
+	for _, args := range [][]string{{"--stale", "0"}, {"--stale", "-1"}, {"--stale", "7", "--done"}} {
+		if err := cli.executeList(args); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestExecutePick(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a completed task and two uncompleted tasks
+	var ids []string
+	for _, title := range []string{"Finished", "First", "Second"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeDone([]string{ids[0]})
+	}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+
+	// Test that the list shows uncompleted tasks first and the picked task is shown
+	output, prompts, err := captureOutputAndStderr(func() error {
+		return withStdin(t, "2\n", func() error {
+			return cli.executeShow([]string{"--pick"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(prompts, "1)  "+ids[1][:8]) || !strings.Contains(prompts, "3)  "+ids[0][:8]) {
+		t.Errorf("Expected uncompleted tasks listed first, got: %s", prompts)
+	}
+	if !strings.Contains(output, "Title: Second") {
+		t.Errorf("Expected the second task to be shown, got: %s", output)
+	}
+
+	// Test that done marks the picked task
+	if _, err := captureOutput(func() error {
+		return withStdin(t, "1\n", func() error {
+			return cli.executeDone([]string{"--pick"})
+		})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if !store.FindTaskByID(ids[1]).Done {
+		t.Errorf("Expected the picked task to be done")
+	}
+
+	// Test invalid selections and --pick with an ID
+	for _, input := range []string{"4\n", "x\n", "\n"} {
+		if _, err := captureOutput(func() error {
+			return withStdin(t, input, func() error {
+				return cli.executeEdit([]string{"--pick", "--title", "New"})
+			})
+		}); err == nil {
+			t.Errorf("Expected error for selection %q, got nil", input)
+		}
+	}
+	if err := cli.executeRemove([]string{"--pick", ids[2]}); err == nil {
+		t.Errorf("Expected error for --pick with an ID, got nil")
+	}
+
+	// Test that a missing ID is still an error when stdout is not a terminal
+	if _, err := captureOutput(func() error {
+		return cli.executeShow([]string{})
+	}); err == nil || err.Error() != "missing ID" {
+		t.Errorf("Expected missing ID error, got %v", err)
+	}
+}
+
+func TestResolveTaskPosition(t *testing.T) {
+	store := model.NewStore()
+	a := model.NewTask("aaaaaaaa-0000-0000-0000-000000000000", "A", "", nil)
+	b := model.NewTask("bbbbbbbb-0000-0000-0000-000000000000", "B", "", nil)
+	c := model.NewTask("cccccccc-0000-0000-0000-000000000000", "C", "", nil)
+	a.Order, b.Order, c.Order = 3, 1, 2
+	b.Done = true
+	store.Tasks = []*model.Task{a, b, c}
+
+	// Test positions among all tasks and among uncompleted tasks, and IDs, by resolveTask and resolveItem
+	for arg, want := range map[string]*model.Task{"%1": b, "%3": a, "%u1": c, "%u2": a, "bbbb": b} {
+		got, err := resolveTask(store, arg)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", arg, err)
+		}
+		if got != want {
+			t.Errorf("Expected %s for %s, got %s", want.Title, arg, got.Title)
+		}
+		if got, memo, err := resolveItem(store, arg); err != nil || got != want || memo != nil {
+			t.Errorf("Expected item %s for %s, got %v %v (%v)", want.Title, arg, got, memo, err)
+		}
+	}
+
+	// Test positions out of range and invalid positions
+	for _, arg := range []string{"%0", "%4", "%u3", "%", "%x", "%-1"} {
+		if _, err := resolveTask(store, arg); err == nil {
+			t.Errorf("Expected error for %s, got nil", arg)
+		}
+		if _, _, err := resolveItem(store, arg); err == nil {
+			t.Errorf("Expected item error for %s, got nil", arg)
+		}
+	}
+}
+
+// TestTaskPositionsInCommands tests that options taking a task ID accept a position too
+func TestTaskPositionsInCommands(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task, and tasks depending on and related to it by its position
+	var ids []string
+	for _, args := range [][]string{{"First"}, {"Second", "--depends-on", "%1", "--related", "%1"}, {"Third"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task %v: %v", args, err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeEdit([]string{"%3", "--add-depends-on", "%2"})
+	}); err != nil {
+		t.Fatalf("Failed to edit task: %v", err)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	second, third := store.FindTaskByID(ids[1]), store.FindTaskByID(ids[2])
+	if len(second.DependsOn) != 1 || second.DependsOn[0] != ids[0] || !strings.Contains(second.Description, "Related: "+ids[0][:8]+" First") {
+		t.Errorf("Expected the second task to depend on and mention the first, got %v %q", second.DependsOn, second.Description)
+	}
+	if len(third.DependsOn) != 1 || third.DependsOn[0] != ids[1] {
+		t.Errorf("Expected the third task to depend on the second, got %v", third.DependsOn)
+	}
+
+	// Test flattask and unblock with positions
+	output, err := captureOutput(func() error {
+		return cli.executeFlattask([]string{"%2"})
+	})
+	if err != nil || !strings.Contains(output, "Second") {
+		t.Errorf("Expected the second task to be flattened, got: %s (%v)", output, err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeUnblock([]string{"%3", "--by", "%2"})
+	}); err != nil {
+		t.Fatalf("Failed to unblock task: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if deps := store.FindTaskByID(ids[2]).DependsOn; len(deps) != 0 {
+		t.Errorf("Expected the dependency to be removed, got %v", deps)
+	}
+}
+
+func TestExecuteAddMemoAttach(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	if err := os.WriteFile("design.png", []byte("png"), 0644); err != nil {
+		t.Fatalf("Failed to write attachment: %v", err)
+	}
+
+	// Test that missing attachments are recorded with a warning
+	var output string
+	stderr, err := captureStderr(func() error {
+		output, err = captureOutput(func() error {
+			return cli.executeAddMemo([]string{"Design", "-c", "Notes", "--attach", "./design.png", "--attach", "docs/spec.pdf", "--check-attachments"})
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	designPath, specPath := filepath.Join(wd, "design.png"), filepath.Join(wd, "docs", "spec.pdf")
+	if stderr != "Warning: attachment not found: "+specPath+"\n" {
+		t.Errorf("Expected a warning for the missing attachment only, got: %q", stderr)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	memo := store.FindMemoByID(memoID)
+	if len(memo.Attachments) != 2 || memo.Attachments[0] != designPath || memo.Attachments[1] != specPath {
+		t.Errorf("Expected both attachments to be recorded as absolute paths, got %v", memo.Attachments)
+	}
+
+	// Test that show lists the attachments
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{memoID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Attachments:\n  "+designPath+"\n  "+specPath+"\n") {
+		t.Errorf("Expected attachments to be listed, got: %s", output)
+	}
+
+	// Test that attachments aren't checked without --check-attachments
+	stderr, err = captureStderr(func() error {
+		_, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{"Other", "-c", "Notes", "--attach", "missing.txt"})
+		})
+		return err
+	})
+	if err != nil || stderr != "" {
+		t.Errorf("Expected no warning without --check-attachments, got %q (%v)", stderr, err)
+	}
+}
+
+func TestExecuteSearchIndex(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Use the index for any number of items
+	oldMinItems := searchIndexMinItems
+	searchIndexMinItems = 0
+	defer func() { searchIndexMinItems = oldMinItems }()
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Fix the parser"}, "add")
+	}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test building the index
+	output, err := captureOutput(func() error {
+		return cli.executeReindex([]string{})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output != "Indexed 1 tasks and 0 memos\n" {
+		t.Errorf("Expected the indexed counts, got: %s", output)
+	}
+
+	// Test that items added after the index was built are found through it
+	if _, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Notes", "-c", "The parser handles fences"})
+	}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	search := func() string {
+		output, err := captureOutput(func() error {
+			return cli.executeSearch([]string{"parser"})
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return output
+	}
+	if output := search(); !strings.Contains(output, "Fix the parser") || !strings.Contains(output, "The parser handles fences") {
+		t.Errorf("Expected the task and the memo to be found, got: %s", output)
+	}
+
+	// Test that the index is used: an index without the words of the task hides it
+	s := storage.NewStorage()
+	idx, err := s.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	for id, entry := range idx.Entries {
+		if len(entry.Words) == 3 {
+			entry.Words = nil
+			idx.Entries[id] = entry
+		}
+	}
+	if err := s.SaveIndex(idx); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	if output := search(); strings.Contains(output, "Fix the parser") {
+		t.Errorf("Expected the task to be missed through the index, got: %s", output)
+	}
+
+	// Test that a broken index falls back to scanning all items
+	if err := os.WriteFile(s.IndexPath(), []byte("{broken"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if output := search(); !strings.Contains(output, "Fix the parser") {
+		t.Errorf("Expected the task to be found with a broken index, got: %s", output)
+	}
+}
+
+func TestExecuteRenumber(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add tasks with messy orders: A and B share an order, and A was created first
+	var ids []string
+	for _, title := range []string{"A", "B", "C"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, order := range []float64{1.0000305175781, 1.0000305175781, 0.25} {
+		task := store.FindTaskByID(ids[i])
+		task.Order = order
+		task.CreatedAt = model.CustomTime{Time: created.Add(time.Duration(i) * time.Hour)}
+	}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test that list and positions break ties by creation time like renumber
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c, a, b := strings.Index(output, "C"), strings.Index(output, "A"), strings.Index(output, "B"); !(c < a && a < b) {
+		t.Errorf("Expected the list in the order C, A, B, got: %s", output)
+	}
+	if task, err := resolveTask(store, "%2"); err != nil || task.ID != ids[0] {
+		t.Errorf("Expected %%2 to be A, got %v (%v)", task, err)
+	}
+
+	// Test that orders are reassigned, breaking ties by creation time
+	output, err = captureOutput(func() error {
+		return cli.executeRenumber([]string{})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output != "3 tasks renumbered\n" {
+		t.Errorf("Expected 3 tasks renumbered, got: %s", output)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	for i, want := range []float64{2, 3, 1} {
+		if order := store.FindTaskByID(ids[i]).Order; order != want {
+			t.Errorf("Expected order %.1f for task %d, got %v", want, i, order)
+		}
+	}
+
+	// Test that renumbering clean orders changes nothing
+	output, err = captureOutput(func() error {
+		return cli.executeRenumber([]string{})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output != "Task orders are already renumbered\n" {
+		t.Errorf("Expected no changes, got: %s", output)
+	}
+
+	// Test that mv renumbers the tasks when there is no room between the neighbors
+	store.FindTaskByID(ids[1]).Order = math.Nextafter(1.0, 2.0)
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeMove([]string{ids[0], "before", ids[1]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Task orders renumbered to make room for the move") {
+		t.Errorf("Expected the tasks to be renumbered, got: %s", output)
+	}
+	store, err = s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	tasks := append([]*model.Task(nil), store.Tasks...)
+	sortTasksByOrder(tasks)
+	if tasks[0].ID != ids[2] || tasks[1].ID != ids[0] || tasks[2].ID != ids[1] {
+		t.Errorf("Expected the order C, A, B, got %s, %s, %s", tasks[0].Title, tasks[1].Title, tasks[2].Title)
+	}
+}
+
+func TestExecuteShowMemoUpdatedHint(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task referencing two memos, one of which is updated after the task
+	var memoIDs []string
+	for _, title := range []string{"Updated", "Unchanged"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{title, "-c", "Content"})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task", "-m", strings.Join(memoIDs, ",")}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(taskID)
+	store.FindMemoByID(memoIDs[0]).UpdatedAt = model.CustomTime{Time: task.UpdatedAt.Add(time.Hour)}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test that only the updated memo is marked, in both layouts
+	for _, args := range [][]string{{taskID}, {taskID, "--with-memos"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeShow(args)
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Count(output, "(memo updated after task)") != 1 || !strings.Contains(output, "Updated  (memo updated after task)") &&
+			!strings.Contains(output, "Updated ---  (memo updated after task)") {
+			t.Errorf("Expected only the updated memo to be marked for %v, got: %s", args, output)
+		}
+	}
+
+	// Test that --no-hints hides the mark
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID, "--no-hints"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(output, "memo updated after task") {
+		t.Errorf("Expected no hints with --no-hints, got: %s", output)
+	}
+}
+
+func TestExecuteSwap(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add three tasks
+	var ids []string
+	for _, title := range []string{"First", "Second", "Third"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+
+	// Test swapping two tasks
+	output, err := captureOutput(func() error {
+		return cli.executeSwap([]string{ids[0], "%3"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "Tasks swapped:\n  " + ids[2][:8] + "  1.0  Third\n  " + ids[0][:8] + "  3.0  First\n"
+	if output != want {
+		t.Errorf("Expected %q, got %q", want, output)
+	}
+
+	// Test that tasks with the same order get distinct orders, the second task first
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	store.FindTaskByID(ids[1]).Order = 3.0
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeSwap([]string{ids[0], ids[1]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want = "Tasks swapped:\n  " + ids[1][:8] + "  3.0  Second\n  " + ids[0][:8] + "  4.0  First\n"
+	if output != want {
+		t.Errorf("Expected %q, got %q", want, output)
+	}
+
+	// Test invalid arguments
+	for _, args := range [][]string{{ids[0]}, {ids[0], ids[0]}, {ids[0], "zzzz"}, {ids[0], ""}} {
+		if _, err := captureOutput(func() error {
+			return cli.executeSwap(args)
+		}); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestExecuteAddTaskAutoSuffix(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// The first task keeps its title, and the following ones are numbered
+	for _, expected := range []string{"Daily standup", "Daily standup (2)", "Daily standup (3)"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{"Daily standup", "--auto-suffix"}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(output, "Title: "+expected+"\n") {
+			t.Errorf("Expected title %q, got: %s", expected, output)
+		}
+	}
+
+	// Without the flag, the same title is used as is
+	if _, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Daily standup"}, "add")
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Numbering follows the highest number in use, and ignores other titles
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	store.Tasks[0].Title = "Daily standup (7)"
+	store.Tasks[1].Title = "Daily standup (x)"
+	store.Tasks[2].Title = "Daily standup review"
+	if got := suffixedTitle(store, "Daily standup"); got != "Daily standup (8)" {
+		t.Errorf("Expected Daily standup (8), got %q", got)
+	}
+	if got := suffixedTitle(store, "Weekly review"); got != "Weekly review" {
+		t.Errorf("Expected an unused title to be unchanged, got %q", got)
+	}
+}
+
+func TestExecuteListHideDoneExceptRecent(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add an open task, a task completed just now, one completed two days ago,
+	// and one completed before completion times were recorded and updated two days ago
+	for _, title := range []string{"Open", "Done now", "Done before", "Legacy done"} {
+		if _, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		}); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	twoDaysAgo := model.CustomTime{Time: time.Now().UTC().Add(-48 * time.Hour)}
+	for _, task := range store.Tasks {
+		switch task.Title {
+		case "Done now":
+			task.MarkDone()
+		case "Done before":
+			task.MarkDone()
+			task.CompletedAt = &twoDaysAgo
+		case "Legacy done":
+			task.Done = true
+			task.UpdatedAt = twoDaysAgo
+		}
+	}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test that only tasks completed within the window are shown with the open tasks
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--hide-done-except-recent", "24h"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Open") || !strings.Contains(output, "Done now") {
+		t.Errorf("Expected the open and recently completed tasks, got: %s", output)
+	}
+	if strings.Contains(output, "Done before") || strings.Contains(output, "Legacy done") {
+		t.Errorf("Expected older completed tasks to be hidden, got: %s", output)
+	}
+	if !strings.Contains(output, "(2 completed tasks hidden)") {
+		t.Errorf("Expected the hidden count, got: %s", output)
+	}
+
+	// Test that a longer window shows them all
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"--hide-done-except-recent", "72h"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Done before") || !strings.Contains(output, "Legacy done") {
+		t.Errorf("Expected all tasks within 72h, got: %s", output)
+	}
+
+	// Test invalid durations and conflicting flags
+	for _, args := range [][]string{{"--hide-done-except-recent", "1d"}, {"--hide-done-except-recent", "-1h"}, {"--hide-done-except-recent", "24h", "--undone"}} {
+		if err := cli.executeList(args); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestExecuteCount(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Test an uninitialized directory exits with status 2
+	cli := NewCLI()
+	var exitErr *ExitError
+	if err := cli.executeCount([]string{}); !errors.As(err, &exitErr) || exitErr.Code != 2 {
+		t.Errorf("Expected exit status 2 before init, got: %v", err)
+	}
+
+	// Initialize tamo
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test an empty store counts 0
+	output, err := captureOutput(func() error {
+		return cli.executeCount([]string{"--undone"})
+	})
+	if err != nil || output != "0\n" {
+		t.Errorf("Expected 0, got %q (%v)", output, err)
+	}
+
+	// Add tasks and a memo
+	var taskIDs []string
+	for _, args := range [][]string{{"Task 1", "--tag", "ops"}, {"Task 2"}, {"Task 3", "-d", "Check #ops"}} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		taskIDs = append(taskIDs, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	if err := cli.executeDone([]string{taskIDs[0]}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo", "-c", "Memo content"})
+	}); err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+
+	for _, tc := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{}, "3\n"},
+		{[]string{"--done"}, "1\n"},
+		{[]string{"--undone"}, "2\n"},
+		{[]string{"--tag", "ops"}, "2\n"},
+		{[]string{"--undone", "--tag", "ops"}, "1\n"},
+		{[]string{"--memos"}, "1\n"},
+	} {
+		output, err := captureOutput(func() error {
+			return cli.executeCount(tc.args)
+		})
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %v", tc.args, err)
+		}
+		if output != tc.expected {
+			t.Errorf("Expected %q for %v, got %q", tc.expected, tc.args, output)
+		}
+	}
+
+	// Test invalid combinations
+	for _, args := range [][]string{{"--done", "--undone"}, {"--memos", "--done"}, {"--overdue"}, {"tasks"}} {
+		if err := cli.executeCount(args); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestExecuteEditEditorTitleLineDeleted(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task and a memo
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task Title", "-d", "Task description"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Memo Title", "-c", "Memo content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+
+	// Test that deleting the title line of a task is an error that keeps the task
+	editor := writeFakeEditor(t, tempDir, "---TAMO-DESCRIPTION---\nNew description\n---TAMO-MEMO-REFS---\n")
+	t.Setenv("EDITOR", "sh "+editor)
+	output, err = captureStderr(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeEdit([]string{"--editor", taskID})
+		})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no changes saved") {
+		t.Errorf("Expected error keeping the task, got: %v", err)
+	}
+	if !strings.Contains(output, "title line") {
+		t.Errorf("Expected the missing title line to be reported, got: %s", output)
+	}
+
+	// Test that deleting the title line of a memo is an error that keeps the memo
+	editor = writeFakeEditor(t, tempDir, "New content\nwith a second line\n")
+	t.Setenv("EDITOR", "sh "+editor)
+	output, err = captureStderr(func() error {
+		return withStdin(t, "n\n", func() error {
+			return cli.executeEdit([]string{"--editor", memoID})
+		})
+	})
+	if err == nil || !strings.Contains(err.Error(), "no changes saved") {
+		t.Errorf("Expected error keeping the memo, got: %v", err)
+	}
+	if !strings.Contains(output, "title line") {
+		t.Errorf("Expected the missing title line to be reported, got: %s", output)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(taskID)
+	if task.Title != "Task Title" || task.Description != "Task description" {
+		t.Errorf("Expected the task to be unchanged, got %q / %q", task.Title, task.Description)
+	}
+	memo := store.FindMemoByID(memoID)
+	if memo.Title == nil || *memo.Title != "Memo Title" || memo.Content != "Memo content" {
+		t.Errorf("Expected the memo to be unchanged, got %v / %q", memo.Title, memo.Content)
+	}
+
+	// Test that an empty title line still removes the title of a memo
+	editor = writeFakeEditor(t, tempDir, "# \n\nNew content\n")
+	t.Setenv("EDITOR", "sh "+editor)
+	if _, err := captureOutput(func() error {
+		return cli.executeEdit([]string{"--editor", memoID})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if memo := store.FindMemoByID(memoID); memo.Title != nil || memo.Content != "New content" {
+		t.Errorf("Expected no title and the new content, got %v / %q", memo.Title, memo.Content)
+	}
+}
+
+func TestExecuteAppend(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo and a task without a description
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Work log", "-c", "Started"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Test appending to a memo by ID prefix, from the command line and from stdin
+	output, err = captureOutput(func() error {
+		return cli.executeAppend([]string{memoID[:8], "Fixed the build"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Appended to memo 'Work log'") {
+		t.Errorf("Expected append message, got: %s", output)
+	}
+	if _, err := captureOutput(func() error {
+		return withStdin(t, "Deployed\n", func() error {
+			return cli.executeAppend([]string{memoID, "--from-stdin", "--timestamp"})
+		})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Test appending to the empty description of a task
+	if _, err := captureOutput(func() error {
+		return cli.executeAppend([]string{taskID[:8], "First note"})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	lines := strings.Split(store.FindMemoByID(memoID).Content, "\n")
+	if len(lines) != 3 || lines[0] != "Started" || lines[1] != "Fixed the build" {
+		t.Errorf("Expected the lines to be appended, got %q", lines)
+	}
+	if len(lines) == 3 {
+		stamp, text, _ := strings.Cut(lines[2], " Deployed")
+		if _, err := time.Parse("2006-01-02 15:04:05", stamp); err != nil || text != "" {
+			t.Errorf("Expected a timestamped line, got %q", lines[2])
+		}
+	}
+	if description := store.FindTaskByID(taskID).Description; description != "First note" {
+		t.Errorf("Expected the task description to be the note, got %q", description)
+	}
+
+	// Test missing and empty text, and unknown IDs
+	for _, args := range [][]string{{memoID}, {memoID, " "}, {"zzzzzzzz", "text"}} {
+		if _, err := captureOutput(func() error {
+			return cli.executeAppend(args)
+		}); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+
+	// Test a prefix matching several items is ambiguous
+	if err := cli.executeAppend([]string{"", "text"}); !errors.Is(err, errAmbiguousID) {
+		t.Errorf("Expected ambiguous ID error, got: %v", err)
+	}
+}
+
+func TestExecuteListActivity(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two tasks today, one two days ago, and one long ago, completing one of today's
+	for _, title := range []string{"Today 1", "Today 2", "Two days ago", "Long ago"} {
+		if _, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		}); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	s := storage.NewStorage()
+	store, err := s.Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	now := time.Now()
+	store.Tasks[0].MarkDone()
+	store.Tasks[2].CreatedAt = model.CustomTime{Time: now.AddDate(0, 0, -2)}
+	store.Tasks[3].CreatedAt = model.CustomTime{Time: now.AddDate(0, 0, -30)}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// Test that the counts of each day are shown as numbers without a terminal
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"--activity", "4", "--activity-completed"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Created:   0 1 0 2  (3 tasks)") {
+		t.Errorf("Expected created counts, got: %s", output)
+	}
+	if !strings.Contains(output, "Completed: 0 0 0 1  (1 tasks)") {
+		t.Errorf("Expected completed counts, got: %s", output)
+	}
+	if strings.Contains(output, "Tasks:") {
+		t.Errorf("Expected the activity instead of the list, got: %s", output)
+	}
+
+	// Test invalid options
+	for _, args := range [][]string{{"--activity", "0"}, {"--activity-completed"}, {"memos", "--activity", "7"}} {
+		if err := cli.executeList(args); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]int{0, 1, 2, 4, 8}); got != "▁▂▃▅█" {
+		t.Errorf("Expected ▁▂▃▅█, got %s", got)
+	}
+	if got := sparkline([]int{0, 0, 0}); got != "▁▁▁" {
+		t.Errorf("Expected the lowest blocks without data, got %s", got)
+	}
+}
+
+func TestExecuteAttachDetach(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add three memos and a task referencing the first
+	var memoIDs []string
+	for _, title := range []string{"Memo 1", "Memo 2", "Memo 3"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddMemo([]string{title, "-c", title + " content"})
+		})
+		if err != nil {
+			t.Fatalf("Failed to add memo: %v", err)
+		}
+		memoIDs = append(memoIDs, strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: ")))
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Task", "-m", memoIDs[0]}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	loadRefs := func() []string {
+		store, err := storage.NewStorage().Load()
+		if err != nil {
+			t.Fatalf("Failed to load data: %v", err)
+		}
+		return store.FindTaskByID(taskID).MemoRefs
+	}
+
+	// Test attach appends by prefix, skipping memos already referenced
+	output, err = captureOutput(func() error {
+		return cli.executeAttach([]string{taskID[:8], memoIDs[2][:8], memoIDs[0][:8], memoIDs[1], memoIDs[2]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refs := loadRefs(); strings.Join(refs, ",") != strings.Join([]string{memoIDs[0], memoIDs[2], memoIDs[1]}, ",") {
+		t.Errorf("Expected memos 1, 3, 2 in order, got %v", refs)
+	}
+	if !strings.Contains(output, "Task 'Task' references 3 memos:") || !strings.Contains(output, memoIDs[1][:8]+"  Memo 2") {
+		t.Errorf("Expected the resulting references, got: %s", output)
+	}
+
+	// Test an unknown memo changes nothing
+	if err := cli.executeAttach([]string{taskID, "zzzzzzzz"}); err == nil {
+		t.Errorf("Expected error for unknown memo, got nil")
+	}
+
+	// Test detach removes by prefix, keeping the order of the others
+	if _, err := captureOutput(func() error {
+		return cli.executeDetach([]string{taskID, memoIDs[2][:8]})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refs := loadRefs(); strings.Join(refs, ",") != memoIDs[0]+","+memoIDs[1] {
+		t.Errorf("Expected memos 1, 2, got %v", refs)
+	}
+	if err := cli.executeDetach([]string{taskID, memoIDs[2]}); err == nil {
+		t.Errorf("Expected error for a memo the task doesn't reference, got nil")
+	}
+
+	// Test detach --all clears the references
+	output, err = captureOutput(func() error {
+		return cli.executeDetach([]string{taskID, "--all"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refs := loadRefs(); len(refs) != 0 {
+		t.Errorf("Expected no references, got %v", refs)
+	}
+	if !strings.Contains(output, "Task 'Task' references no memos") {
+		t.Errorf("Expected no references to be reported, got: %s", output)
+	}
+	if err := cli.executeDetach([]string{taskID, "--all", memoIDs[0]}); err == nil {
+		t.Errorf("Expected error for --all with memo IDs, got nil")
+	}
+}
+
+func TestExecuteRemoveDependency(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a task and another task depending on it
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Design"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	depID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Implement", "--depends-on", depID}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	dependentID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Test that removal is aborted, listing the dependent task
+	output, err = captureStderr(func() error {
+		return cli.executeRemove([]string{depID})
+	})
+	if err == nil {
+		t.Errorf("Expected removal to be aborted")
+	}
+	if !strings.Contains(output, "Task 'Design' is a dependency of 1 tasks") || !strings.Contains(output, dependentID[:8]+"  Implement") {
+		t.Errorf("Expected the dependent task to be listed, got: %s", output)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if store.FindTaskByID(depID) == nil {
+		t.Fatalf("Expected the task to be kept")
+	}
+
+	// Test that forcing removes the task and the dependency on it
+	if _, err := captureOutput(func() error {
+		return cli.executeRemove([]string{depID, "--force"})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if store.FindTaskByID(depID) != nil {
+		t.Errorf("Expected the task to be removed")
+	}
+	if deps := store.FindTaskByID(dependentID).DependsOn; len(deps) != 0 {
+		t.Errorf("Expected the dependency to be removed, got %v", deps)
+	}
+}
+
+func TestExecuteCopy(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo, a completed task referencing it, and another task
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Steps", "-c", "1. Tag the release"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Release checklist", "-d", "Run the steps", "-m", memoID, "--tag", "release"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	if _, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Other"}, "add")
+	}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	if err := cli.executeDone([]string{taskID}); err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+
+	copyID := func(output string) string {
+		line, _, _ := strings.Cut(strings.TrimPrefix(output, "Task copied with ID: "), "\n")
+		return line
+	}
+
+	// Test a shallow copy shares the memo and goes to the end
+	output, err = captureOutput(func() error {
+		return cli.executeCopy([]string{taskID[:8]})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	shallowID := copyID(output)
+
+	// Test a deep copy with a new title copies the memo
+	output, err = captureOutput(func() error {
+		return cli.executeCopy([]string{taskID, "v2.3 release", "--deep"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Copied 1 memos") {
+		t.Errorf("Expected the number of copied memos, got: %s", output)
+	}
+	deepID := copyID(output)
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	source := store.FindTaskByID(taskID)
+	shallow := store.FindTaskByID(shallowID)
+	if shallow == nil {
+		t.Fatalf("Expected the copy %q to exist", shallowID)
+	}
+	if shallow.Title != "Release checklist (copy)" || shallow.Description != "Run the steps" || shallow.Done || !shallow.HasTag("release") {
+		t.Errorf("Expected an uncompleted copy, got %+v", shallow)
+	}
+	if len(shallow.MemoRefs) != 1 || shallow.MemoRefs[0] != memoID {
+		t.Errorf("Expected the copy to share the memo, got %v", shallow.MemoRefs)
+	}
+	if shallow.Order <= source.Order || shallow.Order != store.GetMaxTaskOrder()-1 {
+		t.Errorf("Expected the copy at the end, got order %v", shallow.Order)
+	}
+
+	deep := store.FindTaskByID(deepID)
+	if deep == nil {
+		t.Fatalf("Expected the copy %q to exist", deepID)
+	}
+	if deep.Title != "v2.3 release" || len(deep.MemoRefs) != 1 || deep.MemoRefs[0] == memoID {
+		t.Errorf("Expected a titled copy referencing a new memo, got %+v", deep)
+	}
+	if memo := store.FindMemoByID(deep.MemoRefs[0]); memo == nil || memo.Content != "1. Tag the release" || *memo.Title != "Steps" {
+		t.Errorf("Expected a copy of the memo, got %+v", memo)
+	}
+	if len(store.Memos) != 2 || len(store.Tasks) != 4 {
+		t.Errorf("Expected 2 memos and 4 tasks, got %d and %d", len(store.Memos), len(store.Tasks))
+	}
+
+	// Test invalid arguments
+	for _, args := range [][]string{{}, {"zzzzzzzz"}, {taskID, " "}} {
+		if _, err := captureOutput(func() error {
+			return cli.executeCopy(args)
+		}); err == nil {
+			t.Errorf("Expected error for %v, got nil", args)
+		}
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add a memo and a task referencing it
+	output, err := captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Checklist", "-c", "- [ ] Tag"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Release", "-d", "Ship it", "-m", memoID, "--tag", "release"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	// Save a template sharing the memo and one owning a copy
+	for _, args := range [][]string{{"save", taskID[:8], "shared"}, {"save", taskID, "owned", "--own-memos"}} {
+		if _, err := captureOutput(func() error {
+			return cli.executeTemplate(args)
+		}); err != nil {
+			t.Fatalf("Unexpected error for %v: %v", args, err)
+		}
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeTemplate([]string{"save", taskID, "shared"})
+	}); err == nil {
+		t.Errorf("Expected error for an existing template name, got nil")
+	}
+
+	// Test templates are listed, but not as tasks
+	output, err = captureOutput(func() error {
+		return cli.executeTemplate([]string{"list"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "shared  [1m]  Release") || !strings.Contains(output, "owned   [1m]  Release") {
+		t.Errorf("Expected both templates, got: %s", output)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Count(output, "Release") != 1 {
+		t.Errorf("Expected only the original task in the list, got: %s", output)
+	}
+
+	// Test using the templates
+	taskIDOf := func(output string) string {
+		line, _, _ := strings.Cut(strings.TrimPrefix(output, "Task added with ID: "), "\n")
+		return line
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeTemplate([]string{"use", "shared", "--title", "v2.3 release"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sharedID := taskIDOf(output)
+	output, err = captureOutput(func() error {
+		return cli.executeTemplate([]string{"use", "owned"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Copied 1 memos from the template") {
+		t.Errorf("Expected the owned memo to be copied, got: %s", output)
+	}
+	ownedID := taskIDOf(output)
+
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	shared := store.FindTaskByID(sharedID)
+	if shared == nil || shared.Title != "v2.3 release" || shared.Description != "Ship it" || !shared.HasTag("release") {
+		t.Fatalf("Expected a task from the shared template, got %+v", shared)
+	}
+	if len(shared.MemoRefs) != 1 || shared.MemoRefs[0] != memoID {
+		t.Errorf("Expected the shared memo to be referenced, got %v", shared.MemoRefs)
+	}
+	owned := store.FindTaskByID(ownedID)
+	if owned == nil || owned.Title != "Release" || len(owned.MemoRefs) != 1 || owned.MemoRefs[0] == memoID {
+		t.Fatalf("Expected a task with a copied memo, got %+v", owned)
+	}
+	if memo := store.FindMemoByID(owned.MemoRefs[0]); memo == nil || memo.Content != "- [ ] Tag" {
+		t.Errorf("Expected a copy of the memo, got %+v", memo)
+	}
+	if owned.Order != store.GetMaxTaskOrder() {
+		t.Errorf("Expected the task at the end, got order %v", owned.Order)
+	}
+
+	// Test removing a template keeps the tasks created from it
+	if _, err := captureOutput(func() error {
+		return cli.executeTemplate([]string{"rm", "shared"})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if store.FindTemplate("shared") != nil || store.FindTemplate("owned") == nil {
+		t.Errorf("Expected only the shared template to be removed")
+	}
+	if store.FindTaskByID(sharedID) == nil {
+		t.Errorf("Expected the task created from the template to be kept")
+	}
+	if err := cli.executeTemplate([]string{"use", "shared"}); err == nil {
+		t.Errorf("Expected error for a removed template, got nil")
+	}
+}
+
+func TestExecuteSubtasks(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	addTask := func(args ...string) string {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask(args, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		return strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	}
+
+	// Add a parent, a task between, and a subtask
+	parentID := addTask("Parent")
+	otherID := addTask("Other")
+	childID := addTask("Step 1", "--parent", parentID)
+
+	// Test that the subtask is listed indented beneath its parent
+	output, err := captureOutput(func() error {
+		return cli.executeList([]string{"tasks"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	parentLine := strings.Index(output, parentID[:8])
+	childLine := strings.Index(output, "    "+childID[:8])
+	otherLine := strings.Index(output, otherID[:8])
+	if parentLine < 0 || childLine < parentLine || otherLine < childLine {
+		t.Errorf("Expected the subtask indented beneath its parent, got: %s", output)
+	}
+
+	// Test moving a task under another, and that cycles are rejected
+	if _, err := captureOutput(func() error {
+		return cli.executeMove([]string{otherID, "under", childID})
+	}); err != nil {
+		t.Fatalf("Failed to move task under another: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeMove([]string{parentID, "under", otherID})
+	}); err == nil || !strings.Contains(err.Error(), "own subtask") {
+		t.Errorf("Expected a cycle error, got: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeMove([]string{parentID, "under", parentID})
+	}); err == nil {
+		t.Errorf("Expected an error moving a task under itself")
+	}
+
+	// Test that show lists the subtasks
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{parentID})
+	})
+	if err != nil {
+		t.Fatalf("Failed to show task: %v", err)
+	}
+	if !strings.Contains(output, "Subtasks:\n  "+childID[:8]+"  [ ]  Step 1") {
+		t.Errorf("Expected the subtasks to be listed, got: %s", output)
+	}
+
+	// Test that done warns about undone subtasks
+	stderr, err := captureStderr(func() error {
+		_, err := captureOutput(func() error {
+			return cli.executeDone([]string{parentID})
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+	if !strings.Contains(stderr, "Warning: task 'Parent' has 1 undone subtasks: "+childID[:8]+" Step 1") {
+		t.Errorf("Expected a warning about the undone subtask, got: %s", stderr)
+	}
+
+	// Test that rm makes the subtasks top-level tasks by default
+	if _, err := captureOutput(func() error {
+		return cli.executeRemove([]string{childID, "-f"})
+	}); err != nil {
+		t.Fatalf("Failed to remove task: %v", err)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if other := store.FindTaskByID(otherID); other == nil || other.ParentID != "" {
+		t.Errorf("Expected the subtask to become a top-level task, got %+v", other)
+	}
+
+	// Test that rm --cascade removes the subtasks too
+	childID = addTask("Step 2", "--parent", parentID)
+	if _, err := captureOutput(func() error {
+		return cli.executeRemove([]string{parentID, "-f", "--cascade"})
+	}); err != nil {
+		t.Fatalf("Failed to remove task: %v", err)
+	}
+	store, err = storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if store.FindTaskByID(childID) != nil || store.FindTaskByID(parentID) != nil {
+		t.Errorf("Expected the task and its subtask to be removed")
+	}
+	if store.FindTaskByID(otherID) == nil {
+		t.Errorf("Expected the other task to be kept")
+	}
+}
+
+func TestExecuteBlock(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	var ids []string
+	for _, title := range []string{"Deploy", "Build"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	deployID, buildID := ids[0], ids[1]
+
+	// Test blocking a task, and that blocking each other is rejected
+	output, err := captureOutput(func() error {
+		return cli.executeBlock([]string{deployID, "--by", buildID})
+	})
+	if err != nil {
+		t.Fatalf("Failed to block task: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Deploy' is blocked by 1 tasks:\n  "+buildID[:8]+"  [ ]  Build") {
+		t.Errorf("Expected the blocking task to be listed, got: %s", output)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeBlock([]string{buildID, "--by", deployID})
+	}); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected a cycle error, got: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeBlock([]string{deployID, "--by", deployID})
+	}); err == nil {
+		t.Errorf("Expected an error blocking a task by itself")
+	}
+
+	// Test that list marks the blocked task
+	output, err = captureOutput(func() error {
+		return cli.executeList([]string{"tasks"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to list tasks: %v", err)
+	}
+	if !strings.Contains(output, "Deploy  ⛔") || strings.Contains(output, "Build  ⛔") {
+		t.Errorf("Expected only the blocked task to be marked, got: %s", output)
+	}
+
+	// Test that next and shift --undone skip the blocked task unless --include-blocked is given
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"--compact"})
+	})
+	if err != nil || strings.TrimSpace(output) != buildID[:8]+"  Build" {
+		t.Errorf("Expected next to skip the blocked task, got: %s (%v)", output, err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeNext([]string{"--compact", "--include-blocked"})
+	})
+	if err != nil || strings.TrimSpace(output) != deployID[:8]+"  Deploy" {
+		t.Errorf("Expected next --include-blocked to show the blocked task, got: %s (%v)", output, err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeShift([]string{"task", "--undone"})
+	})
+	if err != nil || !strings.Contains(output, "Title: Build") {
+		t.Errorf("Expected shift --undone to skip the blocked task, got: %s (%v)", output, err)
+	}
+
+	// Test that completing the blocker tells the task is unblocked
+	output, err = captureOutput(func() error {
+		return cli.executeDone([]string{buildID})
+	})
+	if err != nil {
+		t.Fatalf("Failed to mark task as done: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Deploy' is now unblocked") {
+		t.Errorf("Expected the unblocked task to be reported, got: %s", output)
+	}
+
+	// Test unblocking the task
+	output, err = captureOutput(func() error {
+		return cli.executeUnblock([]string{deployID, "--by", buildID[:8]})
+	})
+	if err != nil {
+		t.Fatalf("Failed to unblock task: %v", err)
+	}
+	if !strings.Contains(output, "Task 'Deploy' is not blocked by any task") {
+		t.Errorf("Expected the task to be unblocked, got: %s", output)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeUnblock([]string{deployID, "--by", buildID})
+	}); err == nil {
+		t.Errorf("Expected an error removing a blocker the task doesn't have")
+	}
+}
+
+// TestStdoutPayloadOnly tests that only the requested output goes to stdout, and --quiet prints only IDs
+func TestStdoutPayloadOnly(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Test that --quiet prints only the IDs of added items
+	memoOutput, err := captureOutput(func() error {
+		return cli.run([]string{"--quiet", "add", "memo", "Notes", "-c", "Some notes"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSuffix(memoOutput, "\n")
+	taskOutput, err := captureOutput(func() error {
+		return cli.run([]string{"--quiet", "add", "task", "Write report", "-m", memoID[:8]})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSuffix(taskOutput, "\n")
+	if len(memoID) != 36 || len(taskID) != 36 || strings.Contains(taskID, "\n") {
+		t.Errorf("Expected only the IDs, got: %q and %q", memoOutput, taskOutput)
+	}
+
+	// Test that --quiet suppresses success messages
+	output, err := captureOutput(func() error {
+		return cli.run([]string{"--quiet", "done", taskID[:8]})
+	})
+	if err != nil || output != "" {
+		t.Errorf("Expected no output with --quiet, got: %q (%v)", output, err)
+	}
+	cli = NewCLI()
+
+	// Test that JSON export writes only the JSON document to stdout
+	output, stderr, err := captureOutputAndStderr(func() error {
+		return cli.run([]string{"export"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	var exported map[string]any
+	if err := json.Unmarshal([]byte(output), &exported); err != nil {
+		t.Errorf("Expected stdout to be only JSON, got: %v\n%s", err, output)
+	}
+	if stderr != "" {
+		t.Errorf("Expected nothing on stderr, got: %s", stderr)
+	}
+
+	// Test that flattask writes only the document to stdout
+	output, stderr, err = captureOutputAndStderr(func() error {
+		return cli.run([]string{"flattask", taskID[:8]})
+	})
+	if err != nil {
+		t.Fatalf("Failed to flatten task: %v", err)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	task := store.FindTaskByID(taskID)
+	if expected := flattenTask(store, task, []*model.Task{task}) + "\n"; output != expected {
+		t.Errorf("Expected only the document on stdout, got: %q, want %q", output, expected)
+	}
+	if stderr != "" {
+		t.Errorf("Expected nothing on stderr, got: %s", stderr)
+	}
+
+	// Test that the confirmation of rm goes to stderr and the result to stdout
+	output, stderr, err = captureOutputAndStderr(func() error {
+		return withStdin(t, "y\n", func() error {
+			return cli.run([]string{"rm", taskID[:8]})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Failed to remove task: %v", err)
+	}
+	if output != "Task 'Write report' removed\n" {
+		t.Errorf("Expected only the result on stdout, got: %q", output)
+	}
+	if !strings.Contains(stderr, "The following tasks will be removed:") || !strings.Contains(stderr, "(y/N)") {
+		t.Errorf("Expected the confirmation on stderr, got: %s", stderr)
+	}
+}
+
+// TestExecuteVersion tests the version command and the --version option
+func TestExecuteVersion(t *testing.T) {
+	oldVersion := version.Version
+	version.Version = "v9.9.9"
+	defer func() { version.Version = oldVersion }()
+
+	cli := NewCLI()
+	for _, args := range [][]string{{"version"}, {"--version"}} {
+		output, err := captureOutput(func() error {
+			return cli.run(args)
+		})
+		if err != nil {
+			t.Fatalf("Failed to show the version with %v: %v", args, err)
+		}
+		if !strings.HasPrefix(output, "tamo v9.9.9\n") || !strings.Contains(output, "Commit:") {
+			t.Errorf("Expected the version set with -ldflags, got: %s", output)
+		}
+		if !strings.Contains(output, fmt.Sprintf("Data format: %d\n", model.CurrentVersion)) {
+			t.Errorf("Expected the data format version, got: %s", output)
+		}
+	}
+
+	// Test that arguments are rejected
+	if err := cli.executeVersion([]string{"extra"}); err == nil {
+		t.Errorf("Expected an error for an extra argument")
+	}
+}
+
+// TestConfigSettings tests that the settings of the configuration file are used as defaults that flags override
+func TestConfigSettings(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Test that init creates an empty configuration file
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(".tamo", config.FileName))
+	if err != nil || string(data) != "{}\n" {
+		t.Errorf("Expected an empty configuration file, got: %q (%v)", data, err)
+	}
+
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Write report"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	for _, args := range [][]string{{"set", "date_format", "date"}, {"set", "color", "always"}, {"set", "rm.confirm", "false"}} {
+		if _, err := captureOutput(func() error {
+			return cli.executeConfig(args)
+		}); err != nil {
+			t.Fatalf("Failed to set %s: %v", args[1], err)
+		}
+	}
+
+	// Test the date format
+	output, err = captureOutput(func() error {
+		return NewCLI().run([]string{"--color=never", "show", taskID[:8]})
+	})
+	if err != nil {
+		t.Fatalf("Failed to show task: %v", err)
+	}
+	store, err := storage.NewStorage().Load()
+	if err != nil {
+		t.Fatalf("Failed to load data: %v", err)
+	}
+	if created := store.Tasks[0].CreatedAt.Format("2006-01-02"); !strings.Contains(output, "Created: "+created+"\n") {
+		t.Errorf("Expected the creation date without time, got: %s", output)
+	}
+
+	// Test that the color setting applies unless --color is given
+	output, err = captureOutput(func() error {
+		return NewCLI().run([]string{"list"})
+	})
+	if err != nil || !strings.Contains(output, "\033[") {
+		t.Errorf("Expected colored output from the setting, got: %q (%v)", output, err)
+	}
+	output, err = captureOutput(func() error {
+		return NewCLI().run([]string{"--color=never", "list"})
+	})
+	if err != nil || strings.Contains(output, "\033[") {
+		t.Errorf("Expected --color to override the setting, got: %q (%v)", output, err)
+	}
+
+	// Test that unknown keys warn once without failing, and that rm doesn't ask with rm.confirm set to false
+	content := `{"rm.confirm": "false", "future.key": "x"}`
+	if err := os.WriteFile(filepath.Join(".tamo", config.FileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	output, stderr, err := captureOutputAndStderr(func() error {
+		return NewCLI().run([]string{"rm", taskID[:8]})
+	})
+	if err != nil {
+		t.Fatalf("Failed to remove task: %v", err)
+	}
+	if output != "Task 'Write report' removed\n" || strings.Contains(stderr, "(y/N)") {
+		t.Errorf("Expected removal without confirmation, got: %q and %q", output, stderr)
+	}
+	if strings.Count(stderr, "Warning: unknown config key future.key") != 1 {
+		t.Errorf("Expected one warning about the unknown key, got: %s", stderr)
+	}
+}
+
+func TestCheckStoreDependencies(t *testing.T) {
+	store := model.NewStore()
+	a := model.NewTask("aaaaaaaa-0000-0000-0000-000000000000", "A", "", nil)
+	b := model.NewTask("bbbbbbbb-0000-0000-0000-000000000000", "B", "", nil)
+	c := model.NewTask("cccccccc-0000-0000-0000-000000000000", "C", "", nil)
+	a.DependsOn = []string{b.ID}
+	b.DependsOn = []string{c.ID, "dddddddd-0000-0000-0000-000000000000"}
+	c.DependsOn = []string{a.ID}
+	store.AddTask(a)
+	store.AddTask(b)
+	store.AddTask(c)
+
+	problems := checkStore(store, false)
+	if len(problems) != 2 || !strings.Contains(problems[0], "depends on missing task dddddddd") ||
+		!strings.Contains(problems[1], "Dependency cycle: aaaaaaaa -> bbbbbbbb -> cccccccc -> aaaaaaaa") {
+		t.Fatalf("Expected a missing dependency and a cycle, got %v", problems)
+	}
+
+	// Test that fixing drops the missing dependency and the one closing the cycle
+	checkStore(store, true)
+	if len(b.DependsOn) != 1 || len(c.DependsOn) != 0 || len(a.DependsOn) != 1 {
+		t.Errorf("Expected the dependencies to be repaired, got %v %v %v", a.DependsOn, b.DependsOn, c.DependsOn)
+	}
+	if problems := checkStore(store, false); len(problems) != 0 {
+		t.Errorf("Expected no problems after fixing, got %v", problems)
+	}
+}
+
+func TestCheckStoreIDs(t *testing.T) {
+	store := model.NewStore()
+	memo := model.NewMemo("", nil, "Empty ID")
+	short := model.NewTask("abc", "Short", "", []string{""})
+	valid := model.NewTask("aaaaaaaa-0000-0000-0000-000000000000", "Valid", "", nil)
+	valid.DependsOn = []string{"abc"}
+	valid.ParentID = "abc"
+	archived := model.NewTask("old", "Archived", "", nil)
+	store.AddMemo(memo)
+	store.AddTask(short)
+	store.AddTask(valid)
+	store.ArchivedTasks = append(store.ArchivedTasks, archived)
+
+	problems := checkStore(store, false)
+	if len(problems) != 3 || !strings.Contains(problems[0], `Memo "" (`) || !strings.Contains(problems[1], `Task "abc" (Short) has an invalid ID`) ||
+		!strings.Contains(problems[2], `Archived task "old" (Archived)`) {
+		t.Fatalf("Expected the three invalid IDs, got %v", problems)
+	}
+
+	// Test that fixing gives new IDs and updates the references to them
+	checkStore(store, true)
+	for _, id := range []string{memo.ID, short.ID, archived.ID} {
+		if !utils.IsUUID(id) {
+			t.Errorf("Expected a new UUID, got %q", id)
+		}
+	}
+	if short.MemoRefs[0] != memo.ID || valid.DependsOn[0] != short.ID || valid.ParentID != short.ID {
+		t.Errorf("Expected references to the new IDs, got %v %v %s", short.MemoRefs, valid.DependsOn, valid.ParentID)
+	}
+	if store.FindTaskByID(short.ID) != short || store.FindMemoByID(memo.ID) != memo {
+		t.Errorf("Expected the items to be found by their new IDs")
+	}
+	if problems := checkStore(store, false); len(problems) != 0 {
+		t.Errorf("Expected no problems after fixing, got %v", problems)
+	}
+}
+
+func TestCheckStoreParents(t *testing.T) {
+	store := model.NewStore()
+	a := model.NewTask("aaaaaaaa-0000-0000-0000-000000000000", "A", "", nil)
+	b := model.NewTask("bbbbbbbb-0000-0000-0000-000000000000", "B", "", nil)
+	c := model.NewTask("cccccccc-0000-0000-0000-000000000000", "C", "", nil)
+	d := model.NewTask("dddddddd-0000-0000-0000-000000000000", "D", "", nil)
+	e := model.NewTask("eeeeeeee-0000-0000-0000-000000000000", "E", "", nil)
+	archived := model.NewTask("ffffffff-0000-0000-0000-000000000000", "Archived", "", nil)
+	a.ParentID = b.ID
+	b.ParentID = c.ID
+	c.ParentID = a.ID
+	d.ParentID = "99999999-0000-0000-0000-000000000000"
+	e.ParentID = archived.ID
+	for _, task := range []*model.Task{a, b, c, d, e} {
+		store.AddTask(task)
+	}
+	store.ArchivedTasks = append(store.ArchivedTasks, archived)
+
+	problems := checkStore(store, false)
+	if len(problems) != 2 || !strings.Contains(problems[0], "is a subtask of missing task 99999999") ||
+		!strings.Contains(problems[1], "Parent cycle: aaaaaaaa -> bbbbbbbb -> cccccccc -> aaaaaaaa") {
+		t.Fatalf("Expected a missing parent and a cycle, got %v", problems)
+	}
+
+	// Test that fixing makes the subtasks closing the cycle and under the missing parent top-level tasks
+	checkStore(store, true)
+	if a.ParentID != b.ID || b.ParentID != c.ID || c.ParentID != "" || d.ParentID != "" || e.ParentID != archived.ID {
+		t.Errorf("Expected the parents to be repaired, got %q %q %q %q %q", a.ParentID, b.ParentID, c.ParentID, d.ParentID, e.ParentID)
+	}
+	if problems := checkStore(store, false); len(problems) != 0 {
+		t.Errorf("Expected no problems after fixing, got %v", problems)
+	}
+}
+
+func TestCheckStoreArchivedMemoRefs(t *testing.T) {
+	store := model.NewStore()
+	memo := model.NewMemo("11111111-0000-0000-0000-000000000000", nil, "Memo")
+	missingID := "99999999-0000-0000-0000-000000000000"
+	archived := model.NewTask("aaaaaaaa-0000-0000-0000-000000000000", "Archived", "", []string{missingID, memo.ID})
+	store.AddMemo(memo)
+	store.ArchivedTasks = append(store.ArchivedTasks, archived)
+
+	problems := checkStore(store, false)
+	if len(problems) != 1 || !strings.Contains(problems[0], "Archived task aaaaaaaa-0000-0000-0000-000000000000 (Archived) references missing memo "+missingID) {
+		t.Fatalf("Expected a missing memo reference of the archived task, got %v", problems)
+	}
+
+	// Test that fixing drops the missing reference only
+	checkStore(store, true)
+	if len(archived.MemoRefs) != 1 || archived.MemoRefs[0] != memo.ID {
+		t.Errorf("Expected only the existing memo to be referenced, got %v", archived.MemoRefs)
+	}
+	if problems := checkStore(store, false); len(problems) != 0 {
+		t.Errorf("Expected no problems after fixing, got %v", problems)
+	}
+}
+
+func TestExecuteDoneDependencies(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+
+	// Add two prerequisites and a task depending on both
+	var ids []string
+	for _, title := range []string{"Design", "Review"} {
+		output, err := captureOutput(func() error {
+			return cli.executeAddTask([]string{title}, "add")
+		})
+		if err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: ")))
+	}
+	output, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Build", "--depends-on", ids[0][:8], "--depends-on", ids[1]}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+
+	loadTask := func(id string) *model.Task {
+		store, err := storage.NewStorage().Load()
+		if err != nil {
+			t.Fatalf("Failed to load data: %v", err)
+		}
+		return store.FindTaskByID(id)
+	}
+	if deps := loadTask(taskID).DependsOn; len(deps) != 2 || deps[0] != ids[0] {
+		t.Fatalf("Expected the full IDs of the dependencies, got %v", deps)
+	}
+
+	// Test that --strict refuses to complete the task
+	_, err = captureOutput(func() error {
+		return cli.executeDone([]string{taskID, "--strict"})
+	})
+	if err == nil || !strings.Contains(err.Error(), ids[0][:8]+" Design, "+ids[1][:8]+" Review") {
+		t.Errorf("Expected error listing both dependencies, got %v", err)
+	}
+	if loadTask(taskID).Done {
+		t.Errorf("Expected the task to stay undone with --strict")
+	}
+
+	// Test that completing a dependency together with the task counts it as done
+	_, err = captureStderr(func() error {
+		_, err := captureOutput(func() error {
+			return cli.executeDone([]string{ids[1], taskID, "--strict"})
+		})
+		return err
+	})
+	if err == nil || strings.Contains(err.Error(), "Review") {
+		t.Errorf("Expected error only about the other dependency, got %v", err)
+	}
+
+	// Test that the default warns and completes the task
+	stderr, err := captureStderr(func() error {
+		_, err := captureOutput(func() error {
+			return cli.executeDone([]string{taskID})
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(stderr, "Warning: task 'Build' depends on incomplete tasks: "+ids[0][:8]+" Design, "+ids[1][:8]+" Review") {
+		t.Errorf("Expected warning listing both dependencies, got: %s", stderr)
+	}
+	if !loadTask(taskID).Done {
+		t.Errorf("Expected the task to be done")
+	}
+
+	// Test editing dependencies and showing them
+	if _, err := captureOutput(func() error {
+		return cli.executeEdit([]string{taskID, "--remove-depends-on", ids[1][:8]})
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cli.executeEdit([]string{taskID, "--add-depends-on", taskID}); err == nil {
+		t.Errorf("Expected error for a task depending on itself, got nil")
+	}
+	err = cli.executeEdit([]string{ids[0], "--add-depends-on", taskID})
+	if err == nil || !strings.Contains(err.Error(), "would create a cycle") {
+		t.Errorf("Expected error for a dependency cycle, got %v", err)
+	}
+	if len(loadTask(ids[0]).DependsOn) != 0 {
+		t.Errorf("Expected the cyclic dependency not to be added")
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeShow([]string{taskID})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Depends on:\n  "+ids[0][:8]+"  [ ]  Design\n") {
+		t.Errorf("Expected the remaining dependency, got: %s", output)
+	}
+	if strings.Contains(output, "Review") {
+		t.Errorf("Expected the removed dependency not to be shown, got: %s", output)
+	}
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zishida/tamo/internal/model"
+)
+
+// completeCommand is the hidden command the completion scripts call to list candidates
+const completeCommand = "__complete"
+
+// bashCompletion is the completion script for bash. Only the IDs are completed, as bash can't show descriptions.
+const bashCompletion = `# bash completion for tamo
+_tamo() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "$(tamo __complete commands 2>/dev/null | cut -f1)" -- "$cur"))
+        return
+    fi
+    case "$cur" in
+        -*) return ;;
+    esac
+    COMPREPLY=($(compgen -W "$(tamo __complete ids 2>/dev/null | cut -f1)" -- "$cur"))
+}
+complete -o default -F _tamo tamo
+`
+
+// zshCompletion is the completion script for zsh
+const zshCompletion = `#compdef tamo
+# zsh completion for tamo
+_tamo() {
+    local -a items
+    if (( CURRENT == 2 )); then
+        items=(${(f)"$(tamo __complete commands 2>/dev/null)"})
+        items=(${items/$'\t'/:})
+        _describe 'command' items
+        return
+    fi
+    [[ $PREFIX == -* ]] && return
+    items=(${(f)"$(tamo __complete ids 2>/dev/null)"})
+    items=(${items/$'\t'/:})
+    _describe 'id' items
+}
+compdef _tamo tamo
+`
+
+// fishCompletion is the completion script for fish, which reads "<candidate>\t<description>" lines natively
+const fishCompletion = `# fish completion for tamo
+complete -c tamo -f
+complete -c tamo -n '__fish_use_subcommand' -a '(tamo __complete commands 2>/dev/null)'
+complete -c tamo -n 'not __fish_use_subcommand' -a '(tamo __complete ids 2>/dev/null)'
+`
+
+// executeCompletion handles the 'completion' command
+func (c *CLI) executeCompletion(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: tamo completion bash|zsh|fish\n\n")
+		fmt.Fprintf(os.Stderr, "Print a shell completion script for command names and task and memo IDs\n\n")
+		fmt.Fprintf(os.Stderr, "  bash: source <(tamo completion bash)\n")
+		fmt.Fprintf(os.Stderr, "  zsh:  source <(tamo completion zsh)\n")
+		fmt.Fprintf(os.Stderr, "  fish: tamo completion fish | source\n")
+	}
+
+	if len(args) != 1 {
+		usage()
+		return fmt.Errorf("expected one shell: bash, zsh, or fish")
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	default:
+		usage()
+		return fmt.Errorf("unsupported shell: %s (expected bash, zsh, or fish)", args[0])
+	}
+	return nil
+}
+
+// executeComplete handles the hidden '__complete' command, printing one "<candidate>\t<description>" line
+// per completion candidate. It prints nothing instead of failing, e.g. when no data file exists, so
+// completion never shows errors.
+func (c *CLI) executeComplete(args []string) error {
+	if len(args) < 1 {
+		return nil
+	}
+
+	switch args[0] {
+	case "commands":
+		for _, name := range c.sortedCommandNames() {
+			fmt.Printf("%s\t%s\n", name, completionDescription(c.commands[name].Description))
+		}
+	case "ids":
+		tasks, memos := true, true
+		for _, arg := range args[1:] {
+			switch arg {
+			case "--tasks":
+				memos = false
+			case "--memos":
+				tasks = false
+			}
+		}
+		// Both flags together list both kinds
+		if !tasks && !memos {
+			tasks, memos = true, true
+		}
+
+		s := c.newStorage()
+		if !s.Exists() {
+			return nil
+		}
+		store, err := s.Load()
+		if err != nil {
+			return nil
+		}
+		if tasks {
+			sorted := append([]*model.Task(nil), store.Tasks...)
+			sortTasksByOrder(sorted)
+			for _, task := range sorted {
+				fmt.Printf("%s\t%s\n", task.ID[:8], completionDescription(task.Title))
+			}
+		}
+		if memos {
+			for _, memo := range store.Memos {
+				fmt.Printf("%s\t%s\n", memo.ID[:8], completionDescription(memoTitle(memo)))
+			}
+		}
+	}
+	return nil
+}
+
+// completionDescription puts a description on one line without tabs, as the scripts split candidates on them
+func completionDescription(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
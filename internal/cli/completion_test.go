@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExecuteComplete(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	cli := NewCLI()
+
+	// Test that IDs complete to nothing, without an error, before init
+	output, err := captureOutput(func() error {
+		return cli.run([]string{"__complete", "ids"})
+	})
+	if err != nil || output != "" {
+		t.Errorf("Expected no candidates and no error without data, got: %q (%v)", output, err)
+	}
+
+	// Test that command names come from the registered commands, and the hidden command is not listed
+	output, err = captureOutput(func() error {
+		return cli.run([]string{"__complete", "commands"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to complete commands: %v", err)
+	}
+	if !strings.Contains(output, "list\t"+cli.commands["list"].Description+"\n") || strings.Contains(output, "__complete") {
+		t.Errorf("Expected the registered commands with descriptions, got: %s", output)
+	}
+
+	// Initialize tamo and add a task and a memo
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	output, err = captureOutput(func() error {
+		return cli.executeAddTask([]string{"Write\treport"}, "add")
+	})
+	if err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+	taskID := strings.TrimSpace(strings.TrimPrefix(output, "Task added with ID: "))
+	output, err = captureOutput(func() error {
+		return cli.executeAddMemo([]string{"Notes", "-c", "Content"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add memo: %v", err)
+	}
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "Memo added with ID: "))
+
+	// Test listing the IDs with titles, tasks and memos alike or one kind only
+	output, err = captureOutput(func() error {
+		return cli.run([]string{"__complete", "ids"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to complete IDs: %v", err)
+	}
+	expected := taskID[:8] + "\tWrite report\n" + memoID[:8] + "\tNotes\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+	output, err = captureOutput(func() error {
+		return cli.run([]string{"__complete", "ids", "--memos"})
+	})
+	if err != nil || output != memoID[:8]+"\tNotes\n" {
+		t.Errorf("Expected only the memo, got: %q (%v)", output, err)
+	}
+
+	// Test the scripts
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		output, err := captureOutput(func() error {
+			return cli.executeCompletion([]string{shell})
+		})
+		if err != nil {
+			t.Fatalf("Failed to print the %s script: %v", shell, err)
+		}
+		if !strings.Contains(output, "tamo __complete ids") {
+			t.Errorf("Expected the %s script to call the helper command, got: %s", shell, output)
+		}
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeCompletion([]string{"powershell"})
+	}); err == nil {
+		t.Errorf("Expected an error for an unsupported shell")
+	}
+}
@@ -1,11 +1,12 @@
 package cli
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/zishida/tamo/internal/model"
@@ -13,9 +14,99 @@ import (
 	"github.com/zishida/tamo/internal/utils"
 )
 
+// memoLinkRegex matches the [memo](id) and [memo: title](id) links that replace memo blocks in parsed descriptions
+var memoLinkRegex = regexp.MustCompile(`\[memo(?:: [^\]]*)?\]\(([0-9A-Za-z-]+)\)`)
+
+// fenceRegex matches a code fence line, capturing the backticks and the info string
+var fenceRegex = regexp.MustCompile("^ {0,3}(`{3,})\\s*(.*?)\\s*$")
+
+// fenceLine is the kind of a line in the fenced code blocks followed by fenceTracker
+type fenceLine int
+
+const (
+	outsideFence fenceLine = iota
+	openingFence
+	insideFence
+	closingFence
+)
+
+// fenceTracker follows fenced code blocks line by line. Inside a block, a fence with an info string
+// (like ```go) opens a nested block that the next bare fence closes, so a block can contain fenced code.
+// A block is closed by a bare fence at least as long as the one that opened it, so a block opened
+// with a longer fence (like ````memo) can also contain code fences without info strings.
+type fenceTracker struct {
+	fenceLen int // length of the fence of the open block, 0 outside blocks
+	depth    int // number of nested blocks open inside it
+}
+
+// next classifies the line and updates the state. For an opening fence, it also returns the info string.
+func (f *fenceTracker) next(line string) (fenceLine, string) {
+	match := fenceRegex.FindStringSubmatch(line)
+	if f.fenceLen == 0 {
+		if match == nil {
+			return outsideFence, ""
+		}
+		f.fenceLen, f.depth = len(match[1]), 0
+		return openingFence, match[2]
+	}
+
+	if match != nil {
+		switch {
+		case match[2] != "":
+			f.depth++
+		case f.depth > 0:
+			f.depth--
+		case len(match[1]) >= f.fenceLen:
+			f.fenceLen = 0
+			return closingFence, ""
+		}
+	}
+	return insideFence, ""
+}
+
+// memoBlock is a ```memo block found in Markdown content
+type memoBlock struct {
+	start, end int    // byte offsets of the block, from the opening fence to the end of the closing fence
+	info       string // text after "memo" on the opening fence, a title or ref=<id>
+	body       string
+}
+
+// findMemoBlocks returns the closed ```memo blocks in content, in order.
+// Memo fences inside other code blocks are not memo blocks.
+func findMemoBlocks(content string) []memoBlock {
+	var blocks []memoBlock
+	var tracker fenceTracker
+	var current *memoBlock
+	var body []string
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		text := strings.TrimSuffix(line, "\n")
+		kind, info := tracker.next(text)
+		switch {
+		case kind == openingFence:
+			if fields := strings.Fields(info); len(fields) > 0 && fields[0] == "memo" {
+				current = &memoBlock{start: offset, info: strings.TrimSpace(strings.TrimPrefix(info, "memo"))}
+				body = nil
+			}
+		case kind == insideFence && current != nil:
+			body = append(body, text)
+		case kind == closingFence && current != nil:
+			current.end = offset + len(text)
+			current.body = strings.Join(body, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+		}
+		offset += len(line)
+	}
+	return blocks
+}
+
 // MarkdownParser handles parsing Markdown files to extract tasks and memos
 type MarkdownParser struct {
 	store *model.Store
+
+	// Warnings collects problems found while parsing that don't prevent creating the task
+	Warnings []string
 }
 
 // NewMarkdownParser creates a new MarkdownParser
@@ -27,10 +118,75 @@ func NewMarkdownParser(store *model.Store) *MarkdownParser {
 
 // ParseFromFile parses a Markdown file and extracts task and memos
 func (p *MarkdownParser) ParseFromFile(filePath string) (*model.Task, []*model.Memo, error) {
+	content, filename, err := readMarkdownFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p.parseMarkdown(content, filename)
+}
+
+// ParseFromStdin parses Markdown content from stdin
+func (p *MarkdownParser) ParseFromStdin() (*model.Task, []*model.Memo, error) {
+	// Read from stdin
+	content, err := readText(os.Stdin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading from stdin: %w", err)
+	}
+
+	return p.parseMarkdown(content, "Task from stdin")
+}
+
+// ParseSubtasksFromFile parses a Markdown file into a parent task and a subtask for each H2 section.
+// The parent task is the first of the returned tasks.
+func (p *MarkdownParser) ParseSubtasksFromFile(filePath string) ([]*model.Task, []*model.Memo, error) {
+	content, filename, err := readMarkdownFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p.parseMarkdownSubtasks(content, filename)
+}
+
+// ParseSubtasksFromStdin parses Markdown content from stdin into a parent task and a subtask for each H2 section
+func (p *MarkdownParser) ParseSubtasksFromStdin() ([]*model.Task, []*model.Memo, error) {
+	// Read from stdin
+	content, err := readText(os.Stdin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading from stdin: %w", err)
+	}
+
+	return p.parseMarkdownSubtasks(content, "Task from stdin")
+}
+
+// ParseChecklistFromFile parses the checklist items of a Markdown file into tasks
+func (p *MarkdownParser) ParseChecklistFromFile(filePath string, h1AsPrefix bool) ([]*model.Task, error) {
+	content, _, err := readMarkdownFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseChecklist(content, h1AsPrefix)
+}
+
+// ParseChecklistFromStdin parses the checklist items of Markdown content from stdin into tasks
+func (p *MarkdownParser) ParseChecklistFromStdin(h1AsPrefix bool) ([]*model.Task, error) {
+	// Read from stdin
+	content, err := readText(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("error reading from stdin: %w", err)
+	}
+
+	return p.parseChecklist(content, h1AsPrefix)
+}
+
+// readMarkdownFile reads a Markdown file and returns its content and the file name
+// without the extension, which is the default task title
+func readMarkdownFile(filePath string) (string, string, error) {
 	// Read file content
 	content, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+		return "", "", fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// Get filename for default title
@@ -42,28 +198,83 @@ func (p *MarkdownParser) ParseFromFile(filePath string) (*model.Task, []*model.M
 		filename = filename[:lastDot]
 	}
 
-	return p.parseMarkdown(string(content), filename)
+	return string(content), filename, nil
 }
 
-// ParseFromStdin parses Markdown content from stdin
-func (p *MarkdownParser) ParseFromStdin() (*model.Task, []*model.Memo, error) {
-	// Read from stdin
-	scanner := bufio.NewScanner(os.Stdin)
-	var contentBuilder strings.Builder
-	for scanner.Scan() {
-		contentBuilder.WriteString(scanner.Text())
-		contentBuilder.WriteString("\n")
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, nil, fmt.Errorf("error reading from stdin: %w", err)
+// parseMarkdown parses Markdown content and extracts task and memos
+func (p *MarkdownParser) parseMarkdown(content, defaultTitle string) (*model.Task, []*model.Memo, error) {
+	// Split off the front matter
+	fields, content := parseFrontMatter(content)
+
+	// Extract memo blocks first, so headings inside them are not taken as the task title.
+	// Create memos and replace blocks with references.
+	// Blocks like ```memo ref=<id> reference an existing memo instead of creating one.
+	var memos []*model.Memo
+	var memoRefs []string
+	var unknownRefs []string
+	created := make(map[string]bool)
+	var replaced strings.Builder
+	last := 0
+	for _, block := range findMemoBlocks(content) {
+		replaced.WriteString(content[last:block.start])
+		last = block.end
+
+		if refID, ok := strings.CutPrefix(block.info, "ref="); ok {
+			memo, err := resolveMemo(p.store, refID)
+			if errors.Is(err, errAmbiguousID) {
+				return nil, nil, err
+			}
+			if err != nil {
+				unknownRefs = append(unknownRefs, refID)
+				continue
+			}
+			memoRefs = append(memoRefs, memo.ID)
+			replaced.WriteString(memoLink(memo))
+			continue
+		}
+
+		// Generate UUID for memo
+		memoID, err := utils.GenerateUUID()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate UUID for memo: %w", err)
+		}
+
+		// Create memo, titled by the fence or by a heading on its first line
+		memoTitle, memoContent := parseMemoBlock(block.info, block.body)
+		var titlePtr *string
+		if memoTitle != "" {
+			titlePtr = &memoTitle
+		}
+		memo := model.NewMemo(memoID, titlePtr, memoContent)
+		memos = append(memos, memo)
+		memoRefs = append(memoRefs, memoID)
+		created[memoID] = true
+
+		// Replace memo block with reference
+		replaced.WriteString(memoLink(memo))
 	}
-	content := contentBuilder.String()
+	replaced.WriteString(content[last:])
+	content = replaced.String()
 
-	return p.parseMarkdown(content, "Task from stdin")
-}
+	// Reference the existing memos linked from the content
+	for _, match := range memoLinkRegex.FindAllStringSubmatch(content, -1) {
+		if created[match[1]] {
+			continue
+		}
+		memo, err := resolveMemo(p.store, match[1])
+		if errors.Is(err, errAmbiguousID) {
+			return nil, nil, err
+		}
+		if err != nil {
+			unknownRefs = append(unknownRefs, match[1])
+			continue
+		}
+		memoRefs = append(memoRefs, memo.ID)
+	}
+	if len(unknownRefs) > 0 {
+		return nil, nil, fmt.Errorf("unknown memo references: %s", strings.Join(unknownRefs, ", "))
+	}
 
-// parseMarkdown parses Markdown content and extracts task and memos
-func (p *MarkdownParser) parseMarkdown(content, defaultTitle string) (*model.Task, []*model.Memo, error) {
 	// Extract title (first H1 heading)
 	title := defaultTitle
 	titleRegex := regexp.MustCompile(`(?m)^# (.+)$`)
@@ -74,30 +285,6 @@ func (p *MarkdownParser) parseMarkdown(content, defaultTitle string) (*model.Tas
 		content = titleRegex.ReplaceAllString(content, "")
 	}
 
-	// Extract memo blocks
-	memoRegex := regexp.MustCompile("(?s)```memo\n(.*?)\n```")
-	memoMatches := memoRegex.FindAllStringSubmatch(content, -1)
-
-	// Create memos and replace blocks with references
-	var memos []*model.Memo
-	for _, match := range memoMatches {
-		if len(match) > 1 {
-			// Generate UUID for memo
-			memoID, err := utils.GenerateUUID()
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to generate UUID for memo: %w", err)
-			}
-
-			// Create memo
-			memo := model.NewMemo(memoID, nil, match[1])
-			memos = append(memos, memo)
-
-			// Replace memo block with reference
-			memoRef := fmt.Sprintf("[memo](%s)", memoID)
-			content = strings.Replace(content, match[0], memoRef, 1)
-		}
-	}
-
 	// Clean up the content (remove extra newlines, etc.)
 	content = strings.TrimSpace(content)
 
@@ -107,30 +294,289 @@ func (p *MarkdownParser) parseMarkdown(content, defaultTitle string) (*model.Tas
 		return nil, nil, fmt.Errorf("failed to generate UUID for task: %w", err)
 	}
 
-	// Extract memo references
-	var memoRefs []string
-	for _, memo := range memos {
-		memoRefs = append(memoRefs, memo.ID)
-	}
-
 	// Create task
 	task := model.NewTask(taskID, title, content, memoRefs)
 
 	// Set task order to max + 1.0
 	task.Order = p.store.GetMaxTaskOrder() + 1.0
 
+	// Apply the front matter, which takes precedence over the heading
+	if err := p.applyFrontMatter(task, fields); err != nil {
+		return nil, nil, err
+	}
+
 	return task, memos, nil
 }
 
+// frontMatterField is a key and its value in front matter. List values are joined with commas.
+type frontMatterField struct {
+	key   string
+	value string
+}
+
+// parseFrontMatter splits YAML-style front matter between "---" lines at the start of content
+// from the rest of the content. It understands "key: value" lines, with lists written
+// as "[a, b]" or as "- item" lines under the key. Content without front matter is returned as is.
+func parseFrontMatter(content string) ([]frontMatterField, string) {
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, content
+	}
+
+	var fields []frontMatterField
+	for i, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" || trimmed == "..." {
+			return fields, strings.Join(lines[i+2:], "\n")
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Add a "- item" line to the list of the previous key
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			if last.value != "" {
+				last.value += ","
+			}
+			last.value += unquote(strings.TrimSpace(item))
+			continue
+		}
+
+		// Anything else than "key: value" means the content starts with a horizontal rule instead
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, content
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			var items []string
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				items = append(items, unquote(strings.TrimSpace(item)))
+			}
+			value = strings.Join(items, ",")
+		} else {
+			value = unquote(value)
+		}
+		fields = append(fields, frontMatterField{strings.TrimSpace(key), value})
+	}
+
+	// Without a closing line, the content doesn't start with front matter
+	return nil, content
+}
+
+// unquote removes the quotes around a front matter value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// applyFrontMatter sets the fields of the task given in front matter.
+// Unknown keys are ignored with a warning.
+func (p *MarkdownParser) applyFrontMatter(task *model.Task, fields []frontMatterField) error {
+	for _, field := range fields {
+		switch field.key {
+		case "title":
+			if field.value != "" {
+				task.Title = field.value
+			}
+		case "tags":
+			task.Tags = parseTags([]string{field.value})
+		case "priority":
+			task.Priority = field.value
+		case "done":
+			done, err := strconv.ParseBool(field.value)
+			if err != nil {
+				return fmt.Errorf("invalid value for done in front matter: %s (expected true or false)", field.value)
+			}
+			if done {
+				task.MarkDone()
+			}
+		default:
+			p.Warnings = append(p.Warnings, fmt.Sprintf("unknown front matter key %q ignored", field.key))
+		}
+	}
+	return nil
+}
+
+// parseMarkdownSubtasks splits Markdown content at its H2 headings, outside of code blocks,
+// and parses the part before the first H2 as the parent task and each H2 section as a subtask
+// titled by the heading. Memo blocks are extracted within each section.
+func (p *MarkdownParser) parseMarkdownSubtasks(content, defaultTitle string) ([]*model.Task, []*model.Memo, error) {
+	// Split content into sections
+	var sections []string
+	var current strings.Builder
+	var tracker fenceTracker
+	for _, line := range strings.Split(content, "\n") {
+		if kind, _ := tracker.next(line); kind == outsideFence && strings.HasPrefix(line, "## ") {
+			sections = append(sections, current.String())
+			current.Reset()
+			current.WriteString("# " + strings.TrimSpace(strings.TrimPrefix(line, "## ")) + "\n")
+			continue
+		}
+		current.WriteString(line + "\n")
+	}
+	sections = append(sections, current.String())
+
+	// Parse the parent task
+	parent, memos, err := p.parseMarkdown(sections[0], defaultTitle)
+	if err != nil {
+		return nil, nil, err
+	}
+	tasks := []*model.Task{parent}
+
+	// Parse the subtasks, ordered right after the parent
+	for i, section := range sections[1:] {
+		task, sectionMemos, err := p.parseMarkdown(section, defaultTitle)
+		if err != nil {
+			return nil, nil, err
+		}
+		task.ParentID = parent.ID
+		task.Order = parent.Order + float64(i+1)
+		tasks = append(tasks, task)
+		memos = append(memos, sectionMemos...)
+	}
+
+	return tasks, memos, nil
+}
+
+// memoLink returns the link that replaces a memo block in the description
+func memoLink(memo *model.Memo) string {
+	if memo.Title != nil {
+		return fmt.Sprintf("[memo: %s](%s)", *memo.Title, memo.ID)
+	}
+	return fmt.Sprintf("[memo](%s)", memo.ID)
+}
+
+// checklistItemRegex matches GitHub-style checklist items like "- [ ] title" and "- [x] title"
+var checklistItemRegex = regexp.MustCompile(`^(\s*)[-*+] \[([ xX])\] (.+)$`)
+
+// parseChecklist creates a task for each checklist item, done if the item is checked.
+// The lines indented under an item, including nested items, become its description,
+// and other lines are ignored. The first H1 heading is added to each task as a tag,
+// or as a prefix of the title like "Heading: title" with h1AsPrefix.
+// The tasks are ordered after the existing tasks in the order of the items.
+func (p *MarkdownParser) parseChecklist(content string, h1AsPrefix bool) ([]*model.Task, error) {
+	var heading string
+	var tasks []*model.Task
+	var current *model.Task
+	var indent int
+	var block []string
+
+	// finish sets the description of the current task from its indented block
+	finish := func() {
+		if current != nil {
+			current.Description = dedent(block)
+		}
+		current, block = nil, nil
+	}
+
+	order := p.store.GetMaxTaskOrder()
+	for _, line := range strings.Split(content, "\n") {
+		// Collect the lines indented under the current item
+		lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if current != nil && (strings.TrimSpace(line) == "" || lineIndent > indent) {
+			block = append(block, line)
+			continue
+		}
+		finish()
+
+		if heading == "" && strings.HasPrefix(line, "# ") {
+			heading = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			continue
+		}
+
+		match := checklistItemRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		// Generate UUID for task
+		taskID, err := utils.GenerateUUID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate UUID for task: %w", err)
+		}
+
+		order++
+		current = model.NewTask(taskID, strings.TrimSpace(match[3]), "", nil)
+		current.Order = order
+		if match[2] != " " {
+			current.MarkDone()
+		}
+		indent = len(match[1])
+		tasks = append(tasks, current)
+	}
+	finish()
+
+	// Share the heading among the tasks
+	if heading != "" {
+		for _, task := range tasks {
+			if h1AsPrefix {
+				task.Title = heading + ": " + task.Title
+			} else {
+				task.Tags = []string{heading}
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// dedent joins lines after removing the indentation they have in common, trimming blank lines at both ends
+func dedent(lines []string) string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || lineIndent < common {
+			common = lineIndent
+		}
+	}
+
+	dedented := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= common && common > 0 {
+			line = line[common:]
+		}
+		dedented[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(dedented, "\n"), "\n")
+}
+
+// parseMemoBlock returns the title and content of a memo block. The title given after the fence
+// takes precedence; otherwise a "# " heading on the first line of the block is used and removed from the content.
+func parseMemoBlock(fenceTitle, body string) (string, string) {
+	if title := strings.TrimSpace(fenceTitle); title != "" {
+		return title, body
+	}
+
+	firstLine, rest, _ := strings.Cut(body, "\n")
+	if strings.HasPrefix(firstLine, "# ") {
+		return strings.TrimSpace(strings.TrimPrefix(firstLine, "# ")), strings.TrimLeft(rest, "\n")
+	}
+	return "", body
+}
+
 // SaveTaskAndMemos saves the task and memos to the store
 func (p *MarkdownParser) SaveTaskAndMemos(task *model.Task, memos []*model.Memo, s *storage.Storage) error {
+	return p.SaveTasksAndMemos([]*model.Task{task}, memos, s)
+}
+
+// SaveTasksAndMemos saves the tasks and memos to the store
+func (p *MarkdownParser) SaveTasksAndMemos(tasks []*model.Task, memos []*model.Memo, s *storage.Storage) error {
 	// Add memos to store
 	for _, memo := range memos {
 		p.store.AddMemo(memo)
 	}
 
-	// Add task to store
-	p.store.AddTask(task)
+	// Add tasks to store
+	for _, task := range tasks {
+		p.store.AddTask(task)
+	}
 
 	// Save store
 	if err := s.Save(p.store); err != nil {
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zishida/tamo/internal/model"
+)
+
+func TestParseMarkdownMemoTitles(t *testing.T) {
+	tests := []struct {
+		name        string
+		block       string
+		wantTitle   string
+		wantContent string
+	}{
+		{
+			name:        "untitled",
+			block:       "```memo\nPlain content\n```",
+			wantContent: "Plain content",
+		},
+		{
+			name:        "fence title",
+			block:       "```memo API error codes\n# Not the title\n400 Bad Request\n```",
+			wantTitle:   "API error codes",
+			wantContent: "# Not the title\n400 Bad Request",
+		},
+		{
+			name:        "heading inside",
+			block:       "```memo\n# API error codes\n\n400 Bad Request\n```",
+			wantTitle:   "API error codes",
+			wantContent: "400 Bad Request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewMarkdownParser(model.NewStore())
+			task, memos, err := parser.parseMarkdown("# Task Title\n\nBefore\n\n"+tt.block+"\n\nAfter\n", "default")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if task.Title != "Task Title" {
+				t.Errorf("Expected task title %q, got %q", "Task Title", task.Title)
+			}
+			if len(memos) != 1 {
+				t.Fatalf("Expected 1 memo, got %d", len(memos))
+			}
+			memo := memos[0]
+
+			if tt.wantTitle == "" && memo.Title != nil {
+				t.Errorf("Expected no title, got %q", *memo.Title)
+			}
+			if tt.wantTitle != "" && (memo.Title == nil || *memo.Title != tt.wantTitle) {
+				t.Errorf("Expected title %q, got %v", tt.wantTitle, memo.Title)
+			}
+			if memo.Content != tt.wantContent {
+				t.Errorf("Expected content %q, got %q", tt.wantContent, memo.Content)
+			}
+
+			link := fmt.Sprintf("[memo](%s)", memo.ID)
+			if tt.wantTitle != "" {
+				link = fmt.Sprintf("[memo: %s](%s)", tt.wantTitle, memo.ID)
+			}
+			if task.Description != "Before\n\n"+link+"\n\nAfter" {
+				t.Errorf("Expected the block to be replaced with %s, got %q", link, task.Description)
+			}
+			if !memoLinkRegex.MatchString(task.Description) {
+				t.Errorf("Expected the link to match memoLinkRegex: %q", task.Description)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownDefaultTitle(t *testing.T) {
+	// A heading inside a memo block must not become the task title
+	parser := NewMarkdownParser(model.NewStore())
+	task, memos, err := parser.parseMarkdown("```memo\n# Memo Heading\nContent\n```\n", "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.Title != "default" {
+		t.Errorf("Expected the default title, got %q", task.Title)
+	}
+	if len(memos) != 1 || memos[0].Title == nil || *memos[0].Title != "Memo Heading" {
+		t.Errorf("Expected the memo to be titled by its heading, got %v", memos)
+	}
+	if !strings.HasPrefix(task.Description, "[memo: Memo Heading](") {
+		t.Errorf("Expected the description to be the memo link, got %q", task.Description)
+	}
+}
+
+func TestParseMarkdownSubtasks(t *testing.T) {
+	content := "# Plan\n\nOverview\n\n## Step one\n\nFirst step\n\n```memo Notes\n## Not a section\n```\n\n## Step two\n\nSecond step\n"
+
+	store := model.NewStore()
+	store.AddTask(model.NewTask("existing", "Existing", "", nil))
+	store.Tasks[0].Order = 3.0
+	parser := NewMarkdownParser(store)
+	tasks, memos, err := parser.parseMarkdownSubtasks(content, "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("Expected a parent and 2 subtasks, got %d tasks", len(tasks))
+	}
+
+	parent := tasks[0]
+	if parent.Title != "Plan" || parent.Description != "Overview" || parent.ParentID != "" {
+		t.Errorf("Unexpected parent task: %+v", parent)
+	}
+	for i, want := range []struct{ title, description string }{
+		{"Step one", "First step\n\n[memo: Notes](" + memos[0].ID + ")"},
+		{"Step two", "Second step"},
+	} {
+		task := tasks[i+1]
+		if task.Title != want.title || task.Description != want.description {
+			t.Errorf("Expected %q / %q, got %q / %q", want.title, want.description, task.Title, task.Description)
+		}
+		if task.ParentID != parent.ID {
+			t.Errorf("Expected parent ID %s, got %q", parent.ID, task.ParentID)
+		}
+		if task.Order != parent.Order+float64(i+1) {
+			t.Errorf("Expected order %.1f, got %.1f", parent.Order+float64(i+1), task.Order)
+		}
+	}
+
+	// The memo belongs to the section it appears in
+	if len(memos) != 1 || memos[0].Content != "## Not a section" {
+		t.Fatalf("Expected 1 memo with the heading kept in its content, got %v", memos)
+	}
+	if len(tasks[1].MemoRefs) != 1 || tasks[1].MemoRefs[0] != memos[0].ID || len(parent.MemoRefs) != 0 {
+		t.Errorf("Expected only the first subtask to reference the memo")
+	}
+}
+
+func TestParseMarkdownExistingMemos(t *testing.T) {
+	store := model.NewStore()
+	title := "Existing"
+	existing := model.NewMemo("5b0066af-0000-4000-8000-000000000001", &title, "Existing content")
+	other := model.NewMemo("7c1177b0-0000-4000-8000-000000000002", nil, "Other content")
+	store.AddMemo(existing)
+	store.AddMemo(other)
+
+	// Test that ref= blocks and links reference existing memos, and new blocks still create memos
+	content := "# Task\n\n```memo ref=5b0066af\nignored\n```\n\nSee [memo](" + other.ID + ")\n\n```memo\nNew content\n```\n"
+	parser := NewMarkdownParser(store)
+	task, memos, err := parser.parseMarkdown(content, "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(memos) != 1 || memos[0].Content != "New content" {
+		t.Fatalf("Expected only the new block to create a memo, got %v", memos)
+	}
+	wantRefs := []string{existing.ID, memos[0].ID, other.ID}
+	if strings.Join(task.MemoRefs, ",") != strings.Join(wantRefs, ",") {
+		t.Errorf("Expected memo refs %v, got %v", wantRefs, task.MemoRefs)
+	}
+	if !strings.Contains(task.Description, "[memo: Existing]("+existing.ID+")") {
+		t.Errorf("Expected the ref block to be replaced with a link to the existing memo, got %q", task.Description)
+	}
+	if strings.Contains(task.Description, "ignored") {
+		t.Errorf("Expected the content of the ref block to be dropped, got %q", task.Description)
+	}
+
+	// Test that unknown references are reported
+	_, _, err = parser.parseMarkdown("```memo ref=ffff\nx\n```\n\n[memo](eeee)\n", "default")
+	if err == nil || !strings.Contains(err.Error(), "ffff") || !strings.Contains(err.Error(), "eeee") {
+		t.Errorf("Expected error listing the unknown references, got %v", err)
+	}
+}
+
+func TestParseChecklist(t *testing.T) {
+	content := "# Release\n\nIntro text is ignored.\n\n- [ ] Write notes\n  Mention the new flags\n\n  - [ ] Nested item\n- [x] Tag the release\nNot indented, ignored\n    - [X] Deep item\n* [ ] Announce\n"
+
+	store := model.NewStore()
+	existing := model.NewTask("existing", "Existing", "", nil)
+	existing.Order = 5.0
+	store.AddTask(existing)
+
+	parser := NewMarkdownParser(store)
+	tasks, err := parser.parseChecklist(content, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []struct {
+		title       string
+		description string
+		done        bool
+	}{
+		{"Write notes", "Mention the new flags\n\n- [ ] Nested item", false},
+		{"Tag the release", "", true},
+		{"Deep item", "", true},
+		{"Announce", "", false},
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("Expected %d tasks, got %d", len(want), len(tasks))
+	}
+	for i, w := range want {
+		task := tasks[i]
+		if task.Title != w.title || task.Description != w.description || task.Done != w.done {
+			t.Errorf("Expected %q / %q / %v, got %q / %q / %v", w.title, w.description, w.done, task.Title, task.Description, task.Done)
+		}
+		if task.Done && task.CompletedAt == nil {
+			t.Errorf("Expected completed task %q to have a completion time", task.Title)
+		}
+		if task.Order != 6.0+float64(i) {
+			t.Errorf("Expected order %.1f, got %.1f", 6.0+float64(i), task.Order)
+		}
+		if len(task.Tags) != 1 || task.Tags[0] != "Release" {
+			t.Errorf("Expected the heading as a tag, got %v", task.Tags)
+		}
+	}
+
+	// Test the heading as a prefix
+	tasks, err = parser.parseChecklist(content, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tasks[0].Title != "Release: Write notes" || len(tasks[0].Tags) != 0 {
+		t.Errorf("Expected the heading as a title prefix, got %q %v", tasks[0].Title, tasks[0].Tags)
+	}
+}
+
+func TestParseMarkdownNestedFences(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		contents []string
+		desc     string
+	}{
+		{
+			name:     "code with info string",
+			content:  "# Task\n\n```memo Example\nUse it like this:\n```go\nfmt.Println(\"hi\")\n```\nDone.\n```\n\nAfter\n",
+			contents: []string{"Use it like this:\n```go\nfmt.Println(\"hi\")\n```\nDone."},
+			desc:     "[memo: Example](%s)\n\nAfter",
+		},
+		{
+			name:     "longer outer fence",
+			content:  "# Task\n\n````memo\n```\nplain code\n```\n````\n",
+			contents: []string{"```\nplain code\n```"},
+			desc:     "[memo](%s)",
+		},
+		{
+			name:     "consecutive blocks",
+			content:  "# Task\n\n```memo\nFirst\n```\n```memo\nSecond\n```\n",
+			contents: []string{"First", "Second"},
+			desc:     "[memo](%s)\n[memo](%s)",
+		},
+		{
+			name:     "memo fence inside another block",
+			content:  "# Task\n\n```markdown\n```memo\nexample\n```\n```\n",
+			contents: nil,
+			desc:     "```markdown\n```memo\nexample\n```\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewMarkdownParser(model.NewStore())
+			task, memos, err := parser.parseMarkdown(tt.content, "default")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(memos) != len(tt.contents) {
+				t.Fatalf("Expected %d memos, got %d", len(tt.contents), len(memos))
+			}
+			var ids []any
+			for i, memo := range memos {
+				if memo.Content != tt.contents[i] {
+					t.Errorf("Expected memo content %q, got %q", tt.contents[i], memo.Content)
+				}
+				ids = append(ids, memo.ID)
+			}
+			if want := fmt.Sprintf(tt.desc, ids...); task.Description != want {
+				t.Errorf("Expected description %q, got %q", want, task.Description)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownFrontMatter(t *testing.T) {
+	content := "---\ntitle: \"Deploy v2\"\ndue: 2025-06-01\ntags: [ops, urgent]\npriority: high\ndone: true\n---\n# Heading Title\n\nDescription\n"
+
+	parser := NewMarkdownParser(model.NewStore())
+	task, _, err := parser.parseMarkdown(content, "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.Title != "Deploy v2" {
+		t.Errorf("Expected the front matter title to win, got %q", task.Title)
+	}
+	if task.Description != "Description" {
+		t.Errorf("Expected the front matter to be removed from the description, got %q", task.Description)
+	}
+	if strings.Join(task.Tags, ",") != "ops,urgent" || task.Priority != "high" || !task.Done || task.CompletedAt == nil {
+		t.Errorf("Expected tags, priority, and done to be applied, got %v %q %v", task.Tags, task.Priority, task.Done)
+	}
+	if len(parser.Warnings) != 1 || !strings.Contains(parser.Warnings[0], `"due"`) {
+		t.Errorf("Expected a warning for the unknown key, got %v", parser.Warnings)
+	}
+
+	// Test tags as a block list
+	task, _, err = parser.parseMarkdown("---\ntags:\n  - ops\n  - 'on call'\n---\nBody\n", "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(task.Tags, ",") != "ops,on call" || task.Title != "default" {
+		t.Errorf("Expected block list tags, got %v %q", task.Tags, task.Title)
+	}
+
+	// Test that a leading horizontal rule is not front matter
+	task, _, err = parser.parseMarkdown("---\nJust text\n---\n", "default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.Description != "---\nJust text\n---" {
+		t.Errorf("Expected the content to be kept, got %q", task.Description)
+	}
+
+	// Test an invalid done value
+	if _, _, err := parser.parseMarkdown("---\ndone: maybe\n---\n", "default"); err == nil {
+		t.Errorf("Expected error for invalid done value, got nil")
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zishida/tamo/internal/model"
+)
+
+// Color modes given with --color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI color codes
+const (
+	colorReset   = "0"
+	colorBold    = "1"
+	colorDim     = "2"
+	colorReverse = "7"
+	colorRed     = "31"
+	colorGreen   = "32"
+	colorCyan    = "36"
+)
+
+// colorize wraps text in the given ANSI color. The color is restored after each reset in text,
+// so text that is already partly colored, like a task list line, keeps the color around its parts.
+func colorize(text, color string) string {
+	reset := "\033[" + colorReset + "m"
+	start := "\033[" + color + "m"
+	return start + strings.ReplaceAll(text, reset, reset+start) + reset
+}
+
+// parseColorMode checks a --color value
+func parseColorMode(mode string) (string, error) {
+	switch mode {
+	case colorAuto, colorAlways, colorNever:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid color mode: %s (expected always, never, or auto)", mode)
+}
+
+// renderer formats the parts of the output that are colored on a terminal, such as IDs and
+// task titles. Without color, text is returned unchanged, so plain output stays the same.
+type renderer struct {
+	color bool
+}
+
+// renderer returns the renderer for stdout. Colors are used with --color=always, never with
+// --color=never, and otherwise only when stdout is a terminal and NO_COLOR is not set.
+// Without --color, the color setting gives the mode.
+func (c *CLI) renderer() renderer {
+	mode := c.color
+	if mode == "" {
+		mode = c.configValue("color")
+	}
+	switch mode {
+	case colorAlways:
+		return renderer{color: true}
+	case colorNever:
+		return renderer{}
+	}
+	return renderer{color: os.Getenv("NO_COLOR") == "" && stdoutIsTerminal()}
+}
+
+// paint wraps text in the given ANSI color if colors are enabled
+func (r renderer) paint(text, color string) string {
+	if !r.color {
+		return text
+	}
+	return colorize(text, color)
+}
+
+// id renders an ID, or its displayed prefix, in cyan
+func (r renderer) id(id string) string {
+	return r.paint(id, colorCyan)
+}
+
+// doneMark renders the checkbox mark of a task, with the mark of a done task in green
+func (r renderer) doneMark(task *model.Task) string {
+	if task.Done {
+		return r.paint(taskDoneMark(task), colorGreen)
+	}
+	return taskDoneMark(task)
+}
+
+// title renders text belonging to a task, such as its title: dimmed if the task is done and bold otherwise
+func (r renderer) title(task *model.Task, text string) string {
+	if task.Done {
+		return r.paint(text, colorDim)
+	}
+	return r.paint(text, colorBold)
+}
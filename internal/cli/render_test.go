@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zishida/tamo/internal/model"
+)
+
+func TestRenderer(t *testing.T) {
+	done := &model.Task{Title: "Done task", Done: true}
+	undone := &model.Task{Title: "Undone task"}
+
+	// Test that without color, text is returned unchanged
+	plain := renderer{}
+	if got := plain.id("1a2b3c4d") + plain.doneMark(done) + plain.title(undone, undone.Title); got != "1a2b3c4d[x]Undone task" {
+		t.Errorf("Expected plain text, got: %q", got)
+	}
+
+	// Test the colors of each part
+	r := renderer{color: true}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"id", r.id("1a2b3c4d"), "\033[36m1a2b3c4d\033[0m"},
+		{"done mark", r.doneMark(done), "\033[32m[x]\033[0m"},
+		{"undone mark", r.doneMark(undone), "[ ]"},
+		{"done title", r.title(done, done.Title), "\033[2mDone task\033[0m"},
+		{"undone title", r.title(undone, undone.Title), "\033[1mUndone task\033[0m"},
+		// The outer color is restored after the inner one
+		{"nested", colorize("a"+colorize("b", colorCyan)+"c", colorRed), "\033[31ma\033[36mb\033[0m\033[31mc\033[0m"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, tt.got)
+		}
+	}
+}
+
+func TestColorOption(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Change to the temporary directory
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change to temp dir: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// Initialize tamo and add a task
+	cli := NewCLI()
+	if err := cli.executeInit([]string{}); err != nil {
+		t.Fatalf("Failed to initialize tamo: %v", err)
+	}
+	if _, err := captureOutput(func() error {
+		return cli.executeAddTask([]string{"Write report"}, "add")
+	}); err != nil {
+		t.Fatalf("Failed to add task: %v", err)
+	}
+
+	// Test that --color=always colors the list even though stdout is not a terminal and NO_COLOR is set
+	for _, args := range [][]string{{"--color=always", "list"}, {"--color", "always", "list"}} {
+		output, err := captureOutput(func() error {
+			return NewCLI().run(args)
+		})
+		if err != nil {
+			t.Fatalf("Failed to list tasks: %v", err)
+		}
+		if !strings.Contains(output, "\033[1mWrite report\033[0m") || !strings.Contains(output, "\033[36m") {
+			t.Errorf("Expected colored output with %v, got: %q", args, output)
+		}
+	}
+
+	// Test that the list is plain in auto mode, which the tests run with NO_COLOR set, and with --color=never
+	for _, args := range [][]string{{"list"}, {"--color=auto", "list"}, {"--color=never", "list"}} {
+		output, err := captureOutput(func() error {
+			return NewCLI().run(args)
+		})
+		if err != nil {
+			t.Fatalf("Failed to list tasks: %v", err)
+		}
+		if strings.Contains(output, "\033[") {
+			t.Errorf("Expected plain output with %v, got: %q", args, output)
+		}
+	}
+
+	// Test an invalid mode
+	if err := NewCLI().run([]string{"--color=sometimes", "list"}); err == nil || !strings.Contains(err.Error(), "invalid color mode") {
+		t.Errorf("Expected an invalid color mode error, got: %v", err)
+	}
+}
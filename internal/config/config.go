@@ -0,0 +1,238 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the configuration file in the data directory
+const FileName = "config.json"
+
+// Key describes a known configuration key and the values it accepts
+type Key struct {
+	Name        string
+	Description string
+	Values      []string // Allowed values, or nil for any value
+	Default     string
+}
+
+// Keys lists the known configuration keys
+var Keys = []Key{
+	{
+		Name:        "list.default_target",
+		Description: "What 'list' shows when no subcommand is given",
+		Values:      []string{"tasks", "memos", "all"},
+		Default:     "tasks",
+	},
+	{
+		Name:        "list.pending_first",
+		Description: "Whether 'list' shows uncompleted tasks before completed ones",
+		Values:      []string{"true", "false"},
+		Default:     "false",
+	},
+	{
+		Name:        "list.sort",
+		Description: "How 'list' sorts memos when --sort is not given: in creation order, or by 'usage'",
+		Values:      []string{"default", "usage"},
+		Default:     "default",
+	},
+	{
+		Name:        "show.refs_position",
+		Description: "Where 'show' lists the tasks referencing a memo: above the content, below it, or both",
+		Values:      []string{"top", "bottom", "both"},
+		Default:     "both",
+	},
+	{
+		Name:        "rm.confirm",
+		Description: "Whether 'rm' and the --rm options of 'pop', 'shift', and 'next' ask before removing tasks and memos",
+		Values:      []string{"true", "false"},
+		Default:     "true",
+	},
+	{
+		Name:        "editor",
+		Description: "Editor command for --editor, used unless TAMO_EDITOR is set (falls back to EDITOR, then nano)",
+		Default:     "",
+	},
+	{
+		Name:        "date_format",
+		Description: "How times are shown by 'show', 'next', 'pop', 'shift', and 'trash list'",
+		Values:      []string{"datetime", "date", "rfc3339"},
+		Default:     "datetime",
+	},
+	{
+		Name:        "color",
+		Description: "Whether output is colored when --color is not given (auto: only on a terminal, unless NO_COLOR is set)",
+		Values:      []string{"auto", "always", "never"},
+		Default:     "auto",
+	},
+}
+
+// FindKey returns the known key with the given name, or nil
+func FindKey(name string) *Key {
+	for i := range Keys {
+		if Keys[i].Name == name {
+			return &Keys[i]
+		}
+	}
+	return nil
+}
+
+// Config holds the settings read from the configuration file
+type Config struct {
+	path     string
+	values   map[string]string
+	raw      map[string]json.RawMessage // Values as read from the file, written back unless changed
+	warnings []string
+}
+
+// Load reads the configuration file in the given data directory.
+// A missing file gives an empty configuration. Unknown keys and invalid values don't fail,
+// so a file written by a newer version still works: they are ignored and reported by Warnings.
+func Load(dirPath string) (*Config, error) {
+	c := &Config{
+		path:   filepath.Join(dirPath, FileName),
+		values: make(map[string]string),
+	}
+
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	for name, rawValue := range c.raw {
+		// Keep other JSON values, such as true or 10, as their text
+		var value string
+		if err := json.Unmarshal(rawValue, &value); err != nil {
+			value = string(rawValue)
+		}
+		c.values[name] = value
+	}
+
+	for _, name := range c.Names() {
+		key := FindKey(name)
+		if key == nil {
+			c.warnings = append(c.warnings, fmt.Sprintf("unknown config key %s in %s, ignored", name, c.path))
+		} else if !key.valid(c.values[name]) {
+			c.warnings = append(c.warnings, fmt.Sprintf("invalid value for %s in %s: %s (expected %s), using %q", name, c.path, c.values[name], strings.Join(key.Values, ", "), key.Default))
+		}
+	}
+	return c, nil
+}
+
+// Create writes an empty configuration file in the given data directory, unless one exists
+func Create(dirPath string) error {
+	c := &Config{
+		path:   filepath.Join(dirPath, FileName),
+		values: make(map[string]string),
+	}
+	if _, err := os.Stat(c.path); err == nil {
+		return nil
+	}
+	return c.Save()
+}
+
+// Warnings returns the problems found while loading the configuration file
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// valid reports whether the key accepts the value
+func (k *Key) valid(value string) bool {
+	return k.Values == nil || containsString(k.Values, value)
+}
+
+// Get returns the value of the key, or its default if it is not set or the value is invalid
+func (c *Config) Get(name string) string {
+	key := FindKey(name)
+	if value, ok := c.values[name]; ok && (key == nil || key.valid(value)) {
+		return value
+	}
+	if key != nil {
+		return key.Default
+	}
+	if key := FindKey(name); key != nil {
+		return key.Default
+	}
+	return ""
+}
+
+// IsSet reports whether the key is set in the configuration file
+func (c *Config) IsSet(name string) bool {
+	_, ok := c.values[name]
+	return ok
+}
+
+// Set validates and sets the value of a known key
+func (c *Config) Set(name, value string) error {
+	key := FindKey(name)
+	if key == nil {
+		return fmt.Errorf("unknown config key: %s", name)
+	}
+	if !key.valid(value) {
+		return fmt.Errorf("invalid value for %s: %s (expected %s)", name, value, strings.Join(key.Values, ", "))
+	}
+
+	c.values[name] = value
+	delete(c.raw, name)
+	return nil
+}
+
+// Unset removes the key from the configuration, restoring its default
+func (c *Config) Unset(name string) {
+	delete(c.values, name)
+	delete(c.raw, name)
+}
+
+// Names returns the names of the keys set in the configuration file, sorted
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.values))
+	for name := range c.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the configuration file.
+// Values that were not changed, such as those of unknown keys, are written back as they were read.
+func (c *Config) Save() error {
+	values := make(map[string]interface{}, len(c.values))
+	for name, value := range c.values {
+		if rawValue, ok := c.raw[name]; ok {
+			values[name] = rawValue
+		} else {
+			values[name] = value
+		}
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := ioutil.WriteFile(c.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// containsString checks if a string slice contains a string
+func containsString(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_SetGetSave(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A missing file gives the defaults
+	cfg, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if value := cfg.Get("list.default_target"); value != "tasks" {
+		t.Errorf("Expected default value 'tasks', got '%s'", value)
+	}
+	if cfg.IsSet("list.default_target") {
+		t.Errorf("Expected key not to be set")
+	}
+
+	// Known keys are validated
+	if err := cfg.Set("list.default_target", "everything"); err == nil {
+		t.Errorf("Expected error for invalid value, got nil")
+	}
+	if err := cfg.Set("unknown.key", "value"); err == nil {
+		t.Errorf("Expected error for unknown key, got nil")
+	}
+
+	// Values survive a save and load
+	if err := cfg.Set("list.default_target", "all"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	cfg, err = Load(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if value := cfg.Get("list.default_target"); value != "all" {
+		t.Errorf("Expected value 'all', got '%s'", value)
+	}
+
+	// Unset restores the default
+	cfg.Unset("list.default_target")
+	if value := cfg.Get("list.default_target"); value != "tasks" {
+		t.Errorf("Expected default value 'tasks' after unset, got '%s'", value)
+	}
+}
+
+func TestConfig_LoadTolerant(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create writes an empty file, and doesn't overwrite an existing one
+	if err := Create(tempDir); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, FileName))
+	if err != nil || string(data) != "{}\n" {
+		t.Errorf("Expected an empty config file, got: %q (%v)", data, err)
+	}
+
+	// Unknown keys and invalid values are reported but don't fail, and JSON values other than strings are read as text
+	content := `{"color": "never", "rm.confirm": false, "date_format": "julian", "future.key": "x", "future.limit": 10}`
+	if err := os.WriteFile(filepath.Join(tempDir, FileName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := Create(tempDir); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	cfg, err := Load(tempDir)
+	if err != nil {
+		t.Fatalf("Expected unknown keys not to fail, got: %v", err)
+	}
+	if value := cfg.Get("color"); value != "never" {
+		t.Errorf("Expected value 'never', got '%s'", value)
+	}
+	if value := cfg.Get("rm.confirm"); value != "false" {
+		t.Errorf("Expected the JSON false to be read as 'false', got '%s'", value)
+	}
+	if value := cfg.Get("date_format"); value != "datetime" {
+		t.Errorf("Expected the default for an invalid value, got '%s'", value)
+	}
+	warnings := cfg.Warnings()
+	if len(warnings) != 3 || !strings.Contains(warnings[0], "date_format") || !strings.Contains(warnings[1], "unknown config key future.key") {
+		t.Errorf("Expected warnings about the invalid value and the unknown key, got: %v", warnings)
+	}
+
+	// Unknown keys are kept when saving, for the version that knows them
+	if err := cfg.Set("color", "always"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(tempDir, FileName))
+	if err != nil || !strings.Contains(string(data), `"future.key": "x"`) || !strings.Contains(string(data), `"future.limit": 10`) {
+		t.Errorf("Expected the unknown keys to be kept unchanged, got: %s (%v)", data, err)
+	}
+	if !strings.Contains(string(data), `"rm.confirm": false`) || !strings.Contains(string(data), `"color": "always"`) {
+		t.Errorf("Expected unchanged values to be kept and changed ones to be written, got: %s", data)
+	}
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"sort"
 	"time"
 )
 
@@ -33,51 +34,180 @@ func (t *CustomTime) UnmarshalJSON(data []byte) error {
 
 // Task represents a task to be done with properties like ID, title, description, order, completion status, and memo references
 type Task struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Order       float64     `json:"order"`
+	Done        bool        `json:"done"`
+	MemoRefs    []string    `json:"memo_refs"`
+	Tags        []string    `json:"tags,omitempty"`
+	Priority    string      `json:"priority,omitempty"`
+	ParentID    string      `json:"parent_id,omitempty"`
+	DependsOn   []string    `json:"depends_on,omitempty"`
+	CompletedAt *CustomTime `json:"completed_at,omitempty"`
+	CreatedAt   CustomTime  `json:"created_at"`
+	UpdatedAt   CustomTime  `json:"updated_at"`
+}
+
+// HasTag reports whether the task has the given tag
+func (t *Task) HasTag(tag string) bool {
+	for _, tg := range t.Tags {
+		if tg == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDone marks the task as done or not done, recording when it was completed
+func (t *Task) SetDone(done bool) {
+	if done && !t.Done {
+		t.CompletedAt = &CustomTime{Time: time.Now().UTC()}
+	} else if !done {
+		t.CompletedAt = nil
+	}
+	t.Done = done
+}
+
+// SetMemoRefs replaces the memo references of the task, dropping duplicates
+func (t *Task) SetMemoRefs(memoRefs []string) {
+	t.MemoRefs = uniqueMemoRefs(memoRefs)
+}
+
+// uniqueMemoRefs returns memo references without duplicates, keeping the first of each
+func uniqueMemoRefs(memoRefs []string) []string {
+	if memoRefs == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(memoRefs))
+	unique := make([]string, 0, len(memoRefs))
+	for _, memoID := range memoRefs {
+		if !seen[memoID] {
+			seen[memoID] = true
+			unique = append(unique, memoID)
+		}
+	}
+	return unique
+}
+
+// MarkDone marks the task as done and updates its timestamp
+func (t *Task) MarkDone() {
+	t.SetDone(true)
+	t.Touch()
+}
+
+// MarkUndone marks the task as not done and updates its timestamp
+func (t *Task) MarkUndone() {
+	t.SetDone(false)
+	t.Touch()
+}
+
+// Touch sets the update time of the task to now
+func (t *Task) Touch() {
+	t.UpdatedAt = CustomTime{Time: time.Now().UTC()}
+}
+
+// Memo stores information related to tasks with properties like ID, title, and content
+type Memo struct {
 	ID          string     `json:"id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Order       float64    `json:"order"`
-	Done        bool       `json:"done"`
-	MemoRefs    []string   `json:"memo_refs"`
+	Title       *string    `json:"title"` // Optional
+	Content     string     `json:"content"`
+	Attachments []string   `json:"attachments,omitempty"` // Paths of related files
+	Archived    bool       `json:"archived,omitempty"`
 	CreatedAt   CustomTime `json:"created_at"`
 	UpdatedAt   CustomTime `json:"updated_at"`
 }
 
-// Memo stores information related to tasks with properties like ID, title, and content
-type Memo struct {
-	ID        string     `json:"id"`
-	Title     *string    `json:"title"` // Optional
-	Content   string     `json:"content"`
-	CreatedAt CustomTime `json:"created_at"`
-	UpdatedAt CustomTime `json:"updated_at"`
+// Touch sets the update time of the memo to now
+func (m *Memo) Touch() {
+	m.UpdatedAt = CustomTime{Time: time.Now().UTC()}
+}
+
+// TrashedTask is a removed task kept in the trash with the time it was removed
+type TrashedTask struct {
+	Task
+	DeletedAt CustomTime `json:"deleted_at"`
+}
+
+// TrashedMemo is a removed memo kept in the trash with the time it was removed
+// and the IDs of the tasks that referenced it
+type TrashedMemo struct {
+	Memo
+	RefTaskIDs []string   `json:"ref_task_ids,omitempty"`
+	DeletedAt  CustomTime `json:"deleted_at"`
+}
+
+// Trash holds removed tasks and memos until they are restored or the trash is emptied
+type Trash struct {
+	Tasks []*TrashedTask `json:"tasks"`
+	Memos []*TrashedMemo `json:"memos"`
 }
 
+// TaskTemplate is a named task saved to create similar tasks from
+type TaskTemplate struct {
+	Name        string         `json:"name"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Tags        []string       `json:"tags,omitempty"`
+	Priority    string         `json:"priority,omitempty"`
+	Memos       []TemplateMemo `json:"memos,omitempty"`
+	CreatedAt   CustomTime     `json:"created_at"`
+}
+
+// TemplateMemo is a memo reference of a task template. A template-owned memo is stored in the
+// template and copied for each task created from it; otherwise the tasks reference the shared memo.
+type TemplateMemo struct {
+	MemoID string `json:"memo_id,omitempty"` // Shared memo
+	Memo   *Memo  `json:"memo,omitempty"`    // Template-owned memo
+}
+
+// CurrentVersion is the version of the data file format written by this build
+const CurrentVersion = 2
+
 // Store is the main data structure that contains all tasks and memos
 type Store struct {
 	Version int     `json:"version"`
 	Tasks   []*Task `json:"tasks"`
 	Memos   []*Memo `json:"memos"`
+	Trash   Trash   `json:"trash"`
+
+	// ArchivedTasks holds completed tasks moved out of the task list by 'archive'
+	ArchivedTasks []*Task `json:"archived_tasks,omitempty"`
+
+	// Templates holds the tasks saved by 'template save', which are not part of the task list
+	Templates []*TaskTemplate `json:"templates,omitempty"`
+
+	// Indexes for lookups by ID, built on first use. They are kept up to date by
+	// AddTask, AddMemo, RemoveTask, and RemoveMemo; call Reindex after changing
+	// Tasks, Memos, or the memo references of a task directly.
+	tasksByID  map[string]*Task
+	memosByID  map[string]*Memo
+	refsByMemo map[string][]*Task
 }
 
-// NewStore creates a new empty store with version 1
+// NewStore creates a new empty store with the current version
 func NewStore() *Store {
 	return &Store{
-		Version: 1,
+		Version: CurrentVersion,
 		Tasks:   make([]*Task, 0),
 		Memos:   make([]*Memo, 0),
+		Trash: Trash{
+			Tasks: make([]*TrashedTask, 0),
+			Memos: make([]*TrashedMemo, 0),
+		},
 	}
 }
 
 // NewTask creates a new task with the given title, description, and memo references
 func NewTask(id, title, description string, memoRefs []string) *Task {
-	now := CustomTime{time.Now().UTC()}
+	now := CustomTime{Time: time.Now().UTC()}
 	return &Task{
 		ID:          id,
 		Title:       title,
 		Description: description,
 		Order:       0.0, // Will be set by the caller
 		Done:        false,
-		MemoRefs:    memoRefs,
+		MemoRefs:    uniqueMemoRefs(memoRefs),
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -85,7 +215,7 @@ func NewTask(id, title, description string, memoRefs []string) *Task {
 
 // NewMemo creates a new memo with the given title and content
 func NewMemo(id string, title *string, content string) *Memo {
-	now := CustomTime{time.Now().UTC()}
+	now := CustomTime{Time: time.Now().UTC()}
 	return &Memo{
 		ID:        id,
 		Title:     title,
@@ -121,9 +251,122 @@ func (s *Store) GetMinTaskOrder() float64 {
 	return minOrder
 }
 
+// Reindex rebuilds the indexes used for lookups by ID
+func (s *Store) Reindex() {
+	s.tasksByID = make(map[string]*Task, len(s.Tasks))
+	s.memosByID = make(map[string]*Memo, len(s.Memos))
+	s.refsByMemo = make(map[string][]*Task)
+	for _, task := range s.Tasks {
+		s.indexTask(task)
+	}
+	for _, memo := range s.Memos {
+		// Keep the first memo if IDs are duplicated, like a linear scan would
+		if _, ok := s.memosByID[memo.ID]; !ok {
+			s.memosByID[memo.ID] = memo
+		}
+	}
+}
+
+// ensureIndex builds the indexes if they haven't been built yet
+func (s *Store) ensureIndex() {
+	if s.tasksByID == nil {
+		s.Reindex()
+	}
+}
+
+// indexTask adds a task to the indexes
+func (s *Store) indexTask(task *Task) {
+	if _, ok := s.tasksByID[task.ID]; !ok {
+		s.tasksByID[task.ID] = task
+	}
+	for i, memoID := range task.MemoRefs {
+		// Index each referenced memo once even if the reference is duplicated
+		duplicate := false
+		for _, prev := range task.MemoRefs[:i] {
+			if prev == memoID {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			s.refsByMemo[memoID] = append(s.refsByMemo[memoID], task)
+		}
+	}
+}
+
+// unindexTask removes a task from the indexes
+func (s *Store) unindexTask(task *Task) {
+	if s.tasksByID[task.ID] == task {
+		delete(s.tasksByID, task.ID)
+	}
+	for _, memoID := range task.MemoRefs {
+		tasks := s.refsByMemo[memoID]
+		for i, t := range tasks {
+			if t == task {
+				s.refsByMemo[memoID] = append(tasks[:i:i], tasks[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// dropIndex drops the indexes, to be rebuilt on the next lookup
+func (s *Store) dropIndex() {
+	s.tasksByID = nil
+	s.memosByID = nil
+	s.refsByMemo = nil
+}
+
+// AddMemoRef adds a reference to the memo to the task unless it already has one,
+// and reports whether the reference was added
+func (s *Store) AddMemoRef(task *Task, memoID string) bool {
+	for _, ref := range task.MemoRefs {
+		if ref == memoID {
+			return false
+		}
+	}
+	task.MemoRefs = append(task.MemoRefs, memoID)
+	s.dropIndex()
+	return true
+}
+
+// RemoveMemoRef removes the references to the memo from the task, and reports whether there were any
+func (s *Store) RemoveMemoRef(task *Task, memoID string) bool {
+	refs := make([]string, 0, len(task.MemoRefs))
+	for _, ref := range task.MemoRefs {
+		if ref != memoID {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(task.MemoRefs) {
+		return false
+	}
+	task.MemoRefs = refs
+	s.dropIndex()
+	return true
+}
+
+// SetMemoRefs replaces the memo references of the task, dropping duplicates
+func (s *Store) SetMemoRefs(task *Task, memoRefs []string) {
+	task.SetMemoRefs(memoRefs)
+	s.dropIndex()
+}
+
+// ReplaceTask overwrites the task with the given one, keeping the pointers to it valid
+func (s *Store) ReplaceTask(existing, task *Task) {
+	*existing = *task
+	s.dropIndex()
+}
+
 // FindTaskByID returns a task by its ID
 func (s *Store) FindTaskByID(id string) *Task {
-	for _, task := range s.Tasks {
+	s.ensureIndex()
+	return s.tasksByID[id]
+}
+
+// FindArchivedTaskByID returns an archived task by its ID
+func (s *Store) FindArchivedTaskByID(id string) *Task {
+	for _, task := range s.ArchivedTasks {
 		if task.ID == id {
 			return task
 		}
@@ -133,9 +376,200 @@ func (s *Store) FindTaskByID(id string) *Task {
 
 // FindMemoByID returns a memo by its ID
 func (s *Store) FindMemoByID(id string) *Memo {
+	s.ensureIndex()
+	return s.memosByID[id]
+}
+
+// TasksReferencingMemo returns the tasks that reference the memo, in the order of Tasks
+func (s *Store) TasksReferencingMemo(memoID string) []*Task {
+	s.ensureIndex()
+	var tasks []*Task
+	for _, task := range s.refsByMemo[memoID] {
+		// Skip tasks whose reference has been removed since the index was built
+		for _, ref := range task.MemoRefs {
+			if ref == memoID {
+				tasks = append(tasks, task)
+				break
+			}
+		}
+	}
+	return tasks
+}
+
+// IncompleteDependencies returns the tasks the task depends on that are not done yet.
+// Dependencies on tasks that don't exist are skipped.
+func (s *Store) IncompleteDependencies(task *Task) []*Task {
+	var incomplete []*Task
+	for _, id := range task.DependsOn {
+		if dep := s.FindTaskByID(id); dep != nil && !dep.Done {
+			incomplete = append(incomplete, dep)
+		}
+	}
+	return incomplete
+}
+
+// IsBlocked reports whether the task depends on tasks that are not done yet
+func (s *Store) IsBlocked(task *Task) bool {
+	return len(s.IncompleteDependencies(task)) > 0
+}
+
+// DependsOnTransitively reports whether the task depends on the other task, directly or through other dependencies
+func (s *Store) DependsOnTransitively(taskID, otherID string) bool {
+	seen := make(map[string]bool)
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+		task := s.FindTaskByID(id)
+		if task == nil {
+			return false
+		}
+		for _, depID := range task.DependsOn {
+			if depID == otherID || visit(depID) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(taskID)
+}
+
+// WouldCreateDependencyCycle reports whether making taskID depend on depID would create a cycle,
+// including a task depending on itself
+func (s *Store) WouldCreateDependencyCycle(taskID, depID string) bool {
+	return taskID == depID || s.DependsOnTransitively(depID, taskID)
+}
+
+// TasksDependingOn returns the tasks that depend on the task, in the order of Tasks
+func (s *Store) TasksDependingOn(taskID string) []*Task {
+	var tasks []*Task
+	for _, task := range s.Tasks {
+		for _, id := range task.DependsOn {
+			if id == taskID {
+				tasks = append(tasks, task)
+				break
+			}
+		}
+	}
+	return tasks
+}
+
+// ChildTasks returns the direct subtasks of the task, sorted by Order
+func (s *Store) ChildTasks(taskID string) []*Task {
+	var tasks []*Task
+	for _, task := range s.Tasks {
+		if task.ParentID == taskID && task.ID != taskID {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Order < tasks[j].Order
+	})
+	return tasks
+}
+
+// DescendantTasks returns the subtasks of the task and their subtasks, depth first
+func (s *Store) DescendantTasks(taskID string) []*Task {
+	var tasks []*Task
+	seen := map[string]bool{taskID: true}
+	var walk func(id string)
+	walk = func(id string) {
+		for _, child := range s.ChildTasks(id) {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			tasks = append(tasks, child)
+			walk(child.ID)
+		}
+	}
+	walk(taskID)
+	return tasks
+}
+
+// WouldCreateParentCycle reports whether making parentID the parent of taskID would create a cycle
+func (s *Store) WouldCreateParentCycle(taskID, parentID string) bool {
+	seen := make(map[string]bool)
+	for id := parentID; id != "" && !seen[id]; {
+		if id == taskID {
+			return true
+		}
+		seen[id] = true
+		parent := s.FindTaskByID(id)
+		if parent == nil {
+			break
+		}
+		id = parent.ParentID
+	}
+	return false
+}
+
+// PendingCount returns the number of tasks that are not done
+func (s *Store) PendingCount() int {
+	count := 0
+	for _, task := range s.Tasks {
+		if !task.Done {
+			count++
+		}
+	}
+	return count
+}
+
+// FindLatestTaskWithTag returns the most recently created task that has the given tag
+func (s *Store) FindLatestTaskWithTag(tag string) *Task {
+	var latest *Task
+	for _, task := range s.Tasks {
+		if task.HasTag(tag) && (latest == nil || task.CreatedAt.After(latest.CreatedAt.Time)) {
+			latest = task
+		}
+	}
+	return latest
+}
+
+// OrphanMemos returns the memos that are not referenced by any task, including archived ones,
+// or shared by a task template
+func (s *Store) OrphanMemos() []*Memo {
+	referenced := make(map[string]bool)
+	for _, tasks := range [][]*Task{s.Tasks, s.ArchivedTasks} {
+		for _, task := range tasks {
+			for _, memoID := range task.MemoRefs {
+				referenced[memoID] = true
+			}
+		}
+	}
+	for _, template := range s.Templates {
+		for _, templateMemo := range template.Memos {
+			referenced[templateMemo.MemoID] = true
+		}
+	}
+
+	var memos []*Memo
 	for _, memo := range s.Memos {
-		if memo.ID == id {
-			return memo
+		if !referenced[memo.ID] {
+			memos = append(memos, memo)
+		}
+	}
+	return memos
+}
+
+// FindTemplate returns the task template with the given name, or nil if there is none
+func (s *Store) FindTemplate(name string) *TaskTemplate {
+	for _, template := range s.Templates {
+		if template.Name == name {
+			return template
+		}
+	}
+	return nil
+}
+
+// RemoveTemplate removes the task template with the given name, and returns it
+func (s *Store) RemoveTemplate(name string) *TaskTemplate {
+	for i, template := range s.Templates {
+		if template.Name == name {
+			s.Templates = append(s.Templates[:i], s.Templates[i+1:]...)
+			return template
 		}
 	}
 	return nil
@@ -144,9 +578,91 @@ func (s *Store) FindMemoByID(id string) *Memo {
 // AddTask adds a task to the store
 func (s *Store) AddTask(task *Task) {
 	s.Tasks = append(s.Tasks, task)
+	if s.tasksByID != nil {
+		s.indexTask(task)
+	}
 }
 
 // AddMemo adds a memo to the store
 func (s *Store) AddMemo(memo *Memo) {
 	s.Memos = append(s.Memos, memo)
+	if s.memosByID != nil {
+		if _, ok := s.memosByID[memo.ID]; !ok {
+			s.memosByID[memo.ID] = memo
+		}
+	}
+}
+
+// RemoveTask removes the task with the given ID from the store and moves it to the trash.
+// It returns the removed task, or nil if there is no such task.
+func (s *Store) RemoveTask(id string) *Task {
+	for i, task := range s.Tasks {
+		if task.ID == id {
+			// Remove task from slice
+			s.Tasks = append(s.Tasks[:i], s.Tasks[i+1:]...)
+			if s.tasksByID != nil {
+				s.unindexTask(task)
+			}
+
+			// Keep the task in the trash
+			s.Trash.Tasks = append(s.Trash.Tasks, &TrashedTask{
+				Task:      *task,
+				DeletedAt: CustomTime{Time: time.Now().UTC()},
+			})
+			return task
+		}
+	}
+	return nil
+}
+
+// RemoveMemo removes the memo with the given ID from the store and moves it to the trash,
+// then removes the references to it from the tasks, including archived ones.
+// It returns the number of references removed.
+func (s *Store) RemoveMemo(id string) (refsRemoved int) {
+	referencing := s.TasksReferencingMemo(id)
+	for _, task := range s.ArchivedTasks {
+		for _, memoID := range task.MemoRefs {
+			if memoID == id {
+				referencing = append(referencing, task)
+				break
+			}
+		}
+	}
+
+	for i, memo := range s.Memos {
+		if memo.ID == id {
+			// Remove memo from slice
+			s.Memos = append(s.Memos[:i], s.Memos[i+1:]...)
+			if s.memosByID != nil && s.memosByID[id] == memo {
+				delete(s.memosByID, id)
+			}
+
+			// Keep the memo in the trash, remembering which tasks referenced it
+			var refTaskIDs []string
+			for _, task := range referencing {
+				refTaskIDs = append(refTaskIDs, task.ID)
+			}
+			s.Trash.Memos = append(s.Trash.Memos, &TrashedMemo{
+				Memo:       *memo,
+				RefTaskIDs: refTaskIDs,
+				DeletedAt:  CustomTime{Time: time.Now().UTC()},
+			})
+			break
+		}
+	}
+
+	// Also remove references to this memo from all tasks, including duplicated ones
+	for _, task := range referencing {
+		refs := task.MemoRefs[:0]
+		for _, memoID := range task.MemoRefs {
+			if memoID == id {
+				refsRemoved++
+			} else {
+				refs = append(refs, memoID)
+			}
+		}
+		task.MemoRefs = refs
+	}
+
+	return refsRemoved
 }
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -96,8 +98,8 @@ func TestNewMemo(t *testing.T) {
 func TestNewStore(t *testing.T) {
 	store := NewStore()
 
-	if store.Version != 1 {
-		t.Errorf("Expected store version to be 1, got %d", store.Version)
+	if store.Version != CurrentVersion {
+		t.Errorf("Expected store version to be %d, got %d", CurrentVersion, store.Version)
 	}
 
 	if len(store.Tasks) != 0 {
@@ -248,3 +250,555 @@ func TestStore_GetMinTaskOrder(t *testing.T) {
 		t.Errorf("Expected min order to be 1.0, got %f", minOrder)
 	}
 }
+
+func TestStore_FindLatestTaskWithTag(t *testing.T) {
+	store := NewStore()
+
+	// Empty store
+	if task := store.FindLatestTaskWithTag("backend"); task != nil {
+		t.Errorf("Expected not to find task in empty store, got %v", task)
+	}
+
+	older := NewTask(uuid.New().String(), "Older Task", "", nil)
+	older.Tags = []string{"backend"}
+	older.CreatedAt = CustomTime{Time: time.Now().Add(-time.Hour).UTC()}
+	newer := NewTask(uuid.New().String(), "Newer Task", "", nil)
+	newer.Tags = []string{"ops", "backend"}
+	other := NewTask(uuid.New().String(), "Other Task", "", nil)
+	other.Tags = []string{"frontend"}
+
+	store.AddTask(newer)
+	store.AddTask(older)
+	store.AddTask(other)
+
+	task := store.FindLatestTaskWithTag("backend")
+	if task == nil || task.ID != newer.ID {
+		t.Errorf("Expected to find the newer task, got %v", task)
+	}
+
+	if task := store.FindLatestTaskWithTag("unknown"); task != nil {
+		t.Errorf("Expected not to find task, got %v", task)
+	}
+}
+
+func TestStore_IncompleteDependencies(t *testing.T) {
+	store := NewStore()
+
+	pending := NewTask(uuid.New().String(), "Pending", "", nil)
+	finished := NewTask(uuid.New().String(), "Finished", "", nil)
+	finished.MarkDone()
+	task := NewTask(uuid.New().String(), "Task", "", nil)
+	task.DependsOn = []string{pending.ID, finished.ID, "missing"}
+	store.AddTask(pending)
+	store.AddTask(finished)
+	store.AddTask(task)
+
+	deps := store.IncompleteDependencies(task)
+	if len(deps) != 1 || deps[0].ID != pending.ID {
+		t.Errorf("Expected only the pending task, got %v", deps)
+	}
+
+	pending.MarkDone()
+	if deps := store.IncompleteDependencies(task); len(deps) != 0 {
+		t.Errorf("Expected no incomplete dependencies, got %v", deps)
+	}
+}
+
+func TestStore_TasksDependingOn(t *testing.T) {
+	store := NewStore()
+
+	dep := NewTask(uuid.New().String(), "Dependency", "", nil)
+	first := NewTask(uuid.New().String(), "First", "", nil)
+	first.DependsOn = []string{dep.ID}
+	other := NewTask(uuid.New().String(), "Other", "", nil)
+	second := NewTask(uuid.New().String(), "Second", "", nil)
+	second.DependsOn = []string{"missing", dep.ID}
+	for _, task := range []*Task{dep, first, other, second} {
+		store.AddTask(task)
+	}
+
+	dependents := store.TasksDependingOn(dep.ID)
+	if len(dependents) != 2 || dependents[0] != first || dependents[1] != second {
+		t.Errorf("Expected First and Second, got %v", dependents)
+	}
+	if dependents := store.TasksDependingOn(other.ID); len(dependents) != 0 {
+		t.Errorf("Expected no dependents, got %v", dependents)
+	}
+}
+
+func TestStore_Subtasks(t *testing.T) {
+	store := NewStore()
+
+	parent := NewTask(uuid.New().String(), "Parent", "", nil)
+	second := NewTask(uuid.New().String(), "Second", "", nil)
+	second.ParentID = parent.ID
+	second.Order = 2
+	first := NewTask(uuid.New().String(), "First", "", nil)
+	first.ParentID = parent.ID
+	first.Order = 1
+	grandchild := NewTask(uuid.New().String(), "Grandchild", "", nil)
+	grandchild.ParentID = first.ID
+	for _, task := range []*Task{parent, second, first, grandchild} {
+		store.AddTask(task)
+	}
+
+	children := store.ChildTasks(parent.ID)
+	if len(children) != 2 || children[0] != first || children[1] != second {
+		t.Errorf("Expected First and Second, got %v", children)
+	}
+	descendants := store.DescendantTasks(parent.ID)
+	if len(descendants) != 3 || descendants[0] != first || descendants[1] != grandchild || descendants[2] != second {
+		t.Errorf("Expected First, Grandchild and Second, got %v", descendants)
+	}
+
+	// Test cycle detection
+	if !store.WouldCreateParentCycle(parent.ID, grandchild.ID) {
+		t.Errorf("Expected a task under its grandchild to be a cycle")
+	}
+	if !store.WouldCreateParentCycle(parent.ID, parent.ID) {
+		t.Errorf("Expected a task under itself to be a cycle")
+	}
+	if store.WouldCreateParentCycle(grandchild.ID, second.ID) {
+		t.Errorf("Expected a task under its uncle not to be a cycle")
+	}
+}
+
+func TestStore_IsBlocked(t *testing.T) {
+	store := NewStore()
+
+	first := NewTask(uuid.New().String(), "First", "", nil)
+	second := NewTask(uuid.New().String(), "Second", "", nil)
+	second.DependsOn = []string{first.ID}
+	third := NewTask(uuid.New().String(), "Third", "", nil)
+	third.DependsOn = []string{second.ID, "missing"}
+	for _, task := range []*Task{first, second, third} {
+		store.AddTask(task)
+	}
+
+	if store.IsBlocked(first) || !store.IsBlocked(second) || !store.IsBlocked(third) {
+		t.Errorf("Expected Second and Third to be blocked")
+	}
+	first.MarkDone()
+	if store.IsBlocked(second) {
+		t.Errorf("Expected Second to be unblocked once First is done")
+	}
+
+	if !store.DependsOnTransitively(third.ID, first.ID) {
+		t.Errorf("Expected Third to depend on First through Second")
+	}
+	if store.DependsOnTransitively(first.ID, third.ID) {
+		t.Errorf("Expected First not to depend on Third")
+	}
+
+	if !store.WouldCreateDependencyCycle(first.ID, third.ID) || !store.WouldCreateDependencyCycle(first.ID, first.ID) {
+		t.Errorf("Expected First depending on Third or on itself to create a cycle")
+	}
+	if store.WouldCreateDependencyCycle(third.ID, first.ID) {
+		t.Errorf("Expected Third depending on First not to create a cycle")
+	}
+}
+
+func TestStore_PendingCount(t *testing.T) {
+	store := NewStore()
+	if count := store.PendingCount(); count != 0 {
+		t.Errorf("Expected 0 pending tasks in an empty store, got %d", count)
+	}
+
+	finished := NewTask(uuid.New().String(), "Finished", "", nil)
+	finished.MarkDone()
+	store.AddTask(finished)
+	store.AddTask(NewTask(uuid.New().String(), "Pending", "", nil))
+	if count := store.PendingCount(); count != 1 {
+		t.Errorf("Expected 1 pending task, got %d", count)
+	}
+}
+
+func TestStore_OrphanMemos(t *testing.T) {
+	store := NewStore()
+
+	// Empty store
+	if memos := store.OrphanMemos(); len(memos) != 0 {
+		t.Errorf("Expected no orphan memos in empty store, got %d", len(memos))
+	}
+
+	title := "Memo"
+	referenced := NewMemo(uuid.New().String(), &title, "Referenced")
+	orphan := NewMemo(uuid.New().String(), &title, "Orphan")
+	store.AddMemo(referenced)
+	store.AddMemo(orphan)
+	store.AddTask(NewTask(uuid.New().String(), "Task", "", []string{referenced.ID}))
+
+	memos := store.OrphanMemos()
+	if len(memos) != 1 || memos[0].ID != orphan.ID {
+		t.Errorf("Expected only the orphan memo, got %v", memos)
+	}
+}
+
+func TestTask_SetDone(t *testing.T) {
+	task := NewTask(uuid.New().String(), "Task", "", nil)
+
+	task.SetDone(true)
+	if !task.Done || task.CompletedAt == nil {
+		t.Fatalf("Expected task to be done with a completion time")
+	}
+	completedAt := task.CompletedAt.Time
+
+	// Marking a done task as done again keeps the original completion time
+	task.SetDone(true)
+	if !task.CompletedAt.Equal(completedAt) {
+		t.Errorf("Expected completion time to be kept, got %v", task.CompletedAt)
+	}
+
+	task.SetDone(false)
+	if task.Done || task.CompletedAt != nil {
+		t.Errorf("Expected task to be undone without a completion time")
+	}
+}
+
+func TestStore_RemoveAndReferences(t *testing.T) {
+	store := NewStore()
+	memo := NewMemo(uuid.New().String(), nil, "Memo")
+	store.AddMemo(memo)
+	task1 := NewTask(uuid.New().String(), "Task 1", "", []string{memo.ID, memo.ID})
+	store.AddTask(task1)
+
+	// Look up once so that tasks added later must update the index
+	if found := store.FindTaskByID(task1.ID); found != task1 {
+		t.Fatalf("Expected to find task 1, got %v", found)
+	}
+	task2 := NewTask(uuid.New().String(), "Task 2", "", []string{memo.ID})
+	store.AddTask(task2)
+
+	// Test finding referencing tasks, each listed once
+	refs := store.TasksReferencingMemo(memo.ID)
+	if len(refs) != 2 || refs[0] != task1 || refs[1] != task2 {
+		t.Errorf("Expected tasks 1 and 2 to reference the memo, got %v", refs)
+	}
+
+	// Test removing a task
+	if removed := store.RemoveTask(task1.ID); removed != task1 {
+		t.Errorf("Expected task 1 to be removed, got %v", removed)
+	}
+	if store.FindTaskByID(task1.ID) != nil || len(store.Tasks) != 1 {
+		t.Errorf("Expected task 1 to be gone")
+	}
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 1 || refs[0] != task2 {
+		t.Errorf("Expected only task 2 to reference the memo, got %v", refs)
+	}
+	if removed := store.RemoveTask(task1.ID); removed != nil {
+		t.Errorf("Expected nothing to be removed twice, got %v", removed)
+	}
+
+	// Test that references removed directly are not reported
+	task2.MemoRefs = []string{}
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 0 {
+		t.Errorf("Expected no referencing tasks, got %v", refs)
+	}
+
+	// Test removing a memo
+	if removed := store.RemoveMemo(memo.ID); removed != 0 {
+		t.Errorf("Expected no references to be removed, got %d", removed)
+	}
+	if store.FindMemoByID(memo.ID) != nil || len(store.Memos) != 0 {
+		t.Errorf("Expected memo to be gone")
+	}
+
+	// Test that the indexes are not serialized
+	data, err := json.Marshal(store)
+	if err != nil {
+		t.Fatalf("Failed to marshal store: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal store: %v", err)
+	}
+	if len(fields) != 4 {
+		t.Errorf("Expected only version, tasks, memos, and trash to be serialized, got %s", data)
+	}
+}
+
+func TestStore_MemoRefChanges(t *testing.T) {
+	store := NewStore()
+	memo := NewMemo(uuid.New().String(), nil, "Memo")
+	store.AddMemo(memo)
+	task1 := NewTask(uuid.New().String(), "Task 1", "", nil)
+	task2 := NewTask(uuid.New().String(), "Task 2", "", nil)
+	store.AddTask(task1)
+	store.AddTask(task2)
+
+	// Build the index before changing the references
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 0 {
+		t.Fatalf("Expected no referencing tasks, got %v", refs)
+	}
+
+	// Test adding references, in the order of Tasks
+	if !store.AddMemoRef(task2, memo.ID) || !store.AddMemoRef(task1, memo.ID) {
+		t.Errorf("Expected the references to be added")
+	}
+	if store.AddMemoRef(task1, memo.ID) {
+		t.Errorf("Expected a duplicated reference not to be added")
+	}
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 2 || refs[0] != task1 || refs[1] != task2 {
+		t.Errorf("Expected tasks 1 and 2 to reference the memo, got %v", refs)
+	}
+
+	// Test removing and setting references
+	if !store.RemoveMemoRef(task1, memo.ID) || store.RemoveMemoRef(task1, memo.ID) {
+		t.Errorf("Expected the reference to be removed once")
+	}
+	store.SetMemoRefs(task2, nil)
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 0 {
+		t.Errorf("Expected no referencing tasks, got %v", refs)
+	}
+	store.SetMemoRefs(task1, []string{memo.ID, memo.ID})
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 1 || refs[0] != task1 || len(task1.MemoRefs) != 1 {
+		t.Errorf("Expected task 1 to reference the memo once, got %v", refs)
+	}
+
+	// Test replacing a task
+	replacement := *task2
+	replacement.MemoRefs = []string{memo.ID}
+	store.ReplaceTask(task2, &replacement)
+	if refs := store.TasksReferencingMemo(memo.ID); len(refs) != 2 || refs[1] != task2 {
+		t.Errorf("Expected the replaced task to reference the memo, got %v", refs)
+	}
+}
+
+func TestStore_RemoveTask(t *testing.T) {
+	store := NewStore()
+	task := NewTask(uuid.New().String(), "Task", "", nil)
+	store.AddTask(task)
+
+	// Test that the removed task is moved to the trash
+	if removed := store.RemoveTask(task.ID); removed != task {
+		t.Fatalf("Expected task to be removed, got %v", removed)
+	}
+	if len(store.Tasks) != 0 {
+		t.Errorf("Expected no tasks, got %d", len(store.Tasks))
+	}
+	if len(store.Trash.Tasks) != 1 || store.Trash.Tasks[0].ID != task.ID {
+		t.Errorf("Expected the task in the trash, got %v", store.Trash.Tasks)
+	} else if store.Trash.Tasks[0].DeletedAt.IsZero() {
+		t.Errorf("Expected deletion time to be set")
+	}
+}
+
+func TestStore_RemoveMemo(t *testing.T) {
+	store := NewStore()
+	memo := NewMemo(uuid.New().String(), nil, "Memo")
+	other := NewMemo(uuid.New().String(), nil, "Other")
+	store.AddMemo(memo)
+	store.AddMemo(other)
+	task1 := NewTask(uuid.New().String(), "Task 1", "", []string{memo.ID, other.ID})
+	task2 := NewTask(uuid.New().String(), "Task 2", "", []string{memo.ID})
+	task3 := NewTask(uuid.New().String(), "Task 3", "", []string{other.ID})
+	store.AddTask(task1)
+	store.AddTask(task2)
+	store.AddTask(task3)
+
+	// Test that references are removed and the memo is moved to the trash
+	if removed := store.RemoveMemo(memo.ID); removed != 2 {
+		t.Errorf("Expected 2 references to be removed, got %d", removed)
+	}
+	if store.FindMemoByID(memo.ID) != nil {
+		t.Errorf("Expected memo to be gone")
+	}
+	if len(task1.MemoRefs) != 1 || task1.MemoRefs[0] != other.ID || len(task2.MemoRefs) != 0 || len(task3.MemoRefs) != 1 {
+		t.Errorf("Expected only references to the memo to be removed, got %v, %v, %v", task1.MemoRefs, task2.MemoRefs, task3.MemoRefs)
+	}
+	if len(store.Trash.Memos) != 1 {
+		t.Fatalf("Expected the memo in the trash, got %d trashed memos", len(store.Trash.Memos))
+	}
+	trashed := store.Trash.Memos[0]
+	if trashed.ID != memo.ID || len(trashed.RefTaskIDs) != 2 || trashed.RefTaskIDs[0] != task1.ID || trashed.RefTaskIDs[1] != task2.ID {
+		t.Errorf("Expected the trashed memo to remember tasks 1 and 2, got %v", trashed.RefTaskIDs)
+	}
+
+	// Test removing a memo that doesn't exist
+	if removed := store.RemoveMemo("nonexistent"); removed != 0 {
+		t.Errorf("Expected no references to be removed, got %d", removed)
+	}
+	if len(store.Trash.Memos) != 1 {
+		t.Errorf("Expected nothing more in the trash, got %d trashed memos", len(store.Trash.Memos))
+	}
+}
+
+func TestStore_RemoveMemoDuplicateRefs(t *testing.T) {
+	store := NewStore()
+	memo := NewMemo(uuid.New().String(), nil, "Memo")
+	other := NewMemo(uuid.New().String(), nil, "Other")
+	store.AddMemo(memo)
+	store.AddMemo(other)
+
+	// Duplicated references can be left in data files by earlier versions
+	task := NewTask(uuid.New().String(), "Task", "", nil)
+	task.MemoRefs = []string{memo.ID, other.ID, memo.ID}
+	store.AddTask(task)
+
+	// Test that every occurrence is removed
+	if removed := store.RemoveMemo(memo.ID); removed != 2 {
+		t.Errorf("Expected 2 references to be removed, got %d", removed)
+	}
+	if len(task.MemoRefs) != 1 || task.MemoRefs[0] != other.ID {
+		t.Errorf("Expected only the other memo to be referenced, got %v", task.MemoRefs)
+	}
+}
+
+func TestTask_SetMemoRefs(t *testing.T) {
+	// Test that NewTask drops duplicates
+	task := NewTask(uuid.New().String(), "Task", "", []string{"a", "b", "a", "c", "b"})
+	if got := strings.Join(task.MemoRefs, ","); got != "a,b,c" {
+		t.Errorf("Expected references a,b,c, got %s", got)
+	}
+
+	// Test that SetMemoRefs drops duplicates
+	task.SetMemoRefs([]string{"c", "c", "a"})
+	if got := strings.Join(task.MemoRefs, ","); got != "c,a" {
+		t.Errorf("Expected references c,a, got %s", got)
+	}
+}
+
+func TestTask_MarkDone(t *testing.T) {
+	task := NewTask(uuid.New().String(), "Task", "", nil)
+	past := CustomTime{Time: time.Now().UTC().Add(-time.Hour)}
+	task.UpdatedAt = past
+
+	// Test marking as done
+	task.MarkDone()
+	if !task.Done || task.CompletedAt == nil {
+		t.Errorf("Expected task to be done with a completion time")
+	}
+	if !task.UpdatedAt.After(past.Time) {
+		t.Errorf("Expected update time to be bumped")
+	}
+
+	// Test marking as not done
+	task.UpdatedAt = past
+	task.MarkUndone()
+	if task.Done || task.CompletedAt != nil {
+		t.Errorf("Expected task to be not done without a completion time")
+	}
+	if !task.UpdatedAt.After(past.Time) {
+		t.Errorf("Expected update time to be bumped")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	past := CustomTime{Time: time.Now().UTC().Add(-time.Hour)}
+
+	task := NewTask(uuid.New().String(), "Task", "", nil)
+	task.UpdatedAt = past
+	task.Touch()
+	if !task.UpdatedAt.After(past.Time) || task.CreatedAt.After(task.UpdatedAt.Time) {
+		t.Errorf("Expected task update time to be now, got %v", task.UpdatedAt)
+	}
+
+	memo := NewMemo(uuid.New().String(), nil, "Memo")
+	memo.UpdatedAt = past
+	memo.Touch()
+	if !memo.UpdatedAt.After(past.Time) {
+		t.Errorf("Expected memo update time to be now, got %v", memo.UpdatedAt)
+	}
+}
+
+// newBenchmarkStore creates a store with n memos and n tasks, each referencing a memo
+func newBenchmarkStore(n int) *Store {
+	store := NewStore()
+	for i := 0; i < n; i++ {
+		memo := NewMemo(uuid.New().String(), nil, "Memo")
+		store.AddMemo(memo)
+		store.AddTask(NewTask(uuid.New().String(), "Task", "", []string{memo.ID}))
+	}
+	return store
+}
+
+func BenchmarkStore_FindMemoByID(b *testing.B) {
+	store := newBenchmarkStore(10000)
+	store.FindMemoByID("")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.FindMemoByID(store.Memos[i%len(store.Memos)].ID)
+	}
+}
+
+func BenchmarkStore_FindMemoByIDLinear(b *testing.B) {
+	store := newBenchmarkStore(10000)
+
+	// The linear scan FindMemoByID did before the index
+	find := func(id string) *Memo {
+		for _, memo := range store.Memos {
+			if memo.ID == id {
+				return memo
+			}
+		}
+		return nil
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		find(store.Memos[i%len(store.Memos)].ID)
+	}
+}
+
+func BenchmarkStore_TasksReferencingMemo(b *testing.B) {
+	store := newBenchmarkStore(10000)
+	store.TasksReferencingMemo("")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		store.TasksReferencingMemo(store.Memos[i%len(store.Memos)].ID)
+	}
+}
+
+func BenchmarkStore_TasksReferencingMemoLinear(b *testing.B) {
+	store := newBenchmarkStore(10000)
+
+	// The linear scan used before the index
+	find := func(memoID string) []*Task {
+		var tasks []*Task
+		for _, task := range store.Tasks {
+			for _, ref := range task.MemoRefs {
+				if ref == memoID {
+					tasks = append(tasks, task)
+					break
+				}
+			}
+		}
+		return tasks
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		find(store.Memos[i%len(store.Memos)].ID)
+	}
+}
+
+func TestStore_Templates(t *testing.T) {
+	store := NewStore()
+	memo := NewMemo(uuid.New().String(), nil, "Shared")
+	orphan := NewMemo(uuid.New().String(), nil, "Orphan")
+	store.AddMemo(memo)
+	store.AddMemo(orphan)
+	store.Templates = []*TaskTemplate{
+		{Name: "a", Memos: []TemplateMemo{{MemoID: memo.ID}}},
+		{Name: "b"},
+	}
+
+	if template := store.FindTemplate("b"); template == nil || template.Name != "b" {
+		t.Errorf("Expected template b, got %v", template)
+	}
+	if orphans := store.OrphanMemos(); len(orphans) != 1 || orphans[0] != orphan {
+		t.Errorf("Expected memos shared by templates not to be orphans, got %v", orphans)
+	}
+
+	if removed := store.RemoveTemplate("a"); removed == nil || removed.Name != "a" {
+		t.Errorf("Expected template a to be removed, got %v", removed)
+	}
+	if store.FindTemplate("a") != nil || len(store.Templates) != 1 {
+		t.Errorf("Expected only template b to be left, got %v", store.Templates)
+	}
+	if store.RemoveTemplate("a") != nil {
+		t.Errorf("Expected nothing to be removed")
+	}
+}
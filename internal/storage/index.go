@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/zishida/tamo/internal/model"
+)
+
+const (
+	// IndexFileName is the name of the search index file in the data directory
+	IndexFileName = "index.json"
+	// indexVersion is the version of the search index format
+	indexVersion = 2
+)
+
+// SearchIndex is an inverted index of the words in the titles, descriptions, and contents of
+// tasks and memos. It only narrows down the items to search: the items it returns must still
+// be matched against the keyword.
+type SearchIndex struct {
+	Version  int                   `json:"version"`
+	Revision DataRevision          `json:"revision"` // The data file the index was saved for
+	Entries  map[string]IndexEntry `json:"entries"`  // By task or memo ID
+
+	// postings maps each word to the IDs of the items containing it, built on demand
+	postings map[string][]string
+}
+
+// IndexEntry holds the words of a task or memo, and a hash of the text they were collected from.
+// Update times are stored in seconds, so they can't tell whether an item changed since it was indexed.
+type IndexEntry struct {
+	Hash  string   `json:"hash"`
+	Words []string `json:"words"`
+}
+
+// DataRevision identifies a version of the data file by its size and modification time,
+// so that the index can be checked against it without reading the items
+type DataRevision struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"` // Nanoseconds since the Unix epoch
+}
+
+// DataRevision returns the revision of the data file as it is now
+func (s *Storage) DataRevision() (DataRevision, error) {
+	info, err := os.Stat(s.FilePath)
+	if err != nil {
+		return DataRevision{}, fmt.Errorf("failed to read data file: %w", err)
+	}
+	return DataRevision{Size: info.Size(), ModTime: info.ModTime().UnixNano()}, nil
+}
+
+// IndexPath returns the path of the search index file
+func (s *Storage) IndexPath() string {
+	return filepath.Join(s.DirPath, IndexFileName)
+}
+
+// BuildIndex builds the search index of all tasks and memos in the store
+func BuildIndex(store *model.Store) *SearchIndex {
+	idx := &SearchIndex{Version: indexVersion, Entries: make(map[string]IndexEntry)}
+	idx.Update(store)
+	return idx
+}
+
+// Update brings the index up to date with the store, collecting the words of the items
+// changed since they were indexed and dropping the items that no longer exist
+func (idx *SearchIndex) Update(store *model.Store) {
+	seen := make(map[string]bool, len(store.Tasks)+len(store.Memos))
+	update := func(id, title, text string) {
+		seen[id] = true
+		hash := indexHash(title, text)
+		if entry, ok := idx.Entries[id]; ok && entry.Hash == hash {
+			return
+		}
+		idx.Entries[id] = IndexEntry{Hash: hash, Words: indexWords(title, text)}
+	}
+
+	for _, task := range store.Tasks {
+		update(task.ID, task.Title, task.Description)
+	}
+	for _, memo := range store.Memos {
+		update(memo.ID, memoIndexTitle(memo), memo.Content)
+	}
+
+	for id := range idx.Entries {
+		if !seen[id] {
+			delete(idx.Entries, id)
+		}
+	}
+	idx.postings = nil
+}
+
+// Fresh reports whether the index was saved for the data file at the given revision. Every save
+// updates the index, so the index only goes stale when the data file is changed by other means.
+func (idx *SearchIndex) Fresh(revision DataRevision) bool {
+	return idx.Revision == revision
+}
+
+// Candidates returns the IDs of the items that may contain the keyword, ignoring case: those with
+// a word containing each word of the keyword. It returns false if the keyword has no words,
+// in which case the index can't narrow down the items.
+func (idx *SearchIndex) Candidates(keyword string) (map[string]bool, bool) {
+	keywordWords := indexWords(keyword)
+	if len(keywordWords) == 0 {
+		return nil, false
+	}
+
+	if idx.postings == nil {
+		idx.postings = make(map[string][]string)
+		for id, entry := range idx.Entries {
+			for _, word := range entry.Words {
+				idx.postings[word] = append(idx.postings[word], id)
+			}
+		}
+	}
+
+	var candidates map[string]bool
+	for _, keywordWord := range keywordWords {
+		// An occurrence of the keyword puts each of its words inside a word of the text
+		matches := make(map[string]bool)
+		for word, ids := range idx.postings {
+			if strings.Contains(word, keywordWord) {
+				for _, id := range ids {
+					if candidates == nil || candidates[id] {
+						matches[id] = true
+					}
+				}
+			}
+		}
+		candidates = matches
+	}
+	return candidates, true
+}
+
+// LoadIndex reads the search index file. It returns os.ErrNotExist if the index has not been built.
+func (s *Storage) LoadIndex() (*SearchIndex, error) {
+	data, err := ioutil.ReadFile(s.IndexPath())
+	if err != nil {
+		return nil, err
+	}
+
+	var idx SearchIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse search index: %w", err)
+	}
+	if idx.Version != indexVersion || idx.Entries == nil {
+		return nil, fmt.Errorf("unsupported search index version: %d", idx.Version)
+	}
+	return &idx, nil
+}
+
+// SaveIndex writes the search index file for the data file as it is now
+func (s *Storage) SaveIndex(idx *SearchIndex) error {
+	revision, err := s.DataRevision()
+	if err != nil {
+		return err
+	}
+	idx.Revision = revision
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("failed to marshal search index: %w", err)
+	}
+	if err := WriteFileAtomic(s.IndexPath(), data); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	return nil
+}
+
+// updateIndex brings the search index up to date with the saved store, if the index has been built.
+// An index that can't be read or written is removed, so that search falls back to a full scan
+// until it is rebuilt.
+func (s *Storage) updateIndex(store *model.Store) {
+	idx, err := s.LoadIndex()
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		idx.Update(store)
+		err = s.SaveIndex(idx)
+	}
+	if err != nil {
+		os.Remove(s.IndexPath())
+	}
+}
+
+// memoIndexTitle returns the title of a memo, or an empty string if it has none
+func memoIndexTitle(memo *model.Memo) string {
+	if memo.Title == nil {
+		return ""
+	}
+	return *memo.Title
+}
+
+// indexHash returns a hash of the title and text of an item
+func indexHash(title, text string) string {
+	h := fnv.New64a()
+	h.Write([]byte(title))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// indexWords returns the distinct words in the texts, folded to lower case.
+// A word is a run of letters, digits, and underscores.
+func indexWords(texts ...string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, text := range texts {
+		for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}) {
+			word = foldWord(word)
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
+
+// foldWord folds the case of a word so that words differing only in case, including
+// letters such as the Kelvin sign that lower-case to a different letter, compare equal
+func foldWord(word string) string {
+	return strings.ToLower(strings.ToUpper(word))
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/zishida/tamo/internal/model"
+)
+
+// migrations maps a data file version to the function that migrates a store
+// of that version to the next one. Add an entry here when bumping model.CurrentVersion.
+var migrations = map[int]func(*model.Store) error{
+	1: migrateV1toV2,
+}
+
+// CheckVersion returns an error if a data file of the given version can't be handled by this build
+func CheckVersion(version int) error {
+	if version > model.CurrentVersion {
+		return fmt.Errorf("data file version %d was created by a newer tamo (this tamo supports up to version %d), please upgrade tamo", version, model.CurrentVersion)
+	}
+	return nil
+}
+
+// Migrate upgrades the store to the current version by running the migrations in order,
+// and reports whether anything was migrated. Stores without a version are treated as version 1.
+func Migrate(store *model.Store) (bool, error) {
+	if err := CheckVersion(store.Version); err != nil {
+		return false, err
+	}
+	if store.Version == model.CurrentVersion {
+		return false, nil
+	}
+
+	if store.Version < 1 {
+		store.Version = 1
+	}
+	for store.Version < model.CurrentVersion {
+		migrate, ok := migrations[store.Version]
+		if !ok {
+			return false, fmt.Errorf("no migration from data file version %d", store.Version)
+		}
+		if err := migrate(store); err != nil {
+			return false, fmt.Errorf("failed to migrate data file from version %d: %w", store.Version, err)
+		}
+		store.Version++
+	}
+
+	return true, nil
+}
+
+// migrateV1toV2 records the completion time of done tasks, which version 1 didn't have.
+// The last update time is the best guess for when the task was completed.
+func migrateV1toV2(store *model.Store) error {
+	backfill := func(task *model.Task) {
+		if task.Done && task.CompletedAt == nil {
+			completedAt := task.UpdatedAt
+			task.CompletedAt = &completedAt
+		}
+	}
+
+	for _, task := range store.Tasks {
+		backfill(task)
+	}
+	for _, task := range store.ArchivedTasks {
+		backfill(task)
+	}
+	for _, task := range store.Trash.Tasks {
+		backfill(&task.Task)
+	}
+
+	return nil
+}
@@ -2,10 +2,13 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/zishida/tamo/internal/model"
@@ -16,27 +19,40 @@ const (
 	DefaultDirName = ".tamo"
 	// DefaultFileName is the default file name for tamo data
 	DefaultFileName = "data.json"
+	// TempFilePattern is the file name pattern of temporary files written by Save
+	TempFilePattern = "data.*.json.tmp"
+	// StaleTempFileAge is how old a leftover temporary file must be before it is removed
+	StaleTempFileAge = time.Hour
+	// BackupDirName is the name of the directory in the data directory that holds backups
+	BackupDirName = "backups"
+	// DefaultMaxBackups is the default number of backups kept
+	DefaultMaxBackups = 10
+	// backupTimeFormat is the format of the timestamps in backup file names, which sort chronologically
+	backupTimeFormat = "20060102-150405.000000000"
 )
 
 // Storage handles the persistence of the store
 type Storage struct {
 	DirPath  string
 	FilePath string
+
+	// MaxBackups is the number of backups kept by Save
+	MaxBackups int
+	// NoBackup disables the backup of the data file by Save
+	NoBackup bool
 }
 
 // NewStorage creates a new storage with the default path
 func NewStorage() *Storage {
-	return &Storage{
-		DirPath:  DefaultDirName,
-		FilePath: filepath.Join(DefaultDirName, DefaultFileName),
-	}
+	return NewStorageWithPath(DefaultDirName, filepath.Join(DefaultDirName, DefaultFileName))
 }
 
 // NewStorageWithPath creates a new storage with the given path
 func NewStorageWithPath(dirPath, filePath string) *Storage {
 	return &Storage{
-		DirPath:  dirPath,
-		FilePath: filePath,
+		DirPath:    dirPath,
+		FilePath:   filePath,
+		MaxBackups: DefaultMaxBackups,
 	}
 }
 
@@ -68,7 +84,7 @@ func (s *Storage) Initialize() error {
 func (s *Storage) Load() (*model.Store, error) {
 	// Check if file exists
 	if _, err := os.Stat(s.FilePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("data file not found: %s", s.FilePath)
+		return nil, fmt.Errorf("%w: %s", ErrNotInitialized, s.FilePath)
 	}
 
 	// Read file
@@ -80,30 +96,75 @@ func (s *Storage) Load() (*model.Store, error) {
 	// Parse JSON
 	var store model.Store
 	if err := json.Unmarshal(data, &store); err != nil {
-		return nil, fmt.Errorf("failed to parse data file: %w", err)
+		if line, col, ok := ErrorPosition(data, err); ok {
+			return nil, fmt.Errorf("failed to parse data file at line %d, column %d: %w (run 'tamo doctor' to diagnose)", line, col, err)
+		}
+		return nil, fmt.Errorf("failed to parse data file: %w (run 'tamo doctor' to diagnose)", err)
 	}
 
 	// Fix time fields
 	for _, task := range store.Tasks {
 		if task.CreatedAt.IsZero() {
-			task.CreatedAt = model.CustomTime{time.Now().UTC()}
+			task.CreatedAt = model.CustomTime{Time: time.Now().UTC()}
 		}
 		if task.UpdatedAt.IsZero() {
-			task.UpdatedAt = model.CustomTime{time.Now().UTC()}
+			task.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
 		}
 	}
 	for _, memo := range store.Memos {
 		if memo.CreatedAt.IsZero() {
-			memo.CreatedAt = model.CustomTime{time.Now().UTC()}
+			memo.CreatedAt = model.CustomTime{Time: time.Now().UTC()}
 		}
 		if memo.UpdatedAt.IsZero() {
-			memo.UpdatedAt = model.CustomTime{time.Now().UTC()}
+			memo.UpdatedAt = model.CustomTime{Time: time.Now().UTC()}
+		}
+	}
+
+	// Migrate data files written by older versions, and save them in the current format.
+	// Save backs up the file first, so the original can be restored with 'tamo backup restore'.
+	migrated, err := Migrate(&store)
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		if err := s.Save(&store); err != nil {
+			return nil, fmt.Errorf("failed to save migrated data file: %w", err)
 		}
 	}
 
 	return &store, nil
 }
 
+// ErrorPosition returns the line and column in data at which a JSON decoding error occurred,
+// if the error records its position
+func ErrorPosition(data []byte, err error) (int, int, bool) {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return 0, 0, false
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	line, col := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col, true
+}
+
 // Save saves the store to the file atomically
 func (s *Storage) Save(store *model.Store) error {
 	// Marshal JSON
@@ -112,12 +173,20 @@ func (s *Storage) Save(store *model.Store) error {
 		return fmt.Errorf("failed to marshal data: %w", err)
 	}
 
+	// Remove temporary files left behind by interrupted saves
+	s.CleanupTempFiles(StaleTempFileAge)
+
 	// Create temporary file
-	tmpFile, err := ioutil.TempFile(s.DirPath, "data.*.json.tmp")
+	tmpFile, err := ioutil.TempFile(s.DirPath, TempFilePattern)
 	if err != nil {
 		return fmt.Errorf("failed to create temporary file: %w", err)
 	}
-	defer os.Remove(tmpFile.Name())
+	tmpPath := tmpFile.Name()
+	defer func() {
+		if tmpPath != "" {
+			os.Remove(tmpPath)
+		}
+	}()
 
 	// Write data to temporary file
 	if _, err := tmpFile.Write(data); err != nil {
@@ -130,14 +199,220 @@ func (s *Storage) Save(store *model.Store) error {
 		return fmt.Errorf("failed to close temporary file: %w", err)
 	}
 
+	// Back up the current data file
+	if !s.NoBackup {
+		if _, err := s.Backup(); err != nil {
+			return err
+		}
+	}
+
+	// Rename temporary file to target file (atomic operation)
+	if err := os.Rename(tmpPath, s.FilePath); err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+
+	// The temporary file is now the data file, so it must not be removed
+	tmpPath = ""
+
+	// Keep the search index in step with the data file
+	s.updateIndex(store)
+
+	return nil
+}
+
+// WriteFileAtomic writes data to the file at path through a temporary file in the same directory,
+// so that the file is either left as it was or completely written
+func WriteFileAtomic(path string, data []byte) error {
+	// Create temporary file
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		if tmpPath != "" {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// Write data to temporary file
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write to temporary file: %w", err)
+	}
+	if err := tmpFile.Chmod(0644); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
 	// Rename temporary file to target file (atomic operation)
-	if err := os.Rename(tmpFile.Name(), s.FilePath); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		return fmt.Errorf("failed to rename temporary file: %w", err)
 	}
+	tmpPath = ""
+
+	return nil
+}
+
+// CleanupTempFiles removes temporary files older than maxAge from the data directory
+// and returns the number of files removed. Newer files may belong to a save in progress.
+func (s *Storage) CleanupTempFiles(maxAge time.Duration) int {
+	matches, err := filepath.Glob(filepath.Join(s.DirPath, TempFilePattern))
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// BackupDir returns the directory that holds backups of the data file
+func (s *Storage) BackupDir() string {
+	return filepath.Join(s.DirPath, BackupDirName)
+}
+
+// Backup copies the data file to the backup directory and prunes the oldest backups,
+// returning the timestamp of the new backup. Nothing is done if the data file doesn't exist yet.
+func (s *Storage) Backup() (string, error) {
+	data, err := ioutil.ReadFile(s.FilePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read data file for backup: %w", err)
+	}
+
+	if err := os.MkdirAll(s.BackupDir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	timestamp := time.Now().UTC().Format(backupTimeFormat)
+	if err := ioutil.WriteFile(s.backupPath(timestamp), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	if err := s.pruneBackups(); err != nil {
+		return "", err
+	}
+	return timestamp, nil
+}
+
+// ListBackups returns the timestamps of the backups, oldest first
+func (s *Storage) ListBackups() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.BackupDir(), "data-*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	timestamps := make([]string, 0, len(matches))
+	for _, path := range matches {
+		name := filepath.Base(path)
+		timestamps = append(timestamps, strings.TrimSuffix(strings.TrimPrefix(name, "data-"), ".json"))
+	}
+	sort.Strings(timestamps)
+	return timestamps, nil
+}
+
+// BackupTime returns the time a backup was made from its timestamp
+func BackupTime(timestamp string) (time.Time, error) {
+	return time.Parse(backupTimeFormat, timestamp)
+}
+
+// RestoreBackup replaces the data file with the backup with the given timestamp or timestamp prefix,
+// backing up the current data file first. It returns the timestamp of the restored backup.
+func (s *Storage) RestoreBackup(timestamp string) (string, error) {
+	timestamps, err := s.ListBackups()
+	if err != nil {
+		return "", err
+	}
 
+	var matches []string
+	for _, ts := range timestamps {
+		if ts == timestamp {
+			matches = []string{ts}
+			break
+		}
+		if strings.HasPrefix(ts, timestamp) {
+			matches = append(matches, ts)
+		}
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no backup found with timestamp: %s", timestamp)
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf("timestamp %s matches %d backups, give more of it", timestamp, len(matches))
+	}
+
+	// Read the backup before backing up the current file, which may prune it
+	data, err := ioutil.ReadFile(s.backupPath(matches[0]))
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var store model.Store
+	if err := json.Unmarshal(data, &store); err != nil {
+		return "", fmt.Errorf("failed to parse backup: %w", err)
+	}
+
+	// Refuse backups from a newer tamo, which would be restored without their unknown fields
+	if err := CheckVersion(store.Version); err != nil {
+		return "", fmt.Errorf("cannot restore backup %s: %w", matches[0], err)
+	}
+
+	// Back up the current data file, even if backups are disabled for saves
+	if _, err := s.Backup(); err != nil {
+		return "", err
+	}
+
+	noBackup := s.NoBackup
+	s.NoBackup = true
+	defer func() {
+		s.NoBackup = noBackup
+	}()
+	if err := s.Save(&store); err != nil {
+		return "", err
+	}
+	return matches[0], nil
+}
+
+// backupPath returns the path of the backup with the given timestamp
+func (s *Storage) backupPath(timestamp string) string {
+	return filepath.Join(s.BackupDir(), "data-"+timestamp+".json")
+}
+
+// pruneBackups removes the oldest backups so that at most MaxBackups are kept
+func (s *Storage) pruneBackups() error {
+	timestamps, err := s.ListBackups()
+	if err != nil {
+		return err
+	}
+
+	for len(timestamps) > s.MaxBackups {
+		if err := os.Remove(s.backupPath(timestamps[0])); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		timestamps = timestamps[1:]
+	}
 	return nil
 }
 
+// ErrNotInitialized is returned when the data file doesn't exist, as before 'tamo init'
+var ErrNotInitialized = errors.New("data file not found")
+
 // Exists checks if the data file exists
 func (s *Storage) Exists() bool {
 	_, err := os.Stat(s.FilePath)
@@ -3,7 +3,9 @@ package storage
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/zishida/tamo/internal/model"
@@ -206,6 +208,185 @@ func TestStorage_Save(t *testing.T) {
 	}
 }
 
+func TestStorage_SaveCleansUpTempFiles(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a storage with custom paths
+	tamoDir := filepath.Join(tempDir, ".tamo")
+	dataFile := filepath.Join(tamoDir, "data.json")
+	storage := NewStorageWithPath(tamoDir, dataFile)
+
+	// Create the directory
+	if err := os.Mkdir(tamoDir, 0755); err != nil {
+		t.Fatalf("Failed to create .tamo dir: %v", err)
+	}
+
+	// Leave behind an old and a recent temporary file
+	staleFile := filepath.Join(tamoDir, "data.stale.json.tmp")
+	recentFile := filepath.Join(tamoDir, "data.recent.json.tmp")
+	for _, path := range []string{staleFile, recentFile} {
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatalf("Failed to create temporary file: %v", err)
+		}
+	}
+	oldTime := time.Now().Add(-2 * StaleTempFileAge)
+	if err := os.Chtimes(staleFile, oldTime, oldTime); err != nil {
+		t.Fatalf("Failed to change file time: %v", err)
+	}
+
+	// Save the store
+	if err := storage.Save(model.NewStore()); err != nil {
+		t.Fatalf("Failed to save store: %v", err)
+	}
+
+	// Check that only the stale file was removed
+	if _, err := os.Stat(staleFile); !os.IsNotExist(err) {
+		t.Errorf("Expected stale temporary file to be removed")
+	}
+	if _, err := os.Stat(recentFile); err != nil {
+		t.Errorf("Expected recent temporary file to be kept: %v", err)
+	}
+
+	// Check that the save itself left no temporary file and kept the data file
+	matches, err := filepath.Glob(filepath.Join(tamoDir, TempFilePattern))
+	if err != nil {
+		t.Fatalf("Failed to list temporary files: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("Expected only the recent temporary file to remain, got %v", matches)
+	}
+	if _, err := os.Stat(dataFile); err != nil {
+		t.Errorf("Expected data.json file to exist: %v", err)
+	}
+}
+
+func TestStorage_Backup(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a storage with custom paths
+	tamoDir := filepath.Join(tempDir, ".tamo")
+	dataFile := filepath.Join(tamoDir, "data.json")
+	storage := NewStorageWithPath(tamoDir, dataFile)
+	storage.MaxBackups = 3
+
+	// Initialize the storage, which has nothing to back up yet
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	backups, err := storage.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("Expected no backups after initialization, got %d", len(backups))
+	}
+
+	// Save stores with a growing number of tasks
+	store := model.NewStore()
+	for i := 0; i < 5; i++ {
+		task := model.NewTask(uuid.New().String(), "Task", "", nil)
+		store.AddTask(task)
+		if err := storage.Save(store); err != nil {
+			t.Fatalf("Failed to save store: %v", err)
+		}
+	}
+
+	// Check that only the most recent backups are kept
+	backups, err = storage.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("Expected 3 backups, got %d", len(backups))
+	}
+
+	// Restore the newest backup, which has 4 tasks
+	restored, err := storage.RestoreBackup(backups[2])
+	if err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+	if restored != backups[2] {
+		t.Errorf("Expected backup %s to be restored, got %s", backups[2], restored)
+	}
+	loadedStore, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	if len(loadedStore.Tasks) != 4 {
+		t.Errorf("Expected 4 tasks after restore, got %d", len(loadedStore.Tasks))
+	}
+
+	// Check that the restore backed up the file it replaced
+	backups, err = storage.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("Expected 3 backups, got %d", len(backups))
+	}
+	if _, err := storage.RestoreBackup(backups[2]); err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+	loadedStore, err = storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	if len(loadedStore.Tasks) != 5 {
+		t.Errorf("Expected 5 tasks after restoring the pre-restore backup, got %d", len(loadedStore.Tasks))
+	}
+
+	// Check that saving without backups doesn't add one
+	storage.NoBackup = true
+	before, err := storage.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if err := storage.Save(store); err != nil {
+		t.Fatalf("Failed to save store: %v", err)
+	}
+	after, err := storage.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if before[len(before)-1] != after[len(after)-1] {
+		t.Errorf("Expected no new backup with NoBackup")
+	}
+
+	// Check that unknown timestamps are rejected
+	if _, err := storage.RestoreBackup("19990101"); err == nil {
+		t.Errorf("Expected error for unknown backup, got nil")
+	}
+
+	// Check that a backup written by a newer tamo is not restored
+	newer, err := os.ReadFile(filepath.Join("testdata", "v99.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if err := os.WriteFile(storage.backupPath("29990101-000000.000000000"), newer, 0644); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	if _, err := storage.RestoreBackup("2999"); err == nil || !strings.Contains(err.Error(), "newer tamo") {
+		t.Errorf("Expected newer version error, got %v", err)
+	}
+	loadedStore, err = storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load store: %v", err)
+	}
+	if len(loadedStore.Tasks) != len(store.Tasks) {
+		t.Errorf("Expected the data file to be left as it is, got %d tasks", len(loadedStore.Tasks))
+	}
+}
+
 func TestStorage_Exists(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "tamo-test")
@@ -266,3 +447,249 @@ func TestStorage_EnsureDirectoryExists(t *testing.T) {
 		t.Fatalf("Failed to ensure directory exists when it already does: %v", err)
 	}
 }
+
+func TestStorage_LoadMigrates(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a storage with custom paths
+	tamoDir := filepath.Join(tempDir, ".tamo")
+	dataFile := filepath.Join(tamoDir, "data.json")
+	storage := NewStorageWithPath(tamoDir, dataFile)
+	if err := os.Mkdir(tamoDir, 0755); err != nil {
+		t.Fatalf("Failed to create .tamo dir: %v", err)
+	}
+
+	useFixture := func(name string) {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("Failed to read fixture %s: %v", name, err)
+		}
+		if err := os.WriteFile(dataFile, data, 0644); err != nil {
+			t.Fatalf("Failed to write data file: %v", err)
+		}
+	}
+
+	// Test loading a version 1 file, which has no completion times
+	useFixture("v1.json")
+	store, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load version 1 file: %v", err)
+	}
+	if store.Version != model.CurrentVersion {
+		t.Errorf("Expected version %d after migration, got %d", model.CurrentVersion, store.Version)
+	}
+	done, pending := store.Tasks[0], store.Tasks[1]
+	if done.CompletedAt == nil || !done.CompletedAt.Equal(done.UpdatedAt.Time) {
+		t.Errorf("Expected completion time of done task to be its update time, got %v", done.CompletedAt)
+	}
+	if pending.CompletedAt != nil {
+		t.Errorf("Expected no completion time for pending task, got %v", pending.CompletedAt)
+	}
+
+	// Check that the migrated file was saved, keeping a backup of the original
+	saved, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to reload migrated file: %v", err)
+	}
+	if saved.Version != model.CurrentVersion || saved.Tasks[0].CompletedAt == nil {
+		t.Errorf("Expected migrated data to be saved, got version %d", saved.Version)
+	}
+	backups, err := storage.ListBackups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup of the version 1 file, got %d", len(backups))
+	}
+	original, err := os.ReadFile(storage.backupPath(backups[0]))
+	if err != nil || !strings.Contains(string(original), `"version": 1,`) {
+		t.Errorf("Expected the backup to hold the version 1 file, got: %s (%v)", original, err)
+	}
+
+	// Test loading a version 2 file, which is left as it is
+	useFixture("v2.json")
+	store, err = storage.Load()
+	if err != nil {
+		t.Fatalf("Failed to load version 2 file: %v", err)
+	}
+	want := time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC)
+	if store.Tasks[0].CompletedAt == nil || !store.Tasks[0].CompletedAt.Equal(want) {
+		t.Errorf("Expected completion time %v, got %v", want, store.Tasks[0].CompletedAt)
+	}
+
+	// Test loading a file from a newer tamo
+	useFixture("v99.json")
+	if _, err := storage.Load(); err == nil || !strings.Contains(err.Error(), "newer tamo") {
+		t.Errorf("Expected newer version error, got %v", err)
+	}
+	data, err := os.ReadFile(dataFile)
+	if err != nil {
+		t.Fatalf("Failed to read data file: %v", err)
+	}
+	if !strings.Contains(string(data), "field_from_the_future") {
+		t.Errorf("Expected data file from a newer tamo to be left untouched")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Test writing a new file and replacing it
+	path := filepath.Join(tempDir, "out.md")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("Expected %q, got %q", content, data)
+		}
+	}
+
+	// Check that no temporary files are left
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the written file, got %d entries", len(entries))
+	}
+
+	// Test writing to a missing directory
+	if err := WriteFileAtomic(filepath.Join(tempDir, "missing", "out.md"), []byte("x")); err == nil {
+		t.Errorf("Expected error for missing directory, got nil")
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	store := model.NewStore()
+	title := "Release notes"
+	task := model.NewTask("task-1", "Fix the Parser", "Handle nested_fences in memo blocks", nil)
+	memo := model.NewMemo("memo-1", &title, "Mention the parser fix and ＡＰＩ changes")
+	store.AddTask(task)
+	store.AddMemo(memo)
+
+	idx := BuildIndex(store)
+
+	// Test candidates for keywords matching words, parts of words, and several words
+	for keyword, want := range map[string][]string{
+		"parser":       {"task-1", "memo-1"},
+		"PARS":         {"task-1", "memo-1"},
+		"nested_fence": {"task-1"},
+		"the parser":   {"task-1", "memo-1"},
+		"release fix":  {"memo-1"},
+		"ａｐｉ":          {"memo-1"},
+		"missing":      {},
+	} {
+		candidates, ok := idx.Candidates(keyword)
+		if !ok {
+			t.Fatalf("Expected candidates for %q", keyword)
+		}
+		if len(candidates) != len(want) {
+			t.Errorf("Expected %v for %q, got %v", want, keyword, candidates)
+		}
+		for _, id := range want {
+			if !candidates[id] {
+				t.Errorf("Expected %s to be a candidate for %q, got %v", id, keyword, candidates)
+			}
+		}
+	}
+	if _, ok := idx.Candidates("#!"); ok {
+		t.Errorf("Expected no candidates for a keyword without words")
+	}
+
+	// Test that updating the index picks up the changes to the store
+	task.Title = "Fix the lexer"
+	store.Memos = nil
+	idx.Update(store)
+	if candidates, _ := idx.Candidates("lexer"); !candidates["task-1"] {
+		t.Errorf("Expected the updated title to be indexed, got %v", candidates)
+	}
+	if _, ok := idx.Entries["memo-1"]; ok {
+		t.Errorf("Expected the removed memo to be dropped from the index")
+	}
+}
+
+func TestStorage_SaveUpdatesIndex(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "tamo-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	s := NewStorageWithPath(tempDir, filepath.Join(tempDir, DefaultFileName))
+	s.NoBackup = true
+	store := model.NewStore()
+
+	// Test that saving doesn't create an index
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if _, err := s.LoadIndex(); !os.IsNotExist(err) {
+		t.Fatalf("Expected no index before it is built, got %v", err)
+	}
+
+	// Test that saving updates a built index
+	if err := s.SaveIndex(BuildIndex(store)); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+	store.AddTask(model.NewTask("task-1", "Indexed task", "", nil))
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	idx, err := s.LoadIndex()
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	revision, err := s.DataRevision()
+	if err != nil {
+		t.Fatalf("Failed to get data revision: %v", err)
+	}
+	if !idx.Fresh(revision) || len(idx.Entries) != 1 {
+		t.Errorf("Expected the index to be updated by Save, got %v", idx.Entries)
+	}
+
+	// Test that changing the data file by other means makes the index stale
+	data, err := os.ReadFile(s.FilePath)
+	if err != nil {
+		t.Fatalf("Failed to read data file: %v", err)
+	}
+	if err := os.WriteFile(s.FilePath, append(data, '\n'), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	revision, err = s.DataRevision()
+	if err != nil {
+		t.Fatalf("Failed to get data revision: %v", err)
+	}
+	if idx.Fresh(revision) {
+		t.Errorf("Expected the index to be stale after the data file changed")
+	}
+
+	// Test that a broken index is removed by Save
+	if err := os.WriteFile(s.IndexPath(), []byte("{broken"), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if _, err := s.LoadIndex(); err == nil {
+		t.Errorf("Expected error for a broken index, got nil")
+	}
+	if err := s.Save(store); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if _, err := os.Stat(s.IndexPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected the broken index to be removed, got %v", err)
+	}
+}
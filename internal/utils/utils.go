@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"fmt"
 	"time"
+	"unicode"
 )
 
 // GenerateUUID generates a UUID v4 using the standard library
@@ -26,6 +27,27 @@ func GenerateUUID() (string, error) {
 		uuid[10:16]), nil
 }
 
+// IsUUID reports whether s has the shape of a UUID as written by GenerateUUID:
+// 32 hexadecimal digits in groups of 8, 4, 4, 4, and 12 separated by hyphens
+func IsUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // FormatTimeISO8601 formats a time.Time as ISO 8601 string
 func FormatTimeISO8601(t time.Time) string {
 	return t.UTC().Format(time.RFC3339)
@@ -36,6 +58,18 @@ func ParseTimeISO8601(s string) (time.Time, error) {
 	return time.Parse(time.RFC3339, s)
 }
 
+// ParseDate parses a date given on the command line, either as YYYY-MM-DD in local time
+// or as an ISO 8601 timestamp
+func ParseDate(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := ParseTimeISO8601(s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s (expected YYYY-MM-DD or ISO 8601)", s)
+}
+
 // NewCustomTime creates a new CustomTime from a time.Time
 func NewCustomTime(t time.Time) interface{} {
 	// This function is a placeholder for now
@@ -43,3 +77,145 @@ func NewCustomTime(t time.Time) interface{} {
 	// We can't directly import model here due to import cycle
 	return t
 }
+
+// wideRanges lists the rune ranges displayed with a width of two columns
+// (East Asian Wide and Fullwidth characters, and emoji)
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x231A, 0x231B},   // Watch, hourglass
+	{0x2329, 0x232A},   // Angle brackets
+	{0x23E9, 0x23EC},   // Media controls
+	{0x23F0, 0x23F0},   // Alarm clock
+	{0x23F3, 0x23F3},   // Hourglass
+	{0x25FD, 0x25FE},   // Small squares
+	{0x2614, 0x2615},   // Umbrella, hot beverage
+	{0x2648, 0x2653},   // Zodiac
+	{0x267F, 0x267F},   // Wheelchair
+	{0x2693, 0x2693},   // Anchor
+	{0x26A1, 0x26A1},   // High voltage
+	{0x26AA, 0x26AB},   // Circles
+	{0x26BD, 0x26BE},   // Soccer, baseball
+	{0x26C4, 0x26C5},   // Snowman, sun
+	{0x26CE, 0x26CE},   // Ophiuchus
+	{0x26D4, 0x26D4},   // No entry
+	{0x26EA, 0x26EA},   // Church
+	{0x26F2, 0x26F3},   // Fountain, golf
+	{0x26F5, 0x26F5},   // Sailboat
+	{0x26FA, 0x26FA},   // Tent
+	{0x26FD, 0x26FD},   // Fuel pump
+	{0x2705, 0x2705},   // Check mark
+	{0x270A, 0x270B},   // Fists
+	{0x2728, 0x2728},   // Sparkles
+	{0x274C, 0x274C},   // Cross mark
+	{0x274E, 0x274E},   // Cross mark
+	{0x2753, 0x2755},   // Question marks
+	{0x2757, 0x2757},   // Exclamation mark
+	{0x2795, 0x2797},   // Math symbols
+	{0x27B0, 0x27B0},   // Curly loop
+	{0x27BF, 0x27BF},   // Double curly loop
+	{0x2B1B, 0x2B1C},   // Large squares
+	{0x2B50, 0x2B50},   // Star
+	{0x2B55, 0x2B55},   // Circle
+	{0x2E80, 0x303E},   // CJK Radicals, Kangxi, CJK Symbols and Punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, Bopomofo, Hangul Compatibility Jamo, CJK compatibility
+	{0x3400, 0x4DBF},   // CJK Unified Ideographs Extension A
+	{0x4E00, 0x9FFF},   // CJK Unified Ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xA960, 0xA97F},   // Hangul Jamo Extended-A
+	{0xAC00, 0xD7A3},   // Hangul Syllables
+	{0xF900, 0xFAFF},   // CJK Compatibility Ideographs
+	{0xFE10, 0xFE19},   // Vertical forms
+	{0xFE30, 0xFE6F},   // CJK Compatibility Forms, Small Form Variants
+	{0xFF00, 0xFF60},   // Fullwidth Forms
+	{0xFFE0, 0xFFE6},   // Fullwidth signs
+	{0x16FE0, 0x16FE4}, // Ideographic symbols
+	{0x17000, 0x18AFF}, // Tangut
+	{0x1B000, 0x1B16F}, // Kana Supplement and Extended
+	{0x1F004, 0x1F004}, // Mahjong tile
+	{0x1F0CF, 0x1F0CF}, // Playing card
+	{0x1F18E, 0x1F18E}, // AB button
+	{0x1F191, 0x1F19A}, // Squared words
+	{0x1F200, 0x1F251}, // Enclosed ideographic supplement
+	{0x1F300, 0x1F64F}, // Miscellaneous Symbols and Pictographs, Emoticons
+	{0x1F680, 0x1F6FF}, // Transport and Map Symbols
+	{0x1F7E0, 0x1F7EB}, // Colored circles and squares
+	{0x1F90C, 0x1F9FF}, // Supplemental Symbols and Pictographs
+	{0x1FA70, 0x1FAFF}, // Symbols and Pictographs Extended-A
+	{0x20000, 0x2FFFD}, // CJK Unified Ideographs Extensions B-F
+	{0x30000, 0x3FFFD}, // CJK Unified Ideographs Extension G
+}
+
+// RuneWidth returns the number of terminal columns used to display r
+func RuneWidth(r rune) int {
+	// Control characters, combining marks, and zero width characters take no space
+	if r == 0 || unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	if r < 0x1100 {
+		return 1
+	}
+	for _, rng := range wideRanges {
+		if r < rng[0] {
+			break
+		}
+		if r <= rng[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// DisplayWidth returns the number of terminal columns used to display s
+func DisplayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// TruncateToWidth shortens s so that it fits in the given number of terminal columns,
+// ending it with "..." if it was shortened. Multibyte characters are never split.
+func TruncateToWidth(s string, width int) string {
+	if DisplayWidth(s) <= width {
+		return s
+	}
+
+	const ellipsis = "..."
+	limit := width - len(ellipsis)
+	if limit < 0 {
+		limit = 0
+	}
+
+	used := 0
+	for i, r := range s {
+		w := RuneWidth(r)
+		if used+w > limit {
+			return s[:i] + ellipsis
+		}
+		used += w
+	}
+	return s + ellipsis
+}
+
+// EditDistance returns the Levenshtein distance between a and b, counted in runes
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"abc", 3},
+		{"日本語", 6},
+		{"ｱｲｳ", 3},
+		{"🎉a", 3},
+		{"é", 1},
+	}
+
+	for _, tt := range tests {
+		if got := DisplayWidth(tt.input); got != tt.want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	tests := []struct {
+		input string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"abcdefghijkl", 10, "abcdefg..."},
+		{"これは重要な情報です", 10, "これは..."},
+		{"これは重要な情報です", 20, "これは重要な情報です"},
+		{"あいうえおかきくけこ", 11, "あいうえ..."},
+		{"🎉🚀✨🎉🚀✨", 8, "🎉🚀..."},
+	}
+
+	for _, tt := range tests {
+		got := TruncateToWidth(tt.input, tt.width)
+		if got != tt.want {
+			t.Errorf("TruncateToWidth(%q, %d) = %q, want %q", tt.input, tt.width, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("TruncateToWidth(%q, %d) returned invalid UTF-8: %q", tt.input, tt.width, got)
+		}
+		if DisplayWidth(got) > tt.width {
+			t.Errorf("TruncateToWidth(%q, %d) = %q is wider than %d columns", tt.input, tt.width, got, tt.width)
+		}
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	id, err := GenerateUUID()
+	if err != nil {
+		t.Fatalf("Failed to generate UUID: %v", err)
+	}
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{id, true},
+		{"ABCD0001-0000-0000-0000-000000000000", true},
+		{"", false},
+		{"abc", false},
+		{"abcd0001-0000-0000-0000-00000000000g", false},
+		{"abcd0001000000000000000000000000000", false},
+		{"abcd0001-0000-0000-0000_000000000000", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsUUID(tt.input); got != tt.want {
+			t.Errorf("IsUUID(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := ParseDate("2025-01-02")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if date.Year() != 2025 || date.Month() != 1 || date.Day() != 2 || date.Location() != time.Local {
+		t.Errorf("Expected 2025-01-02 in local time, got %v", date)
+	}
+
+	date, err = ParseDate("2025-01-02T03:04:05Z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !date.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected 2025-01-02T03:04:05Z, got %v", date)
+	}
+
+	if _, err := ParseDate("January 2"); err == nil {
+		t.Errorf("Expected error for invalid date, got nil")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"1a2b3c4d", "1a2b3c4d", 0},
+		{"1a2b3c4x", "1a2b3c4d", 1},
+		{"a12b3c4d", "1a2b3c4d", 2},
+		{"日本", "日本語", 1},
+	}
+
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package version
+
+import (
+	"runtime/debug"
+)
+
+// Build information, set when building with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/zishida/tamo/internal/version.Version=v1.2.0
+//	  -X github.com/zishida/tamo/internal/version.Commit=$(git rev-parse HEAD)
+//	  -X github.com/zishida/tamo/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/tamo
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// unknown is shown for build information that is not available
+const unknown = "unknown"
+
+// Info is the build information of the running binary
+type Info struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// Get returns the build information. Values not set with -ldflags are taken from the information
+// Go embeds in the binary: the module version for 'go install ...@version', and the commit and its
+// time for builds in a git checkout.
+func Get() Info {
+	buildInfo, _ := debug.ReadBuildInfo()
+	return resolve(Info{Version: Version, Commit: Commit, Date: Date}, buildInfo)
+}
+
+// resolve fills the values missing from info with the embedded build information, which may be nil
+func resolve(info Info, buildInfo *debug.BuildInfo) Info {
+	if buildInfo != nil {
+		if info.Version == "" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+		modified, embeddedCommit := false, false
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+					embeddedCommit = true
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		// Tell that the commit doesn't match the source if it came from a checkout with changes
+		if modified && embeddedCommit {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+	if info.Date == "" {
+		info.Date = unknown
+	}
+	return info
+}
@@ -0,0 +1,53 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	vcs := &debug.BuildInfo{
+		Main: debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		info      Info
+		buildInfo *debug.BuildInfo
+		want      Info
+	}{
+		{
+			name: "no build information",
+			want: Info{Version: "dev", Commit: "unknown", Date: "unknown"},
+		},
+		{
+			name:      "ldflags take precedence",
+			info:      Info{Version: "v1.2.0", Commit: "abc1234", Date: "2026-10-02"},
+			buildInfo: vcs,
+			want:      Info{Version: "v1.2.0", Commit: "abc1234", Date: "2026-10-02"},
+		},
+		{
+			name:      "git checkout with changes",
+			buildInfo: vcs,
+			want:      Info{Version: "dev", Commit: "0123456789abcdef-dirty", Date: "2026-10-01T12:00:00Z"},
+		},
+		{
+			name:      "go install with a version",
+			buildInfo: &debug.BuildInfo{Main: debug.Module{Version: "v1.3.0"}},
+			want:      Info{Version: "v1.3.0", Commit: "unknown", Date: "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolve(tt.info, tt.buildInfo); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}